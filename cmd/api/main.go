@@ -1,16 +1,37 @@
 package main
 
 import (
+	"crypto/tls"
+	"database/sql"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"runtime"
+	"syscall"
 	"time"
 
+	"duckex-server/internal/clock"
+	"duckex-server/internal/config"
+	"duckex-server/internal/events"
+	"duckex-server/internal/grpcapi"
 	"duckex-server/internal/handlers"
+	"duckex-server/internal/idempotency"
+	"duckex-server/internal/middleware"
 	"duckex-server/internal/models"
+	"duckex-server/internal/router"
+	"duckex-server/internal/service"
+	"duckex-server/internal/tlsutil"
 	"duckex-server/internal/utils"
+	"duckex-server/internal/webhooks"
+	"duckex-server/pkg/duckexpb"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	_ "modernc.org/sqlite"
 )
 
 // 获取系统内存(MB)，简单实现
@@ -24,9 +45,95 @@ func getSystemMemoryMB() int64 {
 }
 
 func main() {
-	// 初始化仓库
-	itemRepo := models.NewInMemoryItemRepository()
-	
+	// 加载配置
+	cfg, err := config.LoadFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// 所有组件共用同一个时间源
+	clk := clock.System()
+
+	// 初始化事件总线，审计日志和事件计数各自独立订阅
+	eventBus := events.NewBus()
+	eventBus.SubscribeFunc(1024, events.AuditLog)
+	eventCounter := events.NewCounter()
+	eventBus.SubscribeFunc(1024, eventCounter.Handle)
+
+	// 初始化 Webhook 投递器
+	webhookOpts := webhooks.DefaultOptions()
+	webhookOpts.DeadLetterFile = cfg.WebhookDeadLetterFile
+	webhookOpts.Clock = clk
+	webhookDispatcher := webhooks.NewDispatcher(cfg.Webhooks, webhookOpts)
+	webhookEvents, _ := eventBus.Subscribe(1024)
+	go webhookDispatcher.Run(webhookEvents)
+	log.Printf("Webhook dispatcher initialized with %d subscriptions", len(cfg.Webhooks))
+
+	// 初始化仓库：配置了数据库或 Redis 时使用对应的仓库，配置了多个分片时使用分片仓库以减少锁竞争；
+	// 交易、群组和退回箱只在配置了数据库或 Redis 时持久化，其余情况保存在内存中
+	var itemRepo models.ItemRepository
+	var tradeRepo models.TradeRepository
+	var groupRepo models.GroupRepository
+	var returnBox models.ReturnBox
+	var redisClient *redis.Client
+	if cfg.Database.Driver != "" {
+		db, err := sql.Open(cfg.Database.Driver, cfg.Database.DSN)
+		if err != nil {
+			log.Fatalf("Failed to open database: %v", err)
+		}
+		// SQLite 同一时间只允许一个写入者，使用单连接避免锁冲突
+		db.SetMaxOpenConns(1)
+		sqlRepo, err := models.NewSQLItemRepository(db, clk)
+		if err != nil {
+			log.Fatalf("Failed to initialize database: %v", err)
+		}
+		itemRepo = sqlRepo
+		if tradeRepo, err = models.NewSQLTradeRepository(db, clk); err != nil {
+			log.Fatalf("Failed to initialize trade repository: %v", err)
+		}
+		if groupRepo, err = models.NewSQLGroupRepository(db, clk); err != nil {
+			log.Fatalf("Failed to initialize group repository: %v", err)
+		}
+		log.Printf("Using %s item repository", cfg.Database.Driver)
+	} else if cfg.Redis.Addr != "" {
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		redisRepo := models.NewRedisItemRepository(redisClient, cfg.Redis.Prefix, clk)
+		// 补写升级前分享的物品的查询索引
+		if err := redisRepo.RebuildIndexes(); err != nil {
+			log.Fatalf("Failed to rebuild redis item indexes: %v", err)
+		}
+		itemRepo = redisRepo
+		tradeRepo = models.NewRedisTradeRepository(redisClient, cfg.Redis.Prefix)
+		groupRepo = models.NewRedisGroupRepository(redisClient, cfg.Redis.Prefix)
+		returnBox = models.NewRedisReturnBox(redisClient, cfg.Redis.Prefix, models.DefaultReturnRetention, clk)
+		log.Printf("Using redis item repository at %s", cfg.Redis.Addr)
+	} else if cfg.ItemShards > 1 {
+		itemRepo = models.NewShardedItemRepository(cfg.ItemShards, clk)
+		log.Printf("Using sharded item repository with %d shards", cfg.ItemShards)
+	} else {
+		itemRepo = models.NewInMemoryItemRepository(clk)
+	}
+	if tradeRepo == nil {
+		tradeRepo = models.NewInMemoryTradeRepository()
+	}
+	if groupRepo == nil {
+		groupRepo = models.NewInMemoryGroupRepository()
+	}
+	if returnBox == nil {
+		returnBox = models.NewInMemoryReturnBox(models.DefaultReturnRetention, clk)
+	}
+	// 过期物品不再直接销毁，而是退回到分享者的退回箱
+	itemRepo.SetExpiredHandler(func(item *models.Item) {
+		if err := returnBox.Add(item); err != nil {
+			log.Printf("Failed to return expired item %s: %v", item.ID, err)
+		}
+		eventBus.Publish(events.NewItemExpired(item, clk.Now()))
+	})
+
 	// 初始化内存监控器，默认设置为可用内存的80%
 	// 设置最大内存为系统内存的80%，如果无法获取则设置为1GB
 	maxMemoryMB := int64(1024) // 默认1GB
@@ -36,58 +143,118 @@ func main() {
 	log.Printf("Memory monitor initialized with max memory: %d MB", maxMemoryMB)
 	memoryMonitor := utils.NewMemoryMonitor(maxMemoryMB)
 
+	// 初始化物品服务，HTTP 和 gRPC 接口共用
+	itemService := service.NewItemService(service.Deps{
+		ItemRepo:          itemRepo,
+		ReturnBox:         returnBox,
+		MemoryMonitor:     memoryMonitor,
+		EventBus:          eventBus,
+		Clock:             clk,
+		Groups:            groupRepo,
+		PlayerTokenSecret: cfg.PlayerTokenSecret,
+		ReservationLease:  time.Duration(cfg.ReservationLeaseSeconds) * time.Second,
+	})
+	tradeService := service.NewTradeService(service.TradeDeps{
+		Trades:        tradeRepo,
+		ItemRepo:      itemRepo,
+		ReturnBox:     returnBox,
+		MemoryMonitor: memoryMonitor,
+		EventBus:      eventBus,
+		Clock:         clk,
+		TradeTTL:      time.Duration(cfg.TradeTTLSeconds) * time.Second,
+	})
+	groupService := service.NewGroupService(service.GroupDeps{
+		Groups:   groupRepo,
+		ItemRepo: itemRepo,
+		Clock:    clk,
+	})
+
 	// 初始化处理器
-	itemHandler := handlers.NewItemHandler(itemRepo, memoryMonitor)
+	itemHandler := handlers.NewItemHandler(itemService, memoryMonitor)
+	returnHandler := handlers.NewReturnHandler(returnBox, cfg.PlayerTokenSecret)
+	tradeHandler := handlers.NewTradeHandler(tradeService, cfg.PlayerTokenSecret)
+	listingHandler := handlers.NewListingHandler(itemService)
+	groupHandler := handlers.NewGroupHandler(groupService, cfg.PlayerTokenSecret)
+	eventHandler := handlers.NewEventHandler(eventBus, cfg.PlayerTokenSecret, clk)
+	webhookHandler := handlers.NewWebhookHandler(webhookDispatcher)
 
 	// 设置Gin模式
 	gin.SetMode(gin.ReleaseMode)
 
-	// 创建Gin引擎
-	r := gin.Default()
+	// 创建Gin引擎，只信任配置的反向代理设置的客户端IP
+	r, err := router.NewEngine(cfg.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to configure trusted proxies: %v", err)
+	}
+
+	// 添加CORS中间件，按配置的来源白名单和路由组策略处理跨域请求
+	r.Use(middleware.CORS(cfg.CORS.CORSPolicy, cfg.CORS.Groups))
 
-	// 添加CORS中间件
-	r.Use(func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+	// 按路由限流，防止脚本刷接口；放在CORS之后，被拒绝的响应也带有CORS头
+	rateLimits := middleware.NewRateLimits(cfg.RateLimits, clk)
+	r.Use(rateLimits.Handler())
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
+	// 幂等键：重试的 POST 请求重放首次响应而不是重复执行；放在限流之后，被限流的请求不占用幂等键。
+	// 使用 Redis 时幂等键在实例间共享，重试落到任意实例都能重放首次响应
+	var idempotencyStore idempotency.Store
+	if cfg.IdempotencyTTLSeconds > 0 {
+		idempotencyTTL := time.Duration(cfg.IdempotencyTTLSeconds) * time.Second
+		if redisClient != nil {
+			idempotencyStore = idempotency.NewRedisStore(redisClient, cfg.Redis.Prefix, idempotencyTTL, clk)
+		} else {
+			idempotencyStore = idempotency.NewInMemoryStore(idempotencyTTL, clk)
 		}
+		r.Use(middleware.Idempotency(idempotencyStore))
+	}
 
-		c.Next()
+	// 运维管理处理器需要查看限流配额，在限流器创建后初始化
+	adminHandler := handlers.NewAdminHandler(handlers.AdminDeps{
+		ItemRepo:      itemRepo,
+		Items:         itemService,
+		EventCounter:  eventCounter,
+		MemoryMonitor: memoryMonitor,
+		RateLimits:    rateLimits,
+		Idempotency:   idempotencyStore,
+		Clock:         clk,
 	})
 
-	// 健康检查端点
-	r.GET("/health", func(c *gin.Context) {
-		// GetAll()方法现在只会返回未过期的物品
-		pendingItems := itemRepo.GetAll()
-		
-		c.JSON(http.StatusOK, gin.H{
-			"status":          "ok",
-			"message":         "DuckEx Server is quacking!",
-			"timestamp":       models.GetCurrentTime().Format(time.RFC3339),
-			"pending_items_count": len(pendingItems),
-			"pending_items":   pendingItems,
-		})
+	// 注册路由
+	router.Register(r, router.Handlers{
+		Health:        handlers.NewHealthHandler(itemRepo, eventCounter, rateLimits, clk),
+		Item:          itemHandler,
+		Return:        returnHandler,
+		Trade:         tradeHandler,
+		Listing:       listingHandler,
+		Group:         groupHandler,
+		Event:         eventHandler,
+		Webhook:       webhookHandler,
+		Admin:         adminHandler,
+		MemoryMonitor: memoryMonitor,
+		AdminToken:    cfg.AdminToken,
 	})
 
-	// API路由组
-	api := r.Group("/api/v1")
-	{
-		// 分享物品
-		api.POST("/items/share", itemHandler.ShareItem)
-		// 领取物品
-		api.POST("/items/claim", itemHandler.ClaimItem)
-		// 内存状态
-		api.GET("/memory", func(c *gin.Context) {
-			c.JSON(http.StatusOK, memoryMonitor.GetStatus())
-		})
-	}
+	// 启动过期处理任务，过期索引使每次检查只触及已到期的物品，可以近实时运行；
+	// 同时将预留到期未确认的物品恢复为可领取，并结束超时未被接受的交易
+	go func() {
+		ticker := time.NewTicker(1 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := itemRepo.DeleteExpired(); err != nil {
+					log.Printf("Error during expiry processing: %v", err)
+				}
+				if _, err := itemService.ReleaseExpiredReservations(); err != nil {
+					log.Printf("Error releasing expired reservations: %v", err)
+				}
+				if _, err := tradeService.ExpireTrades(); err != nil {
+					log.Printf("Error expiring trades: %v", err)
+				}
+			}
+		}
+	}()
 
-	// 启动定期清理任务（作为额外保障，主要清理仍可能存在的过期物品）
+	// 启动定期清理任务，清理超过保留期的退回物品和交易记录
 	go func() {
 		ticker := time.NewTicker(1 * time.Hour)
 		defer ticker.Stop()
@@ -95,13 +262,38 @@ func main() {
 			select {
 			case <-ticker.C:
 				log.Printf("Running scheduled cleanup task")
-				if err := itemRepo.DeleteExpired(); err != nil {
-					log.Printf("Error during scheduled cleanup: %v", err)
+				if err := returnBox.DeleteExpired(); err != nil {
+					log.Printf("Error during returns cleanup: %v", err)
+				}
+				if _, err := tradeService.DeleteClosed(models.DefaultReturnRetention); err != nil {
+					log.Printf("Error during trades cleanup: %v", err)
 				}
 			}
 		}
 	}()
-	
+
+	// 定期清理闲置的限流令牌桶
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			rateLimits.Cleanup()
+		}
+	}()
+
+	// 定期清理过期的幂等响应
+	if idempotencyStore != nil {
+		go func() {
+			ticker := time.NewTicker(1 * time.Minute)
+			defer ticker.Stop()
+			for range ticker.C {
+				if err := idempotencyStore.Cleanup(); err != nil {
+					log.Printf("Failed to clean up idempotency keys: %v", err)
+				}
+			}
+		}()
+	}
+
 	// 启动内存监控goroutine
 	go func() {
 		ticker := time.NewTicker(30 * time.Second)
@@ -112,10 +304,10 @@ func main() {
 				memoryMonitor.UpdateStatus()
 				status := memoryMonitor.GetStatus()
 				if status["share_disabled"].(bool) {
-					log.Printf("WARNING: Memory usage high (%.1f%%), share functionality temporarily disabled", 
+					log.Printf("WARNING: Memory usage high (%.1f%%), share functionality temporarily disabled",
 						status["usage_percentage"].(float64)*100)
 				} else {
-					log.Printf("Memory usage: %.1f%% of %d MB", 
+					log.Printf("Memory usage: %.1f%% of %d MB",
 						status["usage_percentage"].(float64)*100,
 						status["max_memory_mb"].(int64))
 				}
@@ -124,15 +316,103 @@ func main() {
 	}()
 
 	// 启动服务器
-	serverAddr := ":8080"
+	serverAddr := cfg.Addr
+	scheme := "http"
+	if cfg.TLS.Enabled() {
+		scheme = "https"
+	}
 	log.Printf("DuckEx Server starting on %s", serverAddr)
-	log.Printf("Health check: http://localhost%s/health", serverAddr)
+	log.Printf("Health check: %s://localhost%s/health", scheme, serverAddr)
 	log.Printf("API endpoints:")
-	log.Printf("  POST http://localhost%s/api/v1/items/share - Share an item", serverAddr)
-	log.Printf("  POST http://localhost%s/api/v1/items/claim - Claim an item", serverAddr)
-	log.Printf("  GET  http://localhost%s/api/v1/memory - Check memory status", serverAddr)
+	log.Printf("  POST %s://localhost%s/api/v1/items/share - Share an item", scheme, serverAddr)
+	log.Printf("  POST %s://localhost%s/api/v1/items/claim - Claim an item", scheme, serverAddr)
+	log.Printf("  POST %s://localhost%s/api/v1/items/reserve - Reserve an item (confirm or release it afterwards)", scheme, serverAddr)
+	log.Printf("  GET  %s://localhost%s/api/v1/returns - List returned items", scheme, serverAddr)
+	log.Printf("  POST %s://localhost%s/api/v1/returns/collect - Collect returned items", scheme, serverAddr)
+	log.Printf("  GET  %s://localhost%s/api/v1/listings - Browse public listings (claim via /api/v1/listings/:id/claim)", scheme, serverAddr)
+	log.Printf("  POST %s://localhost%s/api/v1/trades - Open a trade (accept or cancel it via /api/v1/trades/:id)", scheme, serverAddr)
+	log.Printf("  POST %s://localhost%s/api/v1/groups - Create a group (invite, join, leave and list items via /api/v1/groups/:id)", scheme, serverAddr)
+	log.Printf("  GET  %s://localhost%s/api/v1/events - Stream item events (SSE, player token required)", scheme, serverAddr)
+	log.Printf("  GET  %s://localhost%s/api/v1/memory - Check memory status", scheme, serverAddr)
+	log.Printf("  GET  %s://localhost%s/api/v1/admin/webhooks/deliveries - List webhook deliveries", scheme, serverAddr)
+	log.Printf("  GET  %s://localhost%s/api/v1/admin/items - List items (admin)", scheme, serverAddr)
+	log.Printf("API documentation: %s://localhost%s/openapi.json", scheme, serverAddr)
 
-	if err := r.Run(serverAddr); err != nil {
+	// HTTPS：证书文件变化或收到 SIGHUP 时重新加载，无需重启
+	var tlsConfig *tls.Config
+	if cfg.TLS.Enabled() {
+		reloader, err := tlsutil.NewCertReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			log.Fatalf("Failed to load TLS certificate: %v", err)
+		}
+		if cfg.TLS.ReloadIntervalSeconds > 0 {
+			go reloader.Watch(time.Duration(cfg.TLS.ReloadIntervalSeconds)*time.Second, nil)
+		}
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := reloader.Reload(); err != nil {
+					log.Printf("Failed to reload TLS certificate: %v", err)
+				} else {
+					log.Printf("TLS certificate reloaded on SIGHUP")
+				}
+			}
+		}()
+		minVersion, _ := tlsutil.ParseVersion(cfg.TLS.MinVersion)
+		tlsConfig = tlsutil.NewServerConfig(reloader, minVersion)
+	}
+
+	// gRPC 服务在单独的端口监听，与 HTTP 接口共用物品服务
+	if cfg.GRPCAddr != "" {
+		// 分享与 HTTP 接口共用限流令牌桶
+		opts := []grpc.ServerOption{grpc.UnaryInterceptor(grpcapi.RateLimitInterceptor(rateLimits, map[string]string{
+			duckexpb.DuckEx_Share_FullMethodName: config.ShareRoute,
+		}))}
+		if tlsConfig != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+		}
+		grpcServer := grpc.NewServer(opts...)
+		grpcapi.NewServer(itemService, eventBus, cfg.AdminToken, cfg.PlayerTokenSecret).Register(grpcServer)
+		listener, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", cfg.GRPCAddr, err)
+		}
+		go func() {
+			log.Printf("gRPC server listening on %s", cfg.GRPCAddr)
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatalf("Failed to start gRPC server: %v", err)
+			}
+		}()
+	}
+
+	if tlsConfig == nil {
+		if err := r.Run(serverAddr); err != nil {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+		return
+	}
+
+	// 可选的 HTTP 跳转监听
+	if cfg.TLS.RedirectAddr != "" {
+		_, httpsPort, err := net.SplitHostPort(serverAddr)
+		if err != nil {
+			log.Fatalf("Invalid addr %q: %v", serverAddr, err)
+		}
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", cfg.TLS.RedirectAddr)
+			if err := http.ListenAndServe(cfg.TLS.RedirectAddr, tlsutil.RedirectHandler(httpsPort)); err != nil {
+				log.Fatalf("Failed to start HTTP redirect listener: %v", err)
+			}
+		}()
+	}
+
+	server := &http.Server{
+		Addr:      serverAddr,
+		Handler:   r,
+		TLSConfig: tlsConfig,
+	}
+	if err := server.ListenAndServeTLS("", ""); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,324 @@
+// duckex-tool 离线检查和修复工具，直接读写快照、数据库和日志文件，无需启动服务
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/snapshot"
+
+	_ "modernc.org/sqlite"
+)
+
+const usage = `Usage: duckex-tool <command> [flags] FILE
+
+Commands:
+  validate [-format F] FILE               检查文件，发现问题时以状态码1退出
+  list [-format F] [-expired] FILE        列出物品，默认不含已过期物品
+  purge [-format F] [-o OUT] FILE         移除已过期的物品
+  repair [-format F] [-o OUT] FILE        丢弃无法解析、字段错误和取件码重复的记录
+  convert [-from F] [-to F] IN OUT        转换存储格式，OUT 必须不存在
+
+Formats:
+  json  JSON快照（.json）
+  sql   SQLite 数据库（.db、.sqlite、.sqlite3）
+  log   每行一个物品的JSON日志（.log、.jsonl）
+
+未指定格式时按扩展名推断。purge 和 repair 未指定 -o 时原地改写文件。
+`
+
+// 检查发现问题时的退出码
+var errProblemsFound = errors.New("problems found")
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	switch command, args := os.Args[1], os.Args[2:]; command {
+	case "validate":
+		err = runValidate(args)
+	case "list":
+		err = runList(args)
+	case "purge":
+		err = runPurge(args)
+	case "repair":
+		err = runRepair(args)
+	case "convert":
+		err = runConvert(args)
+	case "-h", "-help", "--help", "help":
+		fmt.Fprint(os.Stdout, usage)
+		return
+	default:
+		err = fmt.Errorf("unknown command %q", command)
+	}
+	if errors.Is(err, errProblemsFound) {
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "duckex-tool:", err)
+		os.Exit(1)
+	}
+}
+
+// 检查文件
+func runValidate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	format := fs.String("format", "", "文件格式")
+	fs.Parse(args)
+	path, err := singleArg("validate", fs.Args())
+	if err != nil {
+		return err
+	}
+	contents, _, err := load(path, *format)
+	if err != nil {
+		return err
+	}
+
+	report := snapshot.Inspect(contents, time.Now())
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	if !report.OK() {
+		return errProblemsFound
+	}
+	return nil
+}
+
+// 列出物品
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	format := fs.String("format", "", "文件格式")
+	expired := fs.Bool("expired", false, "包含已过期的物品")
+	fs.Parse(args)
+	path, err := singleArg("list", fs.Args())
+	if err != nil {
+		return err
+	}
+	contents, _, err := load(path, *format)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RECORD\tPICKUP_CODE\tSHARER\tNAME\tNUM\tEXPIRES_AT\tSTATUS")
+	for _, record := range contents.Records {
+		if record.Item == nil {
+			fmt.Fprintf(w, "%d\t-\t-\t-\t-\t-\tcorrupt: %v\n", record.Index, record.Err)
+			continue
+		}
+		item := record.Item
+		status := "pending"
+		switch {
+		case now.After(item.ExpiresAt):
+			if !*expired {
+				continue
+			}
+			status = "expired"
+		case item.IsClaimed:
+			status = "claimed by " + item.ClaimerID
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n", record.Index, item.PickupCode, item.SharerID,
+			item.Name, item.Num, item.ExpiresAt.Format(time.RFC3339), status)
+	}
+	return w.Flush()
+}
+
+// 移除已过期的物品
+func runPurge(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	format := fs.String("format", "", "文件格式")
+	output := fs.String("o", "", "输出文件，默认原地改写")
+	fs.Parse(args)
+	path, err := singleArg("purge", fs.Args())
+	if err != nil {
+		return err
+	}
+	contents, f, err := load(path, *format)
+	if err != nil {
+		return err
+	}
+	if report := snapshot.Inspect(contents, time.Now()); !report.OK() {
+		return fmt.Errorf("%s has %d problems, run validate for details and repair first", path, len(report.Issues))
+	}
+
+	items, purged := snapshot.Purge(contents.Items(), time.Now())
+	if err := save(outputPath(path, *output), f, items); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "purged %d expired items, %d remaining\n", purged, len(items))
+	return nil
+}
+
+// 丢弃损坏的记录后重写文件
+func runRepair(args []string) error {
+	fs := flag.NewFlagSet("repair", flag.ExitOnError)
+	format := fs.String("format", "", "文件格式")
+	output := fs.String("o", "", "输出文件，默认原地改写")
+	fs.Parse(args)
+	path, err := singleArg("repair", fs.Args())
+	if err != nil {
+		return err
+	}
+	contents, f, err := load(path, *format)
+	if err != nil {
+		return err
+	}
+
+	items, dropped := snapshot.Repair(contents)
+	for _, issue := range dropped {
+		fmt.Fprintf(os.Stderr, "dropped record %d: %s\n", issue.Record, issue.Problem)
+	}
+	if contents.Err != nil {
+		fmt.Fprintf(os.Stderr, "discarded unreadable data after last record: %v\n", contents.Err)
+	}
+	if err := save(outputPath(path, *output), f, items); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "kept %d items, dropped %d records\n", len(items), len(dropped))
+	return nil
+}
+
+// 转换存储格式
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "", "输入格式")
+	to := fs.String("to", "", "输出格式")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("convert requires IN and OUT")
+	}
+	in, out := fs.Arg(0), fs.Arg(1)
+	if _, err := os.Stat(out); err == nil {
+		return fmt.Errorf("%s already exists", out)
+	}
+	contents, _, err := load(in, *from)
+	if err != nil {
+		return err
+	}
+	if report := snapshot.Inspect(contents, time.Now()); !report.OK() {
+		return fmt.Errorf("%s has %d problems, run validate for details and repair first", in, len(report.Issues))
+	}
+	outFormat, err := resolveFormat(out, *to)
+	if err != nil {
+		return err
+	}
+	items := contents.Items()
+	if err := save(out, outFormat, items); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "converted %d items to %s\n", len(items), out)
+	return nil
+}
+
+// 检查子命令只有一个位置参数
+func singleArg(command string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s requires exactly one FILE", command)
+	}
+	return args[0], nil
+}
+
+// 未指定输出文件时原地改写
+func outputPath(path, output string) string {
+	if output == "" {
+		return path
+	}
+	return output
+}
+
+// 确定文件格式，未指定时按扩展名推断
+func resolveFormat(path, name string) (snapshot.Format, error) {
+	if name != "" {
+		return snapshot.ParseFormat(name)
+	}
+	return snapshot.DetectFormat(path)
+}
+
+// 逐条读取文件
+func load(path, formatName string) (*snapshot.Contents, snapshot.Format, error) {
+	format, err := resolveFormat(path, formatName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if format == snapshot.FormatSQL {
+		// 数据库文件不存在时 sqlite 会新建空文件，先检查
+		if _, err := os.Stat(path); err != nil {
+			return nil, "", err
+		}
+		db, err := sql.Open("sqlite", path)
+		if err != nil {
+			return nil, "", err
+		}
+		defer db.Close()
+		contents, err := snapshot.DecodeSQL(db)
+		return contents, format, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	var contents *snapshot.Contents
+	if format == snapshot.FormatJSON {
+		contents, err = snapshot.DecodeJSON(f)
+	} else {
+		contents, err = snapshot.DecodeLog(f)
+	}
+	return contents, format, err
+}
+
+// 写出物品：先写到同目录的临时文件，成功后再替换目标文件，失败时原文件不受影响
+func save(path string, format snapshot.Format, items []*models.Item) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	switch format {
+	case snapshot.FormatSQL:
+		tmp.Close()
+		err = saveSQL(tmpPath, items)
+	case snapshot.FormatJSON:
+		err = snapshot.Write(tmp, snapshot.New(items, time.Now()))
+	default:
+		err = snapshot.EncodeLog(tmp, items)
+	}
+	if format != snapshot.FormatSQL {
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// 写入新的 SQLite 数据库文件
+func saveSQL(path string, items []*models.Item) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	return snapshot.EncodeSQL(db, items)
+}
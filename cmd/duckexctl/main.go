@@ -0,0 +1,252 @@
+// duckexctl 运维命令行工具，通过 HTTP API（含管理接口）操作运行中的服务
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"duckex-server/internal/snapshot"
+	"duckex-server/pkg/client"
+)
+
+const usage = `Usage: duckexctl [-server URL] [-token TOKEN] <command> [arguments]
+
+Commands:
+  share -sharer ID -name NAME -desc TEXT -type-id N [-num N] [-durability F]
+                          分享物品并打印取件码
+  claim -claimer ID CODE  领取物品
+  get CODE                按取件码查看物品（不领取）
+  cancel CODE             取消分享，物品退回到分享者的退回箱
+  list [-sharer ID]       列出未过期的物品
+  snapshot dump [-o FILE] 导出快照，默认写到标准输出
+  snapshot restore FILE   从快照文件恢复物品
+  status                  查看内存、限流配额和事件统计
+  tail [-type TYPE]       持续打印事件，Ctrl-C 退出
+
+Environment:
+  DUCKEX_SERVER       服务地址，默认 http://localhost:8080
+  DUCKEX_ADMIN_TOKEN  管理令牌
+`
+
+func main() {
+	global := flag.NewFlagSet("duckexctl", flag.ExitOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	server := global.String("server", envOr("DUCKEX_SERVER", "http://localhost:8080"), "服务地址")
+	token := global.String("token", os.Getenv("DUCKEX_ADMIN_TOKEN"), "管理令牌")
+	global.Parse(os.Args[1:])
+
+	if global.NArg() == 0 {
+		global.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := client.New(*server, client.WithAdminToken(*token))
+	if err := run(ctx, c, os.Stdout, global.Arg(0), global.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "duckexctl:", err)
+		os.Exit(1)
+	}
+}
+
+// 执行子命令，结果写到 stdout
+func run(ctx context.Context, c *client.Client, stdout io.Writer, command string, args []string) error {
+	switch command {
+	case "share":
+		return runShare(ctx, c, stdout, args)
+	case "claim":
+		return runClaim(ctx, c, stdout, args)
+	case "get":
+		code, err := singleArg("get", args)
+		if err != nil {
+			return err
+		}
+		item, err := c.LookupItem(ctx, code)
+		if err != nil {
+			return err
+		}
+		return printJSON(stdout, item)
+	case "cancel":
+		code, err := singleArg("cancel", args)
+		if err != nil {
+			return err
+		}
+		item, err := c.CancelItem(ctx, code)
+		if err != nil {
+			return err
+		}
+		return printJSON(stdout, item)
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ExitOnError)
+		sharerID := fs.String("sharer", "", "只列出该分享者的物品")
+		fs.Parse(args)
+		resp, err := c.ListItems(ctx, *sharerID)
+		if err != nil {
+			return err
+		}
+		return printJSON(stdout, resp.Items)
+	case "snapshot":
+		return runSnapshot(ctx, c, stdout, args)
+	case "status":
+		status, err := c.AdminStatus(ctx)
+		if err != nil {
+			return err
+		}
+		return printJSON(stdout, status)
+	case "tail":
+		fs := flag.NewFlagSet("tail", flag.ExitOnError)
+		eventType := fs.String("type", "", "只打印该类型的事件")
+		fs.Parse(args)
+		err := c.TailEvents(ctx, *eventType, func(event client.Event) error {
+			if event.Name == "ping" {
+				return nil
+			}
+			_, err := fmt.Fprintf(stdout, "%s %s\n", event.Name, event.Data)
+			return err
+		})
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown command %q, run duckexctl -h for usage", command)
+	}
+}
+
+// 分享物品
+func runShare(ctx context.Context, c *client.Client, stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("share", flag.ExitOnError)
+	sharerID := fs.String("sharer", "", "分享者ID")
+	name := fs.String("name", "", "物品名称")
+	description := fs.String("desc", "", "物品描述")
+	typeID := fs.Int("type-id", 0, "物品类型ID")
+	num := fs.Int("num", 1, "数量")
+	durability := fs.Float64("durability", 1, "耐久度")
+	fs.Parse(args)
+
+	resp, err := c.ShareItem(ctx, client.ShareItemRequest{
+		Name:        *name,
+		Description: *description,
+		TypeID:      *typeID,
+		Num:         *num,
+		Durability:  *durability,
+		SharerID:    *sharerID,
+	})
+	if err != nil {
+		return err
+	}
+	return printJSON(stdout, resp)
+}
+
+// 领取物品
+func runClaim(ctx context.Context, c *client.Client, stdout io.Writer, args []string) error {
+	fs := flag.NewFlagSet("claim", flag.ExitOnError)
+	claimerID := fs.String("claimer", "", "领取者ID")
+	fs.Parse(args)
+	code, err := singleArg("claim", fs.Args())
+	if err != nil {
+		return err
+	}
+	resp, err := c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: code, ClaimerID: *claimerID})
+	if err != nil {
+		return err
+	}
+	return printJSON(stdout, resp)
+}
+
+// 导出或恢复快照
+func runSnapshot(ctx context.Context, c *client.Client, stdout io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("snapshot requires dump or restore")
+	}
+	switch args[0] {
+	case "dump":
+		fs := flag.NewFlagSet("snapshot dump", flag.ExitOnError)
+		output := fs.String("o", "", "输出文件，默认标准输出")
+		fs.Parse(args[1:])
+		s, err := c.DumpSnapshot(ctx)
+		if err != nil {
+			return err
+		}
+		if *output == "" {
+			return printJSON(stdout, s)
+		}
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		if err := printJSON(f, s); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "wrote %d items to %s\n", len(s.Items), *output)
+		return nil
+	case "restore":
+		path, err := singleArg("snapshot restore", args[1:])
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s, err := readSnapshot(f)
+		if err != nil {
+			return err
+		}
+		result, err := c.RestoreSnapshot(ctx, s)
+		if err != nil {
+			return err
+		}
+		return printJSON(stdout, result)
+	default:
+		return fmt.Errorf("unknown snapshot command %q", args[0])
+	}
+}
+
+// 读取快照文件，不支持的版本返回错误
+func readSnapshot(r io.Reader) (*client.Snapshot, error) {
+	var s client.Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if s.Version != snapshot.FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	return &s, nil
+}
+
+// 检查子命令只有一个位置参数
+func singleArg(command string, args []string) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("%s requires exactly one argument", command)
+	}
+	return args[0], nil
+}
+
+// 以缩进的JSON打印
+func printJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// 读取环境变量，未设置时使用默认值
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
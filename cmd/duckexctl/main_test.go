@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"duckex-server/internal/config"
+	"duckex-server/internal/events"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+	"duckex-server/internal/snapshot"
+	"duckex-server/pkg/client"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAdminToken = "admin-token"
+
+// 启动只注册 duckexctl 所用路由的测试服务，返回连接它的客户端
+func newTestClient(t *testing.T) (*client.Client, models.ItemRepository) {
+	gin.SetMode(gin.TestMode)
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	items := service.NewItemService(service.Deps{
+		ItemRepo:  itemRepo,
+		ReturnBox: models.NewInMemoryReturnBox(0, nil),
+		EventBus:  events.NewBus(),
+	})
+	itemHandler := handlers.NewItemHandler(items, nil)
+	adminHandler := handlers.NewAdminHandler(handlers.AdminDeps{ItemRepo: itemRepo, Items: items})
+
+	r := gin.New()
+	r.POST("/api/v1/items/share", itemHandler.ShareItem)
+	r.POST("/api/v1/items/claim", itemHandler.ClaimItem)
+	admin := r.Group(config.AdminRouteGroup, middleware.AdminAuth(testAdminToken))
+	{
+		admin.GET("/items", adminHandler.ListItems)
+		admin.GET("/items/:code", adminHandler.GetItem)
+		admin.DELETE("/items/:code", adminHandler.CancelItem)
+		admin.GET("/snapshot", adminHandler.DumpSnapshot)
+		admin.POST("/snapshot", adminHandler.RestoreSnapshot)
+	}
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return client.New(server.URL, client.WithAdminToken(testAdminToken)), itemRepo
+}
+
+// 执行子命令并将输出解析到 v
+func runJSON(t *testing.T, c *client.Client, v interface{}, command string, args ...string) {
+	var stdout bytes.Buffer
+	require.NoError(t, run(context.Background(), c, &stdout, command, args))
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), v))
+}
+
+func TestShareGetAndClaim(t *testing.T) {
+	c, _ := newTestClient(t)
+
+	var shared client.ShareItemResponse
+	runJSON(t, c, &shared, "share", "-sharer", "player1", "-name", "Golden Duck", "-desc", "Shiny", "-type-id", "1001")
+	require.NotEmpty(t, shared.PickupCode)
+
+	var item models.Item
+	runJSON(t, c, &item, "get", shared.PickupCode)
+	assert.Equal(t, "Golden Duck", item.Name)
+	assert.Equal(t, "player1", item.SharerID)
+
+	var listed []*models.Item
+	runJSON(t, c, &listed, "list", "-sharer", "player1")
+	require.Len(t, listed, 1)
+	runJSON(t, c, &listed, "list", "-sharer", "player2")
+	assert.Empty(t, listed)
+
+	var claimed client.ClaimItemResponse
+	runJSON(t, c, &claimed, "claim", "-claimer", "player2", shared.PickupCode)
+	assert.Equal(t, "Golden Duck", claimed.Item.Name)
+
+	// 领取后取件码失效
+	err := run(context.Background(), c, &bytes.Buffer{}, "get", []string{shared.PickupCode})
+	assert.Error(t, err)
+}
+
+func TestSnapshotDumpAndRestore(t *testing.T) {
+	source, sourceRepo := newTestClient(t)
+	for _, code := range []string{"111111", "222222"} {
+		require.NoError(t, sourceRepo.Create(&models.Item{
+			ID:         "item-" + code,
+			Name:       "Golden Duck",
+			Num:        1,
+			SharerID:   "player1",
+			PickupCode: code,
+			ExpiresAt:  time.Now().Add(time.Hour),
+		}))
+	}
+
+	path := filepath.Join(t.TempDir(), "items.json")
+	require.NoError(t, run(context.Background(), source, &bytes.Buffer{}, "snapshot", []string{"dump", "-o", path}))
+
+	target, targetRepo := newTestClient(t)
+	var result snapshot.RestoreResult
+	runJSON(t, target, &result, "snapshot", "restore", path)
+	assert.Equal(t, snapshot.RestoreResult{Restored: 2}, result)
+	assert.Len(t, targetRepo.GetAll(), 2)
+
+	// 缺少取件码或分享者的物品被拒绝，不会写入仓库
+	bad := snapshot.New([]*models.Item{
+		{ID: "item-333333", SharerID: "player1", ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "item-444444", PickupCode: "444444", ExpiresAt: time.Now().Add(time.Hour)},
+	}, time.Now())
+	badPath := filepath.Join(t.TempDir(), "bad.json")
+	f, err := os.Create(badPath)
+	require.NoError(t, err)
+	require.NoError(t, snapshot.Write(f, bad))
+	require.NoError(t, f.Close())
+	runJSON(t, target, &result, "snapshot", "restore", badPath)
+	assert.Equal(t, snapshot.RestoreResult{Rejected: 2}, result)
+	assert.Len(t, targetRepo.GetAll(), 2)
+}
+
+func TestCancel(t *testing.T) {
+	c, itemRepo := newTestClient(t)
+	require.NoError(t, itemRepo.Create(&models.Item{
+		ID:         "item-111111",
+		Num:        1,
+		SharerID:   "player1",
+		PickupCode: "111111",
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}))
+
+	var item models.Item
+	runJSON(t, c, &item, "cancel", "111111")
+	assert.Equal(t, "item-111111", item.ID)
+	remaining, _ := itemRepo.GetByPickupCode("111111")
+	assert.Nil(t, remaining)
+}
+
+func TestUsageErrors(t *testing.T) {
+	c, _ := newTestClient(t)
+	cases := []struct {
+		command string
+		args    []string
+		want    string
+	}{
+		{"unknown", nil, `unknown command "unknown"`},
+		{"get", nil, "get requires exactly one argument"},
+		{"claim", []string{"-claimer", "player2", "111111", "222222"}, "claim requires exactly one argument"},
+		{"snapshot", nil, "snapshot requires dump or restore"},
+		{"snapshot", []string{"load"}, `unknown snapshot command "load"`},
+		{"snapshot", []string{"restore", filepath.Join(t.TempDir(), "missing.json")}, "no such file"},
+	}
+	for _, tc := range cases {
+		err := run(context.Background(), c, &bytes.Buffer{}, tc.command, tc.args)
+		require.Error(t, err, tc.command)
+		assert.Contains(t, err.Error(), tc.want)
+	}
+}
@@ -0,0 +1,101 @@
+// Package clock 提供可注入的时间源，便于测试中精确控制时间
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时间源接口
+type Clock interface {
+	Now() time.Time
+	// After 经过 d 后向返回的通道发送当时的时间
+	After(d time.Duration) <-chan time.Time
+}
+
+// 使用系统时间的实现
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// System 返回使用系统时间的时间源
+func System() Clock {
+	return systemClock{}
+}
+
+// Fake 手动控制的时间源，时间只在调用 Set 或 Advance 时变化
+type Fake struct {
+	now     time.Time
+	waiters []fakeWaiter
+	mutex   sync.RWMutex
+}
+
+// 等待时间推进到 deadline 的 After 调用
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFake 创建从 start 开始的手动时间源
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now 返回当前时间
+func (f *Fake) Now() time.Time {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return f.now
+}
+
+// Set 将时间设置为 t
+func (f *Fake) Set(t time.Time) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = t
+	f.fire()
+}
+
+// Advance 将时间向前推进 d，返回推进后的时间
+func (f *Fake) Advance(d time.Duration) time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.now = f.now.Add(d)
+	f.fire()
+	return f.now
+}
+
+// After 返回在时间被推进到 d 之后时收到当时时间的通道，d 不为正时立即发送
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	ch := make(chan time.Time, 1)
+	if d <= 0 {
+		ch <- f.now
+		return ch
+	}
+	f.waiters = append(f.waiters, fakeWaiter{deadline: f.now.Add(d), ch: ch})
+	return ch
+}
+
+// Waiters 返回尚未到期的 After 调用数，测试中用于确认被测代码已经开始等待
+func (f *Fake) Waiters() int {
+	f.mutex.RLock()
+	defer f.mutex.RUnlock()
+	return len(f.waiters)
+}
+
+// 唤醒已到期的等待，调用方需持有写锁
+func (f *Fake) fire() {
+	pending := f.waiters[:0]
+	for _, w := range f.waiters {
+		if w.deadline.After(f.now) {
+			pending = append(pending, w)
+			continue
+		}
+		w.ch <- f.now
+	}
+	f.waiters = pending
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	assert.Equal(t, start, clk.Now())
+
+	// 时间只在手动推进时变化
+	assert.Equal(t, start.Add(time.Hour), clk.Advance(time.Hour))
+	assert.Equal(t, start.Add(time.Hour), clk.Now())
+
+	clk.Set(start)
+	assert.Equal(t, start, clk.Now())
+}
+
+func TestSystemClock(t *testing.T) {
+	before := time.Now()
+	now := clock.System().Now()
+	assert.False(t, now.Before(before))
+}
+
+func TestFakeClockAfter(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+
+	ch := clk.After(time.Minute)
+	assert.Equal(t, 1, clk.Waiters())
+	clk.Advance(59 * time.Second)
+	select {
+	case <-ch:
+		t.Fatal("fired before the deadline")
+	default:
+	}
+
+	// 推进到期限时收到推进后的时间
+	clk.Advance(time.Second)
+	select {
+	case now := <-ch:
+		assert.Equal(t, start.Add(time.Minute), now)
+	default:
+		t.Fatal("did not fire at the deadline")
+	}
+	assert.Zero(t, clk.Waiters())
+
+	// Set 同样会唤醒到期的等待，非正的等待时间立即到期
+	ch = clk.After(time.Hour)
+	clk.Set(start.Add(2 * time.Hour))
+	assert.Equal(t, start.Add(2*time.Hour), <-ch)
+	assert.Equal(t, start.Add(2*time.Hour), <-clk.After(0))
+}
+
+func TestSystemClockAfter(t *testing.T) {
+	before := time.Now()
+	fired := <-clock.System().After(time.Millisecond)
+	assert.False(t, fired.Before(before.Add(time.Millisecond)))
+}
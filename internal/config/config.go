@@ -0,0 +1,250 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/ratelimit"
+	"duckex-server/internal/tlsutil"
+	"duckex-server/internal/webhooks"
+)
+
+// EnvConfigPath 指定配置文件路径的环境变量
+const EnvConfigPath = "DUCKEX_CONFIG"
+
+// DatabaseConfig SQL数据库配置
+type DatabaseConfig struct {
+	Driver string `json:"driver"` // 目前支持 "sqlite"
+	DSN    string `json:"dsn"`
+}
+
+// RedisConfig Redis 配置
+type RedisConfig struct {
+	Addr     string `json:"addr"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+	Prefix   string `json:"prefix"` // 键前缀，默认为 "duckex:"
+}
+
+// CORSConfig 跨域配置，默认策略的字段直接写在 cors 下
+type CORSConfig struct {
+	middleware.CORSPolicy
+	// 按路由组路径前缀覆盖默认策略，组策略整体替换默认策略
+	Groups map[string]middleware.CORSPolicy `json:"groups"`
+}
+
+// TLSConfig HTTPS 配置，配置证书和私钥后服务以 HTTPS 监听
+type TLSConfig struct {
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
+	// 最低 TLS 版本（"1.2" 或 "1.3"），默认 "1.2"
+	MinVersion string `json:"min_version"`
+	// 检查证书文件变化的间隔（秒），默认60；收到 SIGHUP 时也会立即重新加载
+	ReloadIntervalSeconds int `json:"reload_interval_seconds"`
+	// 配置后在该地址监听 HTTP，并将请求跳转到 HTTPS
+	RedirectAddr string `json:"redirect_addr"`
+}
+
+// Enabled 是否启用 HTTPS
+func (t TLSConfig) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// Config 服务器配置
+type Config struct {
+	// 监听地址，默认 ":8080"
+	Addr string `json:"addr"`
+	// gRPC 监听地址（如 ":9090"），为空时不启动 gRPC 服务；启用 HTTPS 时共用同一证书
+	GRPCAddr string `json:"grpc_addr"`
+	// HTTPS 配置
+	TLS TLSConfig `json:"tls"`
+	// SQL数据库，配置后物品保存在数据库中
+	Database DatabaseConfig `json:"database"`
+	// Redis，配置后物品保存在 Redis 中，多个实例可共享
+	Redis RedisConfig `json:"redis"`
+	// 物品仓库分片数，大于1时使用分片内存仓库（未配置数据库时有效）
+	ItemShards int `json:"item_shards"`
+	// 管理接口令牌，为空时不开放管理接口
+	AdminToken string `json:"admin_token"`
+	// 玩家令牌密钥，游戏服务端用它为玩家签发事件流令牌，为空时不开放玩家事件流
+	PlayerTokenSecret string `json:"player_token_secret"`
+	// Webhook 订阅
+	Webhooks []webhooks.Subscription `json:"webhooks"`
+	// Webhook 死信日志文件路径（JSON Lines），为空时只记录到内存和标准日志
+	WebhookDeadLetterFile string `json:"webhook_dead_letter_file"`
+	// 幂等键的保留时间（秒），默认86400；为0时不处理 Idempotency-Key 请求头
+	IdempotencyTTLSeconds int `json:"idempotency_ttl_seconds"`
+	// 两阶段领取的预留期限（秒），默认60
+	ReservationLeaseSeconds int `json:"reservation_lease_seconds"`
+	// 玩家交易的有效期（秒），超时未被接受的交易结束并退回托管物品，默认3600
+	TradeTTLSeconds int `json:"trade_ttl_seconds"`
+	// 可信反向代理的IP或CIDR，只采信这些地址转发请求时的 X-Forwarded-For；默认为空，即不信任任何代理，
+	// 按连接的对端地址识别客户端
+	TrustedProxies []string `json:"trusted_proxies"`
+	// 按路由配置的限流规则，键为完整路由路径，配置文件中的路由覆盖默认规则
+	RateLimits map[string]ratelimit.RouteRules `json:"rate_limits"`
+	// 跨域策略
+	CORS CORSConfig `json:"cors"`
+}
+
+// ShareRoute 分享物品的路由路径
+const ShareRoute = "/api/v1/items/share"
+
+// AdminRouteGroup 管理接口的路由组前缀
+const AdminRouteGroup = "/api/v1/admin"
+
+// Default 返回默认配置
+func Default() *Config {
+	return &Config{
+		Addr:  ":8080",
+		TLS:   TLSConfig{ReloadIntervalSeconds: 60},
+		Redis: RedisConfig{Prefix: "duckex:"},
+		// 与取件码有效期一致
+		IdempotencyTTLSeconds:   86400,
+		ReservationLeaseSeconds: 60,
+		TradeTTLSeconds:         3600,
+		RateLimits: map[string]ratelimit.RouteRules{
+			// 每个IP每秒1次、每个分享者每5秒1次，允许短时突发
+			ShareRoute: {
+				PerIP:     ratelimit.Rule{Rate: 1, Burst: 10},
+				PerSharer: ratelimit.Rule{Rate: 0.2, Burst: 5},
+			},
+		},
+		CORS: CORSConfig{
+			CORSPolicy: middleware.DefaultCORSPolicy(),
+			// 管理接口默认不允许跨域访问
+			Groups: map[string]middleware.CORSPolicy{
+				AdminRouteGroup: {},
+			},
+		},
+	}
+}
+
+// Load 从JSON文件加载配置，path 为空时返回默认配置
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// LoadFromEnv 从环境变量指定的文件加载配置
+func LoadFromEnv() (*Config, error) {
+	return Load(os.Getenv(EnvConfigPath))
+}
+
+// Validate 校验配置
+func (c *Config) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("addr is required")
+	}
+	if c.GRPCAddr != "" && c.GRPCAddr == c.Addr {
+		return fmt.Errorf("grpc_addr must differ from addr")
+	}
+	if c.TLS.Enabled() {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			return fmt.Errorf("tls: cert_file and key_file must both be set")
+		}
+		if _, err := tlsutil.ParseVersion(c.TLS.MinVersion); err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		if c.TLS.ReloadIntervalSeconds < 0 {
+			return fmt.Errorf("tls: reload_interval_seconds must not be negative")
+		}
+	} else if c.TLS.RedirectAddr != "" {
+		return fmt.Errorf("tls: redirect_addr requires cert_file and key_file")
+	}
+	switch c.Database.Driver {
+	case "":
+	case "sqlite":
+		if c.Database.DSN == "" {
+			return fmt.Errorf("database.dsn is required")
+		}
+	default:
+		return fmt.Errorf("unsupported database driver %q", c.Database.Driver)
+	}
+	if c.Database.Driver != "" && c.Redis.Addr != "" {
+		return fmt.Errorf("database and redis cannot both be configured")
+	}
+	if c.ItemShards < 0 {
+		return fmt.Errorf("item_shards must not be negative")
+	}
+	if c.IdempotencyTTLSeconds < 0 {
+		return fmt.Errorf("idempotency_ttl_seconds must not be negative")
+	}
+	if c.ReservationLeaseSeconds <= 0 {
+		return fmt.Errorf("reservation_lease_seconds must be positive")
+	}
+	if c.TradeTTLSeconds <= 0 {
+		return fmt.Errorf("trade_ttl_seconds must be positive")
+	}
+	ids := make(map[string]bool)
+	for i, sub := range c.Webhooks {
+		if sub.ID == "" {
+			return fmt.Errorf("webhooks[%d]: id is required", i)
+		}
+		if ids[sub.ID] {
+			return fmt.Errorf("webhooks[%d]: duplicate id %q", i, sub.ID)
+		}
+		ids[sub.ID] = true
+		if sub.URL == "" {
+			return fmt.Errorf("webhooks[%d]: url is required", i)
+		}
+	}
+	if err := c.CORS.Validate(); err != nil {
+		return fmt.Errorf("cors: %w", err)
+	}
+	for prefix, policy := range c.CORS.Groups {
+		if err := policy.Validate(); err != nil {
+			return fmt.Errorf("cors.groups[%q]: %w", prefix, err)
+		}
+	}
+	for i, proxy := range c.TrustedProxies {
+		if !validProxy(proxy) {
+			return fmt.Errorf("trusted_proxies[%d]: %q is not an IP address or CIDR", i, proxy)
+		}
+	}
+	for route, rules := range c.RateLimits {
+		if err := validateRateLimitRule(rules.PerIP); err != nil {
+			return fmt.Errorf("rate_limits[%q].per_ip: %w", route, err)
+		}
+		if err := validateRateLimitRule(rules.PerSharer); err != nil {
+			return fmt.Errorf("rate_limits[%q].per_sharer: %w", route, err)
+		}
+	}
+	return nil
+}
+
+// 可信代理是否为IP地址或CIDR
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+// 校验限流规则，Rate 和 Burst 都为0表示不限流
+func validateRateLimitRule(rule ratelimit.Rule) error {
+	if rule.Rate < 0 || rule.Burst < 0 {
+		return fmt.Errorf("rate and burst must not be negative")
+	}
+	if (rule.Rate > 0) != (rule.Burst > 0) {
+		return fmt.Errorf("rate and burst must both be set")
+	}
+	return nil
+}
@@ -0,0 +1,178 @@
+package test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"duckex-server/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoadDefault(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.AdminToken)
+	assert.Empty(t, cfg.PlayerTokenSecret)
+	assert.Empty(t, cfg.Webhooks)
+}
+
+func TestLoadWebhooks(t *testing.T) {
+	path := writeConfig(t, `{
+		"admin_token": "admin",
+		"player_token_secret": "player-secret",
+		"webhooks": [
+			{"id": "discord", "url": "http://localhost:9000/hook", "secret": "s3cret", "events": ["item_claimed"]}
+		]
+	}`)
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "admin", cfg.AdminToken)
+	assert.Equal(t, "player-secret", cfg.PlayerTokenSecret)
+	require.Len(t, cfg.Webhooks, 1)
+	assert.Equal(t, "discord", cfg.Webhooks[0].ID)
+	assert.Equal(t, []string{"item_claimed"}, cfg.Webhooks[0].Events)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := config.Load(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	_, err = config.Load(writeConfig(t, `{"webhooks": [{"id": "a"}]}`))
+	assert.ErrorContains(t, err, "url is required")
+
+	_, err = config.Load(writeConfig(t, `{"webhooks": [{"id": "a", "url": "http://x"}, {"id": "a", "url": "http://y"}]}`))
+	assert.ErrorContains(t, err, "duplicate id")
+}
+
+func TestLoadRateLimits(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.True(t, cfg.RateLimits[config.ShareRoute].PerIP.Enabled())
+	assert.True(t, cfg.RateLimits[config.ShareRoute].PerSharer.Enabled())
+
+	// 配置文件中的路由覆盖默认规则，其他路由保留默认值
+	cfg, err = config.Load(writeConfig(t, `{
+		"rate_limits": {
+			"/api/v1/items/share": {"per_ip": {"rate": 5, "burst": 20}},
+			"/api/v1/items/claim": {"per_ip": {"rate": 2, "burst": 4}}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, 20, cfg.RateLimits[config.ShareRoute].PerIP.Burst)
+	assert.False(t, cfg.RateLimits[config.ShareRoute].PerSharer.Enabled())
+	assert.Equal(t, 2.0, cfg.RateLimits["/api/v1/items/claim"].PerIP.Rate)
+
+	_, err = config.Load(writeConfig(t, `{"rate_limits": {"/x": {"per_ip": {"rate": 1}}}}`))
+	assert.ErrorContains(t, err, "must both be set")
+	_, err = config.Load(writeConfig(t, `{"rate_limits": {"/x": {"per_sharer": {"rate": -1, "burst": 1}}}}`))
+	assert.ErrorContains(t, err, "must not be negative")
+}
+
+func TestLoadCORS(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `{
+		"cors": {
+			"allowed_origins": ["https://*.duckgame.io"],
+			"allow_credentials": true,
+			"groups": {"/api/v1/returns": {"allowed_origins": ["https://duckgame.io"]}}
+		}
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"https://*.duckgame.io"}, cfg.CORS.AllowedOrigins)
+	assert.True(t, cfg.CORS.AllowCredentials)
+	// 未配置的字段保留默认值，默认的管理接口组策略也保留
+	assert.NotEmpty(t, cfg.CORS.AllowedMethods)
+	assert.Contains(t, cfg.CORS.Groups, config.AdminRouteGroup)
+	assert.Contains(t, cfg.CORS.Groups, "/api/v1/returns")
+
+	_, err = config.Load(writeConfig(t, `{"cors": {"allow_credentials": true}}`))
+	assert.ErrorContains(t, err, "allow_credentials")
+}
+
+func TestLoadTLS(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `{
+		"addr": ":8443",
+		"tls": {"cert_file": "cert.pem", "key_file": "key.pem", "min_version": "1.3", "redirect_addr": ":8080"}
+	}`))
+	require.NoError(t, err)
+	assert.True(t, cfg.TLS.Enabled())
+	assert.Equal(t, ":8443", cfg.Addr)
+	assert.Equal(t, 60, cfg.TLS.ReloadIntervalSeconds)
+
+	_, err = config.Load(writeConfig(t, `{"tls": {"cert_file": "cert.pem"}}`))
+	assert.ErrorContains(t, err, "must both be set")
+	_, err = config.Load(writeConfig(t, `{"tls": {"cert_file": "c", "key_file": "k", "min_version": "1.5"}}`))
+	assert.ErrorContains(t, err, "unsupported TLS version")
+	_, err = config.Load(writeConfig(t, `{"tls": {"redirect_addr": ":80"}}`))
+	assert.ErrorContains(t, err, "redirect_addr")
+}
+
+func TestLoadGRPCAddr(t *testing.T) {
+	cfg, err := config.Load(writeConfig(t, `{"grpc_addr": ":9090"}`))
+	require.NoError(t, err)
+	assert.Equal(t, ":9090", cfg.GRPCAddr)
+
+	_, err = config.Load(writeConfig(t, `{"addr": ":9090", "grpc_addr": ":9090"}`))
+	assert.ErrorContains(t, err, "grpc_addr")
+}
+
+func TestLoadIdempotencyTTL(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, 86400, cfg.IdempotencyTTLSeconds)
+
+	cfg, err = config.Load(writeConfig(t, `{"idempotency_ttl_seconds": 0}`))
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.IdempotencyTTLSeconds)
+
+	_, err = config.Load(writeConfig(t, `{"idempotency_ttl_seconds": -1}`))
+	assert.ErrorContains(t, err, "idempotency_ttl_seconds")
+}
+
+func TestLoadReservationLease(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, 60, cfg.ReservationLeaseSeconds)
+
+	cfg, err = config.Load(writeConfig(t, `{"reservation_lease_seconds": 120}`))
+	require.NoError(t, err)
+	assert.Equal(t, 120, cfg.ReservationLeaseSeconds)
+
+	_, err = config.Load(writeConfig(t, `{"reservation_lease_seconds": 0}`))
+	assert.ErrorContains(t, err, "reservation_lease_seconds")
+}
+
+func TestLoadTradeTTL(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Equal(t, 3600, cfg.TradeTTLSeconds)
+
+	cfg, err = config.Load(writeConfig(t, `{"trade_ttl_seconds": 600}`))
+	require.NoError(t, err)
+	assert.Equal(t, 600, cfg.TradeTTLSeconds)
+
+	_, err = config.Load(writeConfig(t, `{"trade_ttl_seconds": -1}`))
+	assert.ErrorContains(t, err, "trade_ttl_seconds")
+}
+
+func TestLoadTrustedProxies(t *testing.T) {
+	cfg, err := config.Load("")
+	require.NoError(t, err)
+	assert.Empty(t, cfg.TrustedProxies)
+
+	cfg, err = config.Load(writeConfig(t, `{"trusted_proxies": ["10.0.0.0/8", "192.168.1.10", "::1"]}`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.10", "::1"}, cfg.TrustedProxies)
+
+	_, err = config.Load(writeConfig(t, `{"trusted_proxies": ["lb.internal"]}`))
+	assert.ErrorContains(t, err, "trusted_proxies[0]")
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// 订阅者默认缓冲区大小
+const defaultBufferSize = 64
+
+// Bus 进程内事件总线
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[uint64]chan Event
+	nextID      uint64
+}
+
+// NewBus 创建新的事件总线
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[uint64]chan Event),
+	}
+}
+
+// Subscribe 订阅所有事件，返回事件通道和取消订阅函数
+func (b *Bus) Subscribe(bufferSize int) (<-chan Event, func()) {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	ch := make(chan Event, bufferSize)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, id)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// SubscribeFunc 订阅所有事件并在独立goroutine中逐个交给 handler 处理，
+// 各订阅者互不影响，返回取消订阅函数
+func (b *Bus) SubscribeFunc(bufferSize int, handler func(Event)) func() {
+	ch, unsubscribe := b.Subscribe(bufferSize)
+	go func() {
+		for event := range ch {
+			handler(event)
+		}
+	}()
+	return unsubscribe
+}
+
+// Publish 发布事件，订阅者缓冲区已满时丢弃该事件，不阻塞发布者
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for id, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Printf("Event subscriber %d is full, dropping %s event", id, event.Type())
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package events
+
+import (
+	"time"
+
+	"duckex-server/internal/models"
+)
+
+// Type 事件类型
+type Type string
+
+const (
+	// TypeItemShared 物品被分享
+	TypeItemShared Type = "item_shared"
+	// TypeItemClaimed 物品被领取
+	TypeItemClaimed Type = "item_claimed"
+	// TypeItemExpired 物品过期未被领取
+	TypeItemExpired Type = "item_expired"
+	// TypeItemCancelled 物品被分享者取消
+	TypeItemCancelled Type = "item_cancelled"
+	// TypeShareRejected 分享请求被拒绝
+	TypeShareRejected Type = "share_rejected"
+	// TypeItemReserved 物品被预留，等待领取者确认
+	TypeItemReserved Type = "item_reserved"
+	// TypeReservationReleased 预留被取消或过期，物品恢复为未领取
+	TypeReservationReleased Type = "reservation_released"
+	// TypeTradeOpened 发起交易，发起方的物品进入托管
+	TypeTradeOpened Type = "trade_opened"
+	// TypeTradeCompleted 交易完成，双方物品已交付
+	TypeTradeCompleted Type = "trade_completed"
+	// TypeTradeClosed 交易被取消或超时，托管物品退回发起方
+	TypeTradeClosed Type = "trade_closed"
+)
+
+// 分享被拒绝的原因
+const (
+	RejectMemoryPressure = "memory_pressure"
+	RejectInvalidRequest = "invalid_request"
+	RejectStorageError   = "storage_error"
+)
+
+// Event 事件接口，订阅者通过类型断言获取具体事件
+type Event interface {
+	Type() Type
+	SharerID() string
+	OccurredAt() time.Time
+}
+
+// 涉及分享者以外玩家的事件，如交易事件同时涉及发起方和接受方
+type multiParty interface {
+	Parties() []string
+}
+
+// Concerns 事件是否与玩家相关：玩家是事件的分享者，或是交易的一方
+// 按玩家推送事件（SSE、gRPC WatchEvents）时使用，保证交易双方都能收到交易事件
+func Concerns(event Event, playerID string) bool {
+	if playerID == "" {
+		return false
+	}
+	if event.SharerID() == playerID {
+		return true
+	}
+	if e, ok := event.(multiParty); ok {
+		for _, party := range e.Parties() {
+			if party == playerID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ItemShared 物品被分享事件
+type ItemShared struct {
+	Item models.Item `json:"item"`
+	At   time.Time   `json:"occurred_at"`
+}
+
+// NewItemShared 基于物品快照创建分享事件，避免订阅者看到后续修改，at 为事件发生时间
+func NewItemShared(item *models.Item, at time.Time) *ItemShared {
+	return &ItemShared{Item: snapshot(item), At: at}
+}
+
+func (e *ItemShared) Type() Type            { return TypeItemShared }
+func (e *ItemShared) SharerID() string      { return e.Item.SharerID }
+func (e *ItemShared) OccurredAt() time.Time { return e.At }
+
+// ItemClaimed 物品被领取事件
+type ItemClaimed struct {
+	Item      models.Item `json:"item"`
+	ClaimerID string      `json:"claimer_id"`
+	At        time.Time   `json:"occurred_at"`
+}
+
+// NewItemClaimed 创建领取事件
+func NewItemClaimed(item *models.Item, claimerID string, at time.Time) *ItemClaimed {
+	return &ItemClaimed{Item: snapshot(item), ClaimerID: claimerID, At: at}
+}
+
+func (e *ItemClaimed) Type() Type            { return TypeItemClaimed }
+func (e *ItemClaimed) SharerID() string      { return e.Item.SharerID }
+func (e *ItemClaimed) OccurredAt() time.Time { return e.At }
+
+// ItemExpired 物品过期事件
+type ItemExpired struct {
+	Item models.Item `json:"item"`
+	At   time.Time   `json:"occurred_at"`
+}
+
+// NewItemExpired 创建过期事件
+func NewItemExpired(item *models.Item, at time.Time) *ItemExpired {
+	return &ItemExpired{Item: snapshot(item), At: at}
+}
+
+func (e *ItemExpired) Type() Type            { return TypeItemExpired }
+func (e *ItemExpired) SharerID() string      { return e.Item.SharerID }
+func (e *ItemExpired) OccurredAt() time.Time { return e.At }
+
+// ItemCancelled 物品被取消事件
+type ItemCancelled struct {
+	Item models.Item `json:"item"`
+	At   time.Time   `json:"occurred_at"`
+}
+
+// NewItemCancelled 创建取消事件
+func NewItemCancelled(item *models.Item, at time.Time) *ItemCancelled {
+	return &ItemCancelled{Item: snapshot(item), At: at}
+}
+
+func (e *ItemCancelled) Type() Type            { return TypeItemCancelled }
+func (e *ItemCancelled) SharerID() string      { return e.Item.SharerID }
+func (e *ItemCancelled) OccurredAt() time.Time { return e.At }
+
+// 预留被释放的原因
+const (
+	ReleaseByClaimer = "released"
+	ReleaseExpired   = "expired"
+)
+
+// 事件中的物品快照不包含取件码和预留令牌：取件码只返回给分享者，令牌只返回给预留者
+// 过期或取消后取件码可能被新物品复用，因此所有事件都不携带取件码
+func snapshot(item *models.Item) models.Item {
+	copied := *item
+	copied.PickupCode = ""
+	copied.Reservation = nil
+	return copied
+}
+
+// ItemReserved 物品被预留事件
+type ItemReserved struct {
+	Item           models.Item `json:"item"`
+	ClaimerID      string      `json:"claimer_id"`
+	LeaseExpiresAt time.Time   `json:"lease_expires_at"`
+	At             time.Time   `json:"occurred_at"`
+}
+
+// NewItemReserved 创建预留事件，item 必须带有预留信息
+func NewItemReserved(item *models.Item, at time.Time) *ItemReserved {
+	return &ItemReserved{Item: snapshot(item), ClaimerID: item.ClaimerID, LeaseExpiresAt: item.Reservation.ExpiresAt, At: at}
+}
+
+func (e *ItemReserved) Type() Type            { return TypeItemReserved }
+func (e *ItemReserved) SharerID() string      { return e.Item.SharerID }
+func (e *ItemReserved) OccurredAt() time.Time { return e.At }
+
+// ReservationReleased 预留被释放事件，Item 为释放后的未领取物品
+type ReservationReleased struct {
+	Item      models.Item `json:"item"`
+	ClaimerID string      `json:"claimer_id"`
+	Reason    string      `json:"reason"`
+	At        time.Time   `json:"occurred_at"`
+}
+
+// NewReservationReleased 基于释放前的物品创建预留释放事件，reason 为 ReleaseByClaimer 或 ReleaseExpired
+func NewReservationReleased(item *models.Item, reason string, at time.Time) *ReservationReleased {
+	released := snapshot(item)
+	released.IsClaimed = false
+	released.ClaimerID = ""
+	return &ReservationReleased{Item: released, ClaimerID: item.ClaimerID, Reason: reason, At: at}
+}
+
+func (e *ReservationReleased) Type() Type            { return TypeReservationReleased }
+func (e *ReservationReleased) SharerID() string      { return e.Item.SharerID }
+func (e *ReservationReleased) OccurredAt() time.Time { return e.At }
+
+// TradeOpened 发起交易事件，事件中的交易不包含取件码
+type TradeOpened struct {
+	Trade models.Trade `json:"trade"`
+	At    time.Time    `json:"occurred_at"`
+}
+
+// NewTradeOpened 创建发起交易事件
+func NewTradeOpened(trade *models.Trade, at time.Time) *TradeOpened {
+	return &TradeOpened{Trade: *trade.ViewFor(""), At: at}
+}
+
+func (e *TradeOpened) Type() Type            { return TypeTradeOpened }
+func (e *TradeOpened) SharerID() string      { return e.Trade.InitiatorID }
+func (e *TradeOpened) OccurredAt() time.Time { return e.At }
+func (e *TradeOpened) Parties() []string     { return tradeParties(&e.Trade) }
+
+// TradeCompleted 交易完成事件
+type TradeCompleted struct {
+	Trade models.Trade `json:"trade"`
+	At    time.Time    `json:"occurred_at"`
+}
+
+// NewTradeCompleted 创建交易完成事件
+func NewTradeCompleted(trade *models.Trade, at time.Time) *TradeCompleted {
+	return &TradeCompleted{Trade: *trade.ViewFor(""), At: at}
+}
+
+func (e *TradeCompleted) Type() Type            { return TypeTradeCompleted }
+func (e *TradeCompleted) SharerID() string      { return e.Trade.InitiatorID }
+func (e *TradeCompleted) OccurredAt() time.Time { return e.At }
+func (e *TradeCompleted) Parties() []string     { return tradeParties(&e.Trade) }
+
+// TradeClosed 交易被取消或超时事件，交易状态为 cancelled 或 expired
+type TradeClosed struct {
+	Trade models.Trade `json:"trade"`
+	At    time.Time    `json:"occurred_at"`
+}
+
+// NewTradeClosed 创建交易结束事件
+func NewTradeClosed(trade *models.Trade, at time.Time) *TradeClosed {
+	return &TradeClosed{Trade: *trade.ViewFor(""), At: at}
+}
+
+func (e *TradeClosed) Type() Type            { return TypeTradeClosed }
+func (e *TradeClosed) SharerID() string      { return e.Trade.InitiatorID }
+func (e *TradeClosed) OccurredAt() time.Time { return e.At }
+func (e *TradeClosed) Parties() []string     { return tradeParties(&e.Trade) }
+
+// 交易事件推送给发起方、指定的对方和接受方
+func tradeParties(trade *models.Trade) []string {
+	parties := []string{trade.InitiatorID}
+	for _, id := range []string{trade.CounterpartyID, trade.AcceptorID} {
+		if id != "" && id != parties[len(parties)-1] {
+			parties = append(parties, id)
+		}
+	}
+	return parties
+}
+
+// ShareRejected 分享请求被拒绝事件
+type ShareRejected struct {
+	Sharer string    `json:"sharer_id,omitempty"` // 请求未能解析时为空
+	Reason string    `json:"reason"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"occurred_at"`
+}
+
+// NewShareRejected 创建分享被拒绝事件
+func NewShareRejected(sharerID, reason, detail string, at time.Time) *ShareRejected {
+	return &ShareRejected{Sharer: sharerID, Reason: reason, Detail: detail, At: at}
+}
+
+func (e *ShareRejected) Type() Type            { return TypeShareRejected }
+func (e *ShareRejected) SharerID() string      { return e.Sharer }
+func (e *ShareRejected) OccurredAt() time.Time { return e.At }
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// AuditLog 将事件写入标准日志的审计订阅者
+func AuditLog(event Event) {
+	switch e := event.(type) {
+	case *ItemShared:
+		log.Printf("AUDIT %s item=%s sharer=%s", e.Type(), e.Item.ID, e.Item.SharerID)
+	case *ItemClaimed:
+		log.Printf("AUDIT %s item=%s sharer=%s claimer=%s", e.Type(), e.Item.ID, e.Item.SharerID, e.ClaimerID)
+	case *ItemExpired:
+		log.Printf("AUDIT %s item=%s sharer=%s", e.Type(), e.Item.ID, e.Item.SharerID)
+	case *ItemCancelled:
+		log.Printf("AUDIT %s item=%s sharer=%s", e.Type(), e.Item.ID, e.Item.SharerID)
+	case *ItemReserved:
+		log.Printf("AUDIT %s item=%s sharer=%s claimer=%s", e.Type(), e.Item.ID, e.Item.SharerID, e.ClaimerID)
+	case *ReservationReleased:
+		log.Printf("AUDIT %s item=%s sharer=%s claimer=%s reason=%s", e.Type(), e.Item.ID, e.Item.SharerID, e.ClaimerID, e.Reason)
+	case *TradeOpened:
+		log.Printf("AUDIT %s trade=%s initiator=%s", e.Type(), e.Trade.ID, e.Trade.InitiatorID)
+	case *TradeCompleted:
+		log.Printf("AUDIT %s trade=%s initiator=%s acceptor=%s", e.Type(), e.Trade.ID, e.Trade.InitiatorID, e.Trade.AcceptorID)
+	case *TradeClosed:
+		log.Printf("AUDIT %s trade=%s initiator=%s status=%s", e.Type(), e.Trade.ID, e.Trade.InitiatorID, e.Trade.Status)
+	case *ShareRejected:
+		log.Printf("AUDIT %s sharer=%s reason=%s", e.Type(), e.Sharer, e.Reason)
+	default:
+		log.Printf("AUDIT %s sharer=%s", event.Type(), event.SharerID())
+	}
+}
+
+// Counter 按事件类型计数的指标订阅者
+type Counter struct {
+	mu     sync.RWMutex
+	counts map[Type]int64
+}
+
+// NewCounter 创建新的事件计数器
+func NewCounter() *Counter {
+	return &Counter{
+		counts: make(map[Type]int64),
+	}
+}
+
+// Handle 记录一个事件，可直接作为 SubscribeFunc 的 handler
+func (c *Counter) Handle(event Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[event.Type()]++
+}
+
+// Snapshot 返回各事件类型的计数
+func (c *Counter) Snapshot() map[Type]int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	snapshot := make(map[Type]int64, len(c.counts))
+	for t, n := range c.counts {
+		snapshot[t] = n
+	}
+	return snapshot
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusPublishSubscribe(t *testing.T) {
+	bus := events.NewBus()
+	first, unsubscribeFirst := bus.Subscribe(4)
+	second, unsubscribeSecond := bus.Subscribe(4)
+	defer unsubscribeSecond()
+
+	item := &models.Item{ID: "item-1", SharerID: "sharer-a", PickupCode: "123456"}
+	bus.Publish(events.NewItemShared(item, time.Now()))
+
+	// 每个订阅者都收到事件
+	event := <-first
+	assert.Equal(t, events.TypeItemShared, event.Type())
+	assert.Equal(t, "sharer-a", event.SharerID())
+	shared, ok := event.(*events.ItemShared)
+	require.True(t, ok)
+	assert.Equal(t, "item-1", shared.Item.ID)
+	assert.Empty(t, shared.Item.PickupCode, "events must not carry pickup codes")
+	assert.Equal(t, "123456", item.PickupCode)
+	assert.Equal(t, events.TypeItemShared, (<-second).Type())
+
+	// 事件中保存的是快照，发布后修改物品不影响事件
+	item.ClaimerID = "claimer"
+	bus.Publish(events.NewItemClaimed(item, "claimer", time.Now()))
+	claimed, ok := (<-first).(*events.ItemClaimed)
+	require.True(t, ok)
+	assert.Equal(t, "claimer", claimed.ClaimerID)
+	assert.Equal(t, "", shared.Item.ClaimerID)
+	<-second
+
+	// 取消订阅后通道被关闭，不再收到事件
+	unsubscribeFirst()
+	unsubscribeFirst()
+	bus.Publish(events.NewItemExpired(item, time.Now()))
+	_, ok = <-first
+	assert.False(t, ok)
+	assert.Equal(t, events.TypeItemExpired, (<-second).Type())
+}
+
+func TestBusPublishDoesNotBlock(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(1)
+	defer unsubscribe()
+
+	// 缓冲区满后继续发布不会阻塞，多余事件被丢弃
+	item := &models.Item{ID: "item-1"}
+	for i := 0; i < 10; i++ {
+		bus.Publish(events.NewItemShared(item, time.Now()))
+	}
+	assert.Len(t, ch, 1)
+
+	// nil 总线发布为空操作
+	var nilBus *events.Bus
+	nilBus.Publish(events.NewItemShared(item, time.Now()))
+}
+
+func TestBusIndependentSubscribers(t *testing.T) {
+	bus := events.NewBus()
+
+	// 一个阻塞的订阅者不影响计数器
+	block := make(chan struct{})
+	defer close(block)
+	unsubscribeSlow := bus.SubscribeFunc(1, func(events.Event) { <-block })
+	defer unsubscribeSlow()
+
+	counter := events.NewCounter()
+	unsubscribeCounter := bus.SubscribeFunc(16, counter.Handle)
+	defer unsubscribeCounter()
+
+	item := &models.Item{ID: "item-1", SharerID: "sharer-a"}
+	bus.Publish(events.NewItemShared(item, time.Now()))
+	bus.Publish(events.NewItemShared(item, time.Now()))
+	bus.Publish(events.NewItemClaimed(item, "claimer", time.Now()))
+	bus.Publish(events.NewShareRejected("sharer-a", events.RejectMemoryPressure, "", time.Now()))
+
+	assert.Eventually(t, func() bool {
+		return counter.Snapshot()[events.TypeShareRejected] == 1
+	}, time.Second, 5*time.Millisecond)
+	snapshot := counter.Snapshot()
+	assert.Equal(t, int64(2), snapshot[events.TypeItemShared])
+	assert.Equal(t, int64(1), snapshot[events.TypeItemClaimed])
+}
+
+func TestConcerns(t *testing.T) {
+	item := &models.Item{ID: "item-1", SharerID: "sharer-a"}
+	shared := events.NewItemShared(item, time.Now())
+	assert.True(t, events.Concerns(shared, "sharer-a"))
+	assert.False(t, events.Concerns(shared, "sharer-b"))
+	assert.False(t, events.Concerns(shared, ""))
+
+	// 交易事件推送给发起方、指定的对方和接受方
+	trade := &models.Trade{ID: "trade-1", InitiatorID: "alice", CounterpartyID: "bob"}
+	opened := events.NewTradeOpened(trade, time.Now())
+	assert.True(t, events.Concerns(opened, "alice"))
+	assert.True(t, events.Concerns(opened, "bob"))
+	assert.False(t, events.Concerns(opened, "carol"))
+
+	trade = &models.Trade{ID: "trade-2", InitiatorID: "alice", AcceptorID: "carol"}
+	completed := events.NewTradeCompleted(trade, time.Now())
+	assert.Equal(t, "alice", completed.SharerID())
+	assert.True(t, events.Concerns(completed, "carol"))
+	assert.False(t, events.Concerns(completed, "bob"))
+}
@@ -0,0 +1,57 @@
+package grpcapi
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+
+	"duckex-server/internal/middleware"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// 带分享者ID的请求，如 ShareRequest
+type sharerRequest interface {
+	GetSharerId() string
+}
+
+// RateLimitInterceptor 按 HTTP 路由的限流规则限制 gRPC 调用，与 HTTP 接口共用令牌桶
+// routes 的键为 gRPC 完整方法名（如 duckexpb.DuckEx_Share_FullMethodName），值为规则所属的 HTTP 路由
+// 按客户端IP和请求中的 sharer_id 限流，被拒绝时返回 ResourceExhausted，并在响应头 retry-after 中给出等待秒数
+func RateLimitInterceptor(limits *middleware.RateLimits, routes map[string]string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		route, ok := routes[info.FullMethod]
+		if !ok {
+			return handler(ctx, req)
+		}
+		var sharerID string
+		if r, ok := req.(sharerRequest); ok {
+			sharerID = r.GetSharerId()
+		}
+		result, allowed := limits.Allow(route, peerIP(ctx), sharerID)
+		if !allowed {
+			retryAfter := strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds())))
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter))
+			return nil, status.Error(codes.ResourceExhausted, "too many requests, please slow down")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// 客户端IP，取不到时返回空字符串
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
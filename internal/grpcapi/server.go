@@ -0,0 +1,293 @@
+// Package grpcapi 实现 DuckEx gRPC 接口，业务逻辑与 HTTP 接口共用 service.ItemService
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/service"
+	"duckex-server/pkg/duckexpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server DuckEx gRPC 服务
+type Server struct {
+	duckexpb.UnimplementedDuckExServer
+	items        *service.ItemService
+	eventBus     *events.Bus
+	adminToken   string
+	playerSecret string
+}
+
+// NewServer 创建 gRPC 服务，adminToken 为空时需要管理令牌的接口不可用，playerSecret 为空时不能订阅单个分享者的事件
+func NewServer(items *service.ItemService, eventBus *events.Bus, adminToken, playerSecret string) *Server {
+	return &Server{
+		items:        items,
+		eventBus:     eventBus,
+		adminToken:   adminToken,
+		playerSecret: playerSecret,
+	}
+}
+
+// Register 将服务注册到 gRPC 服务器
+func (s *Server) Register(g *grpc.Server) {
+	duckexpb.RegisterDuckExServer(g, s)
+}
+
+// Share 分享物品
+func (s *Server) Share(ctx context.Context, req *duckexpb.ShareRequest) (*duckexpb.ShareResponse, error) {
+	item, err := s.items.Share(service.ShareRequest{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		TypeID:      int(req.GetTypeId()),
+		Num:         int(req.GetNum()),
+		Durability:  req.GetDurability(),
+		SharerID:    req.GetSharerId(),
+		Listed:      req.GetListed(),
+		GroupID:     req.GetGroupId(),
+		PlayerToken: bearerToken(ctx),
+	})
+	if err != nil {
+		return nil, itemError("share", err)
+	}
+	return &duckexpb.ShareResponse{
+		PickupCode: item.PickupCode,
+		ExpiresAt:  timestamppb.New(item.ExpiresAt),
+		ListingId:  item.ListingID,
+	}, nil
+}
+
+// Claim 领取物品，分享给群组的物品需要在元数据中携带为领取者签发的玩家令牌
+func (s *Server) Claim(ctx context.Context, req *duckexpb.ClaimRequest) (*duckexpb.ClaimResponse, error) {
+	item, err := s.items.Claim(req.GetPickupCode(), req.GetClaimerId(), bearerToken(ctx))
+	if err != nil {
+		return nil, itemError("claim", err)
+	}
+	return &duckexpb.ClaimResponse{Item: toProtoItem(item)}, nil
+}
+
+// Reserve 预留物品，玩家令牌的要求与 Claim 相同
+func (s *Server) Reserve(ctx context.Context, req *duckexpb.ReserveRequest) (*duckexpb.ReserveResponse, error) {
+	item, err := s.items.Reserve(req.GetPickupCode(), req.GetClaimerId(), bearerToken(ctx))
+	if err != nil {
+		return nil, itemError("reserve", err)
+	}
+	return &duckexpb.ReserveResponse{
+		Item:             toProtoItem(item),
+		ReservationToken: item.Reservation.Token,
+		LeaseExpiresAt:   timestamppb.New(item.Reservation.ExpiresAt),
+	}, nil
+}
+
+// Confirm 确认预留
+func (s *Server) Confirm(ctx context.Context, req *duckexpb.ReservationRequest) (*duckexpb.ClaimResponse, error) {
+	item, err := s.items.Confirm(req.GetPickupCode(), req.GetReservationToken())
+	if err != nil {
+		return nil, itemError("confirm", err)
+	}
+	return &duckexpb.ClaimResponse{Item: toProtoItem(item)}, nil
+}
+
+// Release 取消预留
+func (s *Server) Release(ctx context.Context, req *duckexpb.ReservationRequest) (*duckexpb.ClaimResponse, error) {
+	item, err := s.items.Release(req.GetPickupCode(), req.GetReservationToken())
+	if err != nil {
+		return nil, itemError("release", err)
+	}
+	return &duckexpb.ClaimResponse{Item: toProtoItem(item)}, nil
+}
+
+// Cancel 取消分享（需要管理令牌）
+func (s *Server) Cancel(ctx context.Context, req *duckexpb.CancelRequest) (*duckexpb.CancelResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	item, err := s.items.Cancel(req.GetPickupCode())
+	if err != nil {
+		return nil, itemError("cancel", err)
+	}
+	return &duckexpb.CancelResponse{Item: toProtoItem(item)}, nil
+}
+
+// Lookup 按取件码查看物品（需要管理令牌）
+func (s *Server) Lookup(ctx context.Context, req *duckexpb.LookupRequest) (*duckexpb.LookupResponse, error) {
+	if err := s.authorize(ctx); err != nil {
+		return nil, err
+	}
+	item, err := s.items.Lookup(req.GetPickupCode())
+	if err != nil {
+		return nil, itemError("look up", err)
+	}
+	return &duckexpb.LookupResponse{Item: toProtoItem(item)}, nil
+}
+
+// WatchEvents 推送事件直到客户端断开，与 HTTP 事件流使用相同的认证：
+// 指定 sharer_id 时需要为该分享者签发的玩家令牌，未指定时需要管理令牌；事件中的物品不包含取件码
+func (s *Server) WatchEvents(req *duckexpb.WatchEventsRequest, stream duckexpb.DuckEx_WatchEventsServer) error {
+	sharerID, eventType := req.GetSharerId(), events.Type(req.GetType())
+	var err error
+	if sharerID == "" {
+		err = s.authorize(stream.Context())
+	} else {
+		err = s.authorizePlayer(stream.Context(), sharerID)
+	}
+	if err != nil {
+		return err
+	}
+
+	ch, unsubscribe := s.eventBus.Subscribe(0)
+	defer unsubscribe()
+	// 订阅生效后立即发送响应头，客户端可据此确认订阅已生效
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			if sharerID != "" && !events.Concerns(event, sharerID) {
+				continue
+			}
+			if eventType != "" && event.Type() != eventType {
+				continue
+			}
+			if err := stream.Send(toProtoEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// 校验请求元数据中的管理令牌（"authorization: Bearer <token>"）
+func (s *Server) authorize(ctx context.Context) error {
+	if s.adminToken == "" {
+		return status.Error(codes.PermissionDenied, "admin API is disabled")
+	}
+	for _, provided := range bearerTokens(ctx) {
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(s.adminToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid admin token")
+}
+
+// 校验请求元数据中为该玩家签发的玩家令牌（"authorization: Bearer <token>"）
+func (s *Server) authorizePlayer(ctx context.Context, playerID string) error {
+	if s.playerSecret == "" {
+		return status.Error(codes.PermissionDenied, "player event stream is disabled")
+	}
+	for _, provided := range bearerTokens(ctx) {
+		if playertoken.Verify(s.playerSecret, playerID, provided) {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid player token")
+}
+
+// 请求元数据中的第一个 Bearer 令牌，没有时为空
+func bearerToken(ctx context.Context) string {
+	if tokens := bearerTokens(ctx); len(tokens) > 0 {
+		return tokens[0]
+	}
+	return ""
+}
+
+// 请求元数据中的 Bearer 令牌
+func bearerTokens(ctx context.Context) []string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	tokens := make([]string, 0, len(values))
+	for _, value := range values {
+		tokens = append(tokens, strings.TrimPrefix(value, "Bearer "))
+	}
+	return tokens
+}
+
+// 将业务错误转换为 gRPC 状态
+func itemError(action string, err error) error {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		return status.Error(codes.InvalidArgument, "invalid request: "+err.Error())
+	case errors.Is(err, service.ErrShareDisabled):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrAlreadyClaimed):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrReservationNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, service.ErrPlayerUnauthenticated):
+		return status.Error(codes.Unauthenticated, err.Error())
+	case errors.Is(err, service.ErrNotGroupMember):
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	return status.Errorf(codes.Internal, "failed to %s item: %v", action, err)
+}
+
+// 转换为 protobuf 物品
+func toProtoItem(item *models.Item) *duckexpb.Item {
+	return &duckexpb.Item{
+		Id:          item.ID,
+		Name:        item.Name,
+		Description: item.Description,
+		TypeId:      int32(item.TypeID),
+		Num:         int32(item.Num),
+		Durability:  item.Durability,
+		SharerId:    item.SharerID,
+		PickupCode:  item.PickupCode,
+		CreatedAt:   timestamppb.New(item.CreatedAt),
+		ExpiresAt:   timestamppb.New(item.ExpiresAt),
+		IsClaimed:   item.IsClaimed,
+		ClaimerId:   item.ClaimerID,
+		ListingId:   item.ListingID,
+		GroupId:     item.GroupID,
+	}
+}
+
+// 转换为 protobuf 事件
+func toProtoEvent(event events.Event) *duckexpb.Event {
+	pb := &duckexpb.Event{
+		Type:       string(event.Type()),
+		OccurredAt: timestamppb.New(event.OccurredAt()),
+		SharerId:   event.SharerID(),
+	}
+	switch e := event.(type) {
+	case *events.ItemShared:
+		pb.Item = toProtoItem(&e.Item)
+	case *events.ItemClaimed:
+		pb.Item = toProtoItem(&e.Item)
+		pb.ClaimerId = e.ClaimerID
+	case *events.ItemExpired:
+		pb.Item = toProtoItem(&e.Item)
+	case *events.ItemCancelled:
+		pb.Item = toProtoItem(&e.Item)
+	case *events.ItemReserved:
+		pb.Item = toProtoItem(&e.Item)
+		pb.ClaimerId = e.ClaimerID
+		pb.LeaseExpiresAt = timestamppb.New(e.LeaseExpiresAt)
+	case *events.ReservationReleased:
+		pb.Item = toProtoItem(&e.Item)
+		pb.ClaimerId = e.ClaimerID
+		pb.Reason = e.Reason
+	case *events.ShareRejected:
+		pb.Reason = e.Reason
+		pb.Detail = e.Detail
+	}
+	return pb
+}
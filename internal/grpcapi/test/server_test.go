@@ -0,0 +1,323 @@
+package test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/config"
+	"duckex-server/internal/events"
+	"duckex-server/internal/grpcapi"
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/ratelimit"
+	"duckex-server/internal/service"
+	"duckex-server/internal/utils"
+	"duckex-server/pkg/duckexpb"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	adminToken   = "admin-token"
+	playerSecret = "player-secret"
+)
+
+// 在内存连接上启动 gRPC 服务，返回客户端和仓库
+func newTestClient(t *testing.T, opts ...grpc.ServerOption) (duckexpb.DuckExClient, models.ItemRepository, models.ReturnBox) {
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	returnBox := models.NewInMemoryReturnBox(0, nil)
+	bus := events.NewBus()
+	items := service.NewItemService(service.Deps{
+		ItemRepo:          itemRepo,
+		ReturnBox:         returnBox,
+		MemoryMonitor:     utils.NewMemoryMonitor(4096),
+		EventBus:          bus,
+		Groups:            models.NewInMemoryGroupRepository(),
+		PlayerTokenSecret: playerSecret,
+	})
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(opts...)
+	grpcapi.NewServer(items, bus, adminToken, playerSecret).Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return duckexpb.NewDuckExClient(conn), itemRepo, returnBox
+}
+
+func shareRequest() *duckexpb.ShareRequest {
+	return &duckexpb.ShareRequest{
+		Name:        "Golden Duck",
+		Description: "Shiny",
+		TypeId:      1001,
+		Num:         1,
+		Durability:  90,
+		SharerId:    "player123",
+	}
+}
+
+// 携带管理令牌的上下文
+func adminContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+adminToken)
+}
+
+func playerContext(ctx context.Context, playerID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+playertoken.Sign(playerSecret, playerID))
+}
+
+func TestShareAndClaim(t *testing.T) {
+	client, itemRepo, _ := newTestClient(t)
+	ctx := context.Background()
+
+	shared, err := client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+	assert.Len(t, shared.PickupCode, 6)
+	stored, _ := itemRepo.GetByPickupCode(shared.PickupCode)
+	require.NotNil(t, stored)
+	assert.True(t, shared.ExpiresAt.AsTime().Equal(stored.ExpiresAt))
+
+	claimed, err := client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: shared.PickupCode, ClaimerId: "player456"})
+	require.NoError(t, err)
+	assert.Equal(t, "Golden Duck", claimed.Item.Name)
+	assert.True(t, claimed.Item.IsClaimed)
+	assert.Equal(t, "player456", claimed.Item.ClaimerId)
+
+	_, err = client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: shared.PickupCode, ClaimerId: "player789"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestReserveConfirmRelease(t *testing.T) {
+	client, itemRepo, _ := newTestClient(t)
+	ctx := context.Background()
+	shared, err := client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+
+	reserved, err := client.Reserve(ctx, &duckexpb.ReserveRequest{PickupCode: shared.PickupCode, ClaimerId: "player456"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, reserved.ReservationToken)
+	assert.True(t, reserved.LeaseExpiresAt.AsTime().After(time.Now()))
+	assert.Equal(t, "player456", reserved.Item.ClaimerId)
+
+	_, err = client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: shared.PickupCode, ClaimerId: "player789"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// 取消后重新预留并确认
+	released, err := client.Release(ctx, &duckexpb.ReservationRequest{PickupCode: shared.PickupCode, ReservationToken: reserved.ReservationToken})
+	require.NoError(t, err)
+	assert.False(t, released.Item.IsClaimed)
+	reserved, err = client.Reserve(ctx, &duckexpb.ReserveRequest{PickupCode: shared.PickupCode, ClaimerId: "player789"})
+	require.NoError(t, err)
+
+	_, err = client.Confirm(ctx, &duckexpb.ReservationRequest{PickupCode: shared.PickupCode, ReservationToken: "wrong"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	confirmed, err := client.Confirm(ctx, &duckexpb.ReservationRequest{PickupCode: shared.PickupCode, ReservationToken: reserved.ReservationToken})
+	require.NoError(t, err)
+	assert.Equal(t, "player789", confirmed.Item.ClaimerId)
+	stored, _ := itemRepo.GetByPickupCode(shared.PickupCode)
+	assert.Nil(t, stored)
+}
+
+func TestShareInvalidRequest(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	req := shareRequest()
+	req.Num = 0
+	_, err := client.Share(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.Claim(context.Background(), &duckexpb.ClaimRequest{PickupCode: "123456"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGroupRestrictedItems(t *testing.T) {
+	client, itemRepo, _ := newTestClient(t)
+	ctx := context.Background()
+
+	req := shareRequest()
+	req.GroupId = "group-1"
+	_, err := client.Share(playerContext(ctx, "player123"), req)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.Share(ctx, req)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	item := &models.Item{
+		ID:         "group-item",
+		Name:       "Clan Banner",
+		PickupCode: "654321",
+		SharerID:   "player123",
+		GroupID:    "group-1",
+		CreatedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	require.NoError(t, itemRepo.Create(item))
+	_, err = client.Claim(playerContext(ctx, "player456"), &duckexpb.ClaimRequest{PickupCode: "654321", ClaimerId: "player456"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.Reserve(playerContext(ctx, "player456"), &duckexpb.ReserveRequest{PickupCode: "654321", ClaimerId: "player456"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// 领取群组物品需要领取者本人的玩家令牌
+	_, err = client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: "654321", ClaimerId: "player456"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Reserve(playerContext(ctx, "player789"), &duckexpb.ReserveRequest{PickupCode: "654321", ClaimerId: "player456"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestLookupAndCancelRequireAdminToken(t *testing.T) {
+	client, _, returnBox := newTestClient(t)
+	ctx := context.Background()
+	shared, err := client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+
+	_, err = client.Lookup(ctx, &duckexpb.LookupRequest{PickupCode: shared.PickupCode})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.Cancel(ctx, &duckexpb.CancelRequest{PickupCode: shared.PickupCode})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	looked, err := client.Lookup(adminContext(ctx), &duckexpb.LookupRequest{PickupCode: shared.PickupCode})
+	require.NoError(t, err)
+	assert.False(t, looked.Item.IsClaimed)
+
+	cancelled, err := client.Cancel(adminContext(ctx), &duckexpb.CancelRequest{PickupCode: shared.PickupCode})
+	require.NoError(t, err)
+	assert.Equal(t, looked.Item.Id, cancelled.Item.Id)
+	returned, err := returnBox.List("player123")
+	require.NoError(t, err)
+	assert.Len(t, returned, 1)
+
+	_, err = client.Lookup(adminContext(ctx), &duckexpb.LookupRequest{PickupCode: shared.PickupCode})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWatchEvents(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchEvents(playerContext(ctx, "player123"), &duckexpb.WatchEventsRequest{SharerId: "player123"})
+	require.NoError(t, err)
+	// 收到响应头说明订阅已生效
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	other := shareRequest()
+	other.SharerId = "someone-else"
+	_, err = client.Share(ctx, other)
+	require.NoError(t, err)
+	shared, err := client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+	_, err = client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: shared.PickupCode, ClaimerId: "player456"})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, string(events.TypeItemShared), event.Type)
+	assert.Equal(t, "player123", event.Item.SharerId)
+	assert.Empty(t, event.Item.PickupCode)
+
+	event, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, string(events.TypeItemClaimed), event.Type)
+	assert.Equal(t, "player456", event.ClaimerId)
+}
+
+func TestWatchEventsRequiresPlayerToken(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 与 HTTP 事件流相同，没有令牌、其他分享者的令牌或管理令牌都不能订阅单个分享者
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"missing token", ctx},
+		{"other sharer's token", playerContext(ctx, "someone-else")},
+		{"admin token", adminContext(ctx)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stream, err := client.WatchEvents(tt.ctx, &duckexpb.WatchEventsRequest{SharerId: "player123"})
+			require.NoError(t, err)
+			_, err = stream.Recv()
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestShareRateLimit(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	limits := middleware.NewRateLimits(map[string]ratelimit.RouteRules{
+		config.ShareRoute: {PerSharer: ratelimit.Rule{Rate: 0.2, Burst: 2}},
+	}, clk)
+	interceptor := grpcapi.RateLimitInterceptor(limits, map[string]string{
+		duckexpb.DuckEx_Share_FullMethodName: config.ShareRoute,
+	})
+	client, _, _ := newTestClient(t, grpc.UnaryInterceptor(interceptor))
+	ctx := context.Background()
+
+	// 与 HTTP 分享接口共用令牌桶：先通过 HTTP 消耗一个令牌
+	_, allowed := limits.Allow(config.ShareRoute, "", "player123")
+	require.True(t, allowed)
+	_, err := client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+
+	var header metadata.MD
+	_, err = client.Share(ctx, shareRequest(), grpc.Header(&header))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"5"}, header.Get("retry-after"))
+
+	// 其他分享者不受影响，令牌恢复后可以再次分享
+	other := shareRequest()
+	other.SharerId = "someone-else"
+	_, err = client.Share(ctx, other)
+	require.NoError(t, err)
+	clk.Advance(5 * time.Second)
+	_, err = client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+
+	// 未配置限流的方法直接放行
+	_, err = client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: "000000", ClaimerId: "player456"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestWatchAllEventsRequiresAdminToken(t *testing.T) {
+	client, _, _ := newTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := client.WatchEvents(ctx, &duckexpb.WatchEventsRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	stream, err = client.WatchEvents(adminContext(ctx), &duckexpb.WatchEventsRequest{Type: string(events.TypeItemClaimed)})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	shared, err := client.Share(ctx, shareRequest())
+	require.NoError(t, err)
+	_, err = client.Claim(ctx, &duckexpb.ClaimRequest{PickupCode: shared.PickupCode, ClaimerId: "player456"})
+	require.NoError(t, err)
+
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, string(events.TypeItemClaimed), event.Type)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/idempotency"
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/models"
+	"duckex-server/internal/ratelimit"
+	"duckex-server/internal/service"
+	"duckex-server/internal/snapshot"
+	"duckex-server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminHandler 运维管理处理器
+type AdminHandler struct {
+	itemRepo      models.ItemRepository
+	items         *service.ItemService
+	eventCounter  *events.Counter
+	memoryMonitor *utils.MemoryMonitor
+	rateLimits    *middleware.RateLimits
+	idempotency   idempotency.Store
+	clock         clock.Clock
+}
+
+// AdminDeps 管理处理器的依赖，可选依赖为 nil 时对应的统计不返回
+type AdminDeps struct {
+	ItemRepo      models.ItemRepository
+	Items         *service.ItemService
+	EventCounter  *events.Counter
+	MemoryMonitor *utils.MemoryMonitor
+	RateLimits    *middleware.RateLimits
+	Idempotency   idempotency.Store
+	Clock         clock.Clock
+}
+
+// NewAdminHandler 创建新的运维管理处理器
+func NewAdminHandler(deps AdminDeps) *AdminHandler {
+	if deps.Clock == nil {
+		deps.Clock = clock.System()
+	}
+	return &AdminHandler{
+		itemRepo:      deps.ItemRepo,
+		items:         deps.Items,
+		eventCounter:  deps.EventCounter,
+		memoryMonitor: deps.MemoryMonitor,
+		rateLimits:    deps.RateLimits,
+		idempotency:   deps.Idempotency,
+		clock:         deps.Clock,
+	}
+}
+
+// 物品列表的响应结构
+type ItemsResponse struct {
+	Items []*models.Item `json:"items"`
+}
+
+// 单个物品的响应结构
+type ItemResponse struct {
+	Item *models.Item `json:"item"`
+}
+
+// 运维状态的响应结构
+type AdminStatusResponse struct {
+	Timestamp         string                                `json:"timestamp"`
+	PendingItemsCount int                                   `json:"pending_items_count"`
+	Memory            map[string]interface{}                `json:"memory,omitempty"`
+	RateLimits        map[string]map[string]ratelimit.Stats `json:"rate_limits,omitempty"`
+	Idempotency       *idempotency.Stats                    `json:"idempotency,omitempty"`
+	EventCounts       map[events.Type]int64                 `json:"event_counts,omitempty"`
+}
+
+// ListItems 列出未过期的物品，可按 sharer_id 过滤
+func (h *AdminHandler) ListItems(c *gin.Context) {
+	sharerID := c.Query("sharer_id")
+	items := make([]*models.Item, 0)
+	for _, item := range h.itemRepo.GetAll() {
+		if sharerID == "" || item.SharerID == sharerID {
+			items = append(items, item)
+		}
+	}
+	c.JSON(http.StatusOK, ItemsResponse{Items: items})
+}
+
+// GetItem 按取件码查看物品，不会领取物品
+func (h *AdminHandler) GetItem(c *gin.Context) {
+	item, err := h.items.Lookup(c.Param("code"))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to look up item: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ItemResponse{Item: item})
+}
+
+// CancelItem 取消分享：物品从仓库移除并退回到分享者的退回箱
+func (h *AdminHandler) CancelItem(c *gin.Context) {
+	item, err := h.items.Cancel(c.Param("code"))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: "Item not found"})
+		return
+	case errors.Is(err, service.ErrAlreadyClaimed):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: "Item already claimed"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel item: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, ItemResponse{Item: item})
+}
+
+// DumpSnapshot 导出全部未过期物品的快照
+func (h *AdminHandler) DumpSnapshot(c *gin.Context) {
+	c.JSON(http.StatusOK, snapshot.New(h.itemRepo.GetAll(), h.clock.Now()))
+}
+
+// RestoreSnapshot 从快照恢复物品，已过期、字段不完整和取件码冲突的物品被跳过
+func (h *AdminHandler) RestoreSnapshot(c *gin.Context) {
+	var s snapshot.Snapshot
+	if err := c.ShouldBindJSON(&s); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid snapshot: " + err.Error()})
+		return
+	}
+	if s.Version != snapshot.FormatVersion {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Unsupported snapshot version"})
+		return
+	}
+	result, err := snapshot.Restore(h.itemRepo, &s, h.clock.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to restore snapshot: " + err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Status 返回内存、限流配额和事件统计
+func (h *AdminHandler) Status(c *gin.Context) {
+	response := AdminStatusResponse{
+		Timestamp:         h.clock.Now().Format(time.RFC3339),
+		PendingItemsCount: len(h.itemRepo.GetAll()),
+	}
+	if h.memoryMonitor != nil {
+		h.memoryMonitor.UpdateStatus()
+		response.Memory = h.memoryMonitor.GetStatus()
+	}
+	if h.rateLimits != nil {
+		response.RateLimits = h.rateLimits.Stats()
+	}
+	if h.idempotency != nil {
+		if stats, err := h.idempotency.Stats(); err == nil {
+			response.Idempotency = &stats
+		}
+	}
+	if h.eventCounter != nil {
+		response.EventCounts = h.eventCounter.Snapshot()
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SSE 心跳间隔，防止代理因连接空闲而断开
+const eventHeartbeatInterval = 15 * time.Second
+
+// EventHandler 事件推送处理器
+type EventHandler struct {
+	bus          *events.Bus
+	playerSecret string
+	clock        clock.Clock
+}
+
+// NewEventHandler 创建新的事件推送处理器，playerSecret 为空时玩家事件流不可用，clk 为 nil 时使用系统时间
+func NewEventHandler(bus *events.Bus, playerSecret string, clk clock.Clock) *EventHandler {
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &EventHandler{
+		bus:          bus,
+		playerSecret: playerSecret,
+		clock:        clk,
+	}
+}
+
+// StreamEvents 通过SSE推送与分享者相关的事件
+// 需要为该分享者签发的玩家令牌，通过 "Authorization: Bearer <token>" 或 token 查询参数（供 EventSource 使用）传递
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	sharerID := c.Query("sharer_id")
+	if sharerID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "sharer_id is required",
+		})
+		return
+	}
+	if status, err := authenticatePlayer(c, h.playerSecret, sharerID); err != nil {
+		message := "Invalid player token"
+		if errors.Is(err, errPlayerAuthDisabled) {
+			message = "Player event stream is disabled"
+		}
+		c.JSON(status, ErrorResponse{
+			Error: message,
+		})
+		return
+	}
+
+	// 先发送一个连接成功事件，客户端可据此确认订阅已生效
+	h.stream(c, gin.H{"sharer_id": sharerID}, func(event events.Event) bool {
+		// 只推送该分享者自己的事件，以及其参与的交易的事件
+		return events.Concerns(event, sharerID)
+	})
+}
+
+// StreamAllEvents 通过SSE推送全部事件，供运维查看，可按 type 过滤事件类型
+func (h *EventHandler) StreamAllEvents(c *gin.Context) {
+	eventType := events.Type(c.Query("type"))
+	h.stream(c, gin.H{"type": eventType}, func(event events.Event) bool {
+		return eventType == "" || event.Type() == eventType
+	})
+}
+
+// 订阅事件总线并推送满足 match 的事件，subscribed 为连接成功事件的内容
+func (h *EventHandler) stream(c *gin.Context, subscribed gin.H, match func(events.Event) bool) {
+	ch, unsubscribe := h.bus.Subscribe(0)
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	c.SSEvent("subscribed", subscribed)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-ch:
+			if !ok {
+				return false
+			}
+			if match(event) {
+				c.SSEvent(string(event.Type()), event)
+			}
+			return true
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"timestamp": h.clock.Now().Format(time.RFC3339)})
+			return true
+		}
+	})
+}
@@ -0,0 +1,351 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GroupHandler 玩家群组处理器
+// 每个请求需要为执行操作的玩家（owner_id、inviter_id、actor_id 或 player_id）签发的玩家令牌，
+// 通过 "Authorization: Bearer <token>" 传递
+type GroupHandler struct {
+	groups       *service.GroupService
+	playerSecret string
+}
+
+// NewGroupHandler 创建新的玩家群组处理器，playerSecret 为空时群组接口不可用
+func NewGroupHandler(groups *service.GroupService, playerSecret string) *GroupHandler {
+	return &GroupHandler{
+		groups:       groups,
+		playerSecret: playerSecret,
+	}
+}
+
+// 创建群组的请求结构
+type CreateGroupRequest struct {
+	Name    string `json:"name" binding:"required"`
+	OwnerID string `json:"owner_id" binding:"required"`
+}
+
+// 邀请玩家的请求结构
+type InviteGroupRequest struct {
+	InviterID string `json:"inviter_id" binding:"required"`
+	PlayerID  string `json:"player_id" binding:"required"`
+}
+
+// 加入或退出群组的请求结构
+type GroupPlayerRequest struct {
+	PlayerID string `json:"player_id" binding:"required"`
+}
+
+// 调整成员角色的请求结构，role 为 owner 时转让群组
+type SetGroupRoleRequest struct {
+	ActorID  string           `json:"actor_id" binding:"required"`
+	PlayerID string           `json:"player_id" binding:"required"`
+	Role     models.GroupRole `json:"role" binding:"required"`
+}
+
+// 移除成员或撤回邀请的请求结构
+type RemoveGroupMemberRequest struct {
+	ActorID  string `json:"actor_id" binding:"required"`
+	PlayerID string `json:"player_id" binding:"required"`
+}
+
+// 单个群组的响应结构
+type GroupResponse struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Group   *models.Group `json:"group,omitempty"`
+}
+
+// 群组列表的响应结构
+type GroupsResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Groups  []*models.Group `json:"groups"`
+}
+
+// 群组物品列表的响应结构，next_cursor 为空表示没有更多结果
+type GroupItemsResponse struct {
+	Code       int            `json:"code"`
+	Message    string         `json:"message"`
+	Items      []*models.Item `json:"items"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+// CreateGroup 创建群组，创建者成为群主
+func (h *GroupHandler) CreateGroup(c *gin.Context) {
+	var req CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+
+	if !h.authenticate(c, req.OwnerID) {
+		return
+	}
+
+	group, err := h.groups.Create(req.Name, req.OwnerID)
+	if err != nil {
+		groupError(c, err, "创建群组失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "群组已创建！呱呱！",
+		Group:   group,
+	})
+}
+
+// ListGroups 列出玩家所在或被邀请加入的群组
+func (h *GroupHandler) ListGroups(c *gin.Context) {
+	playerID := c.Query("player_id")
+	if playerID == "" {
+		c.JSON(http.StatusBadRequest, GroupsResponse{
+			Code:    400,
+			Message: "缺少 player_id 参数",
+			Groups:  []*models.Group{},
+		})
+		return
+	}
+	if status, err := authenticatePlayer(c, h.playerSecret, playerID); err != nil {
+		c.JSON(status, GroupsResponse{
+			Code:    status,
+			Message: playerAuthMessage(err),
+			Groups:  []*models.Group{},
+		})
+		return
+	}
+
+	groups, err := h.groups.List(playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, GroupsResponse{
+			Code:    500,
+			Message: "查询群组失败: " + err.Error(),
+			Groups:  []*models.Group{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupsResponse{
+		Code:    200,
+		Message: "查询成功",
+		Groups:  groups,
+	})
+}
+
+// GetGroup 查看群组，只有成员和被邀请的玩家可以查看
+func (h *GroupHandler) GetGroup(c *gin.Context) {
+	playerID := c.Query("player_id")
+	if !h.authenticate(c, playerID) {
+		return
+	}
+
+	group, err := h.groups.Get(c.Param("id"), playerID)
+	if err != nil {
+		groupError(c, err, "查询群组失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "查询成功",
+		Group:   group,
+	})
+}
+
+// InviteToGroup 管理员或群主邀请玩家
+func (h *GroupHandler) InviteToGroup(c *gin.Context) {
+	var req InviteGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+
+	if !h.authenticate(c, req.InviterID) {
+		return
+	}
+
+	group, err := h.groups.Invite(c.Param("id"), req.InviterID, req.PlayerID)
+	if err != nil {
+		groupError(c, err, "邀请玩家失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "邀请已发送",
+		Group:   group,
+	})
+}
+
+// JoinGroup 被邀请的玩家加入群组
+func (h *GroupHandler) JoinGroup(c *gin.Context) {
+	var req GroupPlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+
+	if !h.authenticate(c, req.PlayerID) {
+		return
+	}
+
+	group, err := h.groups.Join(c.Param("id"), req.PlayerID)
+	if err != nil {
+		groupError(c, err, "加入群组失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "已加入群组！呱呱！",
+		Group:   group,
+	})
+}
+
+// LeaveGroup 成员退出群组
+func (h *GroupHandler) LeaveGroup(c *gin.Context) {
+	var req GroupPlayerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+
+	if !h.authenticate(c, req.PlayerID) {
+		return
+	}
+
+	group, err := h.groups.Leave(c.Param("id"), req.PlayerID)
+	if err != nil {
+		groupError(c, err, "退出群组失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "已退出群组",
+		Group:   group,
+	})
+}
+
+// SetGroupRole 群主调整成员的角色或转让群组
+func (h *GroupHandler) SetGroupRole(c *gin.Context) {
+	var req SetGroupRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+
+	if !h.authenticate(c, req.ActorID) {
+		return
+	}
+
+	group, err := h.groups.SetRole(c.Param("id"), req.ActorID, req.PlayerID, req.Role)
+	if err != nil {
+		groupError(c, err, "调整角色失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "角色已调整",
+		Group:   group,
+	})
+}
+
+// RemoveGroupMember 移除成员或撤回邀请
+func (h *GroupHandler) RemoveGroupMember(c *gin.Context) {
+	var req RemoveGroupMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+
+	if !h.authenticate(c, req.ActorID) {
+		return
+	}
+
+	group, err := h.groups.Remove(c.Param("id"), req.ActorID, req.PlayerID)
+	if err != nil {
+		groupError(c, err, "移除成员失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupResponse{
+		Code:    200,
+		Message: "成员已移除",
+		Group:   group,
+	})
+}
+
+// ListGroupItems 列出分享给群组、可以领取的物品，按分享时间从新到旧分页返回
+func (h *GroupHandler) ListGroupItems(c *gin.Context) {
+	playerID := c.Query("player_id")
+	if !h.authenticate(c, playerID) {
+		return
+	}
+
+	var page *models.ItemPage
+	limit, err := intParam(c, "limit")
+	if err == nil {
+		page, err = h.groups.Items(c.Param("id"), playerID, limit, c.Query("cursor"))
+	}
+	if err != nil {
+		groupError(c, err, "查询群组物品失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupItemsResponse{
+		Code:       200,
+		Message:    "查询成功",
+		Items:      page.Items,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// 校验为 playerID 签发的玩家令牌，失败时写入响应并返回 false
+func (h *GroupHandler) authenticate(c *gin.Context, playerID string) bool {
+	status, err := authenticatePlayer(c, h.playerSecret, playerID)
+	if err != nil {
+		groupFailure(c, status, playerAuthMessage(err))
+		return false
+	}
+	return true
+}
+
+// 将群组服务错误转换为响应
+func groupError(c *gin.Context, err error, prefix string) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		groupFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+	case errors.Is(err, service.ErrGroupNotFound):
+		groupFailure(c, http.StatusNotFound, "群组不存在")
+	case errors.Is(err, service.ErrGroupForbidden):
+		groupFailure(c, http.StatusForbidden, "无权执行该群组操作")
+	case errors.Is(err, service.ErrNotGroupMember):
+		groupFailure(c, http.StatusForbidden, "玩家不是群组成员")
+	case errors.Is(err, service.ErrNotInvited):
+		groupFailure(c, http.StatusForbidden, "玩家没有收到群组的邀请")
+	case errors.Is(err, service.ErrAlreadyGroupMember):
+		groupFailure(c, http.StatusConflict, "玩家已经是群组成员")
+	case errors.Is(err, service.ErrGroupConflict):
+		groupFailure(c, http.StatusConflict, "群组正在被修改，请稍后重试")
+	default:
+		groupFailure(c, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
+
+func groupFailure(c *gin.Context, status int, message string) {
+	c.JSON(status, GroupResponse{
+		Code:    status,
+		Message: message,
+	})
+}
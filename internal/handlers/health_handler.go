@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/models"
+	"duckex-server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthHandler 健康检查处理器
+type HealthHandler struct {
+	itemRepo     models.ItemRepository
+	eventCounter *events.Counter
+	rateLimits   *middleware.RateLimits
+	clock        clock.Clock
+}
+
+// NewHealthHandler 创建新的健康检查处理器，eventCounter 和 rateLimits 为 nil 时不返回对应统计
+func NewHealthHandler(itemRepo models.ItemRepository, eventCounter *events.Counter, rateLimits *middleware.RateLimits, clk clock.Clock) *HealthHandler {
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &HealthHandler{
+		itemRepo:     itemRepo,
+		eventCounter: eventCounter,
+		rateLimits:   rateLimits,
+		clock:        clk,
+	}
+}
+
+// 健康检查的响应结构
+type HealthResponse struct {
+	Status            string                                `json:"status"`
+	Message           string                                `json:"message"`
+	Timestamp         string                                `json:"timestamp"`
+	PendingItemsCount int                                   `json:"pending_items_count"`
+	EventCounts       map[events.Type]int64                 `json:"event_counts,omitempty"`
+	RateLimits        map[string]map[string]ratelimit.Stats `json:"rate_limits,omitempty"`
+}
+
+// Health 返回服务状态和仍在等待领取的物品数
+// 接口无需认证，不返回物品本身以免泄露取件码；物品列表由需要管理令牌的 /api/v1/admin/items 提供
+func (h *HealthHandler) Health(c *gin.Context) {
+	response := HealthResponse{
+		Status:    "ok",
+		Message:   "DuckEx Server is quacking!",
+		Timestamp: h.clock.Now().Format(time.RFC3339),
+		// GetAll()方法只会返回未过期的物品
+		PendingItemsCount: len(h.itemRepo.GetAll()),
+	}
+	if h.eventCounter != nil {
+		response.EventCounts = h.eventCounter.Snapshot()
+	}
+	if h.rateLimits != nil {
+		response.RateLimits = h.rateLimits.Stats()
+	}
+	c.JSON(http.StatusOK, response)
+}
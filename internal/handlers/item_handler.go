@@ -1,25 +1,28 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"duckex-server/internal/models"
+	"duckex-server/internal/service"
 	"duckex-server/internal/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// ItemHandler 物品处理器
+// ItemHandler 物品处理器，负责请求解析和响应映射，业务逻辑由 service.ItemService 实现
 type ItemHandler struct {
-	itemRepo       models.ItemRepository
-	memoryMonitor  *utils.MemoryMonitor
+	items         *service.ItemService
+	memoryMonitor *utils.MemoryMonitor
 }
 
-// NewItemHandler 创建新的物品处理器
-func NewItemHandler(itemRepo models.ItemRepository, memoryMonitor *utils.MemoryMonitor) *ItemHandler {
+// NewItemHandler 创建新的物品处理器，memoryMonitor 用于在暂停分享时返回内存状态，可以为 nil
+func NewItemHandler(items *service.ItemService, memoryMonitor *utils.MemoryMonitor) *ItemHandler {
 	return &ItemHandler{
-		itemRepo:      itemRepo,
+		items:         items,
 		memoryMonitor: memoryMonitor,
 	}
 }
@@ -32,13 +35,18 @@ type ShareItemRequest struct {
 	Num         int     `json:"num" binding:"required,min=1"`
 	Durability  float64 `json:"durability" binding:"required,min=0"`
 	SharerID    string  `json:"sharer_id" binding:"required"`
+	// 为 true 时公开上架到市场
+	Listed bool `json:"listed"`
+	// 不为空时只分享给该群组，只有群组成员可以领取
+	GroupID string `json:"group_id"`
 }
 
-// 分享物品的响应结构
+// 分享物品的响应结构，公开上架时包含挂牌ID
 type ShareItemResponse struct {
 	Message    string `json:"message"`
 	PickupCode string `json:"pickup_code"`
 	ExpiresAt  string `json:"expires_at"`
+	ListingID  string `json:"listing_id,omitempty"`
 }
 
 // 领取物品的请求结构
@@ -59,49 +67,75 @@ type ClaimItemResponse struct {
 	Item    *models.Item `json:"item,omitempty"`
 }
 
+// 预留物品的请求结构
+type ReserveItemRequest struct {
+	PickupCode string `json:"pickup_code" binding:"required"`
+	ClaimerID  string `json:"claimer_id" binding:"required"`
+}
+
+// 预留物品的响应结构，成功时包含确认或取消预留所需的令牌
+type ReserveItemResponse struct {
+	Code             int          `json:"code"`
+	Message          string       `json:"message"`
+	ReservationToken string       `json:"reservation_token,omitempty"`
+	LeaseExpiresAt   string       `json:"lease_expires_at,omitempty"`
+	Item             *models.Item `json:"item,omitempty"`
+}
+
+// 确认或取消预留的请求结构
+type ReservationRequest struct {
+	PickupCode       string `json:"pickup_code" binding:"required"`
+	ReservationToken string `json:"reservation_token" binding:"required"`
+}
+
 // ShareItem 分享物品
 func (h *ItemHandler) ShareItem(c *gin.Context) {
-	// 检查内存使用情况，如果内存占用过高，暂停存放接口响应
-	if h.memoryMonitor != nil {
-		h.memoryMonitor.UpdateStatus()
-		if h.memoryMonitor.IsShareDisabled() {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"error": "Storage temporarily disabled due to high memory usage. Please try again later.",
-				"memory_status": h.memoryMonitor.GetStatus(),
-			})
-			return
-		}
-	}
-	
+	// 只解析请求体，字段校验由服务完成，binding 标签用于生成接口文档
 	var req ShareItemRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		h.items.RejectShare(req.SharerID, err)
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error: "Invalid request format: " + err.Error(),
 		})
 		return
 	}
 
-	// 生成取件码
-	pickupCode := utils.GeneratePickupCode()
-	expiresAt := utils.GetExpirationTime()
-
-	// 创建物品
-	item := &models.Item{
-		ID:          models.GetCurrentTime().Format("20060102150405") + req.SharerID,
+	item, err := h.items.Share(service.ShareRequest{
 		Name:        req.Name,
 		Description: req.Description,
 		TypeID:      req.TypeID,
 		Num:         req.Num,
 		Durability:  req.Durability,
 		SharerID:    req.SharerID,
-		PickupCode:  pickupCode,
-		CreatedAt:   models.GetCurrentTime(),
-		ExpiresAt:   models.GetExpirationTime(),
-		IsClaimed:   false,
-	}
-
-	// 保存物品
-	if err := h.itemRepo.Create(item); err != nil {
+		Listed:      req.Listed,
+		GroupID:     req.GroupID,
+		PlayerToken: playerToken(c),
+	})
+	var invalid *service.ValidationError
+	switch {
+	case errors.Is(err, service.ErrShareDisabled):
+		response := gin.H{"error": "Storage temporarily disabled due to high memory usage. Please try again later."}
+		if h.memoryMonitor != nil {
+			response["memory_status"] = h.memoryMonitor.GetStatus()
+		}
+		c.JSON(http.StatusServiceUnavailable, response)
+		return
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid request format: " + err.Error(),
+		})
+		return
+	case errors.Is(err, service.ErrPlayerUnauthenticated):
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error: "Sharing to a group requires the sharer's player token",
+		})
+		return
+	case errors.Is(err, service.ErrNotGroupMember):
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error: "Only group members can share items to the group",
+		})
+		return
+	case err != nil:
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error: "Failed to share item: " + err.Error(),
 		})
@@ -110,8 +144,9 @@ func (h *ItemHandler) ShareItem(c *gin.Context) {
 
 	c.JSON(http.StatusOK, ShareItemResponse{
 		Message:    "Item shared successfully! Quack!",
-		PickupCode: pickupCode,
-		ExpiresAt:  expiresAt.Format(time.RFC3339),
+		PickupCode: item.PickupCode,
+		ExpiresAt:  item.ExpiresAt.Format(time.RFC3339),
+		ListingID:  item.ListingID,
 	})
 }
 
@@ -126,9 +161,33 @@ func (h *ItemHandler) ClaimItem(c *gin.Context) {
 		return
 	}
 
-	// 根据取件码查找物品
-	item, err := h.itemRepo.GetByPickupCode(req.PickupCode)
-	if err != nil {
+	claimedItem, err := h.items.Claim(req.PickupCode, req.ClaimerID, playerToken(c))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    404,
+			Message: "提取码无效",
+		})
+		return
+	case errors.Is(err, service.ErrAlreadyClaimed):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    409,
+			Message: "该物品已被领取",
+		})
+		return
+	case errors.Is(err, service.ErrPlayerUnauthenticated):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    401,
+			Message: "该物品只分享给群组，需要领取者的玩家令牌",
+		})
+		return
+	case errors.Is(err, service.ErrNotGroupMember):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    403,
+			Message: "该物品只有群组成员可以领取",
+		})
+		return
+	case err != nil:
 		c.JSON(http.StatusOK, ClaimItemResponse{
 			Code:    500,
 			Message: "领取物品失败: " + err.Error(),
@@ -136,50 +195,111 @@ func (h *ItemHandler) ClaimItem(c *gin.Context) {
 		return
 	}
 
-	if item == nil {
-		c.JSON(http.StatusOK, ClaimItemResponse{
-			Code:    404,
-			Message: "提取码无效",
+	c.JSON(http.StatusOK, ClaimItemResponse{
+		Code:    200,
+		Message: "物品领取成功！呱呱！",
+		Item:    claimedItem,
+	})
+}
+
+// ReserveItem 预留物品，领取者在预留期限内确认后物品才被领走
+func (h *ItemHandler) ReserveItem(c *gin.Context) {
+	var req ReserveItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ReserveItemResponse{
+			Code:    400,
+			Message: "请求格式无效: " + err.Error(),
 		})
 		return
 	}
 
-	// 检查物品是否已被领取
-	if item.IsClaimed {
-		c.JSON(http.StatusOK, ClaimItemResponse{
+	reserved, err := h.items.Reserve(req.PickupCode, req.ClaimerID, playerToken(c))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusOK, ReserveItemResponse{
+			Code:    404,
+			Message: "提取码无效",
+		})
+		return
+	case errors.Is(err, service.ErrAlreadyClaimed):
+		c.JSON(http.StatusOK, ReserveItemResponse{
 			Code:    409,
 			Message: "该物品已被领取",
 		})
 		return
+	case errors.Is(err, service.ErrPlayerUnauthenticated):
+		c.JSON(http.StatusOK, ReserveItemResponse{
+			Code:    401,
+			Message: "该物品只分享给群组，需要领取者的玩家令牌",
+		})
+		return
+	case errors.Is(err, service.ErrNotGroupMember):
+		c.JSON(http.StatusOK, ReserveItemResponse{
+			Code:    403,
+			Message: "该物品只有群组成员可以领取",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusOK, ReserveItemResponse{
+			Code:    500,
+			Message: "预留物品失败: " + err.Error(),
+		})
+		return
 	}
 
-	// 检查物品是否过期
-	if models.GetCurrentTime().After(item.ExpiresAt) {
-		c.JSON(http.StatusOK, ClaimItemResponse{
-			Code:    410,
-			Message: "该物品已过期",
+	// 令牌只通过 reservation_token 返回
+	token, leaseExpiresAt := reserved.Reservation.Token, reserved.Reservation.ExpiresAt
+	reserved.Reservation = nil
+	c.JSON(http.StatusOK, ReserveItemResponse{
+		Code:             200,
+		Message:          "物品已预留，请在预留到期前确认领取",
+		ReservationToken: token,
+		LeaseExpiresAt:   leaseExpiresAt.Format(time.RFC3339),
+		Item:             reserved,
+	})
+}
+
+// ConfirmItem 确认预留，物品被领走
+func (h *ItemHandler) ConfirmItem(c *gin.Context) {
+	h.settleReservation(c, h.items.Confirm, "物品领取成功！呱呱！", "确认领取失败: ")
+}
+
+// ReleaseItem 取消预留，物品恢复为可领取
+func (h *ItemHandler) ReleaseItem(c *gin.Context) {
+	h.settleReservation(c, h.items.Release, "预留已取消", "取消预留失败: ")
+}
+
+// 确认或取消预留，响应与领取物品一致
+func (h *ItemHandler) settleReservation(c *gin.Context, settle func(pickupCode, token string) (*models.Item, error), success, failure string) {
+	var req ReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ClaimItemResponse{
+			Code:    400,
+			Message: "请求格式无效: " + err.Error(),
 		})
 		return
 	}
 
-	// 保存物品信息用于响应
-	claimedItem := *item
-	claimedItem.IsClaimed = true
-	claimedItem.ClaimerID = req.ClaimerID
-
-	// 物品被领取后立即删除
-	if err := h.itemRepo.Delete(item.PickupCode); err != nil {
-		c.JSON(http.StatusInternalServerError, ClaimItemResponse{
+	item, err := settle(req.PickupCode, req.ReservationToken)
+	switch {
+	case errors.Is(err, service.ErrReservationNotFound):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    410,
+			Message: "预留不存在或已过期",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusOK, ClaimItemResponse{
 			Code:    500,
-			Message: "删除物品失败: " + err.Error(),
+			Message: failure + err.Error(),
 		})
 		return
 	}
 
-	claimedItemPtr := claimedItem
+	item.Reservation = nil
 	c.JSON(http.StatusOK, ClaimItemResponse{
 		Code:    200,
-		Message: "物品领取成功！呱呱！",
-		Item:    &claimedItemPtr,
+		Message: success,
+		Item:    item,
 	})
-}
\ No newline at end of file
+}
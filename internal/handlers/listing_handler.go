@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListingHandler 市场处理器，浏览公开上架的物品并凭挂牌ID领取
+type ListingHandler struct {
+	items *service.ItemService
+}
+
+// NewListingHandler 创建新的市场处理器
+func NewListingHandler(items *service.ItemService) *ListingHandler {
+	return &ListingHandler{
+		items: items,
+	}
+}
+
+// 凭挂牌ID领取物品的请求结构
+type ClaimListingRequest struct {
+	ClaimerID string `json:"claimer_id" binding:"required"`
+}
+
+// 市场挂牌列表的响应结构，next_cursor 为空表示没有更多结果
+type ListingsResponse struct {
+	Code       int               `json:"code"`
+	Message    string            `json:"message"`
+	Listings   []*models.Listing `json:"listings"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// 单个挂牌的响应结构
+type ListingResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Listing *models.Listing `json:"listing,omitempty"`
+}
+
+// SearchListings 浏览和搜索市场，按上架时间从新到旧分页返回
+func (h *ListingHandler) SearchListings(c *gin.Context) {
+	var page *service.ListingPage
+	query, err := listingQuery(c)
+	if err == nil {
+		page, err = h.items.SearchListings(query)
+	}
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		c.JSON(http.StatusBadRequest, ListingsResponse{
+			Code:     400,
+			Message:  "查询参数无效: " + err.Error(),
+			Listings: []*models.Listing{},
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ListingsResponse{
+			Code:     500,
+			Message:  "查询市场失败: " + err.Error(),
+			Listings: []*models.Listing{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListingsResponse{
+		Code:       200,
+		Message:    "查询成功",
+		Listings:   page.Listings,
+		NextCursor: page.NextCursor,
+	})
+}
+
+// 解析搜索条件，数值参数格式错误时返回 *service.ValidationError
+func listingQuery(c *gin.Context) (service.ListingQuery, error) {
+	query := service.ListingQuery{
+		Text:     c.Query("q"),
+		Name:     c.Query("name"),
+		SharerID: c.Query("sharer_id"),
+		Cursor:   c.Query("cursor"),
+	}
+	var err error
+	if query.TypeID, err = intParam(c, "type_id"); err != nil {
+		return query, err
+	}
+	if query.Limit, err = intParam(c, "limit"); err != nil {
+		return query, err
+	}
+	if query.MinDurability, err = floatParam(c, "min_durability"); err != nil {
+		return query, err
+	}
+	if query.MaxDurability, err = floatParam(c, "max_durability"); err != nil {
+		return query, err
+	}
+	return query, nil
+}
+
+func intParam(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, &service.ValidationError{Field: name, Message: "must be an integer"}
+	}
+	return value, nil
+}
+
+func floatParam(c *gin.Context, name string) (*float64, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, &service.ValidationError{Field: name, Message: "must be a number"}
+	}
+	return &value, nil
+}
+
+// GetListing 查看挂牌
+func (h *ListingHandler) GetListing(c *gin.Context) {
+	listing, err := h.items.GetListing(c.Param("id"))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusNotFound, ListingResponse{
+			Code:    404,
+			Message: "挂牌不存在或已下架",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, ListingResponse{
+			Code:    500,
+			Message: "查询挂牌失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ListingResponse{
+		Code:    200,
+		Message: "查询成功",
+		Listing: listing,
+	})
+}
+
+// ClaimListing 凭挂牌ID领取物品，响应与凭取件码领取相同
+func (h *ListingHandler) ClaimListing(c *gin.Context) {
+	var req ClaimListingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ClaimItemResponse{
+			Code:    400,
+			Message: "请求格式无效: " + err.Error(),
+		})
+		return
+	}
+
+	claimedItem, err := h.items.ClaimListing(c.Param("id"), req.ClaimerID, playerToken(c))
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    404,
+			Message: "挂牌不存在或已下架",
+		})
+		return
+	case errors.Is(err, service.ErrAlreadyClaimed):
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    409,
+			Message: "该物品已被领取",
+		})
+		return
+	case err != nil:
+		c.JSON(http.StatusOK, ClaimItemResponse{
+			Code:    500,
+			Message: "领取物品失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ClaimItemResponse{
+		Code:    200,
+		Message: "物品领取成功！呱呱！",
+		Item:    claimedItem,
+	})
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"duckex-server/internal/playertoken"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 玩家身份校验失败的原因
+var (
+	errPlayerAuthDisabled = errors.New("player authentication is disabled")
+	errInvalidPlayerToken = errors.New("invalid player token")
+)
+
+// 请求携带的玩家令牌，通过 "Authorization: Bearer <token>" 或 token 查询参数（供 EventSource 使用）传递
+func playerToken(c *gin.Context) string {
+	if token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
+
+// 校验请求携带的玩家令牌是否为 playerID 签发，失败时返回应使用的状态码：
+// 未配置密钥时为 403，令牌缺失或不匹配时为 401
+func authenticatePlayer(c *gin.Context, secret, playerID string) (int, error) {
+	if secret == "" {
+		return http.StatusForbidden, errPlayerAuthDisabled
+	}
+	if !playertoken.Verify(secret, playerID, playerToken(c)) {
+		return http.StatusUnauthorized, errInvalidPlayerToken
+	}
+	return http.StatusOK, nil
+}
+
+// 玩家身份校验失败时返回给玩家的提示
+func playerAuthMessage(err error) string {
+	if errors.Is(err, errPlayerAuthDisabled) {
+		return "未配置玩家令牌密钥，无法确认玩家身份"
+	}
+	return "玩家令牌无效"
+}
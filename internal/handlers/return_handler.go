@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"net/http"
+
+	"duckex-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReturnHandler 退回箱处理器
+// 查看和领回都需要为 sharer_id 签发的玩家令牌，通过 "Authorization: Bearer <token>" 传递
+type ReturnHandler struct {
+	returnBox    models.ReturnBox
+	playerSecret string
+}
+
+// NewReturnHandler 创建新的退回箱处理器，playerSecret 为空时退回箱接口不可用
+func NewReturnHandler(returnBox models.ReturnBox, playerSecret string) *ReturnHandler {
+	return &ReturnHandler{
+		returnBox:    returnBox,
+		playerSecret: playerSecret,
+	}
+}
+
+// 领回退回物品的请求结构
+type CollectReturnsRequest struct {
+	SharerID string `json:"sharer_id" binding:"required"`
+}
+
+// 退回箱的响应结构
+type ReturnsResponse struct {
+	Code    int                    `json:"code"`
+	Message string                 `json:"message"`
+	Items   []*models.ReturnedItem `json:"items"`
+}
+
+// ListReturns 查看退回箱
+func (h *ReturnHandler) ListReturns(c *gin.Context) {
+	sharerID := c.Query("sharer_id")
+	if sharerID == "" {
+		c.JSON(http.StatusBadRequest, ReturnsResponse{
+			Code:    400,
+			Message: "缺少 sharer_id 参数",
+			Items:   []*models.ReturnedItem{},
+		})
+		return
+	}
+	if !h.authenticate(c, sharerID) {
+		return
+	}
+
+	items, err := h.returnBox.List(sharerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ReturnsResponse{
+			Code:    500,
+			Message: "查询退回箱失败: " + err.Error(),
+			Items:   []*models.ReturnedItem{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReturnsResponse{
+		Code:    200,
+		Message: "查询成功",
+		Items:   items,
+	})
+}
+
+// CollectReturns 领回退回箱中的全部物品
+func (h *ReturnHandler) CollectReturns(c *gin.Context) {
+	var req CollectReturnsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ReturnsResponse{
+			Code:    400,
+			Message: "请求格式无效: " + err.Error(),
+			Items:   []*models.ReturnedItem{},
+		})
+		return
+	}
+	if !h.authenticate(c, req.SharerID) {
+		return
+	}
+
+	items, err := h.returnBox.Collect(req.SharerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ReturnsResponse{
+			Code:    500,
+			Message: "领回物品失败: " + err.Error(),
+			Items:   []*models.ReturnedItem{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReturnsResponse{
+		Code:    200,
+		Message: "退回物品已领回！呱呱！",
+		Items:   items,
+	})
+}
+
+// 校验为 sharerID 签发的玩家令牌，失败时写入响应并返回 false
+func (h *ReturnHandler) authenticate(c *gin.Context, sharerID string) bool {
+	status, err := authenticatePlayer(c, h.playerSecret, sharerID)
+	if err != nil {
+		c.JSON(status, ReturnsResponse{
+			Code:    status,
+			Message: playerAuthMessage(err),
+			Items:   []*models.ReturnedItem{},
+		})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+	"duckex-server/internal/snapshot"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAdminTestRouter(clk clock.Clock) (*gin.Engine, models.ItemRepository, models.ReturnBox) {
+	gin.SetMode(gin.TestMode)
+
+	itemRepo := models.NewInMemoryItemRepository(clk)
+	returnBox := models.NewInMemoryReturnBox(0, clk)
+	items := service.NewItemService(service.Deps{
+		ItemRepo:  itemRepo,
+		ReturnBox: returnBox,
+		EventBus:  events.NewBus(),
+		Clock:     clk,
+	})
+	adminHandler := handlers.NewAdminHandler(handlers.AdminDeps{
+		ItemRepo: itemRepo,
+		Items:    items,
+		Clock:    clk,
+	})
+
+	r := gin.New()
+	admin := r.Group("/api/v1/admin")
+	{
+		admin.GET("/items", adminHandler.ListItems)
+		admin.GET("/items/:code", adminHandler.GetItem)
+		admin.DELETE("/items/:code", adminHandler.CancelItem)
+		admin.GET("/snapshot", adminHandler.DumpSnapshot)
+		admin.POST("/snapshot", adminHandler.RestoreSnapshot)
+		admin.GET("/status", adminHandler.Status)
+	}
+	return r, itemRepo, returnBox
+}
+
+func adminItem(code, sharerID string, expiresAt time.Time) *models.Item {
+	return &models.Item{
+		ID:         "item-" + code,
+		Name:       "Golden Duck",
+		SharerID:   sharerID,
+		PickupCode: code,
+		ExpiresAt:  expiresAt,
+	}
+}
+
+func TestAdminListAndGetItems(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	r, itemRepo, _ := setupAdminTestRouter(clk)
+	require.NoError(t, itemRepo.Create(adminItem("111111", "player1", clk.Now().Add(time.Hour))))
+	require.NoError(t, itemRepo.Create(adminItem("222222", "player2", clk.Now().Add(time.Hour))))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/items?sharer_id=player1", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var list handlers.ItemsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	require.Len(t, list.Items, 1)
+	assert.Equal(t, "111111", list.Items[0].PickupCode)
+
+	// 查看不会领取物品
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/items/222222", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	item, _ := itemRepo.GetByPickupCode("222222")
+	require.NotNil(t, item)
+	assert.False(t, item.IsClaimed)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/items/999999", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminCancelItem(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	r, itemRepo, returnBox := setupAdminTestRouter(clk)
+	require.NoError(t, itemRepo.Create(adminItem("111111", "player1", clk.Now().Add(time.Hour))))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/admin/items/111111", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	item, _ := itemRepo.GetByPickupCode("111111")
+	assert.Nil(t, item)
+	returned, err := returnBox.List("player1")
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	assert.Equal(t, "item-111111", returned[0].Item.ID)
+	assert.False(t, returned[0].Item.IsClaimed)
+
+	// 再次取消时物品已不存在
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/v1/admin/items/111111", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminSnapshotRoundTrip(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	r, itemRepo, _ := setupAdminTestRouter(clk)
+	require.NoError(t, itemRepo.Create(adminItem("111111", "player1", clk.Now().Add(time.Hour))))
+	require.NoError(t, itemRepo.Create(adminItem("222222", "player1", clk.Now().Add(2*time.Hour))))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/snapshot", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	dump := w.Body.Bytes()
+
+	// 恢复到新仓库，1小时后第一个物品已过期
+	target, targetRepo, _ := setupAdminTestRouter(clk)
+	clk.Advance(90 * time.Minute)
+	w = httptest.NewRecorder()
+	target.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/snapshot", bytes.NewReader(dump)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result snapshot.RestoreResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, snapshot.RestoreResult{Restored: 1, Expired: 1}, result)
+	item, _ := targetRepo.GetByPickupCode("222222")
+	require.NotNil(t, item)
+
+	// 再次恢复时取件码冲突
+	w = httptest.NewRecorder()
+	target.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/snapshot", bytes.NewReader(dump)))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, snapshot.RestoreResult{Expired: 1, Conflicts: 1}, result)
+
+	w = httptest.NewRecorder()
+	target.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/snapshot", bytes.NewBufferString(`{"version":99}`)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminRestoreRejectsIncompleteItems(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	r, itemRepo, _ := setupAdminTestRouter(clk)
+	expiresAt := clk.Now().Add(time.Hour)
+	s := snapshot.New([]*models.Item{
+		adminItem("111111", "player1", expiresAt),
+		adminItem("", "player1", expiresAt),
+		adminItem("333333", "", expiresAt),
+		{PickupCode: "444444", SharerID: "player1", ExpiresAt: expiresAt},
+	}, clk.Now())
+	body, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/v1/admin/snapshot", bytes.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	var result snapshot.RestoreResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, snapshot.RestoreResult{Restored: 1, Rejected: 3}, result)
+
+	items := itemRepo.GetAll()
+	require.Len(t, items, 1)
+	assert.Equal(t, "111111", items[0].PickupCode)
+}
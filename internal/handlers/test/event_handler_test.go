@@ -0,0 +1,187 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"duckex-server/internal/events"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/service"
+	"duckex-server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eventTestSecret = "player-secret"
+
+func setupEventTestServer() *httptest.Server {
+	gin.SetMode(gin.TestMode)
+
+	bus := events.NewBus()
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	monitor := utils.NewMemoryMonitor(500)
+	items := service.NewItemService(service.Deps{ItemRepo: itemRepo, MemoryMonitor: monitor, EventBus: bus})
+	itemHandler := handlers.NewItemHandler(items, monitor)
+	eventHandler := handlers.NewEventHandler(bus, eventTestSecret, nil)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	{
+		api.POST("/items/share", itemHandler.ShareItem)
+		api.POST("/items/claim", itemHandler.ClaimItem)
+		api.GET("/events", eventHandler.StreamEvents)
+	}
+	return httptest.NewServer(r)
+}
+
+func postJSON(t *testing.T, url string, body interface{}, out interface{}) {
+	requestBody, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(out))
+}
+
+// 读取下一个SSE事件名，跳过心跳
+func nextEventName(t *testing.T, reader *bufio.Reader) string {
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if name, ok := strings.CutPrefix(strings.TrimSpace(line), "event:"); ok && name != "ping" {
+			return name
+		}
+	}
+}
+
+// 读取事件名之后的数据行
+func nextEventData(t *testing.T, reader *bufio.Reader) string {
+	line, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+	require.True(t, ok)
+	return data
+}
+
+// 使用玩家令牌订阅分享者的事件
+func openEventStream(t *testing.T, serverURL, sharerID, token string) *http.Response {
+	req, err := http.NewRequest(http.MethodGet, serverURL+"/api/v1/events?sharer_id="+sharerID, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestStreamEvents(t *testing.T) {
+	server := setupEventTestServer()
+	defer server.Close()
+
+	resp := openEventStream(t, server.URL, "player123", playertoken.Sign(eventTestSecret, "player123"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, "subscribed", nextEventName(t, reader))
+
+	// 其他分享者的物品事件不应推送给该订阅者
+	var otherShare handlers.ShareItemResponse
+	postJSON(t, server.URL+"/api/v1/items/share", handlers.ShareItemRequest{
+		Name: "Other Item", Description: "Not mine", TypeID: 1, Num: 1, Durability: 10, SharerID: "player999",
+	}, &otherShare)
+
+	// 分享并领取自己的物品
+	var share handlers.ShareItemResponse
+	postJSON(t, server.URL+"/api/v1/items/share", handlers.ShareItemRequest{
+		Name: "Test Weapon", Description: "A powerful sword", TypeID: 1001, Num: 1, Durability: 90, SharerID: "player123",
+	}, &share)
+	var claim handlers.ClaimItemResponse
+	postJSON(t, server.URL+"/api/v1/items/claim", handlers.ClaimItemRequest{
+		PickupCode: share.PickupCode, ClaimerID: "player456",
+	}, &claim)
+	require.Equal(t, 200, claim.Code)
+
+	// 只收到自己的事件，且事件中不包含任何取件码
+	assert.Equal(t, string(events.TypeItemShared), nextEventName(t, reader))
+	data := nextEventData(t, reader)
+	assert.NotContains(t, data, share.PickupCode)
+	assert.NotContains(t, data, otherShare.PickupCode)
+	var shared events.ItemShared
+	require.NoError(t, json.Unmarshal([]byte(data), &shared))
+	assert.Equal(t, "player123", shared.Item.SharerID)
+	assert.Empty(t, shared.Item.PickupCode)
+
+	assert.Equal(t, string(events.TypeItemClaimed), nextEventName(t, reader))
+	data = nextEventData(t, reader)
+	assert.NotContains(t, data, share.PickupCode)
+	var event events.ItemClaimed
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	assert.Empty(t, event.Item.PickupCode)
+	assert.Equal(t, "player456", event.ClaimerID)
+}
+
+func TestStreamEventsQueryToken(t *testing.T) {
+	server := setupEventTestServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/events?sharer_id=player123&token=" + playertoken.Sign(eventTestSecret, "player123"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestStreamEventsRequiresPlayerToken(t *testing.T) {
+	server := setupEventTestServer()
+	defer server.Close()
+
+	// 没有令牌，或使用其他分享者的令牌订阅，都不能收到事件
+	tests := []struct {
+		name, token string
+	}{
+		{"missing token", ""},
+		{"other sharer's token", playertoken.Sign(eventTestSecret, "player999")},
+		{"wrong secret", playertoken.Sign("other-secret", "player123")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := openEventStream(t, server.URL, "player123", tt.token)
+			assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+			assert.NotContains(t, resp.Header.Get("Content-Type"), "text/event-stream")
+		})
+	}
+}
+
+func TestStreamEventsDisabledWithoutSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/events", handlers.NewEventHandler(events.NewBus(), "", nil).StreamEvents)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp := openEventStream(t, server.URL, "player123", playertoken.Sign("", "player123"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestStreamEventsRequiresSharerID(t *testing.T) {
+	server := setupEventTestServer()
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/v1/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
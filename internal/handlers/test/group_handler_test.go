@@ -0,0 +1,239 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlayerSecret = "player-secret"
+
+// 群组路由与分享、领取路由共用群组仓库和物品仓库
+func setupGroupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	groupRepo := models.NewInMemoryGroupRepository()
+	items := service.NewItemService(service.Deps{ItemRepo: itemRepo, Groups: groupRepo, PlayerTokenSecret: testPlayerSecret})
+	groups := service.NewGroupService(service.GroupDeps{Groups: groupRepo, ItemRepo: itemRepo})
+	itemHandler := handlers.NewItemHandler(items, nil)
+	groupHandler := handlers.NewGroupHandler(groups, testPlayerSecret)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	{
+		api.POST("/items/share", itemHandler.ShareItem)
+		api.POST("/items/claim", itemHandler.ClaimItem)
+		api.POST("/groups", groupHandler.CreateGroup)
+		api.GET("/groups", groupHandler.ListGroups)
+		api.GET("/groups/:id", groupHandler.GetGroup)
+		api.POST("/groups/:id/invite", groupHandler.InviteToGroup)
+		api.POST("/groups/:id/join", groupHandler.JoinGroup)
+		api.POST("/groups/:id/leave", groupHandler.LeaveGroup)
+		api.POST("/groups/:id/role", groupHandler.SetGroupRole)
+		api.POST("/groups/:id/remove", groupHandler.RemoveGroupMember)
+		api.GET("/groups/:id/items", groupHandler.ListGroupItems)
+	}
+	return r
+}
+
+// 以玩家 as 的身份发送请求，as 为空时不带玩家令牌，body 为 nil 时不带请求体
+func serveAs(t *testing.T, router *gin.Engine, method, path, as string, body interface{}) *httptest.ResponseRecorder {
+	reader := &bytes.Buffer{}
+	if body != nil {
+		require.NoError(t, json.NewEncoder(reader).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, reader)
+	req.Header.Set("Content-Type", "application/json")
+	if as != "" {
+		req.Header.Set("Authorization", "Bearer "+playertoken.Sign(testPlayerSecret, as))
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// 以玩家 as 的身份发送请求并解析群组响应
+func doGroup(t *testing.T, router *gin.Engine, method, path, as string, body interface{}) (int, handlers.GroupResponse) {
+	w := serveAs(t, router, method, path, as, body)
+	var response handlers.GroupResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, w.Code, response.Code)
+	return w.Code, response
+}
+
+// 创建 player123 为群主、player456 为成员的群组，返回群组路径
+func createClan(t *testing.T, router *gin.Engine) string {
+	status, created := doGroup(t, router, http.MethodPost, "/api/v1/groups", "player123", handlers.CreateGroupRequest{Name: "Duck Clan", OwnerID: "player123"})
+	require.Equal(t, http.StatusOK, status)
+	require.NotNil(t, created.Group)
+	path := "/api/v1/groups/" + created.Group.ID
+
+	status, _ = doGroup(t, router, http.MethodPost, path+"/invite", "player123", handlers.InviteGroupRequest{InviterID: "player123", PlayerID: "player456"})
+	require.Equal(t, http.StatusOK, status)
+	status, joined := doGroup(t, router, http.MethodPost, path+"/join", "player456", handlers.GroupPlayerRequest{PlayerID: "player456"})
+	require.Equal(t, http.StatusOK, status)
+	require.NotNil(t, joined.Group.Member("player456"))
+	return path
+}
+
+func TestGroupLifecycle(t *testing.T) {
+	router := setupGroupRouter()
+	path := createClan(t, router)
+
+	status, viewed := doGroup(t, router, http.MethodGet, path+"?player_id=player456", "player456", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, viewed.Group.Members, 2)
+	status, _ = doGroup(t, router, http.MethodGet, path+"?player_id=player789", "player789", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	// 普通成员不能邀请，群主可以提升为管理员
+	status, _ = doGroup(t, router, http.MethodPost, path+"/invite", "player456", handlers.InviteGroupRequest{InviterID: "player456", PlayerID: "player789"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, promoted := doGroup(t, router, http.MethodPost, path+"/role", "player123", handlers.SetGroupRoleRequest{ActorID: "player123", PlayerID: "player456", Role: models.RoleAdmin})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, models.RoleAdmin, promoted.Group.Member("player456").Role)
+	status, _ = doGroup(t, router, http.MethodPost, path+"/role", "player123", handlers.SetGroupRoleRequest{ActorID: "player123", PlayerID: "player456", Role: "king"})
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = doGroup(t, router, http.MethodPost, path+"/invite", "player456", handlers.InviteGroupRequest{InviterID: "player456", PlayerID: "player789"})
+	require.Equal(t, http.StatusOK, status)
+	status, _ = doGroup(t, router, http.MethodPost, path+"/join", "player456", handlers.GroupPlayerRequest{PlayerID: "player456"})
+	assert.Equal(t, http.StatusConflict, status)
+
+	w := serveAs(t, router, http.MethodGet, "/api/v1/groups?player_id=player789", "player789", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed handlers.GroupsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Groups, 1)
+	assert.True(t, listed.Groups[0].Invited("player789"))
+
+	// 撤回邀请、群主不能退出、成员退出
+	status, removed := doGroup(t, router, http.MethodPost, path+"/remove", "player456", handlers.RemoveGroupMemberRequest{ActorID: "player456", PlayerID: "player789"})
+	require.Equal(t, http.StatusOK, status)
+	assert.False(t, removed.Group.Invited("player789"))
+	status, _ = doGroup(t, router, http.MethodPost, path+"/leave", "player123", handlers.GroupPlayerRequest{PlayerID: "player123"})
+	assert.Equal(t, http.StatusForbidden, status)
+	status, left := doGroup(t, router, http.MethodPost, path+"/leave", "player456", handlers.GroupPlayerRequest{PlayerID: "player456"})
+	require.Equal(t, http.StatusOK, status)
+	assert.Nil(t, left.Group.Member("player456"))
+
+	status, _ = doGroup(t, router, http.MethodPost, "/api/v1/groups/group-missing/join", "player456", handlers.GroupPlayerRequest{PlayerID: "player456"})
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = doGroup(t, router, http.MethodPost, "/api/v1/groups", "player123", map[string]string{"owner_id": "player123"})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestGroupRestrictedShare(t *testing.T) {
+	router := setupGroupRouter()
+	path := createClan(t, router)
+	groupID := path[len("/api/v1/groups/"):]
+
+	share := handlers.ShareItemRequest{
+		Name:        "Clan Banner",
+		Description: "Only for members",
+		TypeID:      3001,
+		Num:         1,
+		Durability:  100,
+		SharerID:    "player123",
+		GroupID:     groupID,
+	}
+	w := serveAs(t, router, http.MethodPost, "/api/v1/items/share", "player123", share)
+	require.Equal(t, http.StatusOK, w.Code)
+	var shared handlers.ShareItemResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	require.Len(t, shared.PickupCode, 6)
+
+	// 非成员不能向群组分享，冒用群主身份而没有其令牌也不行
+	share.SharerID = "player789"
+	w = serveAs(t, router, http.MethodPost, "/api/v1/items/share", "player789", share)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	share.SharerID = "player123"
+	w = serveAs(t, router, http.MethodPost, "/api/v1/items/share", "player789", share)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 成员可以列出群组物品，非成员不能
+	w = serveAs(t, router, http.MethodGet, path+"/items?player_id=player456", "player456", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var listed handlers.GroupItemsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Items, 1)
+	assert.Equal(t, shared.PickupCode, listed.Items[0].PickupCode)
+	status, _ := doGroup(t, router, http.MethodGet, path+"/items?player_id=player789", "player789", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doGroup(t, router, http.MethodGet, path+"/items?player_id=player456&limit=abc", "player456", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	// 非成员领取返回业务错误 403，冒用成员 ID 而没有其令牌返回 401，成员可以领取
+	claim := func(claimerID, as string) handlers.ClaimItemResponse {
+		w := serveAs(t, router, http.MethodPost, "/api/v1/items/claim", as, handlers.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: claimerID})
+		require.Equal(t, http.StatusOK, w.Code)
+		var claimed handlers.ClaimItemResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &claimed))
+		return claimed
+	}
+	assert.Equal(t, 403, claim("player789", "player789").Code)
+	assert.Equal(t, 401, claim("player456", "").Code)
+	assert.Equal(t, 401, claim("player456", "player789").Code)
+	claimed := claim("player456", "player456")
+	assert.Equal(t, 200, claimed.Code)
+	assert.Equal(t, groupID, claimed.Item.GroupID)
+}
+
+func TestGroupActionsRequirePlayerToken(t *testing.T) {
+	router := setupGroupRouter()
+	path := createClan(t, router)
+
+	// 不带令牌或令牌属于他人时，冒用群主身份的操作都被拒绝
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		as     string
+		body   interface{}
+	}{
+		{"create without token", http.MethodPost, "/api/v1/groups", "", handlers.CreateGroupRequest{Name: "Fake Clan", OwnerID: "player123"}},
+		{"view as other player", http.MethodGet, path + "?player_id=player456", "player789", nil},
+		{"self invite as owner", http.MethodPost, path + "/invite", "player789", handlers.InviteGroupRequest{InviterID: "player123", PlayerID: "player789"}},
+		{"join without token", http.MethodPost, path + "/join", "", handlers.GroupPlayerRequest{PlayerID: "player789"}},
+		{"leave as other member", http.MethodPost, path + "/leave", "player789", handlers.GroupPlayerRequest{PlayerID: "player456"}},
+		{"raise own role as owner", http.MethodPost, path + "/role", "player456", handlers.SetGroupRoleRequest{ActorID: "player123", PlayerID: "player456", Role: models.RoleAdmin}},
+		{"remove as owner", http.MethodPost, path + "/remove", "player456", handlers.RemoveGroupMemberRequest{ActorID: "player123", PlayerID: "player456"}},
+		{"list items as member", http.MethodGet, path + "/items?player_id=player456", "", nil},
+	}
+	for _, tc := range cases {
+		status, response := doGroup(t, router, tc.method, tc.path, tc.as, tc.body)
+		assert.Equal(t, http.StatusUnauthorized, status, tc.name)
+		assert.Nil(t, response.Group, tc.name)
+	}
+
+	w := serveAs(t, router, http.MethodGet, "/api/v1/groups?player_id=player456", "player123", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 成员身份和角色没有被改动
+	status, viewed := doGroup(t, router, http.MethodGet, path+"?player_id=player123", "player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, viewed.Group.Members, 2)
+	assert.Equal(t, models.RoleMember, viewed.Group.Member("player456").Role)
+	assert.False(t, viewed.Group.Invited("player789"))
+}
+
+func TestGroupActionsWithoutPlayerSecret(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	groups := service.NewGroupService(service.GroupDeps{Groups: models.NewInMemoryGroupRepository(), ItemRepo: models.NewInMemoryItemRepository(nil)})
+	r := gin.New()
+	r.POST("/api/v1/groups", handlers.NewGroupHandler(groups, "").CreateGroup)
+
+	status, _ := doGroup(t, r, http.MethodPost, "/api/v1/groups", "player123", handlers.CreateGroupRequest{Name: "Duck Clan", OwnerID: "player123"})
+	assert.Equal(t, http.StatusForbidden, status)
+}
@@ -0,0 +1,43 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthDoesNotExposeItems(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clk := clock.NewFake(time.Now())
+	itemRepo := models.NewInMemoryItemRepository(clk)
+	require.NoError(t, itemRepo.Create(&models.Item{
+		ID:         "item-1",
+		Name:       "Golden Duck",
+		SharerID:   "player123",
+		PickupCode: "123456",
+		ExpiresAt:  clk.Now().Add(time.Hour),
+	}))
+	r := gin.New()
+	r.GET("/health", handlers.NewHealthHandler(itemRepo, nil, nil, clk).Health)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// 只返回数量，响应中不包含物品和取件码
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, float64(1), response["pending_items_count"])
+	assert.NotContains(t, response, "pending_items")
+	assert.NotContains(t, w.Body.String(), "123456")
+}
@@ -10,8 +10,11 @@ import (
 	"testing"
 	"time"
 
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
 	"duckex-server/internal/handlers"
 	"duckex-server/internal/models"
+	"duckex-server/internal/service"
 	"duckex-server/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -19,13 +22,24 @@ import (
 )
 
 func setupTestRouter() (*gin.Engine, models.ItemRepository) {
+	return setupTestRouterWithClock(nil)
+}
+
+// 使用指定时间源创建路由，clk 为 nil 时使用系统时间
+func setupTestRouterWithClock(clk clock.Clock) (*gin.Engine, models.ItemRepository) {
 	// 设置为测试模式
 	gin.SetMode(gin.TestMode)
 
 	// 创建仓库和处理器
-	itemRepo := models.NewInMemoryItemRepository()
+	itemRepo := models.NewInMemoryItemRepository(clk)
 	monitor := utils.NewMemoryMonitor(500)
-	itemHandler := handlers.NewItemHandler(itemRepo, monitor)
+	items := service.NewItemService(service.Deps{
+		ItemRepo:      itemRepo,
+		MemoryMonitor: monitor,
+		EventBus:      events.NewBus(),
+		Clock:         clk,
+	})
+	itemHandler := handlers.NewItemHandler(items, monitor)
 
 	// 创建路由
 	r := gin.Default()
@@ -35,6 +49,9 @@ func setupTestRouter() (*gin.Engine, models.ItemRepository) {
 	{
 		api.POST("/items/share", itemHandler.ShareItem)
 		api.POST("/items/claim", itemHandler.ClaimItem)
+		api.POST("/items/reserve", itemHandler.ReserveItem)
+		api.POST("/items/confirm", itemHandler.ConfirmItem)
+		api.POST("/items/release", itemHandler.ReleaseItem)
 	}
 
 	return r, itemRepo
@@ -219,10 +236,9 @@ func TestConcurrentClaimItemRequests(t *testing.T) {
 	// 验证只有一个请求成功领取了物品
 	assert.Equal(t, 1, claimSuccessCount)
 
-	// 验证物品现在已被标记为已领取
+	// 验证物品领取后已从仓库删除
 	claimedItem, _ := itemRepo.GetByPickupCode(pickupCode)
-	assert.True(t, claimedItem.IsClaimed)
-	assert.NotEmpty(t, claimedItem.ClaimerID)
+	assert.Nil(t, claimedItem)
 }
 
 func TestClaimItem(t *testing.T) {
@@ -239,8 +255,8 @@ func TestClaimItem(t *testing.T) {
 		Durability:  85.5,
 		SharerID:    "player123",
 		PickupCode:  pickupCode,
-		CreatedAt:   models.GetCurrentTime(),
-		ExpiresAt:   models.GetExpirationTime(),
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(24 * time.Hour),
 		IsClaimed:   false,
 	}
 	itemRepo.Create(item)
@@ -266,16 +282,13 @@ func TestClaimItem(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 
 	// 解析响应
-	type claimResponse struct {
-		Message string      `json:"message"`
-		Item    models.Item `json:"item"`
-	}
-	var response claimResponse
+	var response handlers.ClaimItemResponse
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
 
 	// 验证响应内容
-	assert.Equal(t, "Item claimed successfully! Quack!", response.Message)
+	assert.Equal(t, 200, response.Code)
+	assert.Equal(t, "物品领取成功！呱呱！", response.Message)
 	assert.True(t, response.Item.IsClaimed)
 	assert.Equal(t, "player456", response.Item.ClaimerID)
 	assert.Equal(t, pickupCode, response.Item.PickupCode)
@@ -301,23 +314,165 @@ func TestClaimItemNotFound(t *testing.T) {
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
-	// 验证响应
-	assert.Equal(t, http.StatusNotFound, w.Code)
+	// 验证响应（业务错误码在响应体中返回）
+	assert.Equal(t, http.StatusOK, w.Code)
 
 	// 解析响应
-	var errorResponse handlers.ErrorResponse
-	err = json.Unmarshal(w.Body.Bytes(), &errorResponse)
+	var response handlers.ClaimItemResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
 	assert.NoError(t, err)
-	assert.Equal(t, "Item not found with this pickup code", errorResponse.Error)
+	assert.Equal(t, 404, response.Code)
+	assert.Equal(t, "提取码无效", response.Message)
 }
 
-// 需要在models包中添加辅助函数
-func init() {
-	// 注册models包中的辅助函数
-	models.GetCurrentTime = func() time.Time {
-		return time.Now()
+func TestShareItemExpiresWithClock(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	router, itemRepo := setupTestRouterWithClock(clk)
+
+	requestBody, err := json.Marshal(handlers.ShareItemRequest{
+		Name:        "Timed Item",
+		Description: "Expires after a day",
+		TypeID:      1001,
+		Num:         1,
+		Durability:  90.0,
+		SharerID:    "player123",
+	})
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/items/share", bytes.NewBuffer(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response handlers.ShareItemResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	// 响应中的过期时间与存储的过期时间一致
+	stored, err := itemRepo.GetByPickupCode(response.PickupCode)
+	assert.NoError(t, err)
+	if assert.NotNil(t, stored) {
+		assert.Equal(t, start, stored.CreatedAt)
+		assert.Equal(t, start.Add(utils.PickupCodeTTL), stored.ExpiresAt)
+		assert.Equal(t, stored.ExpiresAt.Format(time.RFC3339), response.ExpiresAt)
 	}
-	models.GetExpirationTime = func() time.Time {
-		return time.Now().Add(24 * time.Hour)
+
+	// 时间推进超过有效期后物品不可领取
+	clk.Advance(utils.PickupCodeTTL + time.Second)
+	claimBody, err := json.Marshal(handlers.ClaimItemRequest{
+		PickupCode: response.PickupCode,
+		ClaimerID:  "player456",
+	})
+	assert.NoError(t, err)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/items/claim", bytes.NewBuffer(claimBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var claimResponse handlers.ClaimItemResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &claimResponse))
+	assert.Equal(t, 404, claimResponse.Code)
+}
+
+// 发送 JSON 请求并解析响应
+func serveJSON(t *testing.T, router *gin.Engine, path string, body, response interface{}) {
+	requestBody, err := json.Marshal(body)
+	assert.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(requestBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), response))
+}
+
+func TestReserveAndConfirmItem(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	router, itemRepo := setupTestRouterWithClock(clk)
+	assert.NoError(t, itemRepo.Create(&models.Item{
+		ID:         "reservable",
+		Name:       "Reservable Item",
+		TypeID:     2001,
+		Num:        1,
+		Durability: 85.5,
+		SharerID:   "player123",
+		PickupCode: "123456",
+		CreatedAt:  clk.Now(),
+		ExpiresAt:  clk.Now().Add(24 * time.Hour),
+	}))
+
+	var reserved handlers.ReserveItemResponse
+	serveJSON(t, router, "/api/v1/items/reserve", handlers.ReserveItemRequest{PickupCode: "123456", ClaimerID: "player456"}, &reserved)
+	assert.Equal(t, 200, reserved.Code)
+	assert.NotEmpty(t, reserved.ReservationToken)
+	assert.Equal(t, clk.Now().Add(service.DefaultReservationLease).Format(time.RFC3339), reserved.LeaseExpiresAt)
+	if assert.NotNil(t, reserved.Item) {
+		assert.Equal(t, "reservable", reserved.Item.ID)
+		assert.Nil(t, reserved.Item.Reservation)
+	}
+
+	// 预留期间不能被领取或再次预留
+	var claim handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/claim", handlers.ClaimItemRequest{PickupCode: "123456", ClaimerID: "player789"}, &claim)
+	assert.Equal(t, 409, claim.Code)
+	var again handlers.ReserveItemResponse
+	serveJSON(t, router, "/api/v1/items/reserve", handlers.ReserveItemRequest{PickupCode: "123456", ClaimerID: "player789"}, &again)
+	assert.Equal(t, 409, again.Code)
+	assert.Empty(t, again.ReservationToken)
+
+	var wrong handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/confirm", handlers.ReservationRequest{PickupCode: "123456", ReservationToken: "wrong"}, &wrong)
+	assert.Equal(t, 410, wrong.Code)
+
+	var confirmed handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/confirm", handlers.ReservationRequest{PickupCode: "123456", ReservationToken: reserved.ReservationToken}, &confirmed)
+	assert.Equal(t, 200, confirmed.Code)
+	if assert.NotNil(t, confirmed.Item) {
+		assert.True(t, confirmed.Item.IsClaimed)
+		assert.Equal(t, "player456", confirmed.Item.ClaimerID)
 	}
+	got, err := itemRepo.GetByPickupCode("123456")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestReservationRollsBack(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	router, itemRepo := setupTestRouterWithClock(clk)
+	assert.NoError(t, itemRepo.Create(&models.Item{
+		ID:         "reservable",
+		Name:       "Reservable Item",
+		TypeID:     2001,
+		Num:        1,
+		Durability: 85.5,
+		SharerID:   "player123",
+		PickupCode: "123456",
+		CreatedAt:  clk.Now(),
+		ExpiresAt:  clk.Now().Add(24 * time.Hour),
+	}))
+
+	// 取消预留后物品可以再次预留
+	var first handlers.ReserveItemResponse
+	serveJSON(t, router, "/api/v1/items/reserve", handlers.ReserveItemRequest{PickupCode: "123456", ClaimerID: "player456"}, &first)
+	assert.Equal(t, 200, first.Code)
+	var released handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/release", handlers.ReservationRequest{PickupCode: "123456", ReservationToken: first.ReservationToken}, &released)
+	assert.Equal(t, 200, released.Code)
+	if assert.NotNil(t, released.Item) {
+		assert.False(t, released.Item.IsClaimed)
+	}
+
+	// 预留到期未确认时物品恢复为可领取
+	var second handlers.ReserveItemResponse
+	serveJSON(t, router, "/api/v1/items/reserve", handlers.ReserveItemRequest{PickupCode: "123456", ClaimerID: "player456"}, &second)
+	assert.Equal(t, 200, second.Code)
+	clk.Advance(service.DefaultReservationLease + time.Second)
+
+	var late handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/confirm", handlers.ReservationRequest{PickupCode: "123456", ReservationToken: second.ReservationToken}, &late)
+	assert.Equal(t, 410, late.Code)
+	var claim handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/claim", handlers.ClaimItemRequest{PickupCode: "123456", ClaimerID: "player789"}, &claim)
+	assert.Equal(t, 200, claim.Code)
+	assert.Equal(t, "player789", claim.Item.ClaimerID)
 }
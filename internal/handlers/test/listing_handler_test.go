@@ -0,0 +1,110 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupListingRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	items := service.NewItemService(service.Deps{ItemRepo: models.NewInMemoryItemRepository(nil)})
+	itemHandler := handlers.NewItemHandler(items, nil)
+	listingHandler := handlers.NewListingHandler(items)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	{
+		api.POST("/items/share", itemHandler.ShareItem)
+		api.GET("/listings", listingHandler.SearchListings)
+		api.GET("/listings/:id", listingHandler.GetListing)
+		api.POST("/listings/:id/claim", listingHandler.ClaimListing)
+	}
+	return r
+}
+
+func getListings(t *testing.T, router *gin.Engine, target string) (int, handlers.ListingsResponse) {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	var response handlers.ListingsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	return w.Code, response
+}
+
+func TestShareListedItem(t *testing.T) {
+	router := setupListingRouter()
+	share := func(name string, typeID int, listed bool) handlers.ShareItemResponse {
+		var response handlers.ShareItemResponse
+		serveJSON(t, router, "/api/v1/items/share", handlers.ShareItemRequest{
+			Name:        name,
+			Description: "Market test item",
+			TypeID:      typeID,
+			Num:         1,
+			Durability:  75,
+			SharerID:    "player123",
+			Listed:      listed,
+		}, &response)
+		return response
+	}
+	listed := share("Iron Sword", 1001, true)
+	require.NotEmpty(t, listed.ListingID)
+	share("Golden Duck", 2001, true)
+	assert.Empty(t, share("Secret Duck", 1001, false).ListingID)
+
+	status, page := getListings(t, router, "/api/v1/listings?type_id=1001")
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, page.Listings, 1)
+	assert.Equal(t, listed.ListingID, page.Listings[0].ListingID)
+	assert.Equal(t, "Iron Sword", page.Listings[0].Name)
+
+	// 挂牌不包含取件码
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/listings/"+listed.ListingID, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), listed.PickupCode)
+
+	status, page = getListings(t, router, "/api/v1/listings?q=golden+duck")
+	assert.Equal(t, http.StatusOK, status)
+	require.Len(t, page.Listings, 1)
+	assert.Equal(t, "Golden Duck", page.Listings[0].Name)
+
+	status, page = getListings(t, router, "/api/v1/listings?limit=1")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Len(t, page.Listings, 1)
+	assert.NotEmpty(t, page.NextCursor)
+
+	var claimed handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/listings/"+listed.ListingID+"/claim", handlers.ClaimListingRequest{ClaimerID: "player456"}, &claimed)
+	assert.Equal(t, 200, claimed.Code)
+	assert.Equal(t, "Iron Sword", claimed.Item.Name)
+	serveJSON(t, router, "/api/v1/listings/"+listed.ListingID+"/claim", handlers.ClaimListingRequest{ClaimerID: "player789"}, &claimed)
+	assert.Equal(t, 404, claimed.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/listings/"+listed.ListingID, nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSearchListingsInvalidQuery(t *testing.T) {
+	router := setupListingRouter()
+	for _, target := range []string{
+		"/api/v1/listings?type_id=abc",
+		"/api/v1/listings?min_durability=high",
+		"/api/v1/listings?limit=1000",
+		"/api/v1/listings?min_durability=90&max_durability=10",
+	} {
+		status, response := getListings(t, router, target)
+		assert.Equal(t, http.StatusBadRequest, status, target)
+		assert.Equal(t, 400, response.Code)
+		assert.NotNil(t, response.Listings)
+	}
+}
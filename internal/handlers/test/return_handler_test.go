@@ -0,0 +1,149 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReturnRouter(returnBox models.ReturnBox) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	returnHandler := handlers.NewReturnHandler(returnBox, testPlayerSecret)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	{
+		api.GET("/returns", returnHandler.ListReturns)
+		api.POST("/returns/collect", returnHandler.CollectReturns)
+	}
+	return r
+}
+
+// 以 player123 的身份发送请求并解析退回箱响应，body 为 nil 时发送 GET 请求
+func doReturns(t *testing.T, router *gin.Engine, path string, body interface{}) (int, handlers.ReturnsResponse) {
+	return doReturnsAs(t, router, path, "player123", body)
+}
+
+// 以玩家 as 的身份发送请求并解析退回箱响应，as 为空时不带玩家令牌
+func doReturnsAs(t *testing.T, router *gin.Engine, path, as string, body interface{}) (int, handlers.ReturnsResponse) {
+	method := http.MethodGet
+	if body != nil {
+		method = http.MethodPost
+	}
+	w := serveAs(t, router, method, path, as, body)
+
+	var response handlers.ReturnsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, w.Code, response.Code)
+	assert.NotNil(t, response.Items)
+	return w.Code, response
+}
+
+func TestListAndCollectReturns(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+	returnBox := models.NewInMemoryReturnBox(0, clk)
+	router := setupReturnRouter(returnBox)
+	require.NoError(t, returnBox.Add(&models.Item{ID: "expired-1", Name: "Test Weapon", SharerID: "player123", PickupCode: "123456"}))
+	require.NoError(t, returnBox.Add(&models.Item{ID: "expired-2", Name: "Test Shield", SharerID: "player123", PickupCode: "654321"}))
+	require.NoError(t, returnBox.Add(&models.Item{ID: "other", Name: "Other Item", SharerID: "player999", PickupCode: "111111"}))
+
+	// 只列出该分享者的退回物品，查看不会清空退回箱
+	status, listed := doReturns(t, router, "/api/v1/returns?sharer_id=player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, listed.Items, 2)
+	assert.Equal(t, "expired-1", listed.Items[0].Item.ID)
+	assert.Equal(t, clk.Now(), listed.Items[0].ReturnedAt)
+	assert.Equal(t, clk.Now().Add(models.DefaultReturnRetention), listed.Items[0].ExpiresAt)
+	status, listed = doReturns(t, router, "/api/v1/returns?sharer_id=player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, listed.Items, 2)
+
+	status, collected := doReturns(t, router, "/api/v1/returns/collect", handlers.CollectReturnsRequest{SharerID: "player123"})
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, collected.Items, 2)
+	assert.Equal(t, "expired-2", collected.Items[1].Item.ID)
+
+	// 领回后退回箱被清空，再次领回得到空列表，其他分享者的退回箱不受影响
+	status, listed = doReturns(t, router, "/api/v1/returns?sharer_id=player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, listed.Items)
+	status, collected = doReturns(t, router, "/api/v1/returns/collect", handlers.CollectReturnsRequest{SharerID: "player123"})
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, collected.Items)
+	returned, err := returnBox.List("player999")
+	require.NoError(t, err)
+	assert.Len(t, returned, 1)
+}
+
+func TestReturnsEmptyBox(t *testing.T) {
+	router := setupReturnRouter(models.NewInMemoryReturnBox(0, nil))
+
+	status, listed := doReturns(t, router, "/api/v1/returns?sharer_id=player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, listed.Items)
+	status, collected := doReturns(t, router, "/api/v1/returns/collect", handlers.CollectReturnsRequest{SharerID: "player123"})
+	require.Equal(t, http.StatusOK, status)
+	assert.Empty(t, collected.Items)
+}
+
+func TestReturnsInvalidRequest(t *testing.T) {
+	router := setupReturnRouter(models.NewInMemoryReturnBox(0, nil))
+
+	status, _ := doReturns(t, router, "/api/v1/returns", nil)
+	assert.Equal(t, http.StatusBadRequest, status)
+	status, _ = doReturns(t, router, "/api/v1/returns/collect", map[string]string{})
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestReturnsRequirePlayerToken(t *testing.T) {
+	returnBox := models.NewInMemoryReturnBox(0, nil)
+	router := setupReturnRouter(returnBox)
+	require.NoError(t, returnBox.Add(&models.Item{ID: "expired-1", Name: "Test Weapon", SharerID: "player123", PickupCode: "123456"}))
+
+	// 令牌缺失或属于其他玩家时不能查看和领回
+	for _, as := range []string{"", "player999"} {
+		status, listed := doReturnsAs(t, router, "/api/v1/returns?sharer_id=player123", as, nil)
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Empty(t, listed.Items)
+		status, collected := doReturnsAs(t, router, "/api/v1/returns/collect", as, handlers.CollectReturnsRequest{SharerID: "player123"})
+		assert.Equal(t, http.StatusUnauthorized, status)
+		assert.Empty(t, collected.Items)
+	}
+	returned, err := returnBox.List("player123")
+	require.NoError(t, err)
+	assert.Len(t, returned, 1)
+
+	// 未配置玩家令牌密钥时退回箱接口不可用
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/api/v1/returns", handlers.NewReturnHandler(returnBox, "").ListReturns)
+	status, _ := doReturns(t, r, "/api/v1/returns?sharer_id=player123", nil)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+// 领回总是失败的退回箱
+type failingReturnBox struct {
+	models.ReturnBox
+}
+
+func (failingReturnBox) Collect(string) ([]*models.ReturnedItem, error) {
+	return nil, errors.New("storage unavailable")
+}
+
+func TestCollectReturnsStorageError(t *testing.T) {
+	router := setupReturnRouter(failingReturnBox{models.NewInMemoryReturnBox(0, nil)})
+
+	status, response := doReturns(t, router, "/api/v1/returns/collect", handlers.CollectReturnsRequest{SharerID: "player123"})
+	assert.Equal(t, http.StatusInternalServerError, status)
+	assert.Contains(t, response.Message, "storage unavailable")
+}
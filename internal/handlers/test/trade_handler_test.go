@@ -0,0 +1,201 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 交易路由与领取路由共用物品仓库，交付的取件码可以直接领取
+func setupTradeRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	returnBox := models.NewInMemoryReturnBox(0, nil)
+	items := service.NewItemService(service.Deps{ItemRepo: itemRepo})
+	trades := service.NewTradeService(service.TradeDeps{
+		Trades:    models.NewInMemoryTradeRepository(),
+		ItemRepo:  itemRepo,
+		ReturnBox: returnBox,
+	})
+	itemHandler := handlers.NewItemHandler(items, nil)
+	tradeHandler := handlers.NewTradeHandler(trades, testPlayerSecret)
+	returnHandler := handlers.NewReturnHandler(returnBox, testPlayerSecret)
+
+	r := gin.New()
+	api := r.Group("/api/v1")
+	{
+		api.POST("/items/claim", itemHandler.ClaimItem)
+		api.GET("/returns", returnHandler.ListReturns)
+		api.POST("/trades", tradeHandler.OpenTrade)
+		api.GET("/trades", tradeHandler.ListTrades)
+		api.GET("/trades/:id", tradeHandler.GetTrade)
+		api.POST("/trades/:id/accept", tradeHandler.AcceptTrade)
+		api.POST("/trades/:id/cancel", tradeHandler.CancelTrade)
+	}
+	return r
+}
+
+// 以玩家 as 的身份发送请求并解析交易响应，body 为 nil 时不带请求体
+func doTrade(t *testing.T, router *gin.Engine, method, path, as string, body interface{}) (int, handlers.TradeResponse) {
+	w := serveAs(t, router, method, path, as, body)
+	var response handlers.TradeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, w.Code, response.Code)
+	return w.Code, response
+}
+
+func openTradeRequest() handlers.OpenTradeRequest {
+	return handlers.OpenTradeRequest{
+		InitiatorID: "player123",
+		Offer:       []models.TradeItem{{Name: "Test Weapon", Description: "A powerful sword", TypeID: 1001, Num: 1, Durability: 90}},
+		Request:     []models.TradeWant{{TypeID: 2001, Num: 2}},
+	}
+}
+
+func acceptTradeRequest(acceptorID string) handlers.AcceptTradeRequest {
+	return handlers.AcceptTradeRequest{
+		AcceptorID: acceptorID,
+		Items:      []models.TradeItem{{Name: "Health Potion", Description: "Restores health", TypeID: 2001, Num: 2, Durability: 100}},
+	}
+}
+
+func TestOpenAndAcceptTrade(t *testing.T) {
+	router := setupTradeRouter()
+
+	status, opened := doTrade(t, router, http.MethodPost, "/api/v1/trades", "player123", openTradeRequest())
+	require.Equal(t, http.StatusOK, status)
+	require.NotNil(t, opened.Trade)
+	assert.Equal(t, models.TradeOpen, opened.Trade.Status)
+	path := "/api/v1/trades/" + opened.Trade.ID
+
+	status, accepted := doTrade(t, router, http.MethodPost, path+"/accept", "player456", acceptTradeRequest("player456"))
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, models.TradeCompleted, accepted.Trade.Status)
+	require.Len(t, accepted.Trade.AcceptorCodes, 1)
+	assert.Empty(t, accepted.Trade.InitiatorCodes)
+
+	status, viewed := doTrade(t, router, http.MethodGet, path+"?player_id=player123", "player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, viewed.Trade.InitiatorCodes, 1)
+	assert.Empty(t, viewed.Trade.AcceptorCodes)
+
+	// 接受方凭取件码领取发起方的物品
+	var claimed handlers.ClaimItemResponse
+	serveJSON(t, router, "/api/v1/items/claim", handlers.ClaimItemRequest{
+		PickupCode: accepted.Trade.AcceptorCodes[0],
+		ClaimerID:  "player456",
+	}, &claimed)
+	assert.Equal(t, 200, claimed.Code)
+	assert.Equal(t, "Test Weapon", claimed.Item.Name)
+
+	status, _ = doTrade(t, router, http.MethodPost, path+"/accept", "player789", acceptTradeRequest("player789"))
+	assert.Equal(t, http.StatusConflict, status)
+
+	w := serveAs(t, router, http.MethodGet, "/api/v1/trades?player_id=player456", "player456", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var listed handlers.TradesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listed))
+	require.Len(t, listed.Trades, 1)
+	assert.Equal(t, opened.Trade.ID, listed.Trades[0].ID)
+}
+
+func TestTradeErrors(t *testing.T) {
+	router := setupTradeRouter()
+	req := openTradeRequest()
+	req.CounterpartyID = "player456"
+	_, opened := doTrade(t, router, http.MethodPost, "/api/v1/trades", "player123", req)
+	require.NotNil(t, opened.Trade)
+	path := "/api/v1/trades/" + opened.Trade.ID
+
+	invalid := openTradeRequest()
+	invalid.Request[0].Num = 0
+	status, response := doTrade(t, router, http.MethodPost, "/api/v1/trades", "player123", invalid)
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, response.Message, "request[0].num")
+
+	status, _ = doTrade(t, router, http.MethodPost, "/api/v1/trades/missing/accept", "player456", acceptTradeRequest("player456"))
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _ = doTrade(t, router, http.MethodPost, path+"/accept", "player789", acceptTradeRequest("player789"))
+	assert.Equal(t, http.StatusForbidden, status)
+	status, _ = doTrade(t, router, http.MethodGet, path+"?player_id=player789", "player789", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+
+	short := acceptTradeRequest("player456")
+	short.Items[0].Num = 1
+	status, _ = doTrade(t, router, http.MethodPost, path+"/accept", "player456", short)
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	status, _ = doTrade(t, router, http.MethodPost, path+"/cancel", "player456", handlers.CancelTradeRequest{InitiatorID: "player456"})
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestCancelTrade(t *testing.T) {
+	router := setupTradeRouter()
+	_, opened := doTrade(t, router, http.MethodPost, "/api/v1/trades", "player123", openTradeRequest())
+	require.NotNil(t, opened.Trade)
+	path := "/api/v1/trades/" + opened.Trade.ID
+
+	status, cancelled := doTrade(t, router, http.MethodPost, path+"/cancel", "player123", handlers.CancelTradeRequest{InitiatorID: "player123"})
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, models.TradeCancelled, cancelled.Trade.Status)
+
+	// 托管物品退回到发起方的退回箱
+	w := serveAs(t, router, http.MethodGet, "/api/v1/returns?sharer_id=player123", "player123", nil)
+	var returns handlers.ReturnsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &returns))
+	require.Len(t, returns.Items, 1)
+	assert.Equal(t, "Test Weapon", returns.Items[0].Item.Name)
+
+	status, _ = doTrade(t, router, http.MethodPost, path+"/cancel", "player123", handlers.CancelTradeRequest{InitiatorID: "player123"})
+	assert.Equal(t, http.StatusConflict, status)
+}
+
+func TestTradeActionsRequirePlayerToken(t *testing.T) {
+	router := setupTradeRouter()
+	req := openTradeRequest()
+	req.CounterpartyID = "player456"
+	_, opened := doTrade(t, router, http.MethodPost, "/api/v1/trades", "player123", req)
+	require.NotNil(t, opened.Trade)
+	path := "/api/v1/trades/" + opened.Trade.ID
+
+	// 不带令牌或令牌属于他人时，冒用参与方身份的操作都被拒绝
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		as     string
+		body   interface{}
+	}{
+		{"open without token", http.MethodPost, "/api/v1/trades", "", openTradeRequest()},
+		{"view as initiator", http.MethodGet, path + "?player_id=player123", "player789", nil},
+		{"accept as counterparty", http.MethodPost, path + "/accept", "player789", acceptTradeRequest("player456")},
+		{"cancel as initiator", http.MethodPost, path + "/cancel", "player456", handlers.CancelTradeRequest{InitiatorID: "player123"}},
+	}
+	for _, tc := range cases {
+		status, response := doTrade(t, router, tc.method, tc.path, tc.as, tc.body)
+		assert.Equal(t, http.StatusUnauthorized, status, tc.name)
+		assert.Nil(t, response.Trade, tc.name)
+	}
+	w := serveAs(t, router, http.MethodGet, "/api/v1/trades?player_id=player123", "", nil)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 交易仍在进行中，发起方可以查看
+	status, viewed := doTrade(t, router, http.MethodGet, path+"?player_id=player123", "player123", nil)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, models.TradeOpen, viewed.Trade.Status)
+
+	// 不带 player_id 只能匿名查看公开的交易
+	_, public := doTrade(t, router, http.MethodPost, "/api/v1/trades", "player123", openTradeRequest())
+	status, _ = doTrade(t, router, http.MethodGet, "/api/v1/trades/"+public.Trade.ID, "", nil)
+	assert.Equal(t, http.StatusOK, status)
+	status, _ = doTrade(t, router, http.MethodGet, path, "", nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TradeHandler 玩家交易处理器
+// 发起、查看、接受和取消交易需要为执行操作的玩家（initiator_id、acceptor_id 或 player_id）签发的玩家令牌，
+// 通过 "Authorization: Bearer <token>" 传递
+type TradeHandler struct {
+	trades       *service.TradeService
+	playerSecret string
+}
+
+// NewTradeHandler 创建新的玩家交易处理器，playerSecret 为空时交易接口不可用
+func NewTradeHandler(trades *service.TradeService, playerSecret string) *TradeHandler {
+	return &TradeHandler{
+		trades:       trades,
+		playerSecret: playerSecret,
+	}
+}
+
+// 发起交易的请求结构
+type OpenTradeRequest struct {
+	InitiatorID    string             `json:"initiator_id" binding:"required"`
+	CounterpartyID string             `json:"counterparty_id"`
+	Offer          []models.TradeItem `json:"offer" binding:"required"`
+	Request        []models.TradeWant `json:"request" binding:"required"`
+}
+
+// 接受交易的请求结构
+type AcceptTradeRequest struct {
+	AcceptorID string             `json:"acceptor_id" binding:"required"`
+	Items      []models.TradeItem `json:"items" binding:"required"`
+}
+
+// 取消交易的请求结构
+type CancelTradeRequest struct {
+	InitiatorID string `json:"initiator_id" binding:"required"`
+}
+
+// 单个交易的响应结构
+type TradeResponse struct {
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Trade   *models.Trade `json:"trade,omitempty"`
+}
+
+// 交易列表的响应结构
+type TradesResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Trades  []*models.Trade `json:"trades"`
+}
+
+// OpenTrade 发起交易，托管发起方的物品
+func (h *TradeHandler) OpenTrade(c *gin.Context) {
+	var req OpenTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		tradeFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+	if !h.authenticate(c, req.InitiatorID) {
+		return
+	}
+
+	trade, err := h.trades.Open(service.OpenTradeRequest{
+		InitiatorID:    req.InitiatorID,
+		CounterpartyID: req.CounterpartyID,
+		Offer:          req.Offer,
+		Request:        req.Request,
+	})
+	if err != nil {
+		h.tradeError(c, err, "发起交易失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, TradeResponse{
+		Code:    200,
+		Message: "交易已发起，物品已托管！呱呱！",
+		Trade:   trade,
+	})
+}
+
+// ListTrades 列出玩家参与的交易
+func (h *TradeHandler) ListTrades(c *gin.Context) {
+	playerID := c.Query("player_id")
+	if playerID == "" {
+		c.JSON(http.StatusBadRequest, TradesResponse{
+			Code:    400,
+			Message: "缺少 player_id 参数",
+			Trades:  []*models.Trade{},
+		})
+		return
+	}
+	if status, err := authenticatePlayer(c, h.playerSecret, playerID); err != nil {
+		c.JSON(status, TradesResponse{
+			Code:    status,
+			Message: playerAuthMessage(err),
+			Trades:  []*models.Trade{},
+		})
+		return
+	}
+
+	trades, err := h.trades.List(playerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, TradesResponse{
+			Code:    500,
+			Message: "查询交易失败: " + err.Error(),
+			Trades:  []*models.Trade{},
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, TradesResponse{
+		Code:    200,
+		Message: "查询成功",
+		Trades:  trades,
+	})
+}
+
+// GetTrade 查看交易，参与方可以看到交付给自己的取件码
+// 不带 player_id 时只能查看未指定对方的进行中交易，不需要玩家令牌
+func (h *TradeHandler) GetTrade(c *gin.Context) {
+	playerID := c.Query("player_id")
+	if playerID != "" && !h.authenticate(c, playerID) {
+		return
+	}
+
+	trade, err := h.trades.Get(c.Param("id"), playerID)
+	if err != nil {
+		h.tradeError(c, err, "查询交易失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, TradeResponse{
+		Code:    200,
+		Message: "查询成功",
+		Trade:   trade,
+	})
+}
+
+// AcceptTrade 接受交易，存入请求的物品并交换双方的物品
+func (h *TradeHandler) AcceptTrade(c *gin.Context) {
+	var req AcceptTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		tradeFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+	if !h.authenticate(c, req.AcceptorID) {
+		return
+	}
+
+	trade, err := h.trades.Accept(c.Param("id"), req.AcceptorID, req.Items)
+	if err != nil {
+		h.tradeError(c, err, "接受交易失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, TradeResponse{
+		Code:    200,
+		Message: "交易完成，请凭取件码领取物品！呱呱！",
+		Trade:   trade,
+	})
+}
+
+// CancelTrade 发起方取消交易，托管物品退回到退回箱
+func (h *TradeHandler) CancelTrade(c *gin.Context) {
+	var req CancelTradeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		tradeFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+		return
+	}
+	if !h.authenticate(c, req.InitiatorID) {
+		return
+	}
+
+	trade, err := h.trades.Cancel(c.Param("id"), req.InitiatorID)
+	if err != nil {
+		h.tradeError(c, err, "取消交易失败: ")
+		return
+	}
+
+	c.JSON(http.StatusOK, TradeResponse{
+		Code:    200,
+		Message: "交易已取消，托管物品已退回",
+		Trade:   trade,
+	})
+}
+
+// 校验为 playerID 签发的玩家令牌，失败时写入响应并返回 false
+func (h *TradeHandler) authenticate(c *gin.Context, playerID string) bool {
+	status, err := authenticatePlayer(c, h.playerSecret, playerID)
+	if err != nil {
+		tradeFailure(c, status, playerAuthMessage(err))
+		return false
+	}
+	return true
+}
+
+// 将交易服务错误转换为响应
+func (h *TradeHandler) tradeError(c *gin.Context, err error, prefix string) {
+	var invalid *service.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		tradeFailure(c, http.StatusBadRequest, "请求格式无效: "+err.Error())
+	case errors.Is(err, service.ErrShareDisabled):
+		tradeFailure(c, http.StatusServiceUnavailable, "内存使用率过高，暂时无法发起或接受交易")
+	case errors.Is(err, service.ErrTradeNotFound):
+		tradeFailure(c, http.StatusNotFound, "交易不存在")
+	case errors.Is(err, service.ErrTradeForbidden):
+		tradeFailure(c, http.StatusForbidden, "无权操作该交易")
+	case errors.Is(err, service.ErrTradeClosed):
+		tradeFailure(c, http.StatusConflict, "交易已结束")
+	default:
+		tradeFailure(c, http.StatusInternalServerError, prefix+err.Error())
+	}
+}
+
+func tradeFailure(c *gin.Context, status int, message string) {
+	c.JSON(status, TradeResponse{
+		Code:    status,
+		Message: message,
+	})
+}
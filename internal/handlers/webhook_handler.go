@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"duckex-server/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler Webhook 管理处理器
+type WebhookHandler struct {
+	dispatcher *webhooks.Dispatcher
+}
+
+// NewWebhookHandler 创建新的 Webhook 管理处理器
+func NewWebhookHandler(dispatcher *webhooks.Dispatcher) *WebhookHandler {
+	return &WebhookHandler{
+		dispatcher: dispatcher,
+	}
+}
+
+// 投递记录的响应结构
+type DeliveriesResponse struct {
+	Deliveries []webhooks.Delivery `json:"deliveries"`
+}
+
+// ListDeliveries 列出最近的投递记录，可按 status 过滤
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	status := webhooks.DeliveryStatus(c.Query("status"))
+	switch status {
+	case "", webhooks.StatusPending, webhooks.StatusSucceeded, webhooks.StatusDead:
+	default:
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: "Invalid status: " + string(status),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, DeliveriesResponse{
+		Deliveries: h.dispatcher.Deliveries(status),
+	})
+}
+
+// ListDeadLetters 列出重试耗尽的投递记录
+func (h *WebhookHandler) ListDeadLetters(c *gin.Context) {
+	c.JSON(http.StatusOK, DeliveriesResponse{
+		Deliveries: h.dispatcher.DeadLetters(),
+	})
+}
@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"duckex-server/internal/clock"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 幂等键以哈希存储在 idempotency:<键> 中：fingerprint 为请求内容，expires_at 为过期时间（Unix毫秒），
+// 请求完成后写入 status、content_type 和 body；idempotency_keys 有序集合按过期时间记录全部键，
+// idempotency_stats 哈希记录所有实例的重放和冲突次数。脚本使用的键都通过 KEYS 传入
+var (
+	// 占用幂等键：键不存在或已过期时写入 fingerprint 并返回 nil，否则返回 {fingerprint, status, content_type, body}
+	// KEYS: 幂等键, 键的有序集合；ARGV: fingerprint, 当前时间, 过期时间, 有序集合成员
+	redisBeginScript = redis.NewScript(`
+local v = redis.call('HMGET', KEYS[1], 'fingerprint', 'status', 'content_type', 'body', 'expires_at')
+if v[1] and tonumber(v[5]) >= tonumber(ARGV[2]) then
+	return {v[1], v[2], v[3], v[4]}
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], 'fingerprint', ARGV[1], 'expires_at', ARGV[3])
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+return false
+`)
+
+	// 保存响应，键已被释放或过期删除时不做修改
+	// KEYS: 幂等键, 键的有序集合；ARGV: status, content_type, body, 过期时间, 有序集合成员
+	redisCompleteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('HSET', KEYS[1], 'status', ARGV[1], 'content_type', ARGV[2], 'body', ARGV[3], 'expires_at', ARGV[4])
+redis.call('PEXPIREAT', KEYS[1], ARGV[4])
+redis.call('ZADD', KEYS[2], ARGV[4], ARGV[5])
+return 1
+`)
+
+	// 删除幂等键：ARGV[2] 为 release 时只删除仍在处理中的键，否则只删除在 ARGV[3] 之前过期的键
+	// KEYS: 幂等键, 键的有序集合；ARGV: 有序集合成员, 操作, 当前时间
+	redisDeleteScript = redis.NewScript(`
+local v = redis.call('HMGET', KEYS[1], 'status', 'expires_at')
+local remove
+if ARGV[2] == 'release' then
+	remove = v[2] and not v[1]
+else
+	remove = not v[2] or tonumber(v[2]) < tonumber(ARGV[3])
+end
+if remove then
+	redis.call('DEL', KEYS[1])
+	redis.call('ZREM', KEYS[2], ARGV[1])
+end
+return 1
+`)
+)
+
+// RedisStore 基于 Redis 的幂等存储，多个实例共享同一份幂等键，重试的请求落到任意实例都能重放首次响应
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+	clock  clock.Clock
+}
+
+// NewRedisStore 创建 Redis 幂等存储，prefix 为所有键的前缀，
+// ttl 不大于0时使用 DefaultTTL，clk 为 nil 时使用系统时间
+func NewRedisStore(client *redis.Client, prefix string, ttl time.Duration, clk clock.Clock) *RedisStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+		ttl:    ttl,
+		clock:  clk,
+	}
+}
+
+func (s *RedisStore) entryKey(key string) string { return s.prefix + "idempotency:" + key }
+func (s *RedisStore) indexKey() string           { return s.prefix + "idempotency_keys" }
+func (s *RedisStore) statsKey() string           { return s.prefix + "idempotency_stats" }
+
+// 单个幂等键的脚本使用的键
+func (s *RedisStore) keys(key string) []string {
+	return []string{s.entryKey(key), s.indexKey()}
+}
+
+// TTL 返回响应的保留时间
+func (s *RedisStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Begin 为请求占用幂等键
+func (s *RedisStore) Begin(key, fingerprint string) (*Response, error) {
+	ctx := context.Background()
+	now := s.clock.Now()
+	result, err := redisBeginScript.Run(ctx, s.client, s.keys(key),
+		fingerprint, now.UnixMilli(), now.Add(s.ttl).UnixMilli(), key).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	saved, _ := result[0].(string)
+	status, _ := result[1].(string)
+	switch {
+	case saved != fingerprint:
+		return nil, s.conflict(ctx, ErrMismatch)
+	case status == "":
+		return nil, s.conflict(ctx, ErrInProgress)
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.client.HIncrBy(ctx, s.statsKey(), "replayed", 1).Err(); err != nil {
+		return nil, err
+	}
+	contentType, _ := result[2].(string)
+	body, _ := result[3].(string)
+	return &Response{Status: code, ContentType: contentType, Body: []byte(body)}, nil
+}
+
+// 记录一次冲突并返回 err
+func (s *RedisStore) conflict(ctx context.Context, err error) error {
+	if incrErr := s.client.HIncrBy(ctx, s.statsKey(), "conflicts", 1).Err(); incrErr != nil {
+		return incrErr
+	}
+	return err
+}
+
+// Complete 保存请求的响应，保留时间从请求完成时开始计算
+func (s *RedisStore) Complete(key string, response *Response) error {
+	expiresAt := s.clock.Now().Add(s.ttl).UnixMilli()
+	return redisCompleteScript.Run(context.Background(), s.client, s.keys(key),
+		response.Status, response.ContentType, response.Body, expiresAt, key).Err()
+}
+
+// Release 放弃占用的幂等键且不保存响应
+func (s *RedisStore) Release(key string) error {
+	return redisDeleteScript.Run(context.Background(), s.client, s.keys(key), key, "release", 0).Err()
+}
+
+// Cleanup 通过过期时间索引删除已过期的响应，键的 TTL 作为兜底
+func (s *RedisStore) Cleanup() error {
+	ctx := context.Background()
+	now := s.clock.Now().UnixMilli()
+	keys, err := s.client.ZRangeByScore(ctx, s.indexKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(now, 10),
+	}).Result()
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := redisDeleteScript.Run(ctx, s.client, s.keys(key), key, "expire", now).Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stats 返回所有实例共享的幂等存储统计
+func (s *RedisStore) Stats() (Stats, error) {
+	ctx := context.Background()
+	var counts *redis.SliceCmd
+	var tracked *redis.IntCmd
+	_, err := s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		counts = pipe.HMGet(ctx, s.statsKey(), "replayed", "conflicts")
+		tracked = pipe.ZCard(ctx, s.indexKey())
+		return nil
+	})
+	if err != nil {
+		return Stats{}, err
+	}
+	var stats Stats
+	values := counts.Val()
+	if v, ok := values[0].(string); ok {
+		stats.Replayed, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if v, ok := values[1].(string); ok {
+		stats.Conflicts, _ = strconv.ParseInt(v, 10, 64)
+	}
+	stats.TrackedKeys = int(tracked.Val())
+	return stats, nil
+}
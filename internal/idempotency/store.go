@@ -0,0 +1,157 @@
+// Package idempotency 保存带幂等键请求的首次响应，供重试的请求重放
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// DefaultTTL 默认保留响应的时间，与取件码有效期一致
+const DefaultTTL = 24 * time.Hour
+
+var (
+	// ErrInProgress 相同幂等键的请求仍在处理中
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrMismatch 幂等键已被内容不同的请求使用
+	ErrMismatch = errors.New("idempotency key was already used with a different request")
+)
+
+// Response 保存的响应
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// Stats 幂等存储统计
+type Stats struct {
+	Replayed    int64 `json:"replayed"`
+	Conflicts   int64 `json:"conflicts"`
+	TrackedKeys int   `json:"tracked_keys"`
+}
+
+type entry struct {
+	fingerprint string
+	response    *Response // 为 nil 表示请求仍在处理中
+	expiresAt   time.Time
+}
+
+// Store 幂等存储接口，每个键先被首个请求占用，请求完成后保存响应直到过期
+type Store interface {
+	// TTL 返回响应的保留时间
+	TTL() time.Duration
+	// Begin 为请求占用幂等键，fingerprint 标识请求内容
+	// 键未被使用时返回 (nil, nil)，调用者处理请求后必须调用 Complete 或 Release；
+	// 键已有相同内容的响应时返回该响应；请求仍在处理中时返回 ErrInProgress；内容不同时返回 ErrMismatch
+	Begin(key, fingerprint string) (*Response, error)
+	// Complete 保存请求的响应，保留时间从请求完成时开始计算
+	Complete(key string, response *Response) error
+	// Release 放弃占用的幂等键且不保存响应，之后使用该键的请求会被重新处理
+	Release(key string) error
+	// Cleanup 删除已过期的响应
+	Cleanup() error
+	// Stats 返回幂等存储统计
+	Stats() (Stats, error)
+}
+
+// InMemoryStore 内存中的幂等存储，只在单个实例内有效
+type InMemoryStore struct {
+	ttl       time.Duration
+	clock     clock.Clock
+	entries   map[string]*entry
+	mutex     sync.Mutex
+	replayed  int64
+	conflicts int64
+}
+
+// NewInMemoryStore 创建内存幂等存储，ttl 不大于0时使用 DefaultTTL，clk 为 nil 时使用系统时间
+func NewInMemoryStore(ttl time.Duration, clk clock.Clock) *InMemoryStore {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &InMemoryStore{
+		ttl:     ttl,
+		clock:   clk,
+		entries: make(map[string]*entry),
+	}
+}
+
+// TTL 返回响应的保留时间
+func (s *InMemoryStore) TTL() time.Duration {
+	return s.ttl
+}
+
+// Begin 为请求占用幂等键
+func (s *InMemoryStore) Begin(key, fingerprint string) (*Response, error) {
+	now := s.clock.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || now.After(e.expiresAt) {
+		s.entries[key] = &entry{fingerprint: fingerprint, expiresAt: now.Add(s.ttl)}
+		return nil, nil
+	}
+	switch {
+	case e.fingerprint != fingerprint:
+		s.conflicts++
+		return nil, ErrMismatch
+	case e.response == nil:
+		s.conflicts++
+		return nil, ErrInProgress
+	}
+	s.replayed++
+	return e.response, nil
+}
+
+// Complete 保存请求的响应，保留时间从请求完成时开始计算
+func (s *InMemoryStore) Complete(key string, response *Response) error {
+	now := s.clock.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if e, ok := s.entries[key]; ok {
+		e.response = response
+		e.expiresAt = now.Add(s.ttl)
+	}
+	return nil
+}
+
+// Release 放弃占用的幂等键且不保存响应，之后使用该键的请求会被重新处理
+func (s *InMemoryStore) Release(key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if e, ok := s.entries[key]; ok && e.response == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+// Cleanup 删除已过期的响应
+func (s *InMemoryStore) Cleanup() error {
+	now := s.clock.Now()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for key, e := range s.entries {
+		if now.After(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+	return nil
+}
+
+// Stats 返回幂等存储统计
+func (s *InMemoryStore) Stats() (Stats, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return Stats{
+		Replayed:    s.replayed,
+		Conflicts:   s.conflicts,
+		TrackedKeys: len(s.entries),
+	}, nil
+}
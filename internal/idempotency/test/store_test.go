@@ -0,0 +1,137 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/idempotency"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 幂等存储的创建函数
+type storeFactory func(t *testing.T, ttl time.Duration, clk clock.Clock) idempotency.Store
+
+func newInMemoryStore(t *testing.T, ttl time.Duration, clk clock.Clock) idempotency.Store {
+	return idempotency.NewInMemoryStore(ttl, clk)
+}
+
+func newRedisStore(t *testing.T, ttl time.Duration, clk clock.Clock) idempotency.Store {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return idempotency.NewRedisStore(client, "duckex:test:", ttl, clk)
+}
+
+// 对每种实现运行同一组测试
+func runStoreTests(t *testing.T, test func(t *testing.T, newStore storeFactory)) {
+	t.Run("InMemory", func(t *testing.T) { test(t, newInMemoryStore) })
+	t.Run("Redis", func(t *testing.T) { test(t, newRedisStore) })
+}
+
+func TestStoreReplaysCompletedResponse(t *testing.T) {
+	runStoreTests(t, func(t *testing.T, newStore storeFactory) {
+		store := newStore(t, time.Hour, clock.NewFake(time.Now()))
+
+		saved, err := store.Begin("key-1", "body-a")
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		// 首个请求完成前，相同键的请求被拒绝
+		_, err = store.Begin("key-1", "body-a")
+		assert.ErrorIs(t, err, idempotency.ErrInProgress)
+
+		response := &idempotency.Response{Status: 200, ContentType: "application/json", Body: []byte(`{"ok":true}`)}
+		require.NoError(t, store.Complete("key-1", response))
+
+		saved, err = store.Begin("key-1", "body-a")
+		require.NoError(t, err)
+		assert.Equal(t, response, saved)
+
+		_, err = store.Begin("key-1", "body-b")
+		assert.ErrorIs(t, err, idempotency.ErrMismatch)
+
+		stats, err := store.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), stats.Replayed)
+		assert.Equal(t, int64(2), stats.Conflicts)
+		assert.Equal(t, 1, stats.TrackedKeys)
+	})
+}
+
+func TestStoreRelease(t *testing.T) {
+	runStoreTests(t, func(t *testing.T, newStore storeFactory) {
+		store := newStore(t, time.Hour, nil)
+
+		_, err := store.Begin("key-1", "body-a")
+		require.NoError(t, err)
+		require.NoError(t, store.Release("key-1"))
+
+		// 释放后相同键可以携带任意请求体重新处理
+		saved, err := store.Begin("key-1", "body-b")
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		// 已完成的响应不会被释放
+		require.NoError(t, store.Complete("key-1", &idempotency.Response{Status: 200}))
+		require.NoError(t, store.Release("key-1"))
+		saved, err = store.Begin("key-1", "body-b")
+		require.NoError(t, err)
+		assert.NotNil(t, saved)
+	})
+}
+
+func TestStoreExpiry(t *testing.T) {
+	runStoreTests(t, func(t *testing.T, newStore storeFactory) {
+		clk := clock.NewFake(time.Now())
+		store := newStore(t, time.Hour, clk)
+
+		_, err := store.Begin("key-1", "body-a")
+		require.NoError(t, err)
+		require.NoError(t, store.Complete("key-1", &idempotency.Response{Status: 200}))
+
+		clk.Advance(59 * time.Minute)
+		require.NoError(t, store.Cleanup())
+		saved, err := store.Begin("key-1", "body-a")
+		require.NoError(t, err)
+		assert.NotNil(t, saved)
+
+		// 过期后键可以重新使用
+		clk.Advance(2 * time.Minute)
+		saved, err = store.Begin("key-1", "body-b")
+		require.NoError(t, err)
+		assert.Nil(t, saved)
+
+		require.NoError(t, store.Release("key-1"))
+		require.NoError(t, store.Cleanup())
+		stats, err := store.Stats()
+		require.NoError(t, err)
+		assert.Equal(t, 0, stats.TrackedKeys)
+	})
+}
+
+func TestRedisStoreSharedAcrossInstances(t *testing.T) {
+	server := miniredis.RunT(t)
+	newInstance := func() idempotency.Store {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return idempotency.NewRedisStore(client, "duckex:", time.Hour, nil)
+	}
+	first, second := newInstance(), newInstance()
+
+	// 一个实例处理的请求，重试落到另一个实例时重放同一响应
+	_, err := first.Begin("key-1", "body-a")
+	require.NoError(t, err)
+	_, err = second.Begin("key-1", "body-a")
+	assert.ErrorIs(t, err, idempotency.ErrInProgress)
+
+	response := &idempotency.Response{Status: 201, ContentType: "application/json", Body: []byte(`{"pickup_code":"123456"}`)}
+	require.NoError(t, first.Complete("key-1", response))
+	saved, err := second.Begin("key-1", "body-a")
+	require.NoError(t, err)
+	assert.Equal(t, response, saved)
+}
@@ -0,0 +1,194 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSPolicy 跨域策略
+type CORSPolicy struct {
+	// 允许的来源，"*" 允许任意来源，"https://*.example.com" 允许任意子域名
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	// 允许的请求头，"*" 允许预检请求中声明的任意请求头
+	AllowedHeaders []string `json:"allowed_headers"`
+	// 允许浏览器读取的响应头
+	ExposedHeaders   []string `json:"exposed_headers"`
+	AllowCredentials bool     `json:"allow_credentials"`
+	// 预检结果的缓存时间（秒），为0时不发送 Access-Control-Max-Age
+	MaxAge int `json:"max_age"`
+}
+
+// DefaultCORSPolicy 返回默认跨域策略：允许任意来源的不携带凭据的请求
+func DefaultCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "Accept", "Origin", "Cache-Control", "X-Requested-With", "Idempotency-Key"},
+		ExposedHeaders: []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After", "Idempotent-Replayed"},
+		MaxAge:         600,
+	}
+}
+
+// Validate 校验跨域策略
+func (p CORSPolicy) Validate() error {
+	for _, origin := range p.AllowedOrigins {
+		if strings.Count(origin, "*") > 1 {
+			return fmt.Errorf("origin %q: at most one wildcard is allowed", origin)
+		}
+		// 浏览器不接受携带凭据的请求使用通配来源
+		if origin == "*" && p.AllowCredentials {
+			return fmt.Errorf("origin \"*\" cannot be combined with allow_credentials")
+		}
+	}
+	if p.MaxAge < 0 {
+		return fmt.Errorf("max_age must not be negative")
+	}
+	return nil
+}
+
+// 来源是否匹配允许的模式
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+	prefix, suffix, wildcard := strings.Cut(strings.ToLower(pattern), "*")
+	if !wildcard {
+		return prefix == origin
+	}
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	// 通配符只匹配主机名中的一部分，不能跨越路径或端口
+	middle := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(middle, "/:")
+}
+
+// 路径是否位于路由组 prefix 之下，只在路径段边界匹配："/api/v1/admin" 匹配 "/api/v1/admin/stats"，不匹配 "/api/v1/administrator"
+func matchPathPrefix(prefix, path string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// 预处理后的跨域策略
+type compiledCORSPolicy struct {
+	policy      CORSPolicy
+	anyOrigin   bool
+	anyHeader   bool
+	methods     map[string]bool
+	allowMethod string
+	allowHeader string
+	exposed     string
+	maxAge      string
+}
+
+func compileCORSPolicy(policy CORSPolicy) *compiledCORSPolicy {
+	compiled := &compiledCORSPolicy{
+		policy:      policy,
+		methods:     make(map[string]bool),
+		allowMethod: strings.Join(policy.AllowedMethods, ", "),
+		allowHeader: strings.Join(policy.AllowedHeaders, ", "),
+		exposed:     strings.Join(policy.ExposedHeaders, ", "),
+	}
+	for _, origin := range policy.AllowedOrigins {
+		compiled.anyOrigin = compiled.anyOrigin || origin == "*"
+	}
+	for _, header := range policy.AllowedHeaders {
+		compiled.anyHeader = compiled.anyHeader || header == "*"
+	}
+	for _, method := range policy.AllowedMethods {
+		compiled.methods[strings.ToUpper(method)] = true
+	}
+	if policy.MaxAge > 0 {
+		compiled.maxAge = strconv.Itoa(policy.MaxAge)
+	}
+	return compiled
+}
+
+func (p *compiledCORSPolicy) allowsOrigin(origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range p.policy.AllowedOrigins {
+		if matchOrigin(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS 返回跨域中间件，groups 的键为路由组路径前缀，按路径段匹配并选择最长的前缀，未匹配时使用 defaultPolicy
+// 组策略整体替换默认策略；需注册为全局中间件，以便处理没有对应路由的预检请求
+func CORS(defaultPolicy CORSPolicy, groups map[string]CORSPolicy) gin.HandlerFunc {
+	fallback := compileCORSPolicy(defaultPolicy)
+	compiled := make(map[string]*compiledCORSPolicy, len(groups))
+	for prefix, policy := range groups {
+		compiled[prefix] = compileCORSPolicy(policy)
+	}
+
+	return func(c *gin.Context) {
+		policy, matched := fallback, ""
+		for prefix, p := range compiled {
+			if len(prefix) > len(matched) && matchPathPrefix(prefix, c.Request.URL.Path) {
+				policy, matched = p, prefix
+			}
+		}
+
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		if origin == "" {
+			c.Next()
+			return
+		}
+		if !policy.allowsOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			// 不添加跨域响应头，由浏览器拦截响应
+			c.Next()
+			return
+		}
+
+		if policy.anyOrigin && !policy.policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if policy.policy.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if policy.exposed != "" {
+				header.Set("Access-Control-Expose-Headers", policy.exposed)
+			}
+			c.Next()
+			return
+		}
+
+		// 预检请求
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		if !policy.methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		header.Set("Access-Control-Allow-Methods", policy.allowMethod)
+		if policy.anyHeader {
+			if requested := c.GetHeader("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+		} else if policy.allowHeader != "" {
+			header.Set("Access-Control-Allow-Headers", policy.allowHeader)
+		}
+		if policy.maxAge != "" {
+			header.Set("Access-Control-Max-Age", policy.maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
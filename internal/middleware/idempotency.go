@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"duckex-server/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 幂等相关的请求头和响应头
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKeyLength   = 255
+	maxIdempotencyRequestBody = 1 << 20
+)
+
+// Idempotency 处理带 Idempotency-Key 请求头的 POST 请求：同一路由上首次请求的响应被保存，
+// 相同键和相同请求体的重试直接重放该响应，不再执行处理器；相同键但请求体不同时返回 422，
+// 首次请求仍在处理中时返回 409。5xx 响应不被保存，客户端可以使用同一个键重试
+func Idempotency(store idempotency.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Idempotency-Key must be at most 255 characters",
+			})
+			return
+		}
+
+		body, err := readBody(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": "Failed to read request body: " + err.Error(),
+			})
+			return
+		}
+		if len(body) > maxIdempotencyRequestBody {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": "Request body too large for an idempotent request",
+			})
+			return
+		}
+		sum := sha256.Sum256(body)
+
+		// 幂等键只在同一路由内有效
+		scoped := c.FullPath() + " " + key
+		saved, err := store.Begin(scoped, hex.EncodeToString(sum[:]))
+		switch {
+		case errors.Is(err, idempotency.ErrMismatch):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		case err != nil:
+			// 幂等存储不可用时不执行处理器，客户端可以使用同一个键重试
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error": "Idempotency store unavailable: " + err.Error(),
+			})
+			return
+		case saved != nil:
+			c.Header(IdempotentReplayedHeader, "true")
+			c.Data(saved.Status, saved.ContentType, saved.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			// 处理器 panic 或返回 5xx 时释放幂等键
+			if !completed {
+				store.Release(scoped)
+			}
+		}()
+		c.Next()
+
+		if status := recorder.Status(); status < http.StatusInternalServerError {
+			// 保存失败时释放幂等键，重试的请求会被重新处理
+			completed = store.Complete(scoped, &idempotency.Response{
+				Status:      status,
+				ContentType: recorder.Header().Get("Content-Type"),
+				Body:        recorder.body.Bytes(),
+			}) == nil
+		}
+	}
+}
+
+// 读取完整的请求体（最多比上限多一个字节），并恢复请求体供后续处理器使用
+func readBody(c *gin.Context) ([]byte, error) {
+	if c.Request.Body == nil {
+		return nil, nil
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxIdempotencyRequestBody+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	return data, err
+}
+
+// 在写出响应的同时记录响应体
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 限流维度
+const (
+	RateLimitScopeIP     = "per_ip"
+	RateLimitScopeSharer = "per_sharer"
+)
+
+// 读取分享者ID时最多读取的请求体大小
+const maxRateLimitBodySize = 1 << 20
+
+// 单个路由的限流器
+type routeLimiters struct {
+	perIP     *ratelimit.Limiter
+	perSharer *ratelimit.Limiter
+}
+
+// RateLimits 按路由配置的限流中间件，同一路由可同时按客户端IP和分享者ID限流
+type RateLimits struct {
+	routes map[string]*routeLimiters
+}
+
+// NewRateLimits 创建限流中间件，rules 的键为完整路由路径（如 "/api/v1/items/share"）
+func NewRateLimits(rules map[string]ratelimit.RouteRules, clk clock.Clock) *RateLimits {
+	routes := make(map[string]*routeLimiters)
+	for route, rule := range rules {
+		limiters := &routeLimiters{}
+		if rule.PerIP.Enabled() {
+			limiters.perIP = ratelimit.NewLimiter(rule.PerIP, clk)
+		}
+		if rule.PerSharer.Enabled() {
+			limiters.perSharer = ratelimit.NewLimiter(rule.PerSharer, clk)
+		}
+		if limiters.perIP != nil || limiters.perSharer != nil {
+			routes[route] = limiters
+		}
+	}
+	return &RateLimits{routes: routes}
+}
+
+// Handler 返回 gin 中间件，未配置限流的路由直接放行
+// 响应中的 RateLimit-* 头反映剩余令牌最少的维度
+func (l *RateLimits) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limiters, ok := l.routes[c.FullPath()]
+		if !ok {
+			c.Next()
+			return
+		}
+
+		var sharerID string
+		if limiters.perSharer != nil {
+			sharerID = peekSharerID(c)
+		}
+		binding, allowed, limited := limiters.allow(c.ClientIP(), sharerID)
+		if !limited {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(binding.Limit))
+		header.Set("RateLimit-Remaining", strconv.Itoa(binding.Remaining))
+		header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(binding.Reset)))
+		if !allowed {
+			header.Set("Retry-After", strconv.Itoa(ceilSeconds(binding.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": "Too many requests, please slow down",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Allow 按路由的限流规则为一次不经过 HTTP 的请求（如 gRPC）消耗令牌，与 HTTP 请求共用令牌桶
+// 未配置限流的路由、或 ip 和 sharerID 对应的维度都未启用时总是放行；返回剩余令牌最少或等待最久的维度的结果
+func (l *RateLimits) Allow(route, ip, sharerID string) (ratelimit.Result, bool) {
+	limiters, ok := l.routes[route]
+	if !ok {
+		return ratelimit.Result{Allowed: true}, true
+	}
+	binding, allowed, _ := limiters.allow(ip, sharerID)
+	return binding, allowed
+}
+
+// 按IP和分享者消耗令牌，键为空的维度不参与限流；limited 为 false 表示没有维度生效
+func (r *routeLimiters) allow(ip, sharerID string) (binding ratelimit.Result, allowed, limited bool) {
+	var results []ratelimit.Result
+	if r.perIP != nil && ip != "" {
+		results = append(results, r.perIP.Allow(ip))
+	}
+	if r.perSharer != nil && sharerID != "" {
+		results = append(results, r.perSharer.Allow(sharerID))
+	}
+	if len(results) == 0 {
+		return ratelimit.Result{Allowed: true}, true, false
+	}
+
+	binding, allowed = results[0], true
+	for _, result := range results {
+		if !result.Allowed {
+			if allowed || result.RetryAfter > binding.RetryAfter {
+				binding = result
+			}
+			allowed = false
+		} else if allowed && result.Remaining < binding.Remaining {
+			binding = result
+		}
+	}
+	return binding, allowed, true
+}
+
+// Cleanup 清理所有路由中已恢复满额的令牌桶
+func (l *RateLimits) Cleanup() {
+	for _, limiters := range l.routes {
+		if limiters.perIP != nil {
+			limiters.perIP.Cleanup()
+		}
+		if limiters.perSharer != nil {
+			limiters.perSharer.Cleanup()
+		}
+	}
+}
+
+// Stats 返回各路由、各维度的限流统计
+func (l *RateLimits) Stats() map[string]map[string]ratelimit.Stats {
+	stats := make(map[string]map[string]ratelimit.Stats, len(l.routes))
+	for route, limiters := range l.routes {
+		scopes := make(map[string]ratelimit.Stats)
+		if limiters.perIP != nil {
+			scopes[RateLimitScopeIP] = limiters.perIP.Stats()
+		}
+		if limiters.perSharer != nil {
+			scopes[RateLimitScopeSharer] = limiters.perSharer.Stats()
+		}
+		stats[route] = scopes
+	}
+	return stats
+}
+
+// 从JSON请求体中读取分享者ID，并恢复请求体供后续处理器使用
+func peekSharerID(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxRateLimitBodySize))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(data), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+	var body struct {
+		SharerID string `json:"sharer_id"`
+	}
+	if json.Unmarshal(data, &body) != nil {
+		return ""
+	}
+	return body.SharerID
+}
+
+// 向上取整到秒
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
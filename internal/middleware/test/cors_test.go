@@ -0,0 +1,147 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"duckex-server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupCORSRouter(policy middleware.CORSPolicy, groups map[string]middleware.CORSPolicy) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(middleware.CORS(policy, groups))
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	r.GET("/api/v1/items", ok)
+	r.GET("/api/v1/admin", ok)
+	r.GET("/api/v1/admin/stats", ok)
+	r.GET("/api/v1/administrator", ok)
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSAllowlist(t *testing.T) {
+	r := setupCORSRouter(middleware.CORSPolicy{
+		AllowedOrigins:   []string{"https://duckex.example.com", "https://*.duckgame.io"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type"},
+		ExposedHeaders:   []string{"RateLimit-Remaining"},
+		AllowCredentials: true,
+		MaxAge:           300,
+	}, nil)
+
+	// 白名单中的来源被原样返回
+	w := corsRequest(r, http.MethodGet, "/api/v1/items", "https://duckex.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://duckex.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "RateLimit-Remaining", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Contains(t, w.Header().Values("Vary"), "Origin")
+
+	// 通配子域名
+	w = corsRequest(r, http.MethodGet, "/api/v1/items", "https://eu.duckgame.io", nil)
+	assert.Equal(t, "https://eu.duckgame.io", w.Header().Get("Access-Control-Allow-Origin"))
+	w = corsRequest(r, http.MethodGet, "/api/v1/items", "https://evil.com/x.duckgame.io", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// 不在白名单中的来源不返回跨域头
+	w = corsRequest(r, http.MethodGet, "/api/v1/items", "https://evil.example.com", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// 没有 Origin 的请求不受影响
+	w = corsRequest(r, http.MethodGet, "/api/v1/items", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSPreflight(t *testing.T) {
+	r := setupCORSRouter(middleware.CORSPolicy{
+		AllowedOrigins: []string{"https://duckex.example.com"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         300,
+	}, nil)
+
+	preflight := map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "Content-Type",
+	}
+	w := corsRequest(r, http.MethodOptions, "/api/v1/items/share", "https://duckex.example.com", preflight)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://duckex.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "300", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	// 不允许的方法和来源
+	preflight["Access-Control-Request-Method"] = "DELETE"
+	w = corsRequest(r, http.MethodOptions, "/api/v1/items/share", "https://duckex.example.com", preflight)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	preflight["Access-Control-Request-Method"] = "POST"
+	w = corsRequest(r, http.MethodOptions, "/api/v1/items/share", "https://evil.example.com", preflight)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCORSGroupOverride(t *testing.T) {
+	r := setupCORSRouter(middleware.DefaultCORSPolicy(), map[string]middleware.CORSPolicy{
+		"/api/v1/admin": {
+			AllowedOrigins: []string{"https://ops.example.com"},
+			AllowedMethods: []string{"GET"},
+		},
+	})
+
+	// 默认策略允许任意来源，不携带凭据时返回 "*"
+	w := corsRequest(r, http.MethodGet, "/api/v1/items", "https://anyone.example.com", nil)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// 管理接口使用组策略
+	w = corsRequest(r, http.MethodGet, "/api/v1/admin/stats", "https://anyone.example.com", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	w = corsRequest(r, http.MethodGet, "/api/v1/admin/stats", "https://ops.example.com", nil)
+	assert.Equal(t, "https://ops.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORSGroupMatchesPathSegments(t *testing.T) {
+	admin := middleware.CORSPolicy{
+		AllowedOrigins: []string{"https://ops.example.com"},
+		AllowedMethods: []string{"GET"},
+	}
+	for _, prefix := range []string{"/api/v1/admin", "/api/v1/admin/"} {
+		r := setupCORSRouter(middleware.DefaultCORSPolicy(), map[string]middleware.CORSPolicy{prefix: admin})
+
+		// 组路径本身和其下的路径使用组策略
+		for _, path := range []string{"/api/v1/admin", "/api/v1/admin/stats"} {
+			w := corsRequest(r, http.MethodGet, path, "https://anyone.example.com", nil)
+			assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"), prefix+" "+path)
+		}
+
+		// 只是字符串前缀相同的路径不属于该组，使用默认策略
+		w := corsRequest(r, http.MethodGet, "/api/v1/administrator", "https://anyone.example.com", nil)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"), prefix)
+	}
+}
+
+func TestCORSPolicyValidate(t *testing.T) {
+	assert.NoError(t, middleware.DefaultCORSPolicy().Validate())
+	assert.Error(t, middleware.CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}.Validate())
+	assert.Error(t, middleware.CORSPolicy{AllowedOrigins: []string{"https://*.*.example.com"}}.Validate())
+	assert.Error(t, middleware.CORSPolicy{MaxAge: -1}.Validate())
+}
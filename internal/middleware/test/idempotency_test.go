@@ -0,0 +1,135 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/idempotency"
+	"duckex-server/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// 每次执行处理器时返回递增的序号
+func setupIdempotencyRouter(status *int) (*gin.Engine, *int64) {
+	gin.SetMode(gin.TestMode)
+	var calls int64
+	r := gin.New()
+	r.Use(middleware.Idempotency(idempotency.NewInMemoryStore(time.Hour, clock.NewFake(time.Now()))))
+	handler := func(c *gin.Context) {
+		n := atomic.AddInt64(&calls, 1)
+		c.JSON(*status, gin.H{"call": n})
+	}
+	r.POST("/share", handler)
+	r.POST("/claim", handler)
+	return r, &calls
+}
+
+func postWithKey(r *gin.Engine, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(middleware.IdempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplaysResponse(t *testing.T) {
+	status := http.StatusOK
+	r, calls := setupIdempotencyRouter(&status)
+
+	first := postWithKey(r, "/share", "retry-1", `{"sharer_id":"player123"}`)
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+
+	retry := postWithKey(r, "/share", "retry-1", `{"sharer_id":"player123"}`)
+	assert.Equal(t, http.StatusOK, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, int64(1), *calls)
+
+	// 同一个键在不同路由上相互独立
+	assert.Equal(t, http.StatusOK, postWithKey(r, "/claim", "retry-1", `{"sharer_id":"player123"}`).Code)
+	assert.Equal(t, int64(2), *calls)
+
+	// 没有幂等键的请求每次都会执行
+	postWithKey(r, "/share", "", `{}`)
+	postWithKey(r, "/share", "", `{}`)
+	assert.Equal(t, int64(4), *calls)
+}
+
+func TestIdempotencyKeyReusedWithDifferentBody(t *testing.T) {
+	status := http.StatusOK
+	r, calls := setupIdempotencyRouter(&status)
+
+	postWithKey(r, "/share", "retry-1", `{"sharer_id":"player123"}`)
+	w := postWithKey(r, "/share", "retry-1", `{"sharer_id":"player456"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, int64(1), *calls)
+}
+
+func TestIdempotencyServerErrorsAreNotStored(t *testing.T) {
+	status := http.StatusInternalServerError
+	r, calls := setupIdempotencyRouter(&status)
+
+	assert.Equal(t, http.StatusInternalServerError, postWithKey(r, "/share", "retry-1", `{}`).Code)
+	status = http.StatusOK
+	w := postWithKey(r, "/share", "retry-1", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get(middleware.IdempotentReplayedHeader))
+	assert.Equal(t, int64(2), *calls)
+
+	// 4xx 响应与成功响应一样被重放
+	status = http.StatusBadRequest
+	postWithKey(r, "/share", "retry-2", `{}`)
+	assert.Equal(t, http.StatusBadRequest, postWithKey(r, "/share", "retry-2", `{}`).Code)
+	assert.Equal(t, int64(3), *calls)
+}
+
+func TestIdempotencyKeyTooLong(t *testing.T) {
+	status := http.StatusOK
+	r, calls := setupIdempotencyRouter(&status)
+
+	assert.Equal(t, http.StatusBadRequest, postWithKey(r, "/share", strings.Repeat("k", 256), `{}`).Code)
+	assert.Equal(t, int64(0), *calls)
+}
+
+// 始终无法访问的幂等存储
+type unavailableStore struct {
+	idempotency.Store
+}
+
+func (unavailableStore) Begin(key, fingerprint string) (*idempotency.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestIdempotencyStoreUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var calls int64
+	r := gin.New()
+	r.Use(middleware.Idempotency(unavailableStore{}))
+	r.POST("/share", func(c *gin.Context) {
+		atomic.AddInt64(&calls, 1)
+		c.Status(http.StatusOK)
+	})
+
+	// 无法确认是否为重试时不执行处理器
+	w := postWithKey(r, "/share", "retry-1", `{}`)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, int64(0), calls)
+
+	// 不带幂等键的请求不受影响
+	w = postWithKey(r, "/share", "", `{}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/ratelimit"
+	"duckex-server/internal/router"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRateLimitRouter(rules map[string]ratelimit.RouteRules, clk clock.Clock) (*gin.Engine, *middleware.RateLimits) {
+	gin.SetMode(gin.TestMode)
+	limits := middleware.NewRateLimits(rules, clk)
+	r := gin.New()
+	r.Use(limits.Handler())
+	echo := func(c *gin.Context) {
+		// 限流中间件读取请求体后，处理器仍能读取完整的请求体
+		body, _ := io.ReadAll(c.Request.Body)
+		c.Data(http.StatusOK, "application/json", body)
+	}
+	r.POST("/share", echo)
+	r.POST("/claim", echo)
+	return r, limits
+}
+
+func postJSON(r *gin.Engine, path, remoteAddr string, body interface{}) *httptest.ResponseRecorder {
+	data, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewBuffer(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitPerIP(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	r, limits := setupRateLimitRouter(map[string]ratelimit.RouteRules{
+		"/share": {PerIP: ratelimit.Rule{Rate: 1, Burst: 2}},
+	}, clk)
+
+	body := map[string]string{"sharer_id": "player123"}
+	w := postJSON(r, "/share", "10.0.0.1:1234", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+	assert.JSONEq(t, `{"sharer_id":"player123"}`, w.Body.String())
+
+	assert.Equal(t, http.StatusOK, postJSON(r, "/share", "10.0.0.1:1234", body).Code)
+	w = postJSON(r, "/share", "10.0.0.1:1234", body)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2", w.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// 其他IP和未配置限流的路由不受影响
+	assert.Equal(t, http.StatusOK, postJSON(r, "/share", "10.0.0.2:1234", body).Code)
+	w = postJSON(r, "/claim", "10.0.0.1:1234", body)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("RateLimit-Limit"))
+
+	// 令牌补充后恢复
+	clk.Advance(time.Second)
+	assert.Equal(t, http.StatusOK, postJSON(r, "/share", "10.0.0.1:1234", body).Code)
+
+	stats := limits.Stats()["/share"][middleware.RateLimitScopeIP]
+	assert.Equal(t, int64(4), stats.Allowed)
+	assert.Equal(t, int64(1), stats.Rejected)
+}
+
+// 使用服务的引擎配置，按 trustedProxies 识别客户端IP
+func setupProxyRateLimitRouter(t *testing.T, trustedProxies []string, clk clock.Clock) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r, err := router.NewEngine(trustedProxies)
+	require.NoError(t, err)
+	r.Use(middleware.NewRateLimits(map[string]ratelimit.RouteRules{
+		"/share": {PerIP: ratelimit.Rule{Rate: 1, Burst: 1}},
+	}, clk).Handler())
+	r.POST("/share", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+// 以 remoteAddr 为对端地址、携带 X-Forwarded-For 发送请求
+func postForwarded(r *gin.Engine, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/share", bytes.NewBufferString(`{"sharer_id":"player123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+
+	// 默认不信任任何代理，伪造的 X-Forwarded-For 不会换到新的令牌桶
+	r := setupProxyRateLimitRouter(t, nil, clk)
+	assert.Equal(t, http.StatusOK, postForwarded(r, "203.0.113.7:1234", "198.51.100.1"))
+	assert.Equal(t, http.StatusTooManyRequests, postForwarded(r, "203.0.113.7:1234", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, postForwarded(r, "203.0.113.7:1234", "198.51.100.3, 10.0.0.1"))
+
+	// 来自可信代理的请求按其转发的客户端IP限流，其他地址的请求头仍被忽略
+	r = setupProxyRateLimitRouter(t, []string{"10.0.0.0/8"}, clk)
+	assert.Equal(t, http.StatusOK, postForwarded(r, "10.0.0.5:1234", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, postForwarded(r, "10.0.0.5:1234", "198.51.100.2"))
+	assert.Equal(t, http.StatusTooManyRequests, postForwarded(r, "10.0.0.6:1234", "198.51.100.2"))
+	assert.Equal(t, http.StatusOK, postForwarded(r, "203.0.113.7:1234", "198.51.100.9"))
+	assert.Equal(t, http.StatusTooManyRequests, postForwarded(r, "203.0.113.7:1234", "198.51.100.10"))
+}
+
+func TestRateLimitPerSharer(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	r, limits := setupRateLimitRouter(map[string]ratelimit.RouteRules{
+		"/share": {
+			PerIP:     ratelimit.Rule{Rate: 1, Burst: 10},
+			PerSharer: ratelimit.Rule{Rate: 0.5, Burst: 1},
+		},
+	}, clk)
+
+	// 同一分享者从不同IP请求也会被限流
+	assert.Equal(t, http.StatusOK, postJSON(r, "/share", "10.0.0.1:1234", map[string]string{"sharer_id": "spammer"}).Code)
+	w := postJSON(r, "/share", "10.0.0.2:1234", map[string]string{"sharer_id": "spammer"})
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "2", w.Header().Get("Retry-After"))
+
+	// 其他分享者不受影响，响应头反映剩余最少的维度
+	w = postJSON(r, "/share", "10.0.0.1:1234", map[string]string{"sharer_id": "player123"})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+
+	stats := limits.Stats()["/share"]
+	assert.Equal(t, int64(1), stats[middleware.RateLimitScopeSharer].Rejected)
+	assert.Equal(t, int64(3), stats[middleware.RateLimitScopeIP].Allowed)
+}
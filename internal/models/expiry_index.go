@@ -0,0 +1,90 @@
+package models
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryEntry 过期索引中的一项
+type expiryEntry struct {
+	pickupCode string
+	expiresAt  time.Time
+	index      int // 在堆中的位置，由 heap 接口维护
+}
+
+// expiryIndex 以 ExpiresAt 为键的最小堆，配合取件码映射支持 O(log n) 的增删改
+// 调用方负责加锁
+type expiryIndex struct {
+	entries []*expiryEntry
+	byCode  map[string]*expiryEntry
+}
+
+func newExpiryIndex() *expiryIndex {
+	return &expiryIndex{
+		byCode: make(map[string]*expiryEntry),
+	}
+}
+
+// heap.Interface 实现
+func (x *expiryIndex) Len() int { return len(x.entries) }
+func (x *expiryIndex) Less(i, j int) bool {
+	return x.entries[i].expiresAt.Before(x.entries[j].expiresAt)
+}
+func (x *expiryIndex) Swap(i, j int) {
+	x.entries[i], x.entries[j] = x.entries[j], x.entries[i]
+	x.entries[i].index = i
+	x.entries[j].index = j
+}
+func (x *expiryIndex) Push(v interface{}) {
+	entry := v.(*expiryEntry)
+	entry.index = len(x.entries)
+	x.entries = append(x.entries, entry)
+}
+func (x *expiryIndex) Pop() interface{} {
+	n := len(x.entries)
+	entry := x.entries[n-1]
+	x.entries[n-1] = nil
+	x.entries = x.entries[:n-1]
+	entry.index = -1
+	return entry
+}
+
+// set 添加或更新取件码的过期时间
+func (x *expiryIndex) set(pickupCode string, expiresAt time.Time) {
+	if entry, ok := x.byCode[pickupCode]; ok {
+		entry.expiresAt = expiresAt
+		heap.Fix(x, entry.index)
+		return
+	}
+	entry := &expiryEntry{pickupCode: pickupCode, expiresAt: expiresAt}
+	x.byCode[pickupCode] = entry
+	heap.Push(x, entry)
+}
+
+// remove 移除取件码
+func (x *expiryIndex) remove(pickupCode string) {
+	entry, ok := x.byCode[pickupCode]
+	if !ok {
+		return
+	}
+	heap.Remove(x, entry.index)
+	delete(x.byCode, pickupCode)
+}
+
+// popDue 弹出一个在 now 之前过期的取件码，没有则返回 false
+func (x *expiryIndex) popDue(now time.Time) (string, bool) {
+	if len(x.entries) == 0 || !x.entries[0].expiresAt.Before(now) {
+		return "", false
+	}
+	entry := heap.Pop(x).(*expiryEntry)
+	delete(x.byCode, entry.pickupCode)
+	return entry.pickupCode, true
+}
+
+// next 返回最早的过期时间
+func (x *expiryIndex) next() (time.Time, bool) {
+	if len(x.entries) == 0 {
+		return time.Time{}, false
+	}
+	return x.entries[0].expiresAt, true
+}
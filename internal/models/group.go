@@ -0,0 +1,347 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// GroupRole 群组成员的角色
+type GroupRole string
+
+const (
+	// RoleOwner 群主，每个群组只有一个，可以调整角色和移除任何成员
+	RoleOwner GroupRole = "owner"
+	// RoleAdmin 管理员，可以邀请玩家和移除普通成员
+	RoleAdmin GroupRole = "admin"
+	// RoleMember 普通成员，可以向群组分享和领取群组物品
+	RoleMember GroupRole = "member"
+)
+
+// Valid 是否为已知的角色
+func (r GroupRole) Valid() bool {
+	return r == RoleOwner || r == RoleAdmin || r == RoleMember
+}
+
+// 角色的权限等级，只能管理等级更低的成员
+func (r GroupRole) rank() int {
+	switch r {
+	case RoleOwner:
+		return 3
+	case RoleAdmin:
+		return 2
+	case RoleMember:
+		return 1
+	}
+	return 0
+}
+
+// 群组仓库错误
+var (
+	// ErrGroupNotFound 群组不存在
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrDuplicateGroupID 群组ID已存在
+	ErrDuplicateGroupID = errors.New("duplicate group id")
+	// ErrGroupForbidden 玩家的角色不能执行该操作（如普通成员邀请玩家、群主退出群组）
+	ErrGroupForbidden = errors.New("player is not allowed to perform this group action")
+	// ErrNotGroupMember 玩家不是群组成员
+	ErrNotGroupMember = errors.New("player is not a group member")
+	// ErrAlreadyGroupMember 玩家已经是群组成员
+	ErrAlreadyGroupMember = errors.New("player is already a group member")
+	// ErrNotInvited 玩家没有收到群组的邀请
+	ErrNotInvited = errors.New("player has not been invited to the group")
+	// ErrGroupConflict 群组在读取后被并发修改，调用者可以重试
+	ErrGroupConflict = errors.New("group was modified concurrently")
+)
+
+// GroupMember 群组成员
+type GroupMember struct {
+	PlayerID string    `json:"player_id"`
+	Role     GroupRole `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// GroupInvite 尚未接受的邀请，被邀请的玩家加入群组后移除
+type GroupInvite struct {
+	PlayerID  string    `json:"player_id"`
+	InvitedBy string    `json:"invited_by"`
+	InvitedAt time.Time `json:"invited_at"`
+}
+
+// Group 玩家群组：成员可以向群组分享物品，分享给群组的物品只有成员可以领取
+type Group struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	OwnerID   string        `json:"owner_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	Members   []GroupMember `json:"members"`
+	Invites   []GroupInvite `json:"invites,omitempty"`
+}
+
+// Member 返回玩家的成员信息，不是成员时返回 nil
+func (g *Group) Member(playerID string) *GroupMember {
+	for i := range g.Members {
+		if g.Members[i].PlayerID == playerID {
+			return &g.Members[i]
+		}
+	}
+	return nil
+}
+
+// Invited 玩家是否有尚未接受的邀请
+func (g *Group) Invited(playerID string) bool {
+	return g.inviteIndex(playerID) >= 0
+}
+
+func (g *Group) inviteIndex(playerID string) int {
+	for i, invite := range g.Invites {
+		if invite.PlayerID == playerID {
+			return i
+		}
+	}
+	return -1
+}
+
+// 玩家的权限等级，不是成员时为0
+func (g *Group) rankOf(playerID string) int {
+	if member := g.Member(playerID); member != nil {
+		return member.Role.rank()
+	}
+	return 0
+}
+
+// 深拷贝，避免调用者修改仓库中的群组
+func (g *Group) clone() *Group {
+	copied := *g
+	copied.Members = append([]GroupMember(nil), g.Members...)
+	copied.Invites = append([]GroupInvite(nil), g.Invites...)
+	return &copied
+}
+
+// 群组的状态转换，各仓库实现在同一事务或锁内调用，返回错误时不做修改
+
+func inviteToGroup(inviterID, inviteeID string, now time.Time) func(*Group) error {
+	return func(g *Group) error {
+		switch {
+		case g.rankOf(inviterID) < RoleAdmin.rank():
+			return ErrGroupForbidden
+		case g.Member(inviteeID) != nil:
+			return ErrAlreadyGroupMember
+		case g.Invited(inviteeID):
+			// 重复邀请保留第一次的邀请
+			return nil
+		}
+		g.Invites = append(g.Invites, GroupInvite{PlayerID: inviteeID, InvitedBy: inviterID, InvitedAt: now})
+		return nil
+	}
+}
+
+func joinGroup(playerID string, now time.Time) func(*Group) error {
+	return func(g *Group) error {
+		if g.Member(playerID) != nil {
+			return ErrAlreadyGroupMember
+		}
+		i := g.inviteIndex(playerID)
+		if i < 0 {
+			return ErrNotInvited
+		}
+		g.Invites = append(g.Invites[:i], g.Invites[i+1:]...)
+		g.Members = append(g.Members, GroupMember{PlayerID: playerID, Role: RoleMember, JoinedAt: now})
+		return nil
+	}
+}
+
+// 移除成员，不检查权限
+func (g *Group) removeMember(playerID string) {
+	for i, member := range g.Members {
+		if member.PlayerID == playerID {
+			g.Members = append(g.Members[:i], g.Members[i+1:]...)
+			return
+		}
+	}
+}
+
+func leaveGroup(playerID string) func(*Group) error {
+	return func(g *Group) error {
+		switch {
+		case g.Member(playerID) == nil:
+			return ErrNotGroupMember
+		case g.OwnerID == playerID:
+			// 群主需要先转让群组
+			return ErrGroupForbidden
+		}
+		g.removeMember(playerID)
+		return nil
+	}
+}
+
+func setGroupRole(actorID, targetID string, role GroupRole) func(*Group) error {
+	return func(g *Group) error {
+		target := g.Member(targetID)
+		switch {
+		case g.OwnerID != actorID || actorID == targetID:
+			return ErrGroupForbidden
+		case target == nil:
+			return ErrNotGroupMember
+		}
+		if role == RoleOwner {
+			// 转让群组，原群主成为管理员
+			g.Member(actorID).Role = RoleAdmin
+			g.OwnerID = targetID
+		}
+		target.Role = role
+		return nil
+	}
+}
+
+func removeFromGroup(actorID, targetID string) func(*Group) error {
+	return func(g *Group) error {
+		// 管理员可以撤回邀请
+		if i := g.inviteIndex(targetID); i >= 0 && g.Member(targetID) == nil {
+			if g.rankOf(actorID) < RoleAdmin.rank() {
+				return ErrGroupForbidden
+			}
+			g.Invites = append(g.Invites[:i], g.Invites[i+1:]...)
+			return nil
+		}
+		switch {
+		case g.Member(targetID) == nil:
+			return ErrNotGroupMember
+		case g.rankOf(actorID) < RoleAdmin.rank() || g.rankOf(actorID) <= g.rankOf(targetID):
+			return ErrGroupForbidden
+		}
+		g.removeMember(targetID)
+		return nil
+	}
+}
+
+// GroupRepository 群组仓库接口，成员变更都是原子的
+type GroupRepository interface {
+	// Create 保存新群组，ID 已存在时返回 ErrDuplicateGroupID
+	Create(group *Group) error
+	// Get 按ID获取群组，不存在时返回 (nil, nil)
+	Get(id string) (*Group, error)
+	// ListForPlayer 返回玩家所在或被邀请加入的全部群组，按创建时间倒序
+	ListForPlayer(playerID string) ([]*Group, error)
+	// IsMember 玩家是否是群组成员，群组不存在时返回 false
+	IsMember(id, playerID string) (bool, error)
+	// Invite 管理员或群主邀请玩家，返回 ErrGroupNotFound、ErrGroupForbidden 或 ErrAlreadyGroupMember
+	Invite(id, inviterID, inviteeID string, now time.Time) (*Group, error)
+	// Join 被邀请的玩家加入群组，返回 ErrGroupNotFound、ErrNotInvited 或 ErrAlreadyGroupMember
+	Join(id, playerID string, now time.Time) (*Group, error)
+	// Leave 成员退出群组，群主需要先转让群组；返回 ErrGroupNotFound、ErrNotGroupMember 或 ErrGroupForbidden
+	Leave(id, playerID string) (*Group, error)
+	// SetRole 群主调整成员的角色，设为 RoleOwner 时转让群组；返回 ErrGroupNotFound、ErrNotGroupMember 或 ErrGroupForbidden
+	SetRole(id, actorID, targetID string, role GroupRole) (*Group, error)
+	// Remove 移除等级低于操作者的成员，或由管理员撤回邀请；返回 ErrGroupNotFound、ErrNotGroupMember 或 ErrGroupForbidden
+	Remove(id, actorID, targetID string) (*Group, error)
+}
+
+// InMemoryGroupRepository 内存实现的群组仓库
+type InMemoryGroupRepository struct {
+	groups map[string]*Group
+	mutex  sync.RWMutex
+}
+
+// NewInMemoryGroupRepository 创建新的内存群组仓库
+func NewInMemoryGroupRepository() *InMemoryGroupRepository {
+	return &InMemoryGroupRepository{
+		groups: make(map[string]*Group),
+	}
+}
+
+// Create 保存新群组
+func (r *InMemoryGroupRepository) Create(group *Group) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.groups[group.ID]; exists {
+		return ErrDuplicateGroupID
+	}
+	r.groups[group.ID] = group.clone()
+	return nil
+}
+
+// Get 按ID获取群组
+func (r *InMemoryGroupRepository) Get(id string) (*Group, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	group, exists := r.groups[id]
+	if !exists {
+		return nil, nil
+	}
+	return group.clone(), nil
+}
+
+// ListForPlayer 返回玩家所在或被邀请加入的群组
+func (r *InMemoryGroupRepository) ListForPlayer(playerID string) ([]*Group, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	groups := make([]*Group, 0)
+	for _, group := range r.groups {
+		if group.Member(playerID) != nil || group.Invited(playerID) {
+			groups = append(groups, group.clone())
+		}
+	}
+	sortGroups(groups)
+	return groups, nil
+}
+
+// IsMember 玩家是否是群组成员
+func (r *InMemoryGroupRepository) IsMember(id, playerID string) (bool, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	group, exists := r.groups[id]
+	return exists && group.Member(playerID) != nil, nil
+}
+
+// 在写锁内查找群组并执行状态转换，update 返回错误时不做修改
+func (r *InMemoryGroupRepository) transition(id string, update func(*Group) error) (*Group, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	group, exists := r.groups[id]
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+	updated := group.clone()
+	if err := update(updated); err != nil {
+		return nil, err
+	}
+	r.groups[id] = updated
+	return updated.clone(), nil
+}
+
+// Invite 邀请玩家
+func (r *InMemoryGroupRepository) Invite(id, inviterID, inviteeID string, now time.Time) (*Group, error) {
+	return r.transition(id, inviteToGroup(inviterID, inviteeID, now))
+}
+
+// Join 加入群组
+func (r *InMemoryGroupRepository) Join(id, playerID string, now time.Time) (*Group, error) {
+	return r.transition(id, joinGroup(playerID, now))
+}
+
+// Leave 退出群组
+func (r *InMemoryGroupRepository) Leave(id, playerID string) (*Group, error) {
+	return r.transition(id, leaveGroup(playerID))
+}
+
+// SetRole 调整成员的角色
+func (r *InMemoryGroupRepository) SetRole(id, actorID, targetID string, role GroupRole) (*Group, error) {
+	return r.transition(id, setGroupRole(actorID, targetID, role))
+}
+
+// Remove 移除成员或撤回邀请
+func (r *InMemoryGroupRepository) Remove(id, actorID, targetID string) (*Group, error) {
+	return r.transition(id, removeFromGroup(actorID, targetID))
+}
+
+// 按创建时间倒序排列，创建时间相同时按ID排序
+func sortGroups(groups []*Group) {
+	sort.Slice(groups, func(i, j int) bool {
+		if !groups[i].CreatedAt.Equal(groups[j].CreatedAt) {
+			return groups[i].CreatedAt.After(groups[j].CreatedAt)
+		}
+		return groups[i].ID < groups[j].ID
+	})
+}
@@ -1,26 +1,13 @@
 package models
 
 import (
+	"errors"
 	"sync"
 	"time"
-)
 
-// 导出的辅助函数，用于测试
-var (
-	GetCurrentTime    func() time.Time
-	GetExpirationTime func() time.Time
+	"duckex-server/internal/clock"
 )
 
-// 初始化默认实现
-func init() {
-	GetCurrentTime = func() time.Time {
-		return time.Now()
-	}
-	GetExpirationTime = func() time.Time {
-		return time.Now().Add(24 * time.Hour)
-	}
-}
-
 // Item 物品模型
 type Item struct {
 	ID          string    `json:"id"`
@@ -35,104 +22,409 @@ type Item struct {
 	ExpiresAt   time.Time `json:"expires_at"`
 	IsClaimed   bool      `json:"is_claimed"`
 	ClaimerID   string    `json:"claimer_id"`
+	// Reservation 两阶段领取中未确认的预留，预留期间 IsClaimed 为 true、ClaimerID 为预留者
+	Reservation *Reservation `json:"reservation,omitempty"`
+	// ListingID 公开上架到市场时的挂牌ID，为空时物品只能凭取件码领取
+	ListingID string `json:"listing_id,omitempty"`
+	// GroupID 分享给群组时的群组ID，只有群组成员可以领取
+	GroupID string `json:"group_id,omitempty"`
+}
+
+// Reservation 两阶段领取的预留：领取者凭令牌在 ExpiresAt 之前确认，否则物品恢复为未领取
+type Reservation struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Pending 物品在 now 时是否可以被领取：未被领取，或预留已过期未确认
+func (item *Item) Pending(now time.Time) bool {
+	return !item.IsClaimed || (item.Reservation != nil && now.After(item.Reservation.ExpiresAt))
+}
+
+// 返回取消预留后恢复为未领取的副本
+func (item *Item) released() *Item {
+	released := *item
+	released.IsClaimed = false
+	released.ClaimerID = ""
+	released.Reservation = nil
+	return &released
+}
+
+// 返回预留给 claimerID 的副本，预留期限不超过物品的过期时间
+func (item *Item) reserved(claimerID, token string, until time.Time) *Item {
+	if until.After(item.ExpiresAt) {
+		until = item.ExpiresAt
+	}
+	reserved := *item
+	reserved.IsClaimed = true
+	reserved.ClaimerID = claimerID
+	reserved.Reservation = &Reservation{Token: token, ExpiresAt: until}
+	return &reserved
 }
 
+// 预留已过期未确认时返回恢复为未领取的副本，否则返回原物品
+func (item *Item) visibleAt(now time.Time) *Item {
+	if item.Reservation != nil && now.After(item.Reservation.ExpiresAt) {
+		return item.released()
+	}
+	return item
+}
+
+// 仓库操作返回的错误
+var (
+	// ErrItemNotFound 取件码对应的物品不存在或已过期
+	ErrItemNotFound = errors.New("item not found")
+	// ErrItemClaimed 物品已被领取
+	ErrItemClaimed = errors.New("item already claimed")
+	// ErrDuplicatePickupCode 取件码已被未过期的物品占用
+	ErrDuplicatePickupCode = errors.New("pickup code already in use")
+	// ErrReservationNotFound 预留不存在、令牌不匹配或预留已过期
+	ErrReservationNotFound = errors.New("reservation not found or expired")
+)
+
 // ItemRepository 物品仓库接口
 type ItemRepository interface {
 	Create(item *Item) error
 	GetByPickupCode(pickupCode string) (*Item, error)
+	// Claim 原子地领取物品：物品从仓库移除，返回标记为已领取的副本
+	Claim(pickupCode, claimerID string) (*Item, error)
+	// Reserve 原子地为 claimerID 预留物品：物品留在仓库中并标记为已领取，直到确认或预留过期
+	// 预留期限不超过物品的过期时间；预留已过期的物品视为未领取，可以被重新预留或领取
+	Reserve(pickupCode, claimerID, token string, until time.Time) (*Item, error)
+	// Confirm 确认预留，物品从仓库移除并返回；令牌不匹配或预留已过期时返回 ErrReservationNotFound
+	Confirm(pickupCode, token string) (*Item, error)
+	// Release 取消预留，物品恢复为未领取并返回取消前的物品
+	Release(pickupCode, token string) (*Item, error)
+	// ReleaseExpiredReservations 将预留已过期的物品恢复为未领取，返回恢复前的物品
+	ReleaseExpiredReservations() ([]*Item, error)
 	Update(item *Item) error
 	Delete(pickupCode string) error
 	DeleteExpired() error
 	GetAll() []*Item
+	// Query 按条件筛选未过期的物品，排序后按游标分页返回；条件或游标不合法时返回 ErrInvalidQuery
+	Query(q ItemQuery) (*ItemPage, error)
+	SetExpiredHandler(handler ExpiredHandler)
 }
 
+// ExpiredHandler 物品过期被移出仓库时的回调
+type ExpiredHandler func(item *Item)
+
+// 每次持锁处理的过期物品数量上限，避免一次清理长时间阻塞读写
+const expireBatchSize = 1024
+
 // InMemoryItemRepository 内存实现的物品仓库
 type InMemoryItemRepository struct {
-	items map[string]*Item
-	mutex sync.RWMutex
+	items     map[string]*Item
+	expiry    *expiryIndex // 按过期时间排序的索引，清理时无需遍历全部物品
+	leases    *expiryIndex // 按预留过期时间排序的索引
+	search    *searchIndex // 按类型、分享者、挂牌和检索词的二级索引
+	mutex     sync.RWMutex
+	onExpired ExpiredHandler
+	clock     clock.Clock
 }
 
-// NewInMemoryItemRepository 创建新的内存仓库实例
-func NewInMemoryItemRepository() *InMemoryItemRepository {
+// NewInMemoryItemRepository 创建新的内存仓库实例，clk 为判断过期使用的时间源，nil 时使用系统时间
+func NewInMemoryItemRepository(clk clock.Clock) *InMemoryItemRepository {
+	if clk == nil {
+		clk = clock.System()
+	}
 	return &InMemoryItemRepository{
-		items: make(map[string]*Item),
+		items:  make(map[string]*Item),
+		expiry: newExpiryIndex(),
+		leases: newExpiryIndex(),
+		search: newSearchIndex(),
+		clock:  clk,
 	}
 }
 
-// Create 创建新物品
+// 保存物品并更新索引，调用者需持有写锁
+func (r *InMemoryItemRepository) put(item *Item) {
+	r.items[item.PickupCode] = item
+	r.expiry.set(item.PickupCode, item.ExpiresAt)
+	r.search.set(item)
+	if item.Reservation != nil {
+		r.leases.set(item.PickupCode, item.Reservation.ExpiresAt)
+	} else {
+		r.leases.remove(item.PickupCode)
+	}
+}
+
+// 移除物品及其索引，调用者需持有写锁
+func (r *InMemoryItemRepository) remove(pickupCode string) {
+	delete(r.items, pickupCode)
+	r.expiry.remove(pickupCode)
+	r.leases.remove(pickupCode)
+	r.search.remove(pickupCode)
+}
+
+// Create 创建新物品，取件码被未过期的物品占用时返回 ErrDuplicatePickupCode
 func (r *InMemoryItemRepository) Create(item *Item) error {
+	now := r.clock.Now()
 	r.mutex.Lock()
-	defer r.mutex.Unlock()
-	r.items[item.PickupCode] = item
+	existing, exists := r.items[item.PickupCode]
+	if exists && !now.After(existing.ExpiresAt) {
+		r.mutex.Unlock()
+		return ErrDuplicatePickupCode
+	}
+	r.put(item)
+	handler := r.onExpired
+	r.mutex.Unlock()
+
+	// 被替换的过期物品按过期处理
+	if exists && handler != nil {
+		handler(existing.visibleAt(now))
+	}
 	return nil
 }
 
-// GetByPickupCode 通过取件码获取物品
+// GetByPickupCode 通过取件码获取物品，预留已过期的物品按未领取返回
 func (r *InMemoryItemRepository) GetByPickupCode(pickupCode string) (*Item, error) {
+	now := r.clock.Now()
 	r.mutex.RLock()
 	item, exists := r.items[pickupCode]
 	if !exists {
 		r.mutex.RUnlock()
 		return nil, nil
 	}
-	
+
 	// 检查物品是否过期
-	if GetCurrentTime().After(item.ExpiresAt) {
+	if now.After(item.ExpiresAt) {
 		// 解锁读锁，获取写锁删除过期物品
 		r.mutex.RUnlock()
 		r.mutex.Lock()
 		// 再次检查物品是否存在（防止并发删除）
-		if _, stillExists := r.items[pickupCode]; stillExists {
-			delete(r.items, pickupCode)
+		expired, stillExists := r.items[pickupCode]
+		if stillExists {
+			r.remove(pickupCode)
 		}
+		handler := r.onExpired
 		r.mutex.Unlock()
+		if stillExists && handler != nil {
+			handler(expired.visibleAt(now))
+		}
 		return nil, nil
 	}
-	
+
 	r.mutex.RUnlock()
+	return item.visibleAt(now), nil
+}
+
+// 取出可领取的物品：不存在或已过期时返回 ErrItemNotFound，已被领取或预留时返回 ErrItemClaimed
+// 调用者需持有写锁；物品已过期时会移出仓库并返回，由调用者在释放锁后交给过期回调
+func (r *InMemoryItemRepository) takePending(pickupCode string, now time.Time) (item, expired *Item, err error) {
+	item, exists := r.items[pickupCode]
+	if !exists {
+		return nil, nil, ErrItemNotFound
+	}
+	if now.After(item.ExpiresAt) {
+		r.remove(pickupCode)
+		return nil, item.visibleAt(now), ErrItemNotFound
+	}
+	if !item.Pending(now) {
+		return nil, nil, ErrItemClaimed
+	}
+	return item.visibleAt(now), nil, nil
+}
+
+// 在锁外调用过期回调，避免回调中再次访问仓库导致死锁
+func (r *InMemoryItemRepository) notifyExpired(handler ExpiredHandler, expired *Item) {
+	if expired != nil && handler != nil {
+		handler(expired)
+	}
+}
+
+// Claim 原子地领取物品，同一物品只有一个领取者能成功
+func (r *InMemoryItemRepository) Claim(pickupCode, claimerID string) (*Item, error) {
+	now := r.clock.Now()
+	r.mutex.Lock()
+	item, expired, err := r.takePending(pickupCode, now)
+	if err == nil {
+		// 物品被领取后立即删除
+		r.remove(pickupCode)
+	}
+	handler := r.onExpired
+	r.mutex.Unlock()
+
+	r.notifyExpired(handler, expired)
+	if err != nil {
+		return nil, err
+	}
+	claimed := *item
+	claimed.IsClaimed = true
+	claimed.ClaimerID = claimerID
+	return &claimed, nil
+}
+
+// Reserve 原子地预留物品，同一物品同一时间只有一个预留
+func (r *InMemoryItemRepository) Reserve(pickupCode, claimerID, token string, until time.Time) (*Item, error) {
+	now := r.clock.Now()
+	r.mutex.Lock()
+	item, expired, err := r.takePending(pickupCode, now)
+	if err == nil {
+		item = item.reserved(claimerID, token, until)
+		r.put(item)
+	}
+	handler := r.onExpired
+	r.mutex.Unlock()
+
+	r.notifyExpired(handler, expired)
+	if err != nil {
+		return nil, err
+	}
+	reserved := *item
+	return &reserved, nil
+}
+
+// 查找令牌匹配且未过期的预留，调用者需持有写锁
+func (r *InMemoryItemRepository) findReservation(pickupCode, token string, now time.Time) (*Item, error) {
+	item, exists := r.items[pickupCode]
+	if !exists || item.Reservation == nil || item.Reservation.Token != token || now.After(item.Reservation.ExpiresAt) {
+		return nil, ErrReservationNotFound
+	}
+	return item, nil
+}
+
+// Confirm 确认预留，物品从仓库移除
+func (r *InMemoryItemRepository) Confirm(pickupCode, token string) (*Item, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	item, err := r.findReservation(pickupCode, token, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	r.remove(pickupCode)
+	confirmed := *item
+	confirmed.Reservation = nil
+	return &confirmed, nil
+}
+
+// Release 取消预留，物品恢复为未领取
+func (r *InMemoryItemRepository) Release(pickupCode, token string) (*Item, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	item, err := r.findReservation(pickupCode, token, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	r.put(item.released())
 	return item, nil
 }
 
-// Update 更新物品信息
+// ReleaseExpiredReservations 通过预留索引只处理已过期的预留
+func (r *InMemoryItemRepository) ReleaseExpiredReservations() ([]*Item, error) {
+	now := r.clock.Now()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var released []*Item
+	for {
+		code, ok := r.leases.popDue(now)
+		if !ok {
+			return released, nil
+		}
+		if item, exists := r.items[code]; exists && item.Reservation != nil {
+			r.put(item.released())
+			released = append(released, item)
+		}
+	}
+}
+
+// Update 更新物品信息，物品不存在时返回 ErrItemNotFound
 func (r *InMemoryItemRepository) Update(item *Item) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	r.items[item.PickupCode] = item
+	if _, exists := r.items[item.PickupCode]; !exists {
+		return ErrItemNotFound
+	}
+	r.put(item)
 	return nil
 }
 
-// DeleteExpired 删除过期物品
+// DeleteExpired 删除过期物品，并将其交给过期回调（如退回箱）
+// 通过过期索引只处理已到期的物品，并分批持锁
 func (r *InMemoryItemRepository) DeleteExpired() error {
-	r.mutex.Lock()
-	defer r.mutex.Unlock()
-	now := GetCurrentTime()
-	for code, item := range r.items {
-		if item.ExpiresAt.Before(now) {
+	now := r.clock.Now()
+	for {
+		r.mutex.Lock()
+		expired := make([]*Item, 0, 16)
+		for len(expired) < expireBatchSize {
+			code, ok := r.expiry.popDue(now)
+			if !ok {
+				break
+			}
+			// 预留不会超过物品的过期时间，过期物品以未领取状态交给回调
+			expired = append(expired, r.items[code].visibleAt(now))
 			delete(r.items, code)
+			r.leases.remove(code)
+			r.search.remove(code)
+		}
+		handler := r.onExpired
+		r.mutex.Unlock()
+
+		// 在锁外调用回调，避免回调中再次访问仓库导致死锁
+		if handler != nil {
+			for _, item := range expired {
+				handler(item)
+			}
+		}
+		if len(expired) < expireBatchSize {
+			return nil
 		}
 	}
-	return nil
+}
+
+// NextExpiry 返回仓库中最早的过期时间，仓库为空时返回 false
+func (r *InMemoryItemRepository) NextExpiry() (time.Time, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.expiry.next()
+}
+
+// SetExpiredHandler 设置物品过期时的回调
+func (r *InMemoryItemRepository) SetExpiredHandler(handler ExpiredHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onExpired = handler
 }
 
 // Delete 删除物品
 func (r *InMemoryItemRepository) Delete(pickupCode string) error {
 	r.mutex.Lock()
 	defer r.mutex.Unlock()
-	delete(r.items, pickupCode)
+	r.remove(pickupCode)
 	return nil
 }
 
 // GetAll 获取所有物品（主要用于测试）
+// 先处理已到期的物品，返回的即为全部未过期物品
 func (r *InMemoryItemRepository) GetAll() []*Item {
+	r.DeleteExpired()
+
+	now := r.clock.Now()
 	r.mutex.RLock()
 	defer r.mutex.RUnlock()
 	items := make([]*Item, 0, len(r.items))
 	for _, item := range r.items {
-		// 只返回未过期的物品
-		if !GetCurrentTime().After(item.ExpiresAt) {
-			items = append(items, item)
-		}
+		items = append(items, item.visibleAt(now))
 	}
 	return items
-}
\ No newline at end of file
+}
+
+// Query 通过二级索引取得候选物品再筛选，没有可索引的条件时遍历全部物品
+func (r *InMemoryItemRepository) Query(q ItemQuery) (*ItemPage, error) {
+	now := r.clock.Now()
+	r.mutex.RLock()
+	var candidates []*Item
+	if codes, ok := r.search.candidates(&q, searchTerms(q.Text)); ok {
+		candidates = make([]*Item, 0, len(codes))
+		for code := range codes {
+			candidates = append(candidates, r.items[code])
+		}
+	} else {
+		candidates = make([]*Item, 0, len(r.items))
+		for _, item := range r.items {
+			candidates = append(candidates, item)
+		}
+	}
+	r.mutex.RUnlock()
+	return paginate(candidates, q, now)
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Listing 市场中公开展示的物品，不包含取件码和领取信息，领取者凭挂牌ID领取
+type Listing struct {
+	ListingID   string    `json:"listing_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TypeID      int       `json:"type_id"`
+	Num         int       `json:"num"`
+	Durability  float64   `json:"durability"`
+	SharerID    string    `json:"sharer_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// NewListing 返回物品在市场中展示的内容
+func NewListing(item *Item) *Listing {
+	return &Listing{
+		ListingID:   item.ListingID,
+		Name:        item.Name,
+		Description: item.Description,
+		TypeID:      item.TypeID,
+		Num:         item.Num,
+		Durability:  item.Durability,
+		SharerID:    item.SharerID,
+		CreatedAt:   item.CreatedAt,
+		ExpiresAt:   item.ExpiresAt,
+	}
+}
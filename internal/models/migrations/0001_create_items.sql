@@ -0,0 +1,25 @@
+-- 物品表，时间字段以Unix纳秒存储
+CREATE TABLE items (
+    row_id      INTEGER PRIMARY KEY AUTOINCREMENT,
+    id          TEXT    NOT NULL,
+    name        TEXT    NOT NULL DEFAULT '',
+    description TEXT    NOT NULL DEFAULT '',
+    type_id     INTEGER NOT NULL DEFAULT 0,
+    num         INTEGER NOT NULL DEFAULT 0,
+    durability  REAL    NOT NULL DEFAULT 0,
+    sharer_id   TEXT    NOT NULL DEFAULT '',
+    pickup_code TEXT    NOT NULL,
+    created_at  INTEGER NOT NULL,
+    expires_at  INTEGER NOT NULL,
+    is_claimed  INTEGER NOT NULL DEFAULT 0,
+    claimer_id  TEXT    NOT NULL DEFAULT ''
+);
+
+-- 未领取物品的取件码唯一
+CREATE UNIQUE INDEX idx_items_live_pickup_code ON items (pickup_code) WHERE is_claimed = 0;
+
+-- 按取件码查找已领取物品
+CREATE INDEX idx_items_pickup_code ON items (pickup_code);
+
+-- 按过期时间清理
+CREATE INDEX idx_items_expires_at ON items (expires_at);
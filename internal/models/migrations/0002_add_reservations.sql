@@ -0,0 +1,6 @@
+-- 两阶段领取的预留：预留期间 is_claimed 为1，reserved_until 为预留过期时间（Unix纳秒）
+ALTER TABLE items ADD COLUMN reservation_token TEXT NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN reserved_until INTEGER NOT NULL DEFAULT 0;
+
+-- 按预留过期时间恢复未确认的预留
+CREATE INDEX idx_items_reserved_until ON items (reserved_until) WHERE reservation_token != '';
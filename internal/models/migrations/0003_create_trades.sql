@@ -0,0 +1,23 @@
+-- 交易表，data 为完整交易的JSON，其余列用于查询和状态转换；时间字段以Unix纳秒存储，closed_at 为0表示未结束
+CREATE TABLE trades (
+    id              TEXT    PRIMARY KEY,
+    initiator_id    TEXT    NOT NULL,
+    counterparty_id TEXT    NOT NULL DEFAULT '',
+    acceptor_id     TEXT    NOT NULL DEFAULT '',
+    status          TEXT    NOT NULL,
+    data            TEXT    NOT NULL,
+    created_at      INTEGER NOT NULL,
+    expires_at      INTEGER NOT NULL,
+    closed_at       INTEGER NOT NULL DEFAULT 0
+);
+
+-- 按参与方查询
+CREATE INDEX idx_trades_initiator_id ON trades (initiator_id);
+CREATE INDEX idx_trades_counterparty_id ON trades (counterparty_id) WHERE counterparty_id != '';
+CREATE INDEX idx_trades_acceptor_id ON trades (acceptor_id) WHERE acceptor_id != '';
+
+-- 按超时时间处理未被接受的交易
+CREATE INDEX idx_trades_open_expires_at ON trades (expires_at) WHERE status = 'open';
+
+-- 按结束时间清理
+CREATE INDEX idx_trades_closed_at ON trades (closed_at) WHERE closed_at != 0;
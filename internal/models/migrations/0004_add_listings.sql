@@ -0,0 +1,4 @@
+-- 公开上架的物品：listing_id 为市场中的挂牌ID，未上架时为空
+ALTER TABLE items ADD COLUMN listing_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_items_listing_id ON items (listing_id) WHERE listing_id != '';
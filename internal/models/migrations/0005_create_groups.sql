@@ -0,0 +1,24 @@
+-- 玩家群组，data 为完整群组的JSON；version 每次修改加一，用于检测并发修改
+CREATE TABLE player_groups (
+    id         TEXT    PRIMARY KEY,
+    owner_id   TEXT    NOT NULL,
+    data       TEXT    NOT NULL,
+    created_at INTEGER NOT NULL,
+    version    INTEGER NOT NULL DEFAULT 0
+);
+
+-- 群组成员和被邀请的玩家，status 为 member 或 invited，与 data 在同一事务中维护
+CREATE TABLE group_players (
+    group_id  TEXT NOT NULL,
+    player_id TEXT NOT NULL,
+    status    TEXT NOT NULL,
+    PRIMARY KEY (group_id, player_id)
+);
+
+-- 按玩家查询所在的群组
+CREATE INDEX idx_group_players_player_id ON group_players (player_id);
+
+-- 只有群组成员可以领取的物品：group_id 为目标群组，为空时任何人都可以领取
+ALTER TABLE items ADD COLUMN group_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX idx_items_group_id ON items (group_id) WHERE group_id != '';
@@ -0,0 +1,304 @@
+package models
+
+import (
+	"container/heap"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidQuery 查询条件不合法（排序字段未知、游标无法解析或与排序不匹配）
+var ErrInvalidQuery = errors.New("invalid item query")
+
+// ItemSort 查询结果的排序字段
+type ItemSort string
+
+const (
+	// SortByCreatedAt 按分享时间排序（默认）
+	SortByCreatedAt ItemSort = "created_at"
+	// SortByExpiresAt 按过期时间排序
+	SortByExpiresAt ItemSort = "expires_at"
+	// SortByDurability 按耐久度排序
+	SortByDurability ItemSort = "durability"
+)
+
+// DefaultQueryLimit Limit 不大于0时每页返回的数量
+const DefaultQueryLimit = 50
+
+// ItemQuery 物品查询条件，零值字段不参与过滤；默认只返回未过期且可以领取的物品
+type ItemQuery struct {
+	// Text 全文检索：名称和描述中需包含全部检索词，不区分大小写，汉字逐字匹配
+	Text string
+	// Name 名称中包含的子串，不区分大小写
+	Name      string
+	TypeID    int
+	SharerID  string
+	ListingID string
+	GroupID   string
+	// ListedOnly 只返回公开上架的物品
+	ListedOnly bool
+	// IncludeReserved 同时返回已被预留、尚未确认的物品
+	IncludeReserved bool
+	// 时间范围均为闭区间
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	MinDurability *float64
+	MaxDurability *float64
+	// SortBy 为空时按分享时间排序，相同时按取件码排序
+	SortBy     ItemSort
+	Descending bool
+	// Limit 每页数量，不大于0时使用 DefaultQueryLimit
+	Limit int
+	// Cursor 上一页返回的 NextCursor，为空时从第一页开始
+	Cursor string
+}
+
+// ItemPage 一页查询结果，NextCursor 为空表示没有更多结果
+type ItemPage struct {
+	Items      []*Item
+	NextCursor string
+}
+
+// 分页游标，记录上一页最后一个物品的排序键
+type queryCursor struct {
+	SortBy     ItemSort `json:"s"`
+	Descending bool     `json:"d"`
+	Time       int64    `json:"t,omitempty"`
+	Durability float64  `json:"v,omitempty"`
+	PickupCode string   `json:"c"`
+}
+
+// 校验查询条件并补全默认值，返回解析后的游标（没有游标时为 nil）
+func (q *ItemQuery) normalize() (*queryCursor, error) {
+	switch q.SortBy {
+	case "":
+		q.SortBy = SortByCreatedAt
+	case SortByCreatedAt, SortByExpiresAt, SortByDurability:
+	default:
+		return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, q.SortBy)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultQueryLimit
+	}
+	if q.Cursor == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	var cursor queryCursor
+	if err == nil {
+		err = json.Unmarshal(data, &cursor)
+	}
+	if err != nil || cursor.PickupCode == "" {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if cursor.SortBy != q.SortBy || cursor.Descending != q.Descending {
+		return nil, fmt.Errorf("%w: cursor does not match the sort order", ErrInvalidQuery)
+	}
+	return &cursor, nil
+}
+
+// 物品在排序中的位置
+func (q *ItemQuery) cursorOf(item *Item) queryCursor {
+	cursor := queryCursor{SortBy: q.SortBy, Descending: q.Descending, PickupCode: item.PickupCode}
+	switch q.SortBy {
+	case SortByExpiresAt:
+		cursor.Time = item.ExpiresAt.UnixNano()
+	case SortByDurability:
+		cursor.Durability = item.Durability
+	default:
+		cursor.Time = item.CreatedAt.UnixNano()
+	}
+	return cursor
+}
+
+func (c queryCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// 比较两个排序位置，按排序键、再按取件码升序
+func (c queryCursor) compare(other queryCursor) int {
+	switch {
+	case c.Time < other.Time, c.Durability < other.Durability:
+		return -1
+	case c.Time > other.Time, c.Durability > other.Durability:
+		return 1
+	}
+	return strings.Compare(c.PickupCode, other.PickupCode)
+}
+
+// 排序位置 a 在 q 的顺序中是否排在 b 之前
+func (q *ItemQuery) before(a, b queryCursor) bool {
+	cmp := a.compare(b)
+	if q.Descending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// 物品 a 在 q 的顺序中是否排在 b 之前
+func (q *ItemQuery) less(a, b *Item) bool {
+	return q.before(q.cursorOf(a), q.cursorOf(b))
+}
+
+// 物品是否排在游标之后
+func (q *ItemQuery) after(item *Item, cursor *queryCursor) bool {
+	return q.before(*cursor, q.cursorOf(item))
+}
+
+// matches 物品在 now 时是否满足查询条件，terms 为 Text 的检索词
+func (q *ItemQuery) matches(item *Item, terms []string, now time.Time) bool {
+	switch {
+	case now.After(item.ExpiresAt):
+		return false
+	case !q.IncludeReserved && !item.Pending(now):
+		return false
+	case q.TypeID != 0 && item.TypeID != q.TypeID:
+		return false
+	case q.SharerID != "" && item.SharerID != q.SharerID:
+		return false
+	case q.ListingID != "" && item.ListingID != q.ListingID:
+		return false
+	case q.ListedOnly && item.ListingID == "":
+		return false
+	case q.GroupID != "" && item.GroupID != q.GroupID:
+		return false
+	case !q.CreatedAfter.IsZero() && item.CreatedAt.Before(q.CreatedAfter):
+		return false
+	case !q.CreatedBefore.IsZero() && item.CreatedAt.After(q.CreatedBefore):
+		return false
+	case !q.ExpiresAfter.IsZero() && item.ExpiresAt.Before(q.ExpiresAfter):
+		return false
+	case !q.ExpiresBefore.IsZero() && item.ExpiresAt.After(q.ExpiresBefore):
+		return false
+	case q.MinDurability != nil && item.Durability < *q.MinDurability:
+		return false
+	case q.MaxDurability != nil && item.Durability > *q.MaxDurability:
+		return false
+	case q.Name != "" && !strings.Contains(strings.ToLower(item.Name), strings.ToLower(q.Name)):
+		return false
+	}
+	if len(terms) == 0 {
+		return true
+	}
+	itemTerms := make(map[string]bool)
+	for _, term := range itemSearchTerms(item) {
+		itemTerms[term] = true
+	}
+	for _, term := range terms {
+		if !itemTerms[term] {
+			return false
+		}
+	}
+	return true
+}
+
+// 从候选物品中筛选、排序并截取一页，供没有专门查询实现的仓库使用
+// 只保留最靠前的 Limit+1 个，候选再多也不会分配与候选数量成正比的内存
+func paginate(candidates []*Item, q ItemQuery, now time.Time) (*ItemPage, error) {
+	cursor, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+	terms := searchTerms(q.Text)
+	top := &topItems{q: &q, size: q.Limit + 1}
+	for _, item := range candidates {
+		if q.matches(item, terms, now) && (cursor == nil || q.after(item, cursor)) {
+			top.offer(item.visibleAt(now))
+		}
+	}
+	return q.page(top.sorted()), nil
+}
+
+// 排序键已计算好的物品
+type rankedItem struct {
+	item *Item
+	key  queryCursor
+}
+
+// topItems 按查询顺序保留最靠前的 size 个物品，堆顶是其中最靠后的一个
+type topItems struct {
+	q     *ItemQuery
+	size  int
+	items []rankedItem
+}
+
+func (h *topItems) Len() int           { return len(h.items) }
+func (h *topItems) Less(i, j int) bool { return h.q.before(h.items[j].key, h.items[i].key) }
+func (h *topItems) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *topItems) Push(v interface{}) { h.items = append(h.items, v.(rankedItem)) }
+func (h *topItems) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// 加入物品，已满时替换掉排在它之后的堆顶
+func (h *topItems) offer(item *Item) {
+	ranked := rankedItem{item: item, key: h.q.cursorOf(item)}
+	if len(h.items) < h.size {
+		heap.Push(h, ranked)
+		return
+	}
+	if h.q.before(ranked.key, h.items[0].key) {
+		h.items[0] = ranked
+		heap.Fix(h, 0)
+	}
+}
+
+// 按查询顺序返回保留的物品
+func (h *topItems) sorted() []*Item {
+	sort.Slice(h.items, func(i, j int) bool { return h.q.before(h.items[i].key, h.items[j].key) })
+	items := make([]*Item, 0, len(h.items))
+	for _, ranked := range h.items {
+		items = append(items, ranked.item)
+	}
+	return items
+}
+
+// 将已按顺序排列的结果截取为一页，结果多于 Limit 时生成下一页游标
+func (q *ItemQuery) page(sorted []*Item) *ItemPage {
+	page := &ItemPage{Items: sorted}
+	if len(sorted) > q.Limit {
+		page.Items = sorted[:q.Limit]
+		page.NextCursor = q.cursorOf(page.Items[q.Limit-1]).encode()
+	}
+	return page
+}
+
+// 将文本切分为检索词：字母和数字组成的词转为小写，汉字等不以空格分词的文字逐字切分
+func searchTerms(text string) []string {
+	var terms []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			terms = append(terms, word.String())
+			word.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul):
+			flush()
+			terms = append(terms, string(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// 物品的检索词，来自名称和描述
+func itemSearchTerms(item *Item) []string {
+	return searchTerms(item.Name + " " + item.Description)
+}
@@ -0,0 +1,206 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisGroupRepository 基于 Redis 的群组仓库，与 RedisItemRepository 共用同一个 Redis
+// 群组以JSON字符串存储在 group_info:<id> 中，player_groups:<id> 集合记录玩家所在或被邀请加入的群组。
+// 成员变更通过 WATCH/MULTI 乐观锁执行，群组在读取后被并发修改时返回 ErrGroupConflict
+type RedisGroupRepository struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisGroupRepository 创建新的 Redis 群组仓库，prefix 为所有键的前缀
+func NewRedisGroupRepository(client *redis.Client, prefix string) *RedisGroupRepository {
+	return &RedisGroupRepository{client: client, prefix: prefix}
+}
+
+// 物品的群组索引使用 group:<id>，群组本身使用不同的键名
+func (r *RedisGroupRepository) groupKey(id string) string { return r.prefix + "group_info:" + id }
+func (r *RedisGroupRepository) playerKey(playerID string) string {
+	return r.prefix + "player_groups:" + playerID
+}
+
+// 群组的成员和被邀请的玩家，用于维护玩家索引
+func groupPlayers(g *Group) []string {
+	players := make([]string, 0, len(g.Members)+len(g.Invites))
+	for _, member := range g.Members {
+		players = append(players, member.PlayerID)
+	}
+	for _, invite := range g.Invites {
+		players = append(players, invite.PlayerID)
+	}
+	return players
+}
+
+// 读取群组，不存在时返回 (nil, nil)
+func (r *RedisGroupRepository) load(ctx context.Context, c redis.Cmdable, id string) (*Group, error) {
+	data, err := c.Get(ctx, r.groupKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var group Group
+	if err := json.Unmarshal(data, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// 写入群组并更新玩家索引，previous 为修改前的群组，新建时为 nil
+func (r *RedisGroupRepository) save(ctx context.Context, pipe redis.Pipeliner, group, previous *Group) error {
+	data, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	pipe.Set(ctx, r.groupKey(group.ID), data, 0)
+	if previous != nil {
+		for _, playerID := range groupPlayers(previous) {
+			if group.Member(playerID) == nil && !group.Invited(playerID) {
+				pipe.SRem(ctx, r.playerKey(playerID), group.ID)
+			}
+		}
+	}
+	for _, playerID := range groupPlayers(group) {
+		pipe.SAdd(ctx, r.playerKey(playerID), group.ID)
+	}
+	return nil
+}
+
+// Create 保存新群组
+func (r *RedisGroupRepository) Create(group *Group) error {
+	ctx := context.Background()
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, r.groupKey(group.ID)).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrDuplicateGroupID
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return r.save(ctx, pipe, group, nil)
+		})
+		return err
+	}, r.groupKey(group.ID))
+	if errors.Is(err, redis.TxFailedErr) {
+		// 同一ID被并发创建
+		return ErrDuplicateGroupID
+	}
+	return err
+}
+
+// Get 按ID获取群组
+func (r *RedisGroupRepository) Get(id string) (*Group, error) {
+	return r.load(context.Background(), r.client, id)
+}
+
+// ListForPlayer 返回玩家所在或被邀请加入的群组
+func (r *RedisGroupRepository) ListForPlayer(playerID string) ([]*Group, error) {
+	ctx := context.Background()
+	ids, err := r.client.SMembers(ctx, r.playerKey(playerID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	groups := make([]*Group, 0, len(ids))
+	if len(ids) == 0 {
+		return groups, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.groupKey(id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var group Group
+		if err := json.Unmarshal([]byte(data), &group); err != nil {
+			return nil, err
+		}
+		if group.Member(playerID) != nil || group.Invited(playerID) {
+			groups = append(groups, &group)
+		}
+	}
+	sortGroups(groups)
+	return groups, nil
+}
+
+// IsMember 玩家是否是群组成员
+func (r *RedisGroupRepository) IsMember(id, playerID string) (bool, error) {
+	group, err := r.Get(id)
+	if err != nil || group == nil {
+		return false, err
+	}
+	return group.Member(playerID) != nil, nil
+}
+
+// 在 WATCH 事务中读取群组并执行状态转换
+func (r *RedisGroupRepository) transition(id string, update func(*Group) error) (*Group, error) {
+	ctx := context.Background()
+	var updated *Group
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		group, err := r.load(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			return ErrGroupNotFound
+		}
+		previous := group.clone()
+		if err := update(group); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return r.save(ctx, pipe, group, previous)
+		})
+		updated = group
+		return err
+	}, r.groupKey(id))
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil, ErrGroupConflict
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Invite 邀请玩家
+func (r *RedisGroupRepository) Invite(id, inviterID, inviteeID string, now time.Time) (*Group, error) {
+	return r.transition(id, inviteToGroup(inviterID, inviteeID, now))
+}
+
+// Join 加入群组
+func (r *RedisGroupRepository) Join(id, playerID string, now time.Time) (*Group, error) {
+	return r.transition(id, joinGroup(playerID, now))
+}
+
+// Leave 退出群组
+func (r *RedisGroupRepository) Leave(id, playerID string) (*Group, error) {
+	return r.transition(id, leaveGroup(playerID))
+}
+
+// SetRole 调整成员的角色
+func (r *RedisGroupRepository) SetRole(id, actorID, targetID string, role GroupRole) (*Group, error) {
+	return r.transition(id, setGroupRole(actorID, targetID, role))
+}
+
+// Remove 移除成员或撤回邀请
+func (r *RedisGroupRepository) Remove(id, actorID, targetID string) (*Group, error) {
+	return r.transition(id, removeFromGroup(actorID, targetID))
+}
@@ -0,0 +1,664 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"duckex-server/internal/clock"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 过期物品在 Redis 中的额外保留时间：到期后由 DeleteExpired 移交给过期回调，
+// 若长时间没有实例执行清理，键的 TTL 作为兜底自动删除
+const redisExpiryGrace = 24 * time.Hour
+
+// 维护查询索引的 Lua 函数，拼接在需要写入或删除物品的脚本之前
+// delete_item 的 keys 为 {物品键, 过期有序集合, 预留有序集合}，data 为 false 时只清理有序集合
+const redisIndexFunctions = `
+local function index_item(prefix, code, data)
+	local item = cjson.decode(data)
+	if item.sharer_id and item.sharer_id ~= '' then
+		redis.call('SADD', prefix .. 'sharer:' .. item.sharer_id, code)
+	end
+	if item.group_id and item.group_id ~= '' then
+		redis.call('SADD', prefix .. 'group:' .. item.group_id, code)
+	end
+	if item.listing_id and item.listing_id ~= '' then
+		redis.call('HSET', prefix .. 'listings', item.listing_id, code)
+	end
+end
+local function unindex_item(prefix, code, data)
+	local item = cjson.decode(data)
+	if item.sharer_id and item.sharer_id ~= '' then
+		redis.call('SREM', prefix .. 'sharer:' .. item.sharer_id, code)
+	end
+	if item.group_id and item.group_id ~= '' then
+		redis.call('SREM', prefix .. 'group:' .. item.group_id, code)
+	end
+	if item.listing_id and item.listing_id ~= '' and redis.call('HGET', prefix .. 'listings', item.listing_id) == code then
+		redis.call('HDEL', prefix .. 'listings', item.listing_id)
+	end
+end
+local function delete_item(keys, prefix, code, data)
+	if data then
+		unindex_item(prefix, code, data)
+	end
+	redis.call('DEL', keys[1])
+	redis.call('ZREM', keys[2], code)
+	redis.call('ZREM', keys[3], code)
+end
+`
+
+// 物品以哈希存储：data 为物品JSON，expires_at 为过期时间（Unix毫秒），claimed 为是否已领取；
+// 预留期间 claimed 为1，预留记录在 claimer_id、token 和 reserved_until（Unix毫秒）中，data 保持未领取时的内容，
+// 预留过期时间同时记录在预留有序集合中。
+// 查询索引：sharer:<id> 和 group:<id> 集合记录分享者和群组的取件码，listings 哈希记录挂牌ID到取件码的映射；
+// 写入和删除物品的脚本同时维护索引，脚本参数的最后一个为键前缀
+var (
+	// 创建物品，取件码被未过期物品占用时返回 {0}，否则返回 {1[, 被替换的过期物品]}
+	redisCreateScript = redis.NewScript(redisIndexFunctions + `
+local exp = redis.call('HGET', KEYS[1], 'expires_at')
+if exp and tonumber(exp) >= tonumber(ARGV[3]) then
+	return {0}
+end
+local old = false
+if exp then
+	old = redis.call('HGET', KEYS[1], 'data')
+	if old then
+		unindex_item(ARGV[7], ARGV[5], old)
+	end
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], 'data', ARGV[1], 'expires_at', ARGV[2], 'claimed', ARGV[6])
+redis.call('PEXPIREAT', KEYS[1], ARGV[4])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[5])
+redis.call('ZREM', KEYS[3], ARGV[5])
+index_item(ARGV[7], ARGV[5], ARGV[1])
+if old then
+	return {1, old}
+end
+return {1}
+`)
+
+	// 领取物品：{0} 不存在，{1, data} 领取成功，{2, data} 已过期，{3} 已被领取或预留
+	// 预留已过期的物品视为未领取
+	redisClaimScript = redis.NewScript(redisIndexFunctions + `
+local v = redis.call('HMGET', KEYS[1], 'data', 'expires_at', 'claimed', 'reserved_until')
+if not v[1] then
+	return {0}
+end
+local now = tonumber(ARGV[1])
+if tonumber(v[2]) < now then
+	delete_item(KEYS, ARGV[3], ARGV[2], v[1])
+	return {2, v[1]}
+end
+if v[3] == '1' and not (v[4] and tonumber(v[4]) < now) then
+	return {3}
+end
+delete_item(KEYS, ARGV[3], ARGV[2], v[1])
+return {1, v[1]}
+`)
+
+	// 预留物品，返回值与领取相同，预留成功时为 {1, data, 预留过期时间}
+	// ARGV: 当前时间, 取件码, 领取者, 令牌, 预留截止时间, 键前缀
+	redisReserveScript = redis.NewScript(redisIndexFunctions + `
+local v = redis.call('HMGET', KEYS[1], 'data', 'expires_at', 'claimed', 'reserved_until')
+if not v[1] then
+	return {0}
+end
+local now = tonumber(ARGV[1])
+local exp = tonumber(v[2])
+if exp < now then
+	delete_item(KEYS, ARGV[6], ARGV[2], v[1])
+	return {2, v[1]}
+end
+if v[3] == '1' and not (v[4] and tonumber(v[4]) < now) then
+	return {3}
+end
+local untilAt = math.min(tonumber(ARGV[5]), exp)
+redis.call('HSET', KEYS[1], 'claimed', '1', 'claimer_id', ARGV[3], 'token', ARGV[4], 'reserved_until', untilAt)
+redis.call('ZADD', KEYS[3], untilAt, ARGV[2])
+return {1, v[1], untilAt}
+`)
+
+	// 确认或取消预留：令牌不匹配或预留已过期时返回 {0}，否则返回 {1, data, claimer_id, reserved_until}
+	// ARGV[4] 为 "confirm" 时删除物品，否则恢复为未领取
+	redisSettleReservationScript = redis.NewScript(redisIndexFunctions + `
+local v = redis.call('HMGET', KEYS[1], 'data', 'token', 'reserved_until', 'claimer_id')
+if not v[1] or v[2] ~= ARGV[3] or tonumber(v[3]) < tonumber(ARGV[1]) then
+	return {0}
+end
+if ARGV[4] == 'confirm' then
+	delete_item(KEYS, ARGV[5], ARGV[2], v[1])
+else
+	redis.call('HSET', KEYS[1], 'claimed', '0')
+	redis.call('HDEL', KEYS[1], 'claimer_id', 'token', 'reserved_until')
+end
+redis.call('ZREM', KEYS[3], ARGV[2])
+return {1, v[1], v[4], v[3]}
+`)
+
+	// 按预留索引恢复预留已过期的物品，返回 {数量, {{data, claimer_id, token, reserved_until}, ...}}
+	redisReleaseExpiredScript = redis.NewScript(`
+local codes = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local released = {}
+for _, code in ipairs(codes) do
+	local key = ARGV[3] .. code
+	local v = redis.call('HMGET', key, 'data', 'claimer_id', 'token', 'reserved_until')
+	if v[1] and v[3] and tonumber(v[4]) < tonumber(ARGV[1]) then
+		redis.call('HSET', key, 'claimed', '0')
+		redis.call('HDEL', key, 'claimer_id', 'token', 'reserved_until')
+		table.insert(released, {v[1], v[2], v[3], v[4]})
+	end
+	redis.call('ZREM', KEYS[1], code)
+end
+return {#codes, released}
+`)
+
+	// 删除仍处于过期状态的物品，返回被删除的物品，已被其他实例处理时返回 false
+	redisExpireOneScript = redis.NewScript(redisIndexFunctions + `
+local exp = redis.call('HGET', KEYS[1], 'expires_at')
+if not exp or tonumber(exp) >= tonumber(ARGV[1]) then
+	return false
+end
+local data = redis.call('HGET', KEYS[1], 'data')
+delete_item(KEYS, ARGV[3], ARGV[2], data)
+return data
+`)
+
+	// 删除物品，不存在时什么也不做
+	redisDeleteScript = redis.NewScript(redisIndexFunctions + `
+local data = redis.call('HGET', KEYS[1], 'data')
+delete_item(KEYS, ARGV[2], ARGV[1], data)
+return 1
+`)
+
+	// 更新已存在的物品，不存在时返回 0；ARGV[7] 非空时写入预留（ARGV[6..8] 为领取者、令牌和预留截止时间）
+	redisUpdateScript = redis.NewScript(redisIndexFunctions + `
+local old = redis.call('HGET', KEYS[1], 'data')
+if not old then
+	return 0
+end
+unindex_item(ARGV[9], ARGV[4], old)
+index_item(ARGV[9], ARGV[4], ARGV[1])
+redis.call('HSET', KEYS[1], 'data', ARGV[1], 'expires_at', ARGV[2], 'claimed', ARGV[5])
+redis.call('PEXPIREAT', KEYS[1], ARGV[3])
+redis.call('ZADD', KEYS[2], ARGV[2], ARGV[4])
+if ARGV[7] ~= '' then
+	redis.call('HSET', KEYS[1], 'claimer_id', ARGV[6], 'token', ARGV[7], 'reserved_until', ARGV[8])
+	redis.call('ZADD', KEYS[3], ARGV[8], ARGV[4])
+else
+	redis.call('HDEL', KEYS[1], 'claimer_id', 'token', 'reserved_until')
+	redis.call('ZREM', KEYS[3], ARGV[4])
+end
+return 1
+`)
+
+	// 按过期索引弹出一批过期物品，ARGV[3] 为物品键前缀，ARGV[4] 为键前缀
+	redisDeleteExpiredScript = redis.NewScript(redisIndexFunctions + `
+local codes = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', '(' .. ARGV[1], 'LIMIT', 0, tonumber(ARGV[2]))
+local expired = {}
+for _, code in ipairs(codes) do
+	local key = ARGV[3] .. code
+	local data = redis.call('HGET', key, 'data')
+	if data then
+		unindex_item(ARGV[4], code, data)
+		table.insert(expired, data)
+	end
+	redis.call('DEL', key)
+	redis.call('ZREM', KEYS[1], code)
+	redis.call('ZREM', KEYS[2], code)
+end
+return {#codes, expired}
+`)
+)
+
+// RedisItemRepository 基于 Redis 的物品仓库，多个服务实例可共享同一份数据
+// 所有读-改-写操作都通过 Lua 脚本原子执行；过期时间同时记录在有序集合中，
+// 由 DeleteExpired 移交给过期回调，键的 TTL 作为兜底。
+// 脚本根据参数中的键前缀拼接索引键，这些键不经过 KEYS 传入，因此不支持 Redis Cluster，只接受单机客户端
+type RedisItemRepository struct {
+	client    *redis.Client
+	prefix    string
+	mutex     sync.RWMutex
+	onExpired ExpiredHandler
+	clock     clock.Clock
+}
+
+// NewRedisItemRepository 创建新的 Redis 仓库实例，prefix 为所有键的前缀
+// clk 为判断过期使用的时间源，nil 时使用系统时间
+func NewRedisItemRepository(client *redis.Client, prefix string, clk clock.Clock) *RedisItemRepository {
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &RedisItemRepository{
+		client: client,
+		prefix: prefix,
+		clock:  clk,
+	}
+}
+
+func (r *RedisItemRepository) itemKeyPrefix() string { return r.prefix + "item:" }
+func (r *RedisItemRepository) itemKey(code string) string {
+	return r.itemKeyPrefix() + code
+}
+func (r *RedisItemRepository) expiryKey() string  { return r.prefix + "expiry" }
+func (r *RedisItemRepository) leaseKey() string   { return r.prefix + "reservations" }
+func (r *RedisItemRepository) listingKey() string { return r.prefix + "listings" }
+func (r *RedisItemRepository) sharerKey(id string) string {
+	return r.prefix + "sharer:" + id
+}
+func (r *RedisItemRepository) groupKey(id string) string {
+	return r.prefix + "group:" + id
+}
+
+// 单个物品脚本使用的键
+func (r *RedisItemRepository) itemKeys(code string) []string {
+	return []string{r.itemKey(code), r.expiryKey(), r.leaseKey()}
+}
+
+// 物品在 Redis 中的存储字段，预留中的物品 data 为未领取时的内容
+func redisItemArgs(item *Item) (data string, expiresAt, ttlAt int64, claimed string, err error) {
+	stored := item
+	if item.Reservation != nil {
+		stored = item.released()
+	}
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return "", 0, 0, "", err
+	}
+	claimed = "0"
+	if item.IsClaimed {
+		claimed = "1"
+	}
+	expiresAt = item.ExpiresAt.UnixMilli()
+	ttlAt = item.ExpiresAt.Add(redisExpiryGrace).UnixMilli()
+	return string(encoded), expiresAt, ttlAt, claimed, nil
+}
+
+func decodeRedisItem(v interface{}) (*Item, error) {
+	data, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("unexpected redis value %T", v)
+	}
+	var item Item
+	if err := json.Unmarshal([]byte(data), &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// 解析物品和预留字段（HMGET data, claimer_id, token, reserved_until 的结果）
+func decodeRedisReservedItem(values []interface{}) (*Item, error) {
+	item, err := decodeRedisItem(values[0])
+	if err != nil {
+		return nil, err
+	}
+	token, _ := values[2].(string)
+	if token == "" {
+		return item, nil
+	}
+	var reservedUntil int64
+	switch v := values[3].(type) {
+	case string:
+		reservedUntil, err = strconv.ParseInt(v, 10, 64)
+	case int64:
+		reservedUntil = v
+	}
+	if err != nil {
+		return nil, err
+	}
+	claimerID, _ := values[1].(string)
+	return item.reserved(claimerID, token, time.UnixMilli(reservedUntil)), nil
+}
+
+// 在脚本执行后调用过期回调
+func (r *RedisItemRepository) notifyExpired(values ...interface{}) {
+	r.mutex.RLock()
+	handler := r.onExpired
+	r.mutex.RUnlock()
+	if handler == nil {
+		return
+	}
+	for _, v := range values {
+		item, err := decodeRedisItem(v)
+		if err != nil {
+			continue
+		}
+		handler(item)
+	}
+}
+
+// Create 创建新物品，取件码被未过期的物品占用时返回 ErrDuplicatePickupCode
+func (r *RedisItemRepository) Create(item *Item) error {
+	data, expiresAt, ttlAt, claimed, err := redisItemArgs(item)
+	if err != nil {
+		return err
+	}
+	result, err := redisCreateScript.Run(context.Background(), r.client, r.itemKeys(item.PickupCode),
+		data, expiresAt, r.clock.Now().UnixMilli(), ttlAt, item.PickupCode, claimed, r.prefix).Slice()
+	if err != nil {
+		return err
+	}
+	if result[0].(int64) == 0 {
+		return ErrDuplicatePickupCode
+	}
+	r.notifyExpired(result[1:]...)
+	if item.Reservation != nil {
+		// 新建的物品带有预留时（如从快照恢复）补写预留字段
+		return r.Update(item)
+	}
+	return nil
+}
+
+// GetByPickupCode 通过取件码获取物品，过期物品被删除并返回nil，预留已过期的物品按未领取返回
+func (r *RedisItemRepository) GetByPickupCode(pickupCode string) (*Item, error) {
+	ctx := context.Background()
+	values, err := r.client.HMGet(ctx, r.itemKey(pickupCode), "data", "claimer_id", "token", "reserved_until", "expires_at").Result()
+	if err != nil {
+		return nil, err
+	}
+	if values[0] == nil {
+		return nil, nil
+	}
+	expiresAt, err := strconv.ParseInt(values[4].(string), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	now := r.clock.Now()
+	if expiresAt >= now.UnixMilli() {
+		item, err := decodeRedisReservedItem(values)
+		if err != nil {
+			return nil, err
+		}
+		return item.visibleAt(now), nil
+	}
+
+	// 检查到过期，只有删除成功的一方调用过期回调
+	expired, err := redisExpireOneScript.Run(ctx, r.client, r.itemKeys(pickupCode), now.UnixMilli(), pickupCode, r.prefix).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, err
+	}
+	if err == nil {
+		r.notifyExpired(expired)
+	}
+	return nil, nil
+}
+
+// Claim 原子地领取物品，物品被领取后立即删除
+func (r *RedisItemRepository) Claim(pickupCode, claimerID string) (*Item, error) {
+	result, err := redisClaimScript.Run(context.Background(), r.client, r.itemKeys(pickupCode),
+		r.clock.Now().UnixMilli(), pickupCode, r.prefix).Slice()
+	if err != nil {
+		return nil, err
+	}
+	item, err := r.takenItem(result)
+	if err != nil {
+		return nil, err
+	}
+	item.IsClaimed = true
+	item.ClaimerID = claimerID
+	return item, nil
+}
+
+// 解析领取和预留脚本的结果
+func (r *RedisItemRepository) takenItem(result []interface{}) (*Item, error) {
+	switch result[0].(int64) {
+	case 1:
+		return decodeRedisItem(result[1])
+	case 2:
+		r.notifyExpired(result[1])
+		return nil, ErrItemNotFound
+	case 3:
+		return nil, ErrItemClaimed
+	default:
+		return nil, ErrItemNotFound
+	}
+}
+
+// Reserve 原子地预留物品
+func (r *RedisItemRepository) Reserve(pickupCode, claimerID, token string, until time.Time) (*Item, error) {
+	result, err := redisReserveScript.Run(context.Background(), r.client, r.itemKeys(pickupCode),
+		r.clock.Now().UnixMilli(), pickupCode, claimerID, token, until.UnixMilli(), r.prefix).Slice()
+	if err != nil {
+		return nil, err
+	}
+	item, err := r.takenItem(result)
+	if err != nil {
+		return nil, err
+	}
+	return item.reserved(claimerID, token, time.UnixMilli(result[2].(int64))), nil
+}
+
+// 确认或取消预留
+func (r *RedisItemRepository) settleReservation(pickupCode, token, action string) (*Item, error) {
+	result, err := redisSettleReservationScript.Run(context.Background(), r.client, r.itemKeys(pickupCode),
+		r.clock.Now().UnixMilli(), pickupCode, token, action, r.prefix).Slice()
+	if err != nil {
+		return nil, err
+	}
+	if result[0].(int64) == 0 {
+		return nil, ErrReservationNotFound
+	}
+	return decodeRedisReservedItem([]interface{}{result[1], result[2], token, result[3]})
+}
+
+// Confirm 确认预留，物品被删除
+func (r *RedisItemRepository) Confirm(pickupCode, token string) (*Item, error) {
+	item, err := r.settleReservation(pickupCode, token, "confirm")
+	if err != nil {
+		return nil, err
+	}
+	item.Reservation = nil
+	return item, nil
+}
+
+// Release 取消预留，物品恢复为未领取
+func (r *RedisItemRepository) Release(pickupCode, token string) (*Item, error) {
+	return r.settleReservation(pickupCode, token, "release")
+}
+
+// ReleaseExpiredReservations 按预留索引分批恢复预留已过期的物品
+func (r *RedisItemRepository) ReleaseExpiredReservations() ([]*Item, error) {
+	now := r.clock.Now().UnixMilli()
+	var released []*Item
+	for {
+		result, err := redisReleaseExpiredScript.Run(context.Background(), r.client,
+			[]string{r.leaseKey()}, now, expireBatchSize, r.itemKeyPrefix()).Slice()
+		if err != nil {
+			return released, err
+		}
+		batch, _ := result[1].([]interface{})
+		for _, v := range batch {
+			fields, _ := v.([]interface{})
+			if len(fields) != 4 {
+				continue
+			}
+			if item, err := decodeRedisReservedItem(fields); err == nil {
+				released = append(released, item)
+			}
+		}
+		if result[0].(int64) < expireBatchSize {
+			return released, nil
+		}
+	}
+}
+
+// Update 更新已存在的物品信息
+func (r *RedisItemRepository) Update(item *Item) error {
+	data, expiresAt, ttlAt, claimed, err := redisItemArgs(item)
+	if err != nil {
+		return err
+	}
+	var claimerID, token string
+	var reservedUntil int64
+	if item.Reservation != nil {
+		claimerID, token, reservedUntil = item.ClaimerID, item.Reservation.Token, item.Reservation.ExpiresAt.UnixMilli()
+	}
+	updated, err := redisUpdateScript.Run(context.Background(), r.client, r.itemKeys(item.PickupCode),
+		data, expiresAt, ttlAt, item.PickupCode, claimed, claimerID, token, reservedUntil, r.prefix).Int()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// Delete 删除物品
+func (r *RedisItemRepository) Delete(pickupCode string) error {
+	return redisDeleteScript.Run(context.Background(), r.client, r.itemKeys(pickupCode), pickupCode, r.prefix).Err()
+}
+
+// DeleteExpired 按过期索引分批删除过期物品，并交给过期回调
+// 多个实例同时清理时，每个物品只会被其中一个实例移交
+func (r *RedisItemRepository) DeleteExpired() error {
+	now := r.clock.Now().UnixMilli()
+	for {
+		result, err := redisDeleteExpiredScript.Run(context.Background(), r.client,
+			[]string{r.expiryKey(), r.leaseKey()}, now, expireBatchSize, r.itemKeyPrefix(), r.prefix).Slice()
+		if err != nil {
+			return err
+		}
+		expired, _ := result[1].([]interface{})
+		r.notifyExpired(expired...)
+		if result[0].(int64) < expireBatchSize {
+			return nil
+		}
+	}
+}
+
+// GetAll 获取所有未过期的物品，读取失败时返回空列表，需要感知错误时使用 Query
+func (r *RedisItemRepository) GetAll() []*Item {
+	codes, err := r.unexpiredCodes()
+	if err != nil {
+		return make([]*Item, 0)
+	}
+	items, err := r.loadItems(codes)
+	if err != nil {
+		return make([]*Item, 0)
+	}
+	return items
+}
+
+// 过期索引中所有未过期的取件码
+func (r *RedisItemRepository) unexpiredCodes() ([]string, error) {
+	return r.client.ZRangeByScore(context.Background(), r.expiryKey(), &redis.ZRangeBy{
+		Min: strconv.FormatInt(r.clock.Now().UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// 批量读取物品，已不存在的取件码被跳过
+func (r *RedisItemRepository) loadItems(codes []string) ([]*Item, error) {
+	items := make([]*Item, 0, len(codes))
+	if len(codes) == 0 {
+		return items, nil
+	}
+	ctx := context.Background()
+	cmds, err := r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, code := range codes {
+			pipe.HMGet(ctx, r.itemKey(code), "data", "claimer_id", "token", "reserved_until")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	now := r.clock.Now()
+	for _, cmd := range cmds {
+		values := cmd.(*redis.SliceCmd).Val()
+		if values[0] == nil {
+			continue
+		}
+		item, err := decodeRedisReservedItem(values)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item.visibleAt(now))
+	}
+	return items, nil
+}
+
+// Query 按索引取出候选物品后在内存中筛选和排序：
+// 指定挂牌ID、群组、分享者或只查上架物品时只读取索引中的物品，其他条件需要读取全部未过期物品
+func (r *RedisItemRepository) Query(q ItemQuery) (*ItemPage, error) {
+	if _, err := q.normalize(); err != nil {
+		return nil, err
+	}
+	codes, err := r.candidateCodes(q)
+	if err != nil {
+		return nil, err
+	}
+	items, err := r.loadItems(codes)
+	if err != nil {
+		return nil, err
+	}
+	return paginate(items, q, r.clock.Now())
+}
+
+// 按查询条件选择最小的索引，索引中的物品可能已不满足条件，由 paginate 再次筛选
+func (r *RedisItemRepository) candidateCodes(q ItemQuery) ([]string, error) {
+	ctx := context.Background()
+	switch {
+	case q.ListingID != "":
+		code, err := r.client.HGet(ctx, r.listingKey(), q.ListingID).Result()
+		if errors.Is(err, redis.Nil) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []string{code}, nil
+	case q.GroupID != "":
+		return r.client.SMembers(ctx, r.groupKey(q.GroupID)).Result()
+	case q.SharerID != "":
+		return r.client.SMembers(ctx, r.sharerKey(q.SharerID)).Result()
+	case q.ListedOnly:
+		return r.client.HVals(ctx, r.listingKey()).Result()
+	}
+	return r.unexpiredCodes()
+}
+
+// RebuildIndexes 为所有未过期物品补写查询索引，用于升级前写入、没有索引的物品
+// 可以与其他实例和正常读写并发执行：期间被删除的物品可能留下多余的索引项，查询时会被跳过
+func (r *RedisItemRepository) RebuildIndexes() error {
+	codes, err := r.unexpiredCodes()
+	if err != nil {
+		return err
+	}
+	items, err := r.loadItems(codes)
+	if err != nil || len(items) == 0 {
+		return err
+	}
+	ctx := context.Background()
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, item := range items {
+			if item.SharerID != "" {
+				pipe.SAdd(ctx, r.sharerKey(item.SharerID), item.PickupCode)
+			}
+			if item.GroupID != "" {
+				pipe.SAdd(ctx, r.groupKey(item.GroupID), item.PickupCode)
+			}
+			if item.ListingID != "" {
+				pipe.HSet(ctx, r.listingKey(), item.ListingID, item.PickupCode)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// SetExpiredHandler 设置物品过期时的回调
+func (r *RedisItemRepository) SetExpiredHandler(handler ExpiredHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onExpired = handler
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"duckex-server/internal/clock"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisReturnBox 基于 Redis 的退回箱，与 RedisItemRepository 共用同一个 Redis
+// 每个分享者的退回物品以JSON列表存储在 returns:<分享者ID> 中，键在最后一个物品的保留期结束时过期；
+// return_boxes 集合记录非空的退回箱，供 DeleteExpired 遍历
+type RedisReturnBox struct {
+	client    *redis.Client
+	prefix    string
+	retention time.Duration
+	clock     clock.Clock
+}
+
+// NewRedisReturnBox 创建新的 Redis 退回箱，prefix 为所有键的前缀，retention 为退回物品的保留时间，
+// clk 为时间源，nil 时使用系统时间
+func NewRedisReturnBox(client *redis.Client, prefix string, retention time.Duration, clk clock.Clock) *RedisReturnBox {
+	if retention <= 0 {
+		retention = DefaultReturnRetention
+	}
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &RedisReturnBox{
+		client:    client,
+		prefix:    prefix,
+		retention: retention,
+		clock:     clk,
+	}
+}
+
+func (b *RedisReturnBox) boxKey(sharerID string) string { return b.prefix + "returns:" + sharerID }
+func (b *RedisReturnBox) indexKey() string              { return b.prefix + "return_boxes" }
+
+// 解码退回箱列表，只保留仍在保留期内的物品
+func (b *RedisReturnBox) decode(values []string) ([]*ReturnedItem, error) {
+	now := b.clock.Now()
+	returned := make([]*ReturnedItem, 0, len(values))
+	for _, v := range values {
+		var r ReturnedItem
+		if err := json.Unmarshal([]byte(v), &r); err != nil {
+			return nil, err
+		}
+		if !now.After(r.ExpiresAt) {
+			returned = append(returned, &r)
+		}
+	}
+	return returned, nil
+}
+
+// Add 将过期物品放入分享者的退回箱
+func (b *RedisReturnBox) Add(item *Item) error {
+	now := b.clock.Now()
+	returned := &ReturnedItem{
+		Item:       item,
+		ReturnedAt: now,
+		ExpiresAt:  now.Add(b.retention),
+	}
+	data, err := json.Marshal(returned)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	key := b.boxKey(item.SharerID)
+	_, err = b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.RPush(ctx, key, data)
+		// 新放入的物品最晚过期，键的过期时间随之顺延
+		pipe.PExpireAt(ctx, key, returned.ExpiresAt)
+		pipe.SAdd(ctx, b.indexKey(), item.SharerID)
+		return nil
+	})
+	return err
+}
+
+// List 查看分享者退回箱中仍在保留期内的物品
+func (b *RedisReturnBox) List(sharerID string) ([]*ReturnedItem, error) {
+	values, err := b.client.LRange(context.Background(), b.boxKey(sharerID), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	return b.decode(values)
+}
+
+// Collect 领回分享者退回箱中的全部物品，读取和清空在同一事务中执行
+func (b *RedisReturnBox) Collect(sharerID string) ([]*ReturnedItem, error) {
+	ctx := context.Background()
+	key := b.boxKey(sharerID)
+	var values *redis.StringSliceCmd
+	_, err := b.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		values = pipe.LRange(ctx, key, 0, -1)
+		pipe.Del(ctx, key)
+		pipe.SRem(ctx, b.indexKey(), sharerID)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return b.decode(values.Val())
+}
+
+// DeleteExpired 删除超过保留期的退回物品，退回箱在检查后被修改时留到下次清理
+func (b *RedisReturnBox) DeleteExpired() error {
+	ctx := context.Background()
+	sharerIDs, err := b.client.SMembers(ctx, b.indexKey()).Result()
+	if err != nil {
+		return err
+	}
+	for _, sharerID := range sharerIDs {
+		key := b.boxKey(sharerID)
+		err := b.client.Watch(ctx, func(tx *redis.Tx) error {
+			values, err := tx.LRange(ctx, key, 0, -1).Result()
+			if err != nil {
+				return err
+			}
+			kept, err := b.decode(values)
+			if err != nil {
+				return err
+			}
+			if len(kept) == len(values) && len(kept) > 0 {
+				return nil
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Del(ctx, key)
+				if len(kept) == 0 {
+					pipe.SRem(ctx, b.indexKey(), sharerID)
+					return nil
+				}
+				for _, r := range kept {
+					data, err := json.Marshal(r)
+					if err != nil {
+						return err
+					}
+					pipe.RPush(ctx, key, data)
+				}
+				pipe.PExpireAt(ctx, key, kept[len(kept)-1].ExpiresAt)
+				return nil
+			})
+			return err
+		}, key)
+		if err != nil && !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return nil
+}
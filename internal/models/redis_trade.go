@@ -0,0 +1,285 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisTradeRepository 基于 Redis 的交易仓库，与 RedisItemRepository 共用同一个 Redis
+// 交易以JSON字符串存储在 trade:<id> 中；player_trades:<id> 集合记录玩家参与的交易，
+// trades:open 和 trades:closed 有序集合分别按超时时间和结束时间（Unix毫秒）记录交易ID。
+// 状态转换通过 WATCH/MULTI 乐观锁执行，交易在读取后被并发修改时返回 ErrTradeNotOpen
+type RedisTradeRepository struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisTradeRepository 创建新的 Redis 交易仓库，prefix 为所有键的前缀
+func NewRedisTradeRepository(client *redis.Client, prefix string) *RedisTradeRepository {
+	return &RedisTradeRepository{client: client, prefix: prefix}
+}
+
+func (r *RedisTradeRepository) tradeKey(id string) string { return r.prefix + "trade:" + id }
+func (r *RedisTradeRepository) playerKey(playerID string) string {
+	return r.prefix + "player_trades:" + playerID
+}
+func (r *RedisTradeRepository) openKey() string   { return r.prefix + "trades:open" }
+func (r *RedisTradeRepository) closedKey() string { return r.prefix + "trades:closed" }
+
+// 交易的参与方，用于维护玩家索引
+func tradePlayers(t *Trade) []string {
+	players := make([]string, 0, 3)
+	for _, id := range []string{t.InitiatorID, t.CounterpartyID, t.AcceptorID} {
+		if id != "" {
+			players = append(players, id)
+		}
+	}
+	return players
+}
+
+// 读取交易，不存在时返回 (nil, nil)
+func (r *RedisTradeRepository) load(ctx context.Context, c redis.Cmdable, id string) (*Trade, error) {
+	data, err := c.Get(ctx, r.tradeKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var trade Trade
+	if err := json.Unmarshal(data, &trade); err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// 写入交易并更新索引，previous 为修改前的交易，新建时为 nil
+func (r *RedisTradeRepository) save(ctx context.Context, pipe redis.Pipeliner, trade, previous *Trade) error {
+	data, err := json.Marshal(trade)
+	if err != nil {
+		return err
+	}
+	pipe.Set(ctx, r.tradeKey(trade.ID), data, 0)
+	if trade.Status == TradeOpen {
+		pipe.ZAdd(ctx, r.openKey(), redis.Z{Score: float64(trade.ExpiresAt.UnixMilli()), Member: trade.ID})
+	} else {
+		pipe.ZRem(ctx, r.openKey(), trade.ID)
+	}
+	if trade.ClosedAt != nil {
+		pipe.ZAdd(ctx, r.closedKey(), redis.Z{Score: float64(trade.ClosedAt.UnixMilli()), Member: trade.ID})
+	}
+	if previous != nil {
+		// 重新开放的交易不再属于原接受方
+		for _, playerID := range tradePlayers(previous) {
+			if !trade.Involves(playerID) {
+				pipe.SRem(ctx, r.playerKey(playerID), trade.ID)
+			}
+		}
+	}
+	for _, playerID := range tradePlayers(trade) {
+		pipe.SAdd(ctx, r.playerKey(playerID), trade.ID)
+	}
+	return nil
+}
+
+// Create 保存新交易
+func (r *RedisTradeRepository) Create(trade *Trade) error {
+	ctx := context.Background()
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, r.tradeKey(trade.ID)).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrDuplicateTradeID
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return r.save(ctx, pipe, trade, nil)
+		})
+		return err
+	}, r.tradeKey(trade.ID))
+	if errors.Is(err, redis.TxFailedErr) {
+		// 同一ID被并发创建
+		return ErrDuplicateTradeID
+	}
+	return err
+}
+
+// Get 按ID获取交易
+func (r *RedisTradeRepository) Get(id string) (*Trade, error) {
+	return r.load(context.Background(), r.client, id)
+}
+
+// List 返回玩家参与的全部交易
+func (r *RedisTradeRepository) List(playerID string) ([]*Trade, error) {
+	ctx := context.Background()
+	ids, err := r.client.SMembers(ctx, r.playerKey(playerID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	trades, err := r.loadAll(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	involved := trades[:0]
+	for _, trade := range trades {
+		if trade.Involves(playerID) {
+			involved = append(involved, trade)
+		}
+	}
+	sortTrades(involved)
+	return involved, nil
+}
+
+// 批量读取交易，跳过已删除的交易
+func (r *RedisTradeRepository) loadAll(ctx context.Context, ids []string) ([]*Trade, error) {
+	trades := make([]*Trade, 0, len(ids))
+	if len(ids) == 0 {
+		return trades, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = r.tradeKey(id)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var trade Trade
+		if err := json.Unmarshal([]byte(data), &trade); err != nil {
+			return nil, err
+		}
+		trades = append(trades, &trade)
+	}
+	return trades, nil
+}
+
+// 在 WATCH 事务中读取交易并执行状态转换
+func (r *RedisTradeRepository) transition(id string, update func(*Trade) error) (*Trade, error) {
+	ctx := context.Background()
+	var updated *Trade
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		trade, err := r.load(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if trade == nil {
+			return ErrTradeNotFound
+		}
+		previous := trade.clone()
+		if err := update(trade); err != nil {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			return r.save(ctx, pipe, trade, previous)
+		})
+		updated = trade
+		return err
+	}, r.tradeKey(id))
+	if errors.Is(err, redis.TxFailedErr) {
+		return nil, ErrTradeNotOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// Accept 接受交易
+func (r *RedisTradeRepository) Accept(id, acceptorID string, deposit []TradeItem, now time.Time) (*Trade, error) {
+	return r.transition(id, acceptTrade(acceptorID, deposit, now))
+}
+
+// Complete 完成交易
+func (r *RedisTradeRepository) Complete(id string, initiatorCodes, acceptorCodes []string, now time.Time) (*Trade, error) {
+	return r.transition(id, completeTrade(initiatorCodes, acceptorCodes, now))
+}
+
+// Reopen 恢复为 open
+func (r *RedisTradeRepository) Reopen(id string) (*Trade, error) {
+	return r.transition(id, reopenTrade)
+}
+
+// Cancel 取消交易
+func (r *RedisTradeRepository) Cancel(id, initiatorID string, now time.Time) (*Trade, error) {
+	return r.transition(id, cancelTrade(initiatorID, now))
+}
+
+// ExpireOpen 通过超时时间索引将超时的 open 交易转为 expired，
+// 检查后被其他实例接受或取消的交易保持原状
+func (r *RedisTradeRepository) ExpireOpen(now time.Time) ([]*Trade, error) {
+	ids, err := r.client.ZRangeByScore(context.Background(), r.openKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	var expired []*Trade
+	for _, id := range ids {
+		trade, err := r.transition(id, func(t *Trade) error {
+			if t.Status != TradeOpen || !now.After(t.ExpiresAt) {
+				return ErrTradeNotOpen
+			}
+			t.Status = TradeExpired
+			t.ClosedAt = &now
+			return nil
+		})
+		if errors.Is(err, ErrTradeNotOpen) || errors.Is(err, ErrTradeNotFound) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired = append(expired, trade)
+	}
+	sortTrades(expired)
+	return expired, nil
+}
+
+// DeleteClosed 通过结束时间索引删除已结束的旧交易
+func (r *RedisTradeRepository) DeleteClosed(before time.Time) (int, error) {
+	ctx := context.Background()
+	ids, err := r.client.ZRangeByScore(ctx, r.closedKey(), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(before.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return 0, err
+	}
+	trades, err := r.loadAll(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, trade := range trades {
+		// 索引按毫秒记录，按交易中的结束时间精确判断
+		if !trade.Status.Closed() || trade.ClosedAt == nil || !trade.ClosedAt.Before(before) {
+			continue
+		}
+		// 已结束的交易不会再被修改，无需 WATCH
+		_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, r.tradeKey(trade.ID))
+			pipe.ZRem(ctx, r.closedKey(), trade.ID)
+			for _, playerID := range tradePlayers(trade) {
+				pipe.SRem(ctx, r.playerKey(playerID), trade.ID)
+			}
+			return nil
+		})
+		if err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
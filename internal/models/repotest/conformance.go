@@ -0,0 +1,568 @@
+// Package repotest 提供 models.ItemRepository 的一致性测试套件，
+// 每个仓库实现都应在自己的测试中调用 RunConformance，以保证行为一致
+package repotest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Factory 为每个子测试创建一个新的空仓库
+type Factory func(t *testing.T) models.ItemRepository
+
+// 创建测试物品
+func newItem(code string, expiresIn time.Duration) *models.Item {
+	now := time.Now()
+	return &models.Item{
+		ID:          "item-" + code,
+		Name:        "Conformance Item " + code,
+		Description: "Used by the repository conformance suite",
+		TypeID:      1001,
+		Num:         3,
+		Durability:  87.5,
+		SharerID:    "sharer-" + code,
+		PickupCode:  code,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(expiresIn),
+	}
+}
+
+// 记录过期回调收到的物品
+type expiredRecorder struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (r *expiredRecorder) handle(item *models.Item) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ids = append(r.ids, item.ID)
+}
+
+func (r *expiredRecorder) IDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.ids...)
+}
+
+func newRecordingRepo(t *testing.T, factory Factory) (models.ItemRepository, *expiredRecorder) {
+	repo := factory(t)
+	recorder := &expiredRecorder{}
+	repo.SetExpiredHandler(recorder.handle)
+	return repo, recorder
+}
+
+// RunConformance 对仓库实现运行完整的一致性测试
+func RunConformance(t *testing.T, factory Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, factory) })
+	t.Run("GetMissing", func(t *testing.T) { testGetMissing(t, factory) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, factory) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory) })
+	t.Run("DuplicatePickupCode", func(t *testing.T) { testDuplicatePickupCode(t, factory) })
+	t.Run("ExpireOnRead", func(t *testing.T) { testExpireOnRead(t, factory) })
+	t.Run("DeleteExpired", func(t *testing.T) { testDeleteExpired(t, factory) })
+	t.Run("Claim", func(t *testing.T) { testClaim(t, factory) })
+	t.Run("ClaimExpired", func(t *testing.T) { testClaimExpired(t, factory) })
+	t.Run("ConcurrentCreate", func(t *testing.T) { testConcurrentCreate(t, factory) })
+	t.Run("ConcurrentClaim", func(t *testing.T) { testConcurrentClaim(t, factory) })
+	t.Run("Reserve", func(t *testing.T) { testReserve(t, factory) })
+	t.Run("ConfirmAndRelease", func(t *testing.T) { testConfirmAndRelease(t, factory) })
+	t.Run("ReservationLapse", func(t *testing.T) { testReservationLapse(t, factory) })
+	t.Run("ConcurrentReserve", func(t *testing.T) { testConcurrentReserve(t, factory) })
+	t.Run("ListingID", func(t *testing.T) { testListingID(t, factory) })
+	t.Run("GroupID", func(t *testing.T) { testGroupID(t, factory) })
+	t.Run("QueryFilters", func(t *testing.T) { testQueryFilters(t, factory) })
+	t.Run("QuerySortAndPaginate", func(t *testing.T) { testQuerySortAndPaginate(t, factory) })
+	t.Run("QueryReserved", func(t *testing.T) { testQueryReserved(t, factory) })
+	t.Run("QueryTracksChanges", func(t *testing.T) { testQueryTracksChanges(t, factory) })
+}
+
+func testCreateAndGet(t *testing.T, factory Factory) {
+	repo := factory(t)
+	item := newItem("100001", time.Hour)
+	require.NoError(t, repo.Create(item))
+
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, item.ID, got.ID)
+	assert.Equal(t, item.Name, got.Name)
+	assert.Equal(t, item.Description, got.Description)
+	assert.Equal(t, item.TypeID, got.TypeID)
+	assert.Equal(t, item.Num, got.Num)
+	assert.Equal(t, item.Durability, got.Durability)
+	assert.Equal(t, item.SharerID, got.SharerID)
+	assert.Equal(t, item.PickupCode, got.PickupCode)
+	assert.WithinDuration(t, item.CreatedAt, got.CreatedAt, time.Millisecond)
+	assert.WithinDuration(t, item.ExpiresAt, got.ExpiresAt, time.Millisecond)
+	assert.False(t, got.IsClaimed)
+
+	all := repo.GetAll()
+	require.Len(t, all, 1)
+	assert.Equal(t, item.ID, all[0].ID)
+}
+
+func testGetMissing(t *testing.T, factory Factory) {
+	repo := factory(t)
+	got, err := repo.GetByPickupCode("999999")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.NotNil(t, repo.GetAll())
+	assert.Empty(t, repo.GetAll())
+}
+
+func testUpdate(t *testing.T, factory Factory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+
+	updated := newItem("100001", 2*time.Hour)
+	updated.Name = "Renamed"
+	updated.IsClaimed = true
+	updated.ClaimerID = "claimer"
+	require.NoError(t, repo.Update(updated))
+
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "Renamed", got.Name)
+	assert.True(t, got.IsClaimed)
+	assert.Equal(t, "claimer", got.ClaimerID)
+	assert.WithinDuration(t, updated.ExpiresAt, got.ExpiresAt, time.Millisecond)
+
+	// 更新不存在的物品
+	assert.ErrorIs(t, repo.Update(newItem("100002", time.Hour)), models.ErrItemNotFound)
+	got, err = repo.GetByPickupCode("100002")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func testDelete(t *testing.T, factory Factory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+	require.NoError(t, repo.Create(newItem("100002", time.Hour)))
+
+	require.NoError(t, repo.Delete("100001"))
+	got, err := repo.GetByPickupCode("100001")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Len(t, repo.GetAll(), 1)
+
+	// 删除不存在的物品不报错
+	assert.NoError(t, repo.Delete("100001"))
+	assert.NoError(t, repo.Delete("999999"))
+
+	// 删除后取件码可以重新使用
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+}
+
+func testDuplicatePickupCode(t *testing.T, factory Factory) {
+	repo, expired := newRecordingRepo(t, factory)
+	original := newItem("100001", time.Hour)
+	require.NoError(t, repo.Create(original))
+
+	// 未过期的取件码不能重复使用，原物品不受影响
+	duplicate := newItem("100001", time.Hour)
+	duplicate.ID = "duplicate"
+	assert.ErrorIs(t, repo.Create(duplicate), models.ErrDuplicatePickupCode)
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	assert.Equal(t, original.ID, got.ID)
+
+	// 已过期物品的取件码可以重新使用，旧物品按过期处理
+	stale := newItem("100002", -time.Minute)
+	stale.ID = "stale"
+	require.NoError(t, repo.Create(stale))
+	fresh := newItem("100002", time.Hour)
+	fresh.ID = "fresh"
+	require.NoError(t, repo.Create(fresh))
+	got, err = repo.GetByPickupCode("100002")
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", got.ID)
+	assert.Equal(t, []string{"stale"}, expired.IDs())
+}
+
+func testExpireOnRead(t *testing.T, factory Factory) {
+	repo, expired := newRecordingRepo(t, factory)
+	require.NoError(t, repo.Create(newItem("100001", -time.Minute)))
+
+	// 过期物品不可见，读取时交给过期回调且只回调一次
+	got, err := repo.GetByPickupCode("100001")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	got, err = repo.GetByPickupCode("100001")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.Equal(t, []string{"item-100001"}, expired.IDs())
+}
+
+func testDeleteExpired(t *testing.T, factory Factory) {
+	repo, expired := newRecordingRepo(t, factory)
+	for i := 0; i < 6; i++ {
+		expiresIn := time.Hour
+		if i%2 == 0 {
+			expiresIn = -time.Duration(i+1) * time.Minute
+		}
+		require.NoError(t, repo.Create(newItem(fmt.Sprintf("20000%d", i), expiresIn)))
+	}
+
+	// GetAll 不返回过期物品
+	for _, item := range repo.GetAll() {
+		assert.True(t, item.ExpiresAt.After(time.Now()), "GetAll returned expired item %s", item.ID)
+	}
+	assert.Len(t, repo.GetAll(), 3)
+
+	// 每个过期物品恰好交给过期回调一次
+	require.NoError(t, repo.DeleteExpired())
+	require.NoError(t, repo.DeleteExpired())
+	assert.ElementsMatch(t, []string{"item-200000", "item-200002", "item-200004"}, expired.IDs())
+
+	// 未过期物品不受影响
+	for _, code := range []string{"200001", "200003", "200005"} {
+		got, err := repo.GetByPickupCode(code)
+		assert.NoError(t, err)
+		assert.NotNil(t, got, code)
+	}
+}
+
+func testClaim(t *testing.T, factory Factory) {
+	repo := factory(t)
+	item := newItem("100001", time.Hour)
+	require.NoError(t, repo.Create(item))
+
+	claimed, err := repo.Claim("100001", "claimer")
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, item.ID, claimed.ID)
+	assert.True(t, claimed.IsClaimed)
+	assert.Equal(t, "claimer", claimed.ClaimerID)
+
+	// 领取后物品从仓库移除，不能再次领取
+	got, err := repo.GetByPickupCode("100001")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	_, err = repo.Claim("100001", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+
+	// 不存在的取件码
+	_, err = repo.Claim("999999", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+
+	// 已标记领取的物品
+	marked := newItem("100002", time.Hour)
+	require.NoError(t, repo.Create(marked))
+	marked.IsClaimed = true
+	marked.ClaimerID = "someone"
+	require.NoError(t, repo.Update(marked))
+	_, err = repo.Claim("100002", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemClaimed)
+}
+
+func testClaimExpired(t *testing.T, factory Factory) {
+	repo, expired := newRecordingRepo(t, factory)
+	require.NoError(t, repo.Create(newItem("100001", -time.Minute)))
+
+	_, err := repo.Claim("100001", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+	assert.Equal(t, []string{"item-100001"}, expired.IDs())
+}
+
+func testConcurrentCreate(t *testing.T, factory Factory) {
+	repo := factory(t)
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			if err := repo.Create(newItem(fmt.Sprintf("3%05d", index), time.Hour)); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent create failed: %v", err)
+	}
+	assert.Len(t, repo.GetAll(), 50)
+
+	// 并发使用同一取件码只有一个成功
+	var successCount int32
+	var mu sync.Mutex
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			item := newItem("400000", time.Hour)
+			item.ID = fmt.Sprintf("racer-%d", index)
+			err := repo.Create(item)
+			if err == nil {
+				mu.Lock()
+				successCount++
+				mu.Unlock()
+			} else {
+				assert.ErrorIs(t, err, models.ErrDuplicatePickupCode)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), successCount)
+}
+
+func testConcurrentClaim(t *testing.T, factory Factory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+
+	// 并发领取同一物品只有一个成功，其余返回未找到
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			claimerID := fmt.Sprintf("claimer-%d", index)
+			claimed, err := repo.Claim("100001", claimerID)
+			if err != nil {
+				assert.ErrorIs(t, err, models.ErrItemNotFound)
+				return
+			}
+			assert.Equal(t, claimerID, claimed.ClaimerID)
+			mu.Lock()
+			winners = append(winners, claimerID)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, winners, 1)
+}
+
+func testReserve(t *testing.T, factory Factory) {
+	repo := factory(t)
+	item := newItem("100001", time.Hour)
+	require.NoError(t, repo.Create(item))
+
+	until := time.Now().Add(time.Minute)
+	reserved, err := repo.Reserve("100001", "claimer", "token-1", until)
+	require.NoError(t, err)
+	assert.Equal(t, item.ID, reserved.ID)
+	assert.True(t, reserved.IsClaimed)
+	assert.Equal(t, "claimer", reserved.ClaimerID)
+	require.NotNil(t, reserved.Reservation)
+	assert.Equal(t, "token-1", reserved.Reservation.Token)
+	assert.WithinDuration(t, until, reserved.Reservation.ExpiresAt, time.Millisecond)
+
+	// 预留中的物品仍占用取件码，不能再被领取或预留
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.True(t, got.IsClaimed)
+	require.NotNil(t, got.Reservation)
+	assert.Equal(t, "token-1", got.Reservation.Token)
+	_, err = repo.Claim("100001", "other")
+	assert.ErrorIs(t, err, models.ErrItemClaimed)
+	_, err = repo.Reserve("100001", "other", "token-2", until)
+	assert.ErrorIs(t, err, models.ErrItemClaimed)
+	assert.ErrorIs(t, repo.Create(newItem("100001", time.Hour)), models.ErrDuplicatePickupCode)
+
+	// 预留期限不超过物品的过期时间
+	require.NoError(t, repo.Create(newItem("100002", time.Minute)))
+	reserved, err = repo.Reserve("100002", "claimer", "token-3", time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.WithinDuration(t, reserved.ExpiresAt, reserved.Reservation.ExpiresAt, time.Millisecond)
+
+	_, err = repo.Reserve("999999", "claimer", "token-4", until)
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+}
+
+func testConfirmAndRelease(t *testing.T, factory Factory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+	require.NoError(t, repo.Create(newItem("100002", time.Hour)))
+	until := time.Now().Add(time.Minute)
+	_, err := repo.Reserve("100001", "claimer", "token-1", until)
+	require.NoError(t, err)
+	_, err = repo.Reserve("100002", "claimer", "token-2", until)
+	require.NoError(t, err)
+
+	// 令牌必须匹配
+	_, err = repo.Confirm("100001", "token-2")
+	assert.ErrorIs(t, err, models.ErrReservationNotFound)
+	_, err = repo.Release("100001", "wrong")
+	assert.ErrorIs(t, err, models.ErrReservationNotFound)
+
+	// 确认后物品从仓库移除
+	confirmed, err := repo.Confirm("100001", "token-1")
+	require.NoError(t, err)
+	assert.Equal(t, "item-100001", confirmed.ID)
+	assert.True(t, confirmed.IsClaimed)
+	assert.Equal(t, "claimer", confirmed.ClaimerID)
+	got, err := repo.GetByPickupCode("100001")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	_, err = repo.Confirm("100001", "token-1")
+	assert.ErrorIs(t, err, models.ErrReservationNotFound)
+
+	// 取消后物品恢复为未领取，可以再次领取
+	released, err := repo.Release("100002", "token-2")
+	require.NoError(t, err)
+	assert.Equal(t, "claimer", released.ClaimerID)
+	got, err = repo.GetByPickupCode("100002")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.False(t, got.IsClaimed)
+	assert.Empty(t, got.ClaimerID)
+	assert.Nil(t, got.Reservation)
+	_, err = repo.Confirm("100002", "token-2")
+	assert.ErrorIs(t, err, models.ErrReservationNotFound)
+	claimed, err := repo.Claim("100002", "other")
+	require.NoError(t, err)
+	assert.Equal(t, "other", claimed.ClaimerID)
+}
+
+func testReservationLapse(t *testing.T, factory Factory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+	require.NoError(t, repo.Create(newItem("100002", time.Hour)))
+	require.NoError(t, repo.Create(newItem("100003", time.Hour)))
+	past := time.Now().Add(-time.Second)
+	for _, code := range []string{"100001", "100002", "100003"} {
+		_, err := repo.Reserve(code, "claimer", "token-"+code, past)
+		require.NoError(t, err)
+	}
+
+	// 预留过期后不能再确认，物品按未领取返回
+	_, err := repo.Confirm("100001", "token-100001")
+	assert.ErrorIs(t, err, models.ErrReservationNotFound)
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.False(t, got.IsClaimed)
+	assert.Nil(t, got.Reservation)
+	for _, item := range repo.GetAll() {
+		assert.False(t, item.IsClaimed, item.PickupCode)
+	}
+
+	// 预留过期的物品可以被重新预留或直接领取
+	reserved, err := repo.Reserve("100001", "other", "token-new", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "other", reserved.ClaimerID)
+	claimed, err := repo.Claim("100002", "other")
+	require.NoError(t, err)
+	assert.Equal(t, "other", claimed.ClaimerID)
+
+	// 只有仍处于过期预留状态的物品被恢复
+	released, err := repo.ReleaseExpiredReservations()
+	require.NoError(t, err)
+	require.Len(t, released, 1)
+	assert.Equal(t, "item-100003", released[0].ID)
+	assert.Equal(t, "claimer", released[0].ClaimerID)
+	got, err = repo.GetByPickupCode("100003")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.False(t, got.IsClaimed)
+
+	released, err = repo.ReleaseExpiredReservations()
+	require.NoError(t, err)
+	assert.Empty(t, released)
+	got, err = repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.True(t, got.IsClaimed)
+}
+
+func testConcurrentReserve(t *testing.T, factory Factory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newItem("100001", time.Hour)))
+
+	// 并发预留同一物品只有一个成功，其余返回已领取
+	until := time.Now().Add(time.Minute)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			token := fmt.Sprintf("token-%d", index)
+			_, err := repo.Reserve("100001", "claimer", token, until)
+			if err != nil {
+				assert.ErrorIs(t, err, models.ErrItemClaimed)
+				return
+			}
+			mu.Lock()
+			winners = append(winners, token)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, winners, 1)
+	_, err := repo.Confirm("100001", winners[0])
+	assert.NoError(t, err)
+}
+
+// 挂牌ID随物品保存，预留、取消预留和领取后保持不变
+func testListingID(t *testing.T, factory Factory) {
+	repo := factory(t)
+	item := newItem("100001", time.Hour)
+	item.ListingID = "listing-100001"
+	require.NoError(t, repo.Create(item))
+	require.NoError(t, repo.Create(newItem("100002", time.Hour)))
+
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "listing-100001", got.ListingID)
+	listed := 0
+	for _, item := range repo.GetAll() {
+		if item.ListingID != "" {
+			listed++
+			assert.Equal(t, "listing-100001", item.ListingID)
+		}
+	}
+	assert.Equal(t, 1, listed)
+
+	reserved, err := repo.Reserve("100001", "claimer", "token", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, "listing-100001", reserved.ListingID)
+	released, err := repo.Release("100001", "token")
+	require.NoError(t, err)
+	assert.Equal(t, "listing-100001", released.ListingID)
+
+	claimed, err := repo.Claim("100001", "claimer")
+	require.NoError(t, err)
+	assert.Equal(t, "listing-100001", claimed.ListingID)
+}
+
+// 群组ID随物品保存，可以按群组查询
+func testGroupID(t *testing.T, factory Factory) {
+	repo := factory(t)
+	item := newItem("100001", time.Hour)
+	item.GroupID = "group-1"
+	require.NoError(t, repo.Create(item))
+	require.NoError(t, repo.Create(newItem("100002", time.Hour)))
+
+	got, err := repo.GetByPickupCode("100001")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "group-1", got.GroupID)
+
+	page, err := repo.Query(models.ItemQuery{GroupID: "group-1"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "100001", page.Items[0].PickupCode)
+
+	claimed, err := repo.Claim("100001", "claimer")
+	require.NoError(t, err)
+	assert.Equal(t, "group-1", claimed.GroupID)
+	page, err = repo.Query(models.ItemQuery{GroupID: "group-1"})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+}
@@ -0,0 +1,265 @@
+package repotest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// GroupFactory 为每个子测试创建一个新的空群组仓库
+type GroupFactory func(t *testing.T) models.GroupRepository
+
+// 创建只有群主的测试群组
+func newGroup(id, ownerID string) *models.Group {
+	now := time.Now()
+	return &models.Group{
+		ID:        id,
+		Name:      "Clan " + id,
+		OwnerID:   ownerID,
+		CreatedAt: now,
+		Members:   []models.GroupMember{{PlayerID: ownerID, Role: models.RoleOwner, JoinedAt: now}},
+	}
+}
+
+// 邀请并加入，返回加入后的群组
+func addMember(t *testing.T, repo models.GroupRepository, groupID, inviterID, playerID string) *models.Group {
+	t.Helper()
+	_, err := repo.Invite(groupID, inviterID, playerID, time.Now())
+	require.NoError(t, err)
+	group, err := repo.Join(groupID, playerID, time.Now())
+	require.NoError(t, err)
+	return group
+}
+
+// RunGroupConformance 对群组仓库实现运行完整的一致性测试
+func RunGroupConformance(t *testing.T, factory GroupFactory) {
+	t.Run("CreateAndGet", func(t *testing.T) { testGroupCreateAndGet(t, factory) })
+	t.Run("InviteAndJoin", func(t *testing.T) { testGroupInviteAndJoin(t, factory) })
+	t.Run("ListForPlayer", func(t *testing.T) { testGroupListForPlayer(t, factory) })
+	t.Run("Leave", func(t *testing.T) { testGroupLeave(t, factory) })
+	t.Run("Roles", func(t *testing.T) { testGroupRoles(t, factory) })
+	t.Run("Remove", func(t *testing.T) { testGroupRemove(t, factory) })
+	t.Run("ConcurrentJoin", func(t *testing.T) { testGroupConcurrentJoin(t, factory) })
+}
+
+func testGroupCreateAndGet(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	group := newGroup("group-1", "alice")
+	require.NoError(t, repo.Create(group))
+	assert.ErrorIs(t, repo.Create(newGroup("group-1", "bob")), models.ErrDuplicateGroupID)
+
+	got, err := repo.Get("group-1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "Clan group-1", got.Name)
+	assert.Equal(t, "alice", got.OwnerID)
+	require.Len(t, got.Members, 1)
+	assert.Equal(t, models.RoleOwner, got.Members[0].Role)
+	assert.WithinDuration(t, group.CreatedAt, got.CreatedAt, time.Millisecond)
+
+	// 修改返回值不影响仓库中的群组
+	got.Members[0].Role = models.RoleMember
+	again, err := repo.Get("group-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleOwner, again.Members[0].Role)
+
+	missing, err := repo.Get("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+	_, err = repo.Invite("missing", "alice", "bob", time.Now())
+	assert.ErrorIs(t, err, models.ErrGroupNotFound)
+}
+
+func testGroupInviteAndJoin(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newGroup("group-1", "alice")))
+
+	// 没有邀请不能加入
+	_, err := repo.Join("group-1", "bob", time.Now())
+	assert.ErrorIs(t, err, models.ErrNotInvited)
+
+	group, err := repo.Invite("group-1", "alice", "bob", time.Now())
+	require.NoError(t, err)
+	assert.True(t, group.Invited("bob"))
+	member, err := repo.IsMember("group-1", "bob")
+	require.NoError(t, err)
+	assert.False(t, member, "invited players are not members")
+
+	group, err = repo.Join("group-1", "bob", time.Now())
+	require.NoError(t, err)
+	assert.False(t, group.Invited("bob"))
+	require.NotNil(t, group.Member("bob"))
+	assert.Equal(t, models.RoleMember, group.Member("bob").Role)
+	member, err = repo.IsMember("group-1", "bob")
+	require.NoError(t, err)
+	assert.True(t, member)
+
+	_, err = repo.Join("group-1", "bob", time.Now())
+	assert.ErrorIs(t, err, models.ErrAlreadyGroupMember)
+	_, err = repo.Invite("group-1", "alice", "bob", time.Now())
+	assert.ErrorIs(t, err, models.ErrAlreadyGroupMember)
+	// 普通成员不能邀请
+	_, err = repo.Invite("group-1", "bob", "carol", time.Now())
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+	_, err = repo.Invite("group-1", "mallory", "carol", time.Now())
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+
+	member, err = repo.IsMember("missing", "bob")
+	require.NoError(t, err)
+	assert.False(t, member)
+}
+
+func testGroupListForPlayer(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	first := newGroup("group-1", "alice")
+	second := newGroup("group-2", "bob")
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	require.NoError(t, repo.Create(first))
+	require.NoError(t, repo.Create(second))
+	require.NoError(t, repo.Create(newGroup("group-3", "carol")))
+	_, err := repo.Invite("group-2", "bob", "alice", time.Now())
+	require.NoError(t, err)
+
+	// 所在和被邀请加入的群组都会列出，新创建的在前
+	groups, err := repo.ListForPlayer("alice")
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "group-2", groups[0].ID)
+	assert.Equal(t, "group-1", groups[1].ID)
+
+	groups, err = repo.ListForPlayer("nobody")
+	require.NoError(t, err)
+	assert.NotNil(t, groups)
+	assert.Empty(t, groups)
+}
+
+func testGroupLeave(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newGroup("group-1", "alice")))
+	addMember(t, repo, "group-1", "alice", "bob")
+
+	group, err := repo.Leave("group-1", "bob")
+	require.NoError(t, err)
+	assert.Nil(t, group.Member("bob"))
+	member, err := repo.IsMember("group-1", "bob")
+	require.NoError(t, err)
+	assert.False(t, member)
+	groups, err := repo.ListForPlayer("bob")
+	require.NoError(t, err)
+	assert.Empty(t, groups)
+
+	_, err = repo.Leave("group-1", "bob")
+	assert.ErrorIs(t, err, models.ErrNotGroupMember)
+	// 群主需要先转让群组
+	_, err = repo.Leave("group-1", "alice")
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+}
+
+func testGroupRoles(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newGroup("group-1", "alice")))
+	addMember(t, repo, "group-1", "alice", "bob")
+	addMember(t, repo, "group-1", "alice", "carol")
+
+	group, err := repo.SetRole("group-1", "alice", "bob", models.RoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, group.Member("bob").Role)
+
+	// 管理员可以邀请，但只有群主可以调整角色
+	_, err = repo.Invite("group-1", "bob", "dave", time.Now())
+	require.NoError(t, err)
+	_, err = repo.SetRole("group-1", "bob", "carol", models.RoleAdmin)
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+	_, err = repo.SetRole("group-1", "alice", "alice", models.RoleMember)
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+	_, err = repo.SetRole("group-1", "alice", "dave", models.RoleAdmin)
+	assert.ErrorIs(t, err, models.ErrNotGroupMember)
+
+	// 转让群组后原群主成为管理员
+	group, err = repo.SetRole("group-1", "alice", "carol", models.RoleOwner)
+	require.NoError(t, err)
+	assert.Equal(t, "carol", group.OwnerID)
+	assert.Equal(t, models.RoleOwner, group.Member("carol").Role)
+	assert.Equal(t, models.RoleAdmin, group.Member("alice").Role)
+	got, err := repo.Get("group-1")
+	require.NoError(t, err)
+	assert.Equal(t, "carol", got.OwnerID)
+	_, err = repo.Leave("group-1", "alice")
+	assert.NoError(t, err)
+}
+
+func testGroupRemove(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newGroup("group-1", "alice")))
+	addMember(t, repo, "group-1", "alice", "bob")
+	addMember(t, repo, "group-1", "alice", "carol")
+	addMember(t, repo, "group-1", "alice", "dave")
+	_, err := repo.SetRole("group-1", "alice", "bob", models.RoleAdmin)
+	require.NoError(t, err)
+
+	// 只能移除等级更低的成员
+	_, err = repo.Remove("group-1", "carol", "dave")
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+	_, err = repo.Remove("group-1", "bob", "alice")
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+	group, err := repo.Remove("group-1", "bob", "dave")
+	require.NoError(t, err)
+	assert.Nil(t, group.Member("dave"))
+	group, err = repo.Remove("group-1", "alice", "bob")
+	require.NoError(t, err)
+	assert.Nil(t, group.Member("bob"))
+	_, err = repo.Remove("group-1", "alice", "bob")
+	assert.ErrorIs(t, err, models.ErrNotGroupMember)
+
+	// 撤回邀请
+	_, err = repo.Invite("group-1", "alice", "erin", time.Now())
+	require.NoError(t, err)
+	_, err = repo.Remove("group-1", "carol", "erin")
+	assert.ErrorIs(t, err, models.ErrGroupForbidden)
+	group, err = repo.Remove("group-1", "alice", "erin")
+	require.NoError(t, err)
+	assert.False(t, group.Invited("erin"))
+	_, err = repo.Join("group-1", "erin", time.Now())
+	assert.ErrorIs(t, err, models.ErrNotInvited)
+}
+
+func testGroupConcurrentJoin(t *testing.T, factory GroupFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newGroup("group-1", "alice")))
+	const players = 10
+	for i := 0; i < players; i++ {
+		_, err := repo.Invite("group-1", "alice", fmt.Sprintf("player-%d", i), time.Now())
+		require.NoError(t, err)
+	}
+
+	// 并发加入时每个玩家最终都成为成员，冲突的修改可以重试
+	var wg sync.WaitGroup
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			playerID := fmt.Sprintf("player-%d", index)
+			for {
+				_, err := repo.Join("group-1", playerID, time.Now())
+				if err == nil {
+					return
+				}
+				if !assert.ErrorIs(t, err, models.ErrGroupConflict) {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	group, err := repo.Get("group-1")
+	require.NoError(t, err)
+	assert.Len(t, group.Members, players+1)
+	assert.Empty(t, group.Invites)
+}
@@ -0,0 +1,218 @@
+package repotest
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 查询测试使用的物品，分享时间按 offset 错开
+type queryItem struct {
+	code        string
+	name        string
+	description string
+	typeID      int
+	sharerID    string
+	durability  float64
+	offset      time.Duration
+	expiresIn   time.Duration
+	listingID   string
+	groupID     string
+}
+
+// 创建查询测试物品，返回基准时间
+func seedQueryItems(t *testing.T, repo models.ItemRepository, items []queryItem) time.Time {
+	base := time.Now().Add(-time.Hour)
+	for _, q := range items {
+		item := newItem(q.code, q.expiresIn)
+		item.Name = q.name
+		item.Description = q.description
+		item.TypeID = q.typeID
+		item.SharerID = q.sharerID
+		item.Durability = q.durability
+		item.CreatedAt = base.Add(q.offset)
+		item.ListingID = q.listingID
+		item.GroupID = q.groupID
+		require.NoError(t, repo.Create(item))
+	}
+	return base
+}
+
+func codesOf(items []*models.Item) []string {
+	codes := make([]string, 0, len(items))
+	for _, item := range items {
+		codes = append(codes, item.PickupCode)
+	}
+	return codes
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+var queryFixture = []queryItem{
+	{"100001", "Iron Sword", "A sturdy blade", 1001, "alice", 40, 1 * time.Minute, time.Hour, "listing-1", ""},
+	{"100002", "Golden Duck", "Shiny and rare", 2001, "bob", 95, 2 * time.Minute, 2 * time.Hour, "", "group-1"},
+	{"100003", "Iron Axe", "Chops wood", 1001, "bob", 80, 3 * time.Minute, 3 * time.Hour, "listing-3", ""},
+	{"100004", "金色鸭子", "稀有的收藏品", 2001, "alice", 60, 4 * time.Minute, 4 * time.Hour, "", "group-1"},
+	{"100005", "Rusty Sword", "Barely holds an edge", 1001, "carol", 10, 5 * time.Minute, 5 * time.Hour, "", ""},
+}
+
+func testQueryFilters(t *testing.T, factory Factory) {
+	repo := factory(t)
+	base := seedQueryItems(t, repo, queryFixture)
+	now := time.Now()
+
+	cases := []struct {
+		name  string
+		query models.ItemQuery
+		want  []string
+	}{
+		{"all", models.ItemQuery{}, []string{"100001", "100002", "100003", "100004", "100005"}},
+		{"type", models.ItemQuery{TypeID: 1001}, []string{"100001", "100003", "100005"}},
+		{"sharer", models.ItemQuery{SharerID: "alice"}, []string{"100001", "100004"}},
+		{"listed", models.ItemQuery{ListedOnly: true}, []string{"100001", "100003"}},
+		{"listing id", models.ItemQuery{ListingID: "listing-3"}, []string{"100003"}},
+		{"missing listing id", models.ItemQuery{ListingID: "listing-9"}, []string{}},
+		{"group", models.ItemQuery{GroupID: "group-1"}, []string{"100002", "100004"}},
+		{"group and sharer", models.ItemQuery{GroupID: "group-1", SharerID: "alice"}, []string{"100004"}},
+		{"name", models.ItemQuery{Name: "sWoRd"}, []string{"100001", "100005"}},
+		{"text", models.ItemQuery{Text: "iron"}, []string{"100001", "100003"}},
+		{"text all terms", models.ItemQuery{Text: "sword edge"}, []string{"100005"}},
+		{"text description", models.ItemQuery{Text: "RARE"}, []string{"100002"}},
+		{"text chinese", models.ItemQuery{Text: "鸭子"}, []string{"100004"}},
+		{"text no match", models.ItemQuery{Text: "iron duck"}, []string{}},
+		{"durability", models.ItemQuery{MinDurability: float(40), MaxDurability: float(80)}, []string{"100001", "100003", "100004"}},
+		{"created range", models.ItemQuery{CreatedAfter: base.Add(2 * time.Minute), CreatedBefore: base.Add(4 * time.Minute)}, []string{"100002", "100003", "100004"}},
+		{"expires range", models.ItemQuery{ExpiresAfter: now.Add(90 * time.Minute), ExpiresBefore: now.Add(210 * time.Minute)}, []string{"100002", "100003"}},
+		{"combined", models.ItemQuery{TypeID: 1001, SharerID: "bob", Text: "axe"}, []string{"100003"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			page, err := repo.Query(c.query)
+			require.NoError(t, err)
+			assert.Equal(t, c.want, codesOf(page.Items))
+			assert.Empty(t, page.NextCursor)
+		})
+	}
+
+	// 全文条件筛掉中间的物品时分页仍然连续
+	page, err := repo.Query(models.ItemQuery{Text: "sword", Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100001"}, codesOf(page.Items))
+	require.NotEmpty(t, page.NextCursor)
+	page, err = repo.Query(models.ItemQuery{Text: "sword", Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100005"}, codesOf(page.Items))
+	assert.Empty(t, page.NextCursor)
+}
+
+func testQuerySortAndPaginate(t *testing.T, factory Factory) {
+	repo := factory(t)
+	items := append([]queryItem(nil), queryFixture...)
+	// 耐久度相同时按取件码排序
+	items = append(items, queryItem{"100006", "Copper Ring", "Plain", 3001, "dave", 80, 6 * time.Minute, 30 * time.Minute, "", ""})
+	seedQueryItems(t, repo, items)
+
+	cases := []struct {
+		sortBy     models.ItemSort
+		descending bool
+		want       []string
+	}{
+		{models.SortByCreatedAt, false, []string{"100001", "100002", "100003", "100004", "100005", "100006"}},
+		{models.SortByCreatedAt, true, []string{"100006", "100005", "100004", "100003", "100002", "100001"}},
+		{models.SortByExpiresAt, false, []string{"100006", "100001", "100002", "100003", "100004", "100005"}},
+		{models.SortByDurability, false, []string{"100005", "100001", "100004", "100003", "100006", "100002"}},
+		{models.SortByDurability, true, []string{"100002", "100006", "100003", "100004", "100001", "100005"}},
+	}
+	for _, c := range cases {
+		name := string(c.sortBy)
+		if c.descending {
+			name += " desc"
+		}
+		t.Run(name, func(t *testing.T) {
+			query := models.ItemQuery{SortBy: c.sortBy, Descending: c.descending, Limit: 4}
+			var got []string
+			pages := 0
+			for {
+				page, err := repo.Query(query)
+				require.NoError(t, err)
+				pages++
+				got = append(got, codesOf(page.Items)...)
+				if page.NextCursor == "" {
+					break
+				}
+				require.Less(t, pages, 3, "pagination does not terminate")
+				query.Cursor = page.NextCursor
+			}
+			assert.Equal(t, 2, pages)
+			assert.Equal(t, c.want, got)
+		})
+	}
+
+	// 游标只能用于生成它的排序方式
+	page, err := repo.Query(models.ItemQuery{Limit: 2})
+	require.NoError(t, err)
+	require.NotEmpty(t, page.NextCursor)
+	_, err = repo.Query(models.ItemQuery{Limit: 2, Cursor: page.NextCursor, Descending: true})
+	assert.ErrorIs(t, err, models.ErrInvalidQuery)
+	_, err = repo.Query(models.ItemQuery{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, models.ErrInvalidQuery)
+	_, err = repo.Query(models.ItemQuery{SortBy: "name"})
+	assert.ErrorIs(t, err, models.ErrInvalidQuery)
+}
+
+// 默认只返回可领取的物品，预留过期的物品按未领取返回
+func testQueryReserved(t *testing.T, factory Factory) {
+	repo := factory(t)
+	seedQueryItems(t, repo, queryFixture[:3])
+	_, err := repo.Reserve("100001", "claimer", "token-1", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	_, err = repo.Reserve("100002", "claimer", "token-2", time.Now().Add(-time.Second))
+	require.NoError(t, err)
+
+	page, err := repo.Query(models.ItemQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100002", "100003"}, codesOf(page.Items))
+	for _, item := range page.Items {
+		assert.False(t, item.IsClaimed, item.PickupCode)
+	}
+
+	page, err = repo.Query(models.ItemQuery{IncludeReserved: true, ListingID: "listing-1"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.True(t, page.Items[0].IsClaimed)
+	assert.Equal(t, "claimer", page.Items[0].ClaimerID)
+}
+
+// 更新、领取、删除和过期的物品在查询结果中同步变化
+func testQueryTracksChanges(t *testing.T, factory Factory) {
+	repo := factory(t)
+	seedQueryItems(t, repo, queryFixture[:3])
+	require.NoError(t, repo.Create(newItem("100009", -time.Second)))
+
+	renamed := newItem("100001", time.Hour)
+	renamed.Name = "Silver Spoon"
+	renamed.TypeID = 4001
+	require.NoError(t, repo.Update(renamed))
+	_, err := repo.Claim("100002", "claimer")
+	require.NoError(t, err)
+
+	query := func(q models.ItemQuery) []string {
+		page, err := repo.Query(q)
+		require.NoError(t, err)
+		return codesOf(page.Items)
+	}
+	assert.Equal(t, []string{"100003"}, query(models.ItemQuery{Text: "iron"}))
+	assert.Equal(t, []string{"100001"}, query(models.ItemQuery{Text: "spoon", TypeID: 4001}))
+	assert.Empty(t, query(models.ItemQuery{TypeID: 2001}))
+	assert.Empty(t, query(models.ItemQuery{ListingID: "listing-1"}))
+
+	require.NoError(t, repo.Delete("100003"))
+	assert.Empty(t, query(models.ItemQuery{ListedOnly: true}))
+	assert.Equal(t, []string{"100001"}, query(models.ItemQuery{}))
+}
@@ -0,0 +1,261 @@
+package repotest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TradeFactory 为每个子测试创建一个新的空交易仓库
+type TradeFactory func(t *testing.T) models.TradeRepository
+
+// 创建测试交易
+func newTrade(id, initiatorID string, expiresIn time.Duration) *models.Trade {
+	now := time.Now()
+	return &models.Trade{
+		ID:          id,
+		InitiatorID: initiatorID,
+		Offer:       []models.TradeItem{{Name: "Golden Duck", Description: "Shiny", TypeID: 1001, Num: 1, Durability: 90}},
+		Request:     []models.TradeWant{{TypeID: 2002, Num: 3}},
+		Status:      models.TradeOpen,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(expiresIn),
+	}
+}
+
+var tradeDeposit = []models.TradeItem{{Name: "Feather", Description: "Soft", TypeID: 2002, Num: 3, Durability: 100}}
+
+// RunTradeConformance 对交易仓库实现运行完整的一致性测试
+func RunTradeConformance(t *testing.T, factory TradeFactory) {
+	t.Run("CreateAndGet", func(t *testing.T) { testTradeCreateAndGet(t, factory) })
+	t.Run("List", func(t *testing.T) { testTradeList(t, factory) })
+	t.Run("AcceptAndComplete", func(t *testing.T) { testTradeAcceptAndComplete(t, factory) })
+	t.Run("AcceptRules", func(t *testing.T) { testTradeAcceptRules(t, factory) })
+	t.Run("Reopen", func(t *testing.T) { testTradeReopen(t, factory) })
+	t.Run("Cancel", func(t *testing.T) { testTradeCancel(t, factory) })
+	t.Run("ExpireAndDelete", func(t *testing.T) { testTradeExpireAndDelete(t, factory) })
+	t.Run("ConcurrentAccept", func(t *testing.T) { testTradeConcurrentAccept(t, factory) })
+}
+
+func testTradeCreateAndGet(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	trade := newTrade("trade-1", "alice", time.Hour)
+	trade.CounterpartyID = "bob"
+	require.NoError(t, repo.Create(trade))
+	assert.ErrorIs(t, repo.Create(newTrade("trade-1", "carol", time.Hour)), models.ErrDuplicateTradeID)
+
+	got, err := repo.Get("trade-1")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "alice", got.InitiatorID)
+	assert.Equal(t, "bob", got.CounterpartyID)
+	assert.Equal(t, trade.Offer, got.Offer)
+	assert.Equal(t, trade.Request, got.Request)
+	assert.Equal(t, models.TradeOpen, got.Status)
+	assert.WithinDuration(t, trade.ExpiresAt, got.ExpiresAt, time.Millisecond)
+	assert.Nil(t, got.ClosedAt)
+
+	// 修改返回值不影响仓库中的交易
+	got.Offer[0].Num = 99
+	again, err := repo.Get("trade-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, again.Offer[0].Num)
+
+	missing, err := repo.Get("missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+func testTradeList(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	first := newTrade("trade-1", "alice", time.Hour)
+	second := newTrade("trade-2", "bob", time.Hour)
+	second.CreatedAt = first.CreatedAt.Add(time.Second)
+	second.CounterpartyID = "alice"
+	require.NoError(t, repo.Create(first))
+	require.NoError(t, repo.Create(second))
+	require.NoError(t, repo.Create(newTrade("trade-3", "carol", time.Hour)))
+
+	// 发起方和指定的对方都能看到交易，按创建时间倒序
+	trades, err := repo.List("alice")
+	require.NoError(t, err)
+	require.Len(t, trades, 2)
+	assert.Equal(t, "trade-2", trades[0].ID)
+	assert.Equal(t, "trade-1", trades[1].ID)
+
+	// 接受方在接受后能看到交易
+	_, err = repo.Accept("trade-3", "dave", tradeDeposit, time.Now())
+	require.NoError(t, err)
+	trades, err = repo.List("dave")
+	require.NoError(t, err)
+	require.Len(t, trades, 1)
+	assert.Equal(t, "trade-3", trades[0].ID)
+
+	trades, err = repo.List("nobody")
+	require.NoError(t, err)
+	assert.NotNil(t, trades)
+	assert.Empty(t, trades)
+}
+
+func testTradeAcceptAndComplete(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newTrade("trade-1", "alice", time.Hour)))
+
+	accepted, err := repo.Accept("trade-1", "bob", tradeDeposit, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeAccepted, accepted.Status)
+	assert.Equal(t, "bob", accepted.AcceptorID)
+	assert.Equal(t, tradeDeposit, accepted.Deposit)
+
+	// 已被接受的交易不能再次接受或取消
+	_, err = repo.Accept("trade-1", "carol", tradeDeposit, time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeNotOpen)
+	_, err = repo.Cancel("trade-1", "alice", time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeNotOpen)
+
+	now := time.Now()
+	completed, err := repo.Complete("trade-1", []string{"111111"}, []string{"222222"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeCompleted, completed.Status)
+	require.NotNil(t, completed.ClosedAt)
+	assert.WithinDuration(t, now, *completed.ClosedAt, time.Millisecond)
+
+	got, err := repo.Get("trade-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeCompleted, got.Status)
+	assert.Equal(t, []string{"111111"}, got.InitiatorCodes)
+	assert.Equal(t, []string{"222222"}, got.AcceptorCodes)
+
+	_, err = repo.Complete("trade-1", nil, nil, now)
+	assert.ErrorIs(t, err, models.ErrTradeNotAccepted)
+	_, err = repo.Accept("missing", "bob", tradeDeposit, now)
+	assert.ErrorIs(t, err, models.ErrTradeNotFound)
+}
+
+func testTradeAcceptRules(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	restricted := newTrade("trade-1", "alice", time.Hour)
+	restricted.CounterpartyID = "bob"
+	require.NoError(t, repo.Create(restricted))
+	require.NoError(t, repo.Create(newTrade("trade-2", "alice", -time.Minute)))
+
+	// 不能接受自己的交易，指定了对方时只有对方可以接受
+	_, err := repo.Accept("trade-1", "alice", tradeDeposit, time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeForbidden)
+	_, err = repo.Accept("trade-1", "carol", tradeDeposit, time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeForbidden)
+	_, err = repo.Accept("trade-1", "bob", tradeDeposit, time.Now())
+	assert.NoError(t, err)
+
+	// 超时的交易不能接受
+	_, err = repo.Accept("trade-2", "bob", tradeDeposit, time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeNotOpen)
+}
+
+func testTradeReopen(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newTrade("trade-1", "alice", time.Hour)))
+	_, err := repo.Reopen("trade-1")
+	assert.ErrorIs(t, err, models.ErrTradeNotAccepted)
+
+	_, err = repo.Accept("trade-1", "bob", tradeDeposit, time.Now())
+	require.NoError(t, err)
+	reopened, err := repo.Reopen("trade-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeOpen, reopened.Status)
+	assert.Empty(t, reopened.AcceptorID)
+	assert.Empty(t, reopened.Deposit)
+
+	// 恢复后可以被其他玩家接受
+	accepted, err := repo.Accept("trade-1", "carol", tradeDeposit, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "carol", accepted.AcceptorID)
+}
+
+func testTradeCancel(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newTrade("trade-1", "alice", time.Hour)))
+
+	_, err := repo.Cancel("trade-1", "bob", time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeForbidden)
+	cancelled, err := repo.Cancel("trade-1", "alice", time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeCancelled, cancelled.Status)
+	assert.NotNil(t, cancelled.ClosedAt)
+
+	_, err = repo.Cancel("trade-1", "alice", time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeNotOpen)
+	_, err = repo.Accept("trade-1", "bob", tradeDeposit, time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeNotOpen)
+	_, err = repo.Cancel("missing", "alice", time.Now())
+	assert.ErrorIs(t, err, models.ErrTradeNotFound)
+}
+
+func testTradeExpireAndDelete(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newTrade("trade-1", "alice", -time.Minute)))
+	require.NoError(t, repo.Create(newTrade("trade-2", "alice", time.Hour)))
+	require.NoError(t, repo.Create(newTrade("trade-3", "alice", -time.Minute)))
+	_, err := repo.Cancel("trade-3", "alice", time.Now())
+	require.NoError(t, err)
+
+	// 只有超时的 open 交易被转为 expired
+	expired, err := repo.ExpireOpen(time.Now())
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "trade-1", expired[0].ID)
+	assert.Equal(t, models.TradeExpired, expired[0].Status)
+	got, err := repo.Get("trade-1")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeExpired, got.Status)
+
+	expired, err = repo.ExpireOpen(time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+
+	// 删除已结束的交易，进行中的交易保留
+	deleted, err := repo.DeleteClosed(time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	got, err = repo.Get("trade-2")
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+	got, err = repo.Get("trade-1")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func testTradeConcurrentAccept(t *testing.T, factory TradeFactory) {
+	repo := factory(t)
+	require.NoError(t, repo.Create(newTrade("trade-1", "alice", time.Hour)))
+
+	// 并发接受同一交易只有一个成功
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var winners []string
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			acceptorID := fmt.Sprintf("player-%d", index)
+			if _, err := repo.Accept("trade-1", acceptorID, tradeDeposit, time.Now()); err != nil {
+				assert.ErrorIs(t, err, models.ErrTradeNotOpen)
+				return
+			}
+			mu.Lock()
+			winners = append(winners, acceptorID)
+			mu.Unlock()
+		}(i)
+	}
+	wg.Wait()
+	require.Len(t, winners, 1)
+	got, err := repo.Get("trade-1")
+	require.NoError(t, err)
+	assert.Equal(t, winners[0], got.AcceptorID)
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// DefaultReturnRetention 退回箱中物品的默认保留时间
+const DefaultReturnRetention = 7 * 24 * time.Hour
+
+// ReturnedItem 过期后退回给分享者的物品
+type ReturnedItem struct {
+	Item       *Item     `json:"item"`
+	ReturnedAt time.Time `json:"returned_at"`
+	ExpiresAt  time.Time `json:"expires_at"` // 超过该时间仍未领回则被彻底删除
+}
+
+// ReturnBox 按分享者划分的退回箱接口
+type ReturnBox interface {
+	// Add 将物品放入其分享者的退回箱
+	Add(item *Item) error
+	// List 查看分享者退回箱中仍在保留期内的物品，按退回顺序排列
+	List(sharerID string) ([]*ReturnedItem, error)
+	// Collect 领回分享者退回箱中仍在保留期内的物品，领回后清空该退回箱
+	Collect(sharerID string) ([]*ReturnedItem, error)
+	// DeleteExpired 删除超过保留期的退回物品
+	DeleteExpired() error
+}
+
+// InMemoryReturnBox 内存实现的退回箱
+type InMemoryReturnBox struct {
+	boxes     map[string][]*ReturnedItem
+	retention time.Duration
+	mutex     sync.RWMutex
+	clock     clock.Clock
+}
+
+// NewInMemoryReturnBox 创建新的内存退回箱，retention 为退回物品的保留时间，clk 为时间源，nil 时使用系统时间
+func NewInMemoryReturnBox(retention time.Duration, clk clock.Clock) *InMemoryReturnBox {
+	if retention <= 0 {
+		retention = DefaultReturnRetention
+	}
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &InMemoryReturnBox{
+		boxes:     make(map[string][]*ReturnedItem),
+		retention: retention,
+		clock:     clk,
+	}
+}
+
+// Add 将过期物品放入分享者的退回箱
+func (b *InMemoryReturnBox) Add(item *Item) error {
+	now := b.clock.Now()
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.boxes[item.SharerID] = append(b.boxes[item.SharerID], &ReturnedItem{
+		Item:       item,
+		ReturnedAt: now,
+		ExpiresAt:  now.Add(b.retention),
+	})
+	return nil
+}
+
+// List 查看分享者退回箱中仍在保留期内的物品
+func (b *InMemoryReturnBox) List(sharerID string) ([]*ReturnedItem, error) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	now := b.clock.Now()
+	returned := make([]*ReturnedItem, 0, len(b.boxes[sharerID]))
+	for _, r := range b.boxes[sharerID] {
+		if !now.After(r.ExpiresAt) {
+			returned = append(returned, r)
+		}
+	}
+	return returned, nil
+}
+
+// Collect 领回分享者退回箱中的全部物品，领回后从退回箱移除
+func (b *InMemoryReturnBox) Collect(sharerID string) ([]*ReturnedItem, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := b.clock.Now()
+	returned := make([]*ReturnedItem, 0, len(b.boxes[sharerID]))
+	for _, r := range b.boxes[sharerID] {
+		if !now.After(r.ExpiresAt) {
+			returned = append(returned, r)
+		}
+	}
+	delete(b.boxes, sharerID)
+	return returned, nil
+}
+
+// DeleteExpired 删除超过保留期的退回物品
+func (b *InMemoryReturnBox) DeleteExpired() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	now := b.clock.Now()
+	for sharerID, box := range b.boxes {
+		kept := box[:0]
+		for _, r := range box {
+			if !now.After(r.ExpiresAt) {
+				kept = append(kept, r)
+			}
+		}
+		if len(kept) == 0 {
+			delete(b.boxes, sharerID)
+		} else {
+			b.boxes[sharerID] = kept
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package models
+
+// 字符串集合
+type codeSet map[string]struct{}
+
+func (s codeSet) add(code string) {
+	s[code] = struct{}{}
+}
+
+// 从键为 K 的倒排表中移除取件码，集合为空时删除该键
+func removeFromPostings[K comparable](postings map[K]codeSet, key K, code string) {
+	if set, ok := postings[key]; ok {
+		delete(set, code)
+		if len(set) == 0 {
+			delete(postings, key)
+		}
+	}
+}
+
+func addToPostings[K comparable](postings map[K]codeSet, key K, code string) {
+	set, ok := postings[key]
+	if !ok {
+		set = make(codeSet)
+		postings[key] = set
+	}
+	set.add(code)
+}
+
+// searchIndex 内存仓库的二级索引：按类型、分享者、群组、挂牌ID和检索词记录取件码，
+// 查询时从最小的候选集合开始筛选，无需遍历全部物品
+type searchIndex struct {
+	byType    map[int]codeSet
+	bySharer  map[string]codeSet
+	byGroup   map[string]codeSet
+	byTerm    map[string]codeSet
+	byListing map[string]string // 挂牌ID到取件码
+	listed    codeSet
+	indexed   map[string]*Item // 取件码当前被索引的物品，用于更新和移除时清理旧的索引项
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		byType:    make(map[int]codeSet),
+		bySharer:  make(map[string]codeSet),
+		byGroup:   make(map[string]codeSet),
+		byTerm:    make(map[string]codeSet),
+		byListing: make(map[string]string),
+		listed:    make(codeSet),
+		indexed:   make(map[string]*Item),
+	}
+}
+
+// set 索引物品，替换同一取件码之前的索引项
+func (x *searchIndex) set(item *Item) {
+	code := item.PickupCode
+	if old, ok := x.indexed[code]; ok {
+		// 预留和取消预留只改变领取状态，可索引的字段不变时无需重建
+		if old.TypeID == item.TypeID && old.SharerID == item.SharerID && old.GroupID == item.GroupID &&
+			old.ListingID == item.ListingID && old.Name == item.Name && old.Description == item.Description {
+			x.indexed[code] = item
+			return
+		}
+		x.remove(code)
+	}
+	x.indexed[code] = item
+	addToPostings(x.byType, item.TypeID, code)
+	addToPostings(x.bySharer, item.SharerID, code)
+	if item.GroupID != "" {
+		addToPostings(x.byGroup, item.GroupID, code)
+	}
+	for _, term := range itemSearchTerms(item) {
+		addToPostings(x.byTerm, term, code)
+	}
+	if item.ListingID != "" {
+		x.byListing[item.ListingID] = code
+		x.listed.add(code)
+	}
+}
+
+// remove 移除取件码的全部索引项
+func (x *searchIndex) remove(code string) {
+	item, ok := x.indexed[code]
+	if !ok {
+		return
+	}
+	delete(x.indexed, code)
+	removeFromPostings(x.byType, item.TypeID, code)
+	removeFromPostings(x.bySharer, item.SharerID, code)
+	removeFromPostings(x.byGroup, item.GroupID, code)
+	for _, term := range itemSearchTerms(item) {
+		removeFromPostings(x.byTerm, term, code)
+	}
+	if item.ListingID != "" {
+		if x.byListing[item.ListingID] == code {
+			delete(x.byListing, item.ListingID)
+		}
+		delete(x.listed, code)
+	}
+}
+
+// candidates 返回满足查询中可索引条件的最小候选集合；没有可索引的条件时返回 false，由调用者遍历全部物品
+// 返回的集合只是候选，调用者仍需用完整的条件筛选
+func (x *searchIndex) candidates(q *ItemQuery, terms []string) (codeSet, bool) {
+	var smallest codeSet
+	found := false
+	consider := func(set codeSet) {
+		if !found || len(set) < len(smallest) {
+			smallest = set
+			found = true
+		}
+	}
+	if q.ListingID != "" {
+		set := make(codeSet)
+		if code, ok := x.byListing[q.ListingID]; ok {
+			set.add(code)
+		}
+		return set, true
+	}
+	if q.TypeID != 0 {
+		consider(x.byType[q.TypeID])
+	}
+	if q.SharerID != "" {
+		consider(x.bySharer[q.SharerID])
+	}
+	if q.GroupID != "" {
+		consider(x.byGroup[q.GroupID])
+	}
+	if q.ListedOnly {
+		consider(x.listed)
+	}
+	for _, term := range terms {
+		consider(x.byTerm[term])
+	}
+	return smallest, found
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// ShardedItemRepository 分片的内存物品仓库，按取件码哈希将物品分散到多个
+// InMemoryItemRepository 中，不同分片上的操作互不阻塞
+type ShardedItemRepository struct {
+	shards []*InMemoryItemRepository
+}
+
+// NewShardedItemRepository 创建新的分片仓库，shardCount 小于1时按1处理，所有分片共用时间源 clk
+func NewShardedItemRepository(shardCount int, clk clock.Clock) *ShardedItemRepository {
+	if shardCount < 1 {
+		shardCount = 1
+	}
+	shards := make([]*InMemoryItemRepository, shardCount)
+	for i := range shards {
+		shards[i] = NewInMemoryItemRepository(clk)
+	}
+	return &ShardedItemRepository{
+		shards: shards,
+	}
+}
+
+// 根据取件码选择分片
+func (r *ShardedItemRepository) shard(pickupCode string) *InMemoryItemRepository {
+	h := fnv.New32a()
+	h.Write([]byte(pickupCode))
+	return r.shards[h.Sum32()%uint32(len(r.shards))]
+}
+
+// ShardCount 返回分片数量
+func (r *ShardedItemRepository) ShardCount() int {
+	return len(r.shards)
+}
+
+// Create 创建新物品
+func (r *ShardedItemRepository) Create(item *Item) error {
+	return r.shard(item.PickupCode).Create(item)
+}
+
+// GetByPickupCode 通过取件码获取物品
+func (r *ShardedItemRepository) GetByPickupCode(pickupCode string) (*Item, error) {
+	return r.shard(pickupCode).GetByPickupCode(pickupCode)
+}
+
+// Claim 原子地领取物品
+func (r *ShardedItemRepository) Claim(pickupCode, claimerID string) (*Item, error) {
+	return r.shard(pickupCode).Claim(pickupCode, claimerID)
+}
+
+// Reserve 原子地预留物品
+func (r *ShardedItemRepository) Reserve(pickupCode, claimerID, token string, until time.Time) (*Item, error) {
+	return r.shard(pickupCode).Reserve(pickupCode, claimerID, token, until)
+}
+
+// Confirm 确认预留
+func (r *ShardedItemRepository) Confirm(pickupCode, token string) (*Item, error) {
+	return r.shard(pickupCode).Confirm(pickupCode, token)
+}
+
+// Release 取消预留
+func (r *ShardedItemRepository) Release(pickupCode, token string) (*Item, error) {
+	return r.shard(pickupCode).Release(pickupCode, token)
+}
+
+// ReleaseExpiredReservations 逐个分片恢复预留已过期的物品
+func (r *ShardedItemRepository) ReleaseExpiredReservations() ([]*Item, error) {
+	var released []*Item
+	for _, shard := range r.shards {
+		items, err := shard.ReleaseExpiredReservations()
+		if err != nil {
+			return released, err
+		}
+		released = append(released, items...)
+	}
+	return released, nil
+}
+
+// Update 更新物品信息
+func (r *ShardedItemRepository) Update(item *Item) error {
+	return r.shard(item.PickupCode).Update(item)
+}
+
+// Delete 删除物品
+func (r *ShardedItemRepository) Delete(pickupCode string) error {
+	return r.shard(pickupCode).Delete(pickupCode)
+}
+
+// DeleteExpired 逐个分片删除过期物品
+func (r *ShardedItemRepository) DeleteExpired() error {
+	for _, shard := range r.shards {
+		if err := shard.DeleteExpired(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetAll 获取所有分片中的未过期物品
+func (r *ShardedItemRepository) GetAll() []*Item {
+	var items []*Item
+	for _, shard := range r.shards {
+		items = append(items, shard.GetAll()...)
+	}
+	if items == nil {
+		items = make([]*Item, 0)
+	}
+	return items
+}
+
+// Query 在每个分片上执行同一查询，合并各分片的第一页后重新截取
+// 每个分片最多返回 Limit 个，合并后的前 Limit 个即为全局的一页
+func (r *ShardedItemRepository) Query(q ItemQuery) (*ItemPage, error) {
+	if _, err := q.normalize(); err != nil {
+		return nil, err
+	}
+	merged := make([]*Item, 0)
+	more := false
+	for _, shard := range r.shards {
+		page, err := shard.Query(q)
+		if err != nil {
+			return nil, err
+		}
+		merged = append(merged, page.Items...)
+		more = more || page.NextCursor != ""
+	}
+	sort.Slice(merged, func(i, j int) bool { return q.less(merged[i], merged[j]) })
+	page := q.page(merged)
+	if more && page.NextCursor == "" && len(page.Items) > 0 {
+		page.NextCursor = q.cursorOf(page.Items[len(page.Items)-1]).encode()
+	}
+	return page, nil
+}
+
+// SetExpiredHandler 为所有分片设置物品过期时的回调
+func (r *ShardedItemRepository) SetExpiredHandler(handler ExpiredHandler) {
+	for _, shard := range r.shards {
+		shard.SetExpiredHandler(handler)
+	}
+}
+
+// NextExpiry 返回所有分片中最早的过期时间
+func (r *ShardedItemRepository) NextExpiry() (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, shard := range r.shards {
+		if t, ok := shard.NextExpiry(); ok && (!found || t.Before(next)) {
+			next = t
+			found = true
+		}
+	}
+	return next, found
+}
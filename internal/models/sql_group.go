@@ -0,0 +1,193 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// SQLGroupRepository 基于 database/sql 的群组仓库，与 SQLItemRepository 共用数据库
+type SQLGroupRepository struct {
+	db *sql.DB
+}
+
+// NewSQLGroupRepository 创建新的SQL群组仓库，并将数据库结构迁移到最新版本
+// clk 为记录迁移执行时间使用的时间源，nil 时使用系统时间
+func NewSQLGroupRepository(db *sql.DB, clk clock.Clock) (*SQLGroupRepository, error) {
+	if err := Migrate(db, clk); err != nil {
+		return nil, err
+	}
+	return &SQLGroupRepository{db: db}, nil
+}
+
+// 群组玩家表中的状态
+const (
+	groupPlayerMember  = "member"
+	groupPlayerInvited = "invited"
+)
+
+// 读取群组JSON和版本
+func scanGroup(row sqlScanner) (*Group, int64, error) {
+	var (
+		data    string
+		version int64
+	)
+	if err := row.Scan(&data, &version); err != nil {
+		return nil, 0, err
+	}
+	var group Group
+	if err := json.Unmarshal([]byte(data), &group); err != nil {
+		return nil, 0, err
+	}
+	return &group, version, nil
+}
+
+// 按群组当前的成员和邀请重建群组玩家表
+func saveGroupPlayers(q sqlQuerier, group *Group) error {
+	if _, err := q.Exec(`DELETE FROM group_players WHERE group_id = ?`, group.ID); err != nil {
+		return err
+	}
+	for _, member := range group.Members {
+		if _, err := q.Exec(`INSERT INTO group_players (group_id, player_id, status) VALUES (?, ?, ?)`,
+			group.ID, member.PlayerID, groupPlayerMember); err != nil {
+			return err
+		}
+	}
+	for _, invite := range group.Invites {
+		if _, err := q.Exec(`INSERT INTO group_players (group_id, player_id, status) VALUES (?, ?, ?)`,
+			group.ID, invite.PlayerID, groupPlayerInvited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create 保存新群组
+func (r *SQLGroupRepository) Create(group *Group) error {
+	data, err := json.Marshal(group)
+	if err != nil {
+		return err
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO player_groups (id, owner_id, data, created_at) VALUES (?, ?, ?, ?)`,
+		group.ID, group.OwnerID, string(data), group.CreatedAt.UnixNano())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateGroupID
+		}
+		return err
+	}
+	if err := saveGroupPlayers(tx, group); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Get 按ID获取群组
+func (r *SQLGroupRepository) Get(id string) (*Group, error) {
+	group, _, err := scanGroup(r.db.QueryRow(`SELECT data, version FROM player_groups WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return group, err
+}
+
+// ListForPlayer 通过群组玩家表返回玩家所在或被邀请加入的群组
+func (r *SQLGroupRepository) ListForPlayer(playerID string) ([]*Group, error) {
+	rows, err := r.db.Query(`SELECT g.data, g.version FROM player_groups g
+		JOIN group_players p ON p.group_id = g.id
+		WHERE p.player_id = ? ORDER BY g.created_at DESC, g.id`, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	groups := make([]*Group, 0)
+	for rows.Next() {
+		group, _, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// IsMember 玩家是否是群组成员
+func (r *SQLGroupRepository) IsMember(id, playerID string) (bool, error) {
+	var n int
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM group_players WHERE group_id = ? AND player_id = ? AND status = ?`,
+		id, playerID, groupPlayerMember).Scan(&n)
+	return n > 0, err
+}
+
+// 在事务中读取群组并执行状态转换，版本与读取时不同说明发生了并发修改
+func (r *SQLGroupRepository) transition(id string, update func(*Group) error) (*Group, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	group, version, err := scanGroup(tx.QueryRow(`SELECT data, version FROM player_groups WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := update(group); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(group)
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(`UPDATE player_groups SET owner_id = ?, data = ?, version = version + 1
+		WHERE id = ? AND version = ?`, group.OwnerID, string(data), id, version)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrGroupConflict
+	}
+	if err := saveGroupPlayers(tx, group); err != nil {
+		return nil, err
+	}
+	return group, tx.Commit()
+}
+
+// Invite 邀请玩家
+func (r *SQLGroupRepository) Invite(id, inviterID, inviteeID string, now time.Time) (*Group, error) {
+	return r.transition(id, inviteToGroup(inviterID, inviteeID, now))
+}
+
+// Join 加入群组
+func (r *SQLGroupRepository) Join(id, playerID string, now time.Time) (*Group, error) {
+	return r.transition(id, joinGroup(playerID, now))
+}
+
+// Leave 退出群组
+func (r *SQLGroupRepository) Leave(id, playerID string) (*Group, error) {
+	return r.transition(id, leaveGroup(playerID))
+}
+
+// SetRole 调整成员的角色
+func (r *SQLGroupRepository) SetRole(id, actorID, targetID string, role GroupRole) (*Group, error) {
+	return r.transition(id, setGroupRole(actorID, targetID, role))
+}
+
+// Remove 移除成员或撤回邀请
+func (r *SQLGroupRepository) Remove(id, actorID, targetID string) (*Group, error) {
+	return r.transition(id, removeFromGroup(actorID, targetID))
+}
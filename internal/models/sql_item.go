@@ -0,0 +1,655 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// 查询物品时使用的列，顺序与 scanItem 一致
+const itemColumns = `row_id, id, name, description, type_id, num, durability, sharer_id,
+	pickup_code, created_at, expires_at, is_claimed, claimer_id, reservation_token, reserved_until, listing_id, group_id`
+
+// SQLItemRepository 基于 database/sql 的物品仓库
+// SQL 使用 "?" 占位符和部分索引，面向 SQLite
+type SQLItemRepository struct {
+	db        *sql.DB
+	mutex     sync.RWMutex
+	onExpired ExpiredHandler
+	clock     clock.Clock
+}
+
+// NewSQLItemRepository 创建新的SQL仓库实例，并将数据库结构迁移到最新版本
+// clk 为判断过期使用的时间源，nil 时使用系统时间
+func NewSQLItemRepository(db *sql.DB, clk clock.Clock) (*SQLItemRepository, error) {
+	if err := Migrate(db, clk); err != nil {
+		return nil, err
+	}
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &SQLItemRepository{
+		db:    db,
+		clock: clk,
+	}, nil
+}
+
+// 可执行查询的对象，*sql.DB 和 *sql.Tx 都满足
+type sqlQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type sqlScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// 读取一行物品数据，同时返回行号
+func scanItem(row sqlScanner) (*Item, int64, error) {
+	var (
+		item                 Item
+		rowID                int64
+		createdAt, expiresAt int64
+		isClaimed            int
+		token                string
+		reservedUntil        int64
+	)
+	err := row.Scan(&rowID, &item.ID, &item.Name, &item.Description, &item.TypeID, &item.Num,
+		&item.Durability, &item.SharerID, &item.PickupCode, &createdAt, &expiresAt, &isClaimed, &item.ClaimerID,
+		&token, &reservedUntil, &item.ListingID, &item.GroupID)
+	if err != nil {
+		return nil, 0, err
+	}
+	item.CreatedAt = time.Unix(0, createdAt)
+	item.ExpiresAt = time.Unix(0, expiresAt)
+	item.IsClaimed = isClaimed != 0
+	if token != "" {
+		item.Reservation = &Reservation{Token: token, ExpiresAt: time.Unix(0, reservedUntil)}
+	}
+	return &item, rowID, nil
+}
+
+// 物品预留在表中的存储字段，未预留时为空
+func reservationColumns(item *Item) (token string, reservedUntil int64) {
+	if item.Reservation == nil {
+		return "", 0
+	}
+	return item.Reservation.Token, item.Reservation.ExpiresAt.UnixNano()
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// 查找取件码当前对应的物品：优先未领取的，其次最新的
+func findByPickupCode(q sqlQuerier, pickupCode string) (*Item, int64, error) {
+	row := q.QueryRow(`SELECT `+itemColumns+` FROM items WHERE pickup_code = ?
+		ORDER BY is_claimed ASC, row_id DESC LIMIT 1`, pickupCode)
+	item, rowID, err := scanItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, nil
+	}
+	return item, rowID, err
+}
+
+// 删除取件码下已过期的物品，返回被删除的物品
+func deleteExpiredByCode(q sqlQuerier, pickupCode string, now time.Time) ([]*Item, error) {
+	rows, err := q.Query(`SELECT `+itemColumns+` FROM items WHERE pickup_code = ? AND expires_at < ?`,
+		pickupCode, now.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	var expired []*Item
+	for rows.Next() {
+		item, _, err := scanItem(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		expired = append(expired, item.visibleAt(now))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(expired) > 0 {
+		if _, err := q.Exec(`DELETE FROM items WHERE pickup_code = ? AND expires_at < ?`,
+			pickupCode, now.UnixNano()); err != nil {
+			return nil, err
+		}
+	}
+	return expired, nil
+}
+
+// 在锁外调用过期回调
+func (r *SQLItemRepository) notifyExpired(items []*Item) {
+	r.mutex.RLock()
+	handler := r.onExpired
+	r.mutex.RUnlock()
+	if handler == nil {
+		return
+	}
+	for _, item := range items {
+		handler(item)
+	}
+}
+
+// Create 创建新物品，取件码被未过期的物品占用时返回 ErrDuplicatePickupCode
+func (r *SQLItemRepository) Create(item *Item) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// 同取件码的过期物品先按过期处理，释放取件码
+	expired, err := deleteExpiredByCode(tx, item.PickupCode, r.clock.Now())
+	if err != nil {
+		return err
+	}
+	// 剩余的同取件码物品均未过期（包括已标记领取但仍保留的物品）
+	existing, _, err := findByPickupCode(tx, item.PickupCode)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrDuplicatePickupCode
+	}
+
+	token, reservedUntil := reservationColumns(item)
+	_, err = tx.Exec(`INSERT INTO items (id, name, description, type_id, num, durability, sharer_id,
+		pickup_code, created_at, expires_at, is_claimed, claimer_id, reservation_token, reserved_until, listing_id, group_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.ID, item.Name, item.Description, item.TypeID, item.Num, item.Durability, item.SharerID,
+		item.PickupCode, item.CreatedAt.UnixNano(), item.ExpiresAt.UnixNano(), boolToInt(item.IsClaimed), item.ClaimerID,
+		token, reservedUntil, item.ListingID, item.GroupID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicatePickupCode
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	r.notifyExpired(expired)
+	return nil
+}
+
+// GetByPickupCode 通过取件码获取物品，过期物品被删除并返回nil，预留已过期的物品按未领取返回
+func (r *SQLItemRepository) GetByPickupCode(pickupCode string) (*Item, error) {
+	item, _, err := findByPickupCode(r.db, pickupCode)
+	if err != nil || item == nil {
+		return nil, err
+	}
+	if now := r.clock.Now(); !now.After(item.ExpiresAt) {
+		return item.visibleAt(now), nil
+	}
+
+	// 检查到过期，在事务中删除并交给过期回调，并发读取时只有一方会删除成功
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	expired, err := deleteExpiredByCode(tx, pickupCode, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.notifyExpired(expired)
+	return nil, nil
+}
+
+// Claim 在事务中领取物品，物品被领取后立即删除
+func (r *SQLItemRepository) Claim(pickupCode, claimerID string) (*Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := r.clock.Now()
+	expired, err := deleteExpiredByCode(tx, pickupCode, now)
+	if err != nil {
+		return nil, err
+	}
+
+	item, rowID, err := findByPickupCode(tx, pickupCode)
+	if err != nil {
+		return nil, err
+	}
+	claimErr := checkPending(item, now)
+	if claimErr == nil {
+		// 以删除成功作为领取成功的依据，防止并发事务重复领取
+		result, err := tx.Exec(`DELETE FROM items WHERE row_id = ? AND `+pendingCondition, rowID, now.UnixNano())
+		if err != nil {
+			return nil, err
+		}
+		claimErr = affectedOrNotFound(result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.notifyExpired(expired)
+	if claimErr != nil {
+		return nil, claimErr
+	}
+
+	claimed := item.visibleAt(now)
+	claimed.IsClaimed = true
+	claimed.ClaimerID = claimerID
+	return claimed, nil
+}
+
+// 可领取物品的条件：未领取，或预留已过期（参数为当前时间）
+const pendingCondition = `(is_claimed = 0 OR (reservation_token != '' AND reserved_until < ?))`
+
+// 检查物品是否可以被领取
+func checkPending(item *Item, now time.Time) error {
+	switch {
+	case item == nil:
+		return ErrItemNotFound
+	case !item.Pending(now):
+		return ErrItemClaimed
+	}
+	return nil
+}
+
+// 条件更新没有影响任何行时，物品已被并发事务取走
+func affectedOrNotFound(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrItemNotFound
+	}
+	return nil
+}
+
+// Reserve 在事务中预留物品
+func (r *SQLItemRepository) Reserve(pickupCode, claimerID, token string, until time.Time) (*Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := r.clock.Now()
+	expired, err := deleteExpiredByCode(tx, pickupCode, now)
+	if err != nil {
+		return nil, err
+	}
+
+	item, rowID, err := findByPickupCode(tx, pickupCode)
+	if err != nil {
+		return nil, err
+	}
+	reserveErr := checkPending(item, now)
+	if reserveErr == nil {
+		item = item.visibleAt(now).reserved(claimerID, token, until)
+		result, err := tx.Exec(`UPDATE items SET is_claimed = 1, claimer_id = ?, reservation_token = ?, reserved_until = ?
+			WHERE row_id = ? AND `+pendingCondition,
+			claimerID, token, item.Reservation.ExpiresAt.UnixNano(), rowID, now.UnixNano())
+		if err != nil {
+			return nil, err
+		}
+		reserveErr = affectedOrNotFound(result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	r.notifyExpired(expired)
+	if reserveErr != nil {
+		return nil, reserveErr
+	}
+	return item, nil
+}
+
+// 查找令牌匹配且未过期的预留
+func findReservation(q sqlQuerier, pickupCode, token string, now time.Time) (*Item, int64, error) {
+	row := q.QueryRow(`SELECT `+itemColumns+` FROM items
+		WHERE pickup_code = ? AND reservation_token = ? AND reservation_token != '' AND reserved_until >= ?`,
+		pickupCode, token, now.UnixNano())
+	item, rowID, err := scanItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, ErrReservationNotFound
+	}
+	return item, rowID, err
+}
+
+// Confirm 确认预留，物品从表中删除
+func (r *SQLItemRepository) Confirm(pickupCode, token string) (*Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	item, rowID, err := findReservation(tx, pickupCode, token, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`DELETE FROM items WHERE row_id = ?`, rowID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	item.Reservation = nil
+	return item, nil
+}
+
+// Release 取消预留，物品恢复为未领取
+func (r *SQLItemRepository) Release(pickupCode, token string) (*Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	item, rowID, err := findReservation(tx, pickupCode, token, r.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(releaseReservationSQL+` WHERE row_id = ?`, rowID); err != nil {
+		return nil, err
+	}
+	return item, tx.Commit()
+}
+
+// 将预留恢复为未领取
+const releaseReservationSQL = `UPDATE items SET is_claimed = 0, claimer_id = '', reservation_token = '', reserved_until = 0`
+
+// ReleaseExpiredReservations 通过预留时间索引恢复预留已过期的物品
+func (r *SQLItemRepository) ReleaseExpiredReservations() ([]*Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := r.clock.Now().UnixNano()
+	rows, err := tx.Query(`SELECT `+itemColumns+` FROM items WHERE reservation_token != '' AND reserved_until < ?`, now)
+	if err != nil {
+		return nil, err
+	}
+	var released []*Item
+	for rows.Next() {
+		item, _, err := scanItem(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		released = append(released, item)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(released) == 0 {
+		return nil, nil
+	}
+	if _, err := tx.Exec(releaseReservationSQL+` WHERE reservation_token != '' AND reserved_until < ?`, now); err != nil {
+		return nil, err
+	}
+	return released, tx.Commit()
+}
+
+// Update 更新取件码当前对应的物品
+func (r *SQLItemRepository) Update(item *Item) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	existing, rowID, err := findByPickupCode(tx, item.PickupCode)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrItemNotFound
+	}
+	token, reservedUntil := reservationColumns(item)
+	_, err = tx.Exec(`UPDATE items SET id = ?, name = ?, description = ?, type_id = ?, num = ?, durability = ?,
+		sharer_id = ?, created_at = ?, expires_at = ?, is_claimed = ?, claimer_id = ?, reservation_token = ?,
+		reserved_until = ?, listing_id = ?, group_id = ? WHERE row_id = ?`,
+		item.ID, item.Name, item.Description, item.TypeID, item.Num, item.Durability, item.SharerID,
+		item.CreatedAt.UnixNano(), item.ExpiresAt.UnixNano(), boolToInt(item.IsClaimed), item.ClaimerID,
+		token, reservedUntil, item.ListingID, item.GroupID, rowID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicatePickupCode
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// Delete 删除取件码下的所有物品
+func (r *SQLItemRepository) Delete(pickupCode string) error {
+	_, err := r.db.Exec(`DELETE FROM items WHERE pickup_code = ?`, pickupCode)
+	return err
+}
+
+// DeleteExpired 通过过期时间索引分批删除过期物品，并交给过期回调
+func (r *SQLItemRepository) DeleteExpired() error {
+	now := r.clock.Now().UnixNano()
+	for {
+		expired, err := r.deleteExpiredBatch(now)
+		if err != nil {
+			return err
+		}
+		r.notifyExpired(expired)
+		if len(expired) < expireBatchSize {
+			return nil
+		}
+	}
+}
+
+// 删除一批过期物品
+func (r *SQLItemRepository) deleteExpiredBatch(now int64) ([]*Item, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT `+itemColumns+` FROM items WHERE expires_at < ?
+		ORDER BY expires_at LIMIT ?`, now, expireBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		expired []*Item
+		rowIDs  []interface{}
+	)
+	for rows.Next() {
+		item, rowID, err := scanItem(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		// 预留不会超过物品的过期时间，过期物品以未领取状态交给回调
+		expired = append(expired, item.visibleAt(time.Unix(0, now)))
+		rowIDs = append(rowIDs, rowID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(rowIDs) == 0 {
+		return nil, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(rowIDs)), ",")
+	if _, err := tx.Exec(`DELETE FROM items WHERE row_id IN (`+placeholders+`)`, rowIDs...); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return expired, nil
+}
+
+// GetAll 获取所有未过期的物品
+func (r *SQLItemRepository) GetAll() []*Item {
+	items := make([]*Item, 0)
+	rows, err := r.db.Query(`SELECT `+itemColumns+` FROM items WHERE expires_at >= ? ORDER BY created_at`,
+		r.clock.Now().UnixNano())
+	if err != nil {
+		return items
+	}
+	defer rows.Close()
+	now := r.clock.Now()
+	for rows.Next() {
+		item, _, err := scanItem(rows)
+		if err != nil {
+			return items
+		}
+		items = append(items, item.visibleAt(now))
+	}
+	return items
+}
+
+// 排序字段对应的列
+var sqlSortColumns = map[ItemSort]string{
+	SortByCreatedAt:  "created_at",
+	SortByExpiresAt:  "expires_at",
+	SortByDurability: "durability",
+}
+
+// 将查询中可由数据库处理的条件转为 WHERE 子句
+func sqlQueryConditions(q *ItemQuery, now time.Time) ([]string, []interface{}) {
+	conds := []string{"expires_at >= ?"}
+	args := []interface{}{now.UnixNano()}
+	add := func(cond string, arg ...interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg...)
+	}
+	if !q.IncludeReserved {
+		add(pendingCondition, now.UnixNano())
+	}
+	if q.TypeID != 0 {
+		add("type_id = ?", q.TypeID)
+	}
+	if q.SharerID != "" {
+		add("sharer_id = ?", q.SharerID)
+	}
+	if q.ListingID != "" {
+		add("listing_id = ?", q.ListingID)
+	}
+	if q.ListedOnly {
+		add("listing_id != ''")
+	}
+	if q.GroupID != "" {
+		add("group_id = ?", q.GroupID)
+	}
+	if !q.CreatedAfter.IsZero() {
+		add("created_at >= ?", q.CreatedAfter.UnixNano())
+	}
+	if !q.CreatedBefore.IsZero() {
+		add("created_at <= ?", q.CreatedBefore.UnixNano())
+	}
+	if !q.ExpiresAfter.IsZero() {
+		add("expires_at >= ?", q.ExpiresAfter.UnixNano())
+	}
+	if !q.ExpiresBefore.IsZero() {
+		add("expires_at <= ?", q.ExpiresBefore.UnixNano())
+	}
+	if q.MinDurability != nil {
+		add("durability >= ?", *q.MinDurability)
+	}
+	if q.MaxDurability != nil {
+		add("durability <= ?", *q.MaxDurability)
+	}
+	return conds, args
+}
+
+// Query 结构化条件由数据库筛选，并按排序列和取件码做键集分页
+// 名称和全文检索在读取后筛选，筛掉的行较多时分批继续读取，直到凑满一页
+func (r *SQLItemRepository) Query(q ItemQuery) (*ItemPage, error) {
+	cursor, err := q.normalize()
+	if err != nil {
+		return nil, err
+	}
+	now := r.clock.Now()
+	terms := searchTerms(q.Text)
+	column := sqlSortColumns[q.SortBy]
+	order, op := "ASC", ">"
+	if q.Descending {
+		order, op = "DESC", "<"
+	}
+	conds, args := sqlQueryConditions(&q, now)
+	batch := q.Limit + 1
+
+	matched := make([]*Item, 0, batch)
+	for len(matched) < batch {
+		where, whereArgs := conds, args
+		if cursor != nil {
+			var key interface{} = cursor.Time
+			if q.SortBy == SortByDurability {
+				key = cursor.Durability
+			}
+			where = append(where[:len(where):len(where)],
+				fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND pickup_code %[2]s ?))", column, op))
+			whereArgs = append(whereArgs[:len(whereArgs):len(whereArgs)], key, key, cursor.PickupCode)
+		}
+		rows, err := r.db.Query(fmt.Sprintf(`SELECT `+itemColumns+` FROM items WHERE %s
+			ORDER BY %s %s, pickup_code %s LIMIT ?`, strings.Join(where, " AND "), column, order, order),
+			append(whereArgs, batch)...)
+		if err != nil {
+			return nil, err
+		}
+		fetched := 0
+		for rows.Next() {
+			item, _, err := scanItem(rows)
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+			fetched++
+			last := q.cursorOf(item)
+			cursor = &last
+			if q.matches(item, terms, now) {
+				matched = append(matched, item.visibleAt(now))
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		if fetched < batch {
+			break
+		}
+	}
+	return q.page(matched), nil
+}
+
+// SetExpiredHandler 设置物品过期时的回调
+func (r *SQLItemRepository) SetExpiredHandler(handler ExpiredHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.onExpired = handler
+}
+
+// 判断是否违反唯一约束（兼容常见驱动的错误信息）
+func isUniqueViolation(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "unique constraint") || strings.Contains(msg, "duplicate")
+}
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// SQLTradeRepository 基于 database/sql 的交易仓库，与 SQLItemRepository 共用数据库
+type SQLTradeRepository struct {
+	db *sql.DB
+}
+
+// NewSQLTradeRepository 创建新的SQL交易仓库，并将数据库结构迁移到最新版本
+// clk 为记录迁移执行时间使用的时间源，nil 时使用系统时间
+func NewSQLTradeRepository(db *sql.DB, clk clock.Clock) (*SQLTradeRepository, error) {
+	if err := Migrate(db, clk); err != nil {
+		return nil, err
+	}
+	return &SQLTradeRepository{db: db}, nil
+}
+
+// 交易中用于查询的列
+func tradeColumns(trade *Trade) (data string, closedAt int64, err error) {
+	encoded, err := json.Marshal(trade)
+	if err != nil {
+		return "", 0, err
+	}
+	if trade.ClosedAt != nil {
+		closedAt = trade.ClosedAt.UnixNano()
+	}
+	return string(encoded), closedAt, nil
+}
+
+// 读取交易JSON
+func scanTrade(row sqlScanner) (*Trade, error) {
+	var data string
+	if err := row.Scan(&data); err != nil {
+		return nil, err
+	}
+	var trade Trade
+	if err := json.Unmarshal([]byte(data), &trade); err != nil {
+		return nil, err
+	}
+	return &trade, nil
+}
+
+// Create 保存新交易
+func (r *SQLTradeRepository) Create(trade *Trade) error {
+	data, closedAt, err := tradeColumns(trade)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`INSERT INTO trades (id, initiator_id, counterparty_id, acceptor_id, status, data,
+		created_at, expires_at, closed_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		trade.ID, trade.InitiatorID, trade.CounterpartyID, trade.AcceptorID, string(trade.Status), data,
+		trade.CreatedAt.UnixNano(), trade.ExpiresAt.UnixNano(), closedAt)
+	if err != nil && isUniqueViolation(err) {
+		return ErrDuplicateTradeID
+	}
+	return err
+}
+
+// Get 按ID获取交易
+func (r *SQLTradeRepository) Get(id string) (*Trade, error) {
+	trade, err := scanTrade(r.db.QueryRow(`SELECT data FROM trades WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return trade, err
+}
+
+// List 返回玩家参与的全部交易
+func (r *SQLTradeRepository) List(playerID string) ([]*Trade, error) {
+	rows, err := r.db.Query(`SELECT data FROM trades
+		WHERE initiator_id = ? OR counterparty_id = ? OR acceptor_id = ?
+		ORDER BY created_at DESC, id`, playerID, playerID, playerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanTrades(rows)
+}
+
+func scanTrades(rows *sql.Rows) ([]*Trade, error) {
+	trades := make([]*Trade, 0)
+	for rows.Next() {
+		trade, err := scanTrade(rows)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, rows.Err()
+}
+
+// 写回交易，状态与读取时不同说明发生了并发修改
+func saveTrade(q sqlQuerier, trade *Trade, previous TradeStatus) error {
+	data, closedAt, err := tradeColumns(trade)
+	if err != nil {
+		return err
+	}
+	result, err := q.Exec(`UPDATE trades SET acceptor_id = ?, status = ?, data = ?, closed_at = ?
+		WHERE id = ? AND status = ?`,
+		trade.AcceptorID, string(trade.Status), data, closedAt, trade.ID, string(previous))
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrTradeNotOpen
+	}
+	return nil
+}
+
+// 在事务中读取交易并执行状态转换
+func (r *SQLTradeRepository) transition(id string, update func(*Trade) error) (*Trade, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	trade, err := scanTrade(tx.QueryRow(`SELECT data FROM trades WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTradeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	previous := trade.Status
+	if err := update(trade); err != nil {
+		return nil, err
+	}
+	if err := saveTrade(tx, trade, previous); err != nil {
+		return nil, err
+	}
+	return trade, tx.Commit()
+}
+
+// Accept 接受交易
+func (r *SQLTradeRepository) Accept(id, acceptorID string, deposit []TradeItem, now time.Time) (*Trade, error) {
+	return r.transition(id, acceptTrade(acceptorID, deposit, now))
+}
+
+// Complete 完成交易
+func (r *SQLTradeRepository) Complete(id string, initiatorCodes, acceptorCodes []string, now time.Time) (*Trade, error) {
+	return r.transition(id, completeTrade(initiatorCodes, acceptorCodes, now))
+}
+
+// Reopen 恢复为 open
+func (r *SQLTradeRepository) Reopen(id string) (*Trade, error) {
+	return r.transition(id, reopenTrade)
+}
+
+// Cancel 取消交易
+func (r *SQLTradeRepository) Cancel(id, initiatorID string, now time.Time) (*Trade, error) {
+	return r.transition(id, cancelTrade(initiatorID, now))
+}
+
+// ExpireOpen 通过超时时间索引将超时的 open 交易转为 expired
+func (r *SQLTradeRepository) ExpireOpen(now time.Time) ([]*Trade, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT data FROM trades WHERE status = 'open' AND expires_at < ?
+		ORDER BY created_at DESC, id`, now.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	expired, err := scanTrades(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	for _, trade := range expired {
+		trade.Status = TradeExpired
+		trade.ClosedAt = &now
+		if err := saveTrade(tx, trade, TradeOpen); err != nil {
+			return nil, err
+		}
+	}
+	return expired, tx.Commit()
+}
+
+// DeleteClosed 删除已结束的旧交易
+func (r *SQLTradeRepository) DeleteClosed(before time.Time) (int, error) {
+	result, err := r.db.Exec(`DELETE FROM trades WHERE closed_at != 0 AND closed_at < ?`, before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,91 @@
+package test
+
+import (
+	"testing"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/models/repotest"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestInMemoryItemRepositoryConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) models.ItemRepository {
+		return models.NewInMemoryItemRepository(nil)
+	})
+}
+
+func TestShardedItemRepositoryConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) models.ItemRepository {
+		return models.NewShardedItemRepository(8, nil)
+	})
+}
+
+func TestSQLItemRepositoryConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) models.ItemRepository {
+		repo, _ := newSQLiteRepository(t)
+		return repo
+	})
+}
+
+func TestRedisItemRepositoryConformance(t *testing.T) {
+	repotest.RunConformance(t, func(t *testing.T) models.ItemRepository {
+		repo, _ := newRedisRepository(t)
+		return repo
+	})
+}
+
+func TestInMemoryTradeRepositoryConformance(t *testing.T) {
+	repotest.RunTradeConformance(t, func(t *testing.T) models.TradeRepository {
+		return models.NewInMemoryTradeRepository()
+	})
+}
+
+func TestSQLTradeRepositoryConformance(t *testing.T) {
+	repotest.RunTradeConformance(t, func(t *testing.T) models.TradeRepository {
+		_, db := newSQLiteRepository(t)
+		repo, err := models.NewSQLTradeRepository(db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+func TestInMemoryGroupRepositoryConformance(t *testing.T) {
+	repotest.RunGroupConformance(t, func(t *testing.T) models.GroupRepository {
+		return models.NewInMemoryGroupRepository()
+	})
+}
+
+func TestSQLGroupRepositoryConformance(t *testing.T) {
+	repotest.RunGroupConformance(t, func(t *testing.T) models.GroupRepository {
+		_, db := newSQLiteRepository(t)
+		repo, err := models.NewSQLGroupRepository(db, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return repo
+	})
+}
+
+// 创建连接到进程内 miniredis 的客户端
+func newRedisClient(t *testing.T) *redis.Client {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestRedisTradeRepositoryConformance(t *testing.T) {
+	repotest.RunTradeConformance(t, func(t *testing.T) models.TradeRepository {
+		return models.NewRedisTradeRepository(newRedisClient(t), "duckex:test:")
+	})
+}
+
+func TestRedisGroupRepositoryConformance(t *testing.T) {
+	repotest.RunGroupConformance(t, func(t *testing.T) models.GroupRepository {
+		return models.NewRedisGroupRepository(newRedisClient(t), "duckex:test:")
+	})
+}
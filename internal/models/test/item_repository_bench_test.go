@@ -0,0 +1,202 @@
+package test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/models"
+)
+
+// 基准测试使用的仓库规模
+const benchRepoSize = 1000000
+
+// 创建包含 n 个物品的仓库，过期时间在 [base, base+spread) 内均匀分布
+func newBenchRepository(b *testing.B, n int, base time.Time, spread time.Duration) *models.InMemoryItemRepository {
+	return newBenchRepositoryWithClock(b, n, base, spread, nil)
+}
+
+// 使用指定时间源创建包含 n 个物品的仓库
+func newBenchRepositoryWithClock(b *testing.B, n int, base time.Time, spread time.Duration, clk clock.Clock) *models.InMemoryItemRepository {
+	b.Helper()
+	repo := models.NewInMemoryItemRepository(clk)
+	step := spread / time.Duration(n)
+	for i := 0; i < n; i++ {
+		repo.Create(&models.Item{
+			ID:         fmt.Sprintf("bench-%d", i),
+			PickupCode: fmt.Sprintf("%07d", i),
+			ExpiresAt:  base.Add(time.Duration(i) * step),
+		})
+	}
+	return repo
+}
+
+func BenchmarkInMemoryCreate(b *testing.B) {
+	repo := models.NewInMemoryItemRepository(nil)
+	expiresAt := time.Now().Add(24 * time.Hour)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo.Create(&models.Item{
+			PickupCode: fmt.Sprintf("%09d", i),
+			ExpiresAt:  expiresAt.Add(time.Duration(i%3600) * time.Second),
+		})
+	}
+}
+
+// 1M 物品时读取单个物品的开销
+func BenchmarkInMemoryGetByPickupCode1M(b *testing.B) {
+	repo := newBenchRepository(b, benchRepoSize, time.Now().Add(time.Hour), 24*time.Hour)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo.GetByPickupCode(fmt.Sprintf("%07d", i%benchRepoSize))
+	}
+}
+
+// 1M 物品且没有到期物品时，一次过期检查只需查看堆顶
+func BenchmarkInMemoryDeleteExpiredNoneDue1M(b *testing.B) {
+	repo := newBenchRepository(b, benchRepoSize, time.Now().Add(time.Hour), 24*time.Hour)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo.DeleteExpired()
+	}
+}
+
+// 1M 物品中每秒有少量到期时的增量清理（模拟每秒运行的过期任务）
+func BenchmarkInMemoryDeleteExpiredIncremental1M(b *testing.B) {
+	base := time.Now()
+	clk := clock.NewFake(base)
+	// 1M 物品在 24 小时内均匀过期，约每秒 11 个
+	repo := newBenchRepositoryWithClock(b, benchRepoSize, base, 24*time.Hour, clk)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clk.Advance(time.Second)
+		repo.DeleteExpired()
+	}
+}
+
+// 1M 物品全部到期时一次清理的总开销
+func BenchmarkInMemoryDeleteExpiredAllDue1M(b *testing.B) {
+	base := time.Now().Add(-48 * time.Hour)
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		repo := newBenchRepository(b, benchRepoSize, base, 24*time.Hour)
+		b.StartTimer()
+		repo.DeleteExpired()
+	}
+}
+
+// 1M 物品时获取全部未过期物品的开销
+func BenchmarkInMemoryGetAll1M(b *testing.B) {
+	repo := newBenchRepository(b, benchRepoSize, time.Now().Add(time.Hour), 24*time.Hour)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo.GetAll()
+	}
+}
+
+// 创建 1M 物品的仓库用于查询，类型ID在 100 个取值中循环，名称各不相同
+func newQueryBenchRepository(b *testing.B) *models.InMemoryItemRepository {
+	b.Helper()
+	repo := models.NewInMemoryItemRepository(nil)
+	now := time.Now()
+	for i := 0; i < benchRepoSize; i++ {
+		repo.Create(&models.Item{
+			ID:         fmt.Sprintf("bench-%d", i),
+			Name:       fmt.Sprintf("Duck %d", i),
+			TypeID:     i % 100,
+			PickupCode: fmt.Sprintf("%07d", i),
+			CreatedAt:  now.Add(time.Duration(i) * time.Millisecond),
+			ExpiresAt:  now.Add(24 * time.Hour),
+		})
+	}
+	return repo
+}
+
+// 1M 物品时按类型查询一页：从类型索引取得 1% 的候选
+func BenchmarkInMemoryQueryByType1M(b *testing.B) {
+	repo := newQueryBenchRepository(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo.Query(models.ItemQuery{TypeID: 42, Limit: 20})
+	}
+}
+
+// 1M 物品时全文检索一页：从倒排索引取得唯一的候选
+func BenchmarkInMemoryQueryText1M(b *testing.B) {
+	repo := newQueryBenchRepository(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		repo.Query(models.ItemQuery{Text: "duck 123456", Limit: 20})
+	}
+}
+
+// 并发基准测试使用的预置物品数量
+const benchParallelSize = 100000
+
+// 预置物品供并发读写
+func fillRepository(repo models.ItemRepository, n int) {
+	expiresAt := time.Now().Add(24 * time.Hour)
+	for i := 0; i < n; i++ {
+		repo.Create(&models.Item{
+			ID:         fmt.Sprintf("bench-%d", i),
+			PickupCode: fmt.Sprintf("%07d", i),
+			ExpiresAt:  expiresAt,
+		})
+	}
+}
+
+// 混合负载：约 80% 读取、10% 创建、10% 删除，模拟活动日的分享与领取
+func runParallelMixed(b *testing.B, repo models.ItemRepository) {
+	fillRepository(repo, benchParallelSize)
+	expiresAt := time.Now().Add(24 * time.Hour)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			code := fmt.Sprintf("%07d", i%benchParallelSize)
+			switch i % 10 {
+			case 0:
+				repo.Create(&models.Item{PickupCode: code, ExpiresAt: expiresAt})
+			case 1:
+				repo.Delete(code)
+			default:
+				repo.GetByPickupCode(code)
+			}
+			i++
+		}
+	})
+}
+
+// 只读负载
+func runParallelRead(b *testing.B, repo models.ItemRepository) {
+	fillRepository(repo, benchParallelSize)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			repo.GetByPickupCode(fmt.Sprintf("%07d", i%benchParallelSize))
+			i++
+		}
+	})
+}
+
+func BenchmarkParallelMixedInMemory(b *testing.B) {
+	runParallelMixed(b, models.NewInMemoryItemRepository(nil))
+}
+
+func BenchmarkParallelMixedSharded16(b *testing.B) {
+	runParallelMixed(b, models.NewShardedItemRepository(16, nil))
+}
+
+func BenchmarkParallelMixedSharded64(b *testing.B) {
+	runParallelMixed(b, models.NewShardedItemRepository(64, nil))
+}
+
+func BenchmarkParallelReadInMemory(b *testing.B) {
+	runParallelRead(b, models.NewInMemoryItemRepository(nil))
+}
+
+func BenchmarkParallelReadSharded16(b *testing.B) {
+	runParallelRead(b, models.NewShardedItemRepository(16, nil))
+}
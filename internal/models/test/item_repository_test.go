@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"duckex-server/internal/clock"
 	"duckex-server/internal/models"
 	"duckex-server/internal/utils"
 
@@ -13,10 +14,11 @@ import (
 )
 
 func TestInMemoryItemRepository(t *testing.T) {
-	repo := models.NewInMemoryItemRepository()
+	repo := models.NewInMemoryItemRepository(nil)
+	codes := utils.NewPickupCodeGenerator(nil)
 
 	// 测试创建物品
-	pickupCode := utils.GeneratePickupCode()
+	pickupCode := codes.Generate()
 	item := &models.Item{
 		ID:          "test-item-1",
 		Name:        "Test Item",
@@ -27,7 +29,7 @@ func TestInMemoryItemRepository(t *testing.T) {
 		SharerID:    "test-sharer",
 		PickupCode:  pickupCode,
 		CreatedAt:   time.Now(),
-		ExpiresAt:   utils.GetExpirationTime(),
+		ExpiresAt:   time.Now().Add(utils.PickupCodeTTL),
 		IsClaimed:   false,
 	}
 
@@ -62,8 +64,14 @@ func TestInMemoryItemRepository(t *testing.T) {
 	assert.Equal(t, "test-claimer", updatedItem.ClaimerID)
 
 	// 测试删除过期物品
+	// 注册过期回调，记录被移出仓库的物品
+	var expiredItems []*models.Item
+	repo.SetExpiredHandler(func(item *models.Item) {
+		expiredItems = append(expiredItems, item)
+	})
+
 	// 创建一个过期物品
-	expiredPickupCode := utils.GeneratePickupCode()
+	expiredPickupCode := codes.Generate()
 	expiredItem := &models.Item{
 		ID:          "test-item-expired",
 		Name:        "Expired Item",
@@ -80,15 +88,14 @@ func TestInMemoryItemRepository(t *testing.T) {
 	err = repo.Create(expiredItem)
 	assert.NoError(t, err)
 
-	// 验证过期物品已创建
-	expiredRetrieved, err := repo.GetByPickupCode(expiredPickupCode)
-	assert.NoError(t, err)
-	assert.NotNil(t, expiredRetrieved)
-
 	// 删除过期物品
 	err = repo.DeleteExpired()
 	assert.NoError(t, err)
 
+	// 验证过期物品交给了过期回调
+	assert.Len(t, expiredItems, 1)
+	assert.Equal(t, expiredItem.ID, expiredItems[0].ID)
+
 	// 验证过期物品已被删除
 	expiredRetrievedAfterDelete, err := repo.GetByPickupCode(expiredPickupCode)
 	assert.NoError(t, err)
@@ -100,8 +107,42 @@ func TestInMemoryItemRepository(t *testing.T) {
 	assert.NotNil(t, stillExists)
 }
 
+func TestInMemoryItemRepositoryExpireOnRead(t *testing.T) {
+	repo := models.NewInMemoryItemRepository(nil)
+	codes := utils.NewPickupCodeGenerator(nil)
+	var expiredItems []*models.Item
+	repo.SetExpiredHandler(func(item *models.Item) {
+		expiredItems = append(expiredItems, item)
+	})
+
+	// 创建一个已过期的物品
+	pickupCode := codes.Generate()
+	err := repo.Create(&models.Item{
+		ID:         "test-item-expire-on-read",
+		Name:       "Expired Item",
+		SharerID:   "test-sharer",
+		PickupCode: pickupCode,
+		CreatedAt:  time.Now().Add(-48 * time.Hour),
+		ExpiresAt:  time.Now().Add(-time.Hour),
+	})
+	assert.NoError(t, err)
+
+	// 读取时发现过期，应返回nil并交给过期回调
+	item, err := repo.GetByPickupCode(pickupCode)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Len(t, expiredItems, 1)
+
+	// 再次读取不应重复触发回调
+	item, err = repo.GetByPickupCode(pickupCode)
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Len(t, expiredItems, 1)
+}
+
 func TestInMemoryItemRepositoryConcurrentAccess(t *testing.T) {
-	repo := models.NewInMemoryItemRepository()
+	repo := models.NewInMemoryItemRepository(nil)
+	codes := utils.NewPickupCodeGenerator(nil)
 	var wg sync.WaitGroup
 	errChan := make(chan error, 100)
 	mutex := &sync.Mutex{}
@@ -111,7 +152,7 @@ func TestInMemoryItemRepositoryConcurrentAccess(t *testing.T) {
 		wg.Add(1)
 		go func(index int) {
 			defer wg.Done()
-			pickupCode := utils.GeneratePickupCode()
+			pickupCode := codes.Generate()
 			item := &models.Item{
 				ID:          fmt.Sprintf("test-item-concurrent-%d", index),
 				Name:        fmt.Sprintf("Concurrent Item %d", index),
@@ -122,7 +163,7 @@ func TestInMemoryItemRepositoryConcurrentAccess(t *testing.T) {
 				SharerID:    fmt.Sprintf("test-sharer-%d", index),
 				PickupCode:  pickupCode,
 				CreatedAt:   time.Now(),
-				ExpiresAt:   utils.GetExpirationTime(),
+				ExpiresAt:   time.Now().Add(utils.PickupCodeTTL),
 				IsClaimed:   false,
 			}
 
@@ -171,4 +212,58 @@ func TestInMemoryItemRepositoryConcurrentAccess(t *testing.T) {
 		}
 	}
 	assert.Greater(t, claimedCount, 0)
-}
\ No newline at end of file
+}
+func TestInMemoryItemRepositoryExpiryIndex(t *testing.T) {
+	now := time.Now()
+	clk := clock.NewFake(now)
+	repo := models.NewInMemoryItemRepository(clk)
+
+	// 空仓库没有下一个过期时间
+	_, ok := repo.NextExpiry()
+	assert.False(t, ok)
+
+	for i, offset := range []time.Duration{3 * time.Hour, time.Hour, 2 * time.Hour} {
+		err := repo.Create(&models.Item{
+			ID:         fmt.Sprintf("item-%d", i),
+			PickupCode: fmt.Sprintf("00000%d", i),
+			ExpiresAt:  now.Add(offset),
+		})
+		assert.NoError(t, err)
+	}
+
+	// 最早的过期时间来自 item-1
+	next, ok := repo.NextExpiry()
+	assert.True(t, ok)
+	assert.Equal(t, now.Add(time.Hour), next)
+
+	// 更新过期时间后索引随之调整
+	item, err := repo.GetByPickupCode("000001")
+	assert.NoError(t, err)
+	updated := *item
+	updated.ExpiresAt = now.Add(4 * time.Hour)
+	assert.NoError(t, repo.Update(&updated))
+	next, _ = repo.NextExpiry()
+	assert.Equal(t, now.Add(2*time.Hour), next)
+
+	// 删除后索引中也不再包含
+	assert.NoError(t, repo.Delete("000002"))
+	next, _ = repo.NextExpiry()
+	assert.Equal(t, now.Add(3*time.Hour), next)
+
+	// 模拟时间流逝，按过期顺序逐个清理
+	var expiredIDs []string
+	repo.SetExpiredHandler(func(item *models.Item) {
+		expiredIDs = append(expiredIDs, item.ID)
+	})
+
+	clk.Set(now.Add(3*time.Hour + time.Minute))
+	assert.NoError(t, repo.DeleteExpired())
+	assert.Equal(t, []string{"item-0"}, expiredIDs)
+	assert.Len(t, repo.GetAll(), 1)
+
+	clk.Set(now.Add(5 * time.Hour))
+	assert.Empty(t, repo.GetAll())
+	assert.Equal(t, []string{"item-0", "item-1"}, expiredIDs)
+	_, ok = repo.NextExpiry()
+	assert.False(t, ok)
+}
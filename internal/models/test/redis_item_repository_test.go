@@ -0,0 +1,269 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 创建连接到进程内 miniredis 的仓库
+func newRedisRepository(t *testing.T) (*models.RedisItemRepository, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return models.NewRedisItemRepository(client, "duckex:test:", nil), server
+}
+
+func TestRedisItemRepository(t *testing.T) {
+	repo, server := newRedisRepository(t)
+	now := time.Now()
+
+	item := &models.Item{
+		ID:         "test-item-1",
+		Name:       "Test Item",
+		TypeID:     123,
+		Num:        2,
+		Durability: 95.5,
+		SharerID:   "test-sharer",
+		PickupCode: "123456",
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(time.Hour),
+	}
+	require.NoError(t, repo.Create(item))
+
+	// 键带有前缀并设置了TTL兜底
+	assert.True(t, server.Exists("duckex:test:item:123456"))
+	assert.Greater(t, server.TTL("duckex:test:item:123456"), time.Hour)
+
+	retrieved, err := repo.GetByPickupCode("123456")
+	require.NoError(t, err)
+	require.NotNil(t, retrieved)
+	assert.Equal(t, item.Name, retrieved.Name)
+	assert.Equal(t, item.Durability, retrieved.Durability)
+
+	// 未过期的取件码不能重复使用
+	err = repo.Create(&models.Item{ID: "dup", PickupCode: "123456", ExpiresAt: now.Add(time.Hour)})
+	assert.ErrorIs(t, err, models.ErrDuplicatePickupCode)
+
+	// 更新物品
+	retrieved.Name = "Renamed"
+	require.NoError(t, repo.Update(retrieved))
+	retrieved, _ = repo.GetByPickupCode("123456")
+	assert.Equal(t, "Renamed", retrieved.Name)
+	assert.ErrorIs(t, repo.Update(&models.Item{PickupCode: "000000"}), models.ErrItemNotFound)
+
+	// 领取后物品被删除
+	claimed, err := repo.Claim("123456", "claimer")
+	require.NoError(t, err)
+	assert.True(t, claimed.IsClaimed)
+	assert.Equal(t, "claimer", claimed.ClaimerID)
+	_, err = repo.Claim("123456", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+	assert.False(t, server.Exists("duckex:test:item:123456"))
+
+	// 已标记领取的物品不能再次领取
+	require.NoError(t, repo.Create(&models.Item{ID: "claimed", PickupCode: "111111", ExpiresAt: now.Add(time.Hour), IsClaimed: true}))
+	_, err = repo.Claim("111111", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemClaimed)
+
+	// 删除
+	require.NoError(t, repo.Delete("111111"))
+	retrieved, _ = repo.GetByPickupCode("111111")
+	assert.Nil(t, retrieved)
+}
+
+func TestRedisItemRepositoryExpiry(t *testing.T) {
+	repo, _ := newRedisRepository(t)
+	now := time.Now()
+
+	var mu sync.Mutex
+	var expiredIDs []string
+	repo.SetExpiredHandler(func(item *models.Item) {
+		mu.Lock()
+		expiredIDs = append(expiredIDs, item.ID)
+		mu.Unlock()
+	})
+
+	for i := 0; i < 10; i++ {
+		expiresAt := now.Add(time.Hour)
+		if i%2 == 0 {
+			expiresAt = now.Add(-time.Duration(i+1) * time.Minute)
+		}
+		require.NoError(t, repo.Create(&models.Item{
+			ID:         fmt.Sprintf("item-%d", i),
+			PickupCode: fmt.Sprintf("%06d", i),
+			ExpiresAt:  expiresAt,
+		}))
+	}
+	assert.Len(t, repo.GetAll(), 5)
+
+	// 读取过期物品时删除并交给过期回调
+	item, err := repo.GetByPickupCode("000000")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Equal(t, []string{"item-0"}, expiredIDs)
+
+	// 过期物品的取件码可以被新物品使用，旧物品按过期处理
+	require.NoError(t, repo.Create(&models.Item{ID: "reuse", PickupCode: "000002", ExpiresAt: now.Add(time.Hour)}))
+	assert.Equal(t, []string{"item-0", "item-2"}, expiredIDs)
+
+	// 领取过期物品按过期处理
+	_, err = repo.Claim("000004", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+	assert.Equal(t, []string{"item-0", "item-2", "item-4"}, expiredIDs)
+
+	// 按过期时间顺序清理剩余的过期物品
+	require.NoError(t, repo.DeleteExpired())
+	assert.Equal(t, []string{"item-0", "item-2", "item-4", "item-8", "item-6"}, expiredIDs)
+	assert.Len(t, repo.GetAll(), 6)
+}
+
+func TestRedisItemRepositoryMultiInstance(t *testing.T) {
+	server := miniredis.RunT(t)
+	now := time.Now()
+
+	// 两个实例共享同一个 Redis
+	newInstance := func() *models.RedisItemRepository {
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return models.NewRedisItemRepository(client, "duckex:", nil)
+	}
+	first, second := newInstance(), newInstance()
+
+	// 一个实例分享，另一个实例可以领取
+	require.NoError(t, first.Create(&models.Item{ID: "shared", PickupCode: "123456", ExpiresAt: now.Add(time.Hour)}))
+	claimed, err := second.Claim("123456", "claimer")
+	require.NoError(t, err)
+	assert.Equal(t, "shared", claimed.ID)
+
+	// 并发领取同一物品只有一个成功
+	require.NoError(t, first.Create(&models.Item{ID: "contested", PickupCode: "654321", ExpiresAt: now.Add(time.Hour)}))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successCount := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(repo *models.RedisItemRepository, index int) {
+			defer wg.Done()
+			if _, err := repo.Claim("654321", fmt.Sprintf("claimer-%d", index)); err == nil {
+				mu.Lock()
+				successCount++
+				mu.Unlock()
+			}
+		}([]*models.RedisItemRepository{first, second}[i%2], i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, successCount)
+
+	// 两个实例同时清理时，每个过期物品只移交一次
+	var expiredCount int
+	handler := func(item *models.Item) {
+		mu.Lock()
+		expiredCount++
+		mu.Unlock()
+	}
+	first.SetExpiredHandler(handler)
+	second.SetExpiredHandler(handler)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, first.Create(&models.Item{
+			ID:         fmt.Sprintf("expired-%d", i),
+			PickupCode: fmt.Sprintf("9%05d", i),
+			ExpiresAt:  now.Add(-time.Minute),
+		}))
+	}
+	wg.Add(2)
+	go func() { defer wg.Done(); first.DeleteExpired() }()
+	go func() { defer wg.Done(); second.DeleteExpired() }()
+	wg.Wait()
+	assert.Equal(t, 20, expiredCount)
+}
+
+func TestRedisItemRepositoryIndexes(t *testing.T) {
+	repo, server := newRedisRepository(t)
+	now := time.Now()
+
+	create := func(code, sharerID, groupID, listingID string, expiresAt time.Time) {
+		require.NoError(t, repo.Create(&models.Item{
+			ID: "item-" + code, PickupCode: code, SharerID: sharerID, GroupID: groupID, ListingID: listingID,
+			CreatedAt: now, ExpiresAt: expiresAt,
+		}))
+	}
+	create("100001", "alice", "", "listing-1", now.Add(time.Hour))
+	create("100002", "alice", "group-1", "", now.Add(time.Hour))
+	create("100003", "bob", "", "", now.Add(-time.Minute))
+
+	members, err := server.SMembers("duckex:test:sharer:alice")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"100001", "100002"}, members)
+	assert.Equal(t, "100001", server.HGet("duckex:test:listings", "listing-1"))
+	assert.True(t, server.Exists("duckex:test:group:group-1"))
+
+	// 领取、删除和过期时同步清理索引
+	_, err = repo.Claim("100001", "claimer")
+	require.NoError(t, err)
+	assert.False(t, server.Exists("duckex:test:listings"))
+	require.NoError(t, repo.Delete("100002"))
+	assert.False(t, server.Exists("duckex:test:sharer:alice"))
+	assert.False(t, server.Exists("duckex:test:group:group-1"))
+	require.NoError(t, repo.DeleteExpired())
+	assert.False(t, server.Exists("duckex:test:sharer:bob"))
+
+	// 更新物品时索引随之变化
+	create("100004", "carol", "", "listing-4", now.Add(time.Hour))
+	updated, err := repo.GetByPickupCode("100004")
+	require.NoError(t, err)
+	updated.ListingID = ""
+	updated.GroupID = "group-2"
+	require.NoError(t, repo.Update(updated))
+	assert.False(t, server.Exists("duckex:test:listings"))
+	page, err := repo.Query(models.ItemQuery{GroupID: "group-2"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "100004", page.Items[0].PickupCode)
+}
+
+func TestRedisItemRepositoryRebuildIndexes(t *testing.T) {
+	repo, server := newRedisRepository(t)
+	now := time.Now()
+	require.NoError(t, repo.Create(&models.Item{
+		ID: "item-1", PickupCode: "100001", SharerID: "alice", ListingID: "listing-1",
+		CreatedAt: now, ExpiresAt: now.Add(time.Hour),
+	}))
+
+	// 模拟升级前写入、没有索引的物品
+	server.Del("duckex:test:sharer:alice")
+	server.Del("duckex:test:listings")
+	page, err := repo.Query(models.ItemQuery{ListingID: "listing-1"})
+	require.NoError(t, err)
+	assert.Empty(t, page.Items)
+
+	require.NoError(t, repo.RebuildIndexes())
+	page, err = repo.Query(models.ItemQuery{ListingID: "listing-1"})
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	page, err = repo.Query(models.ItemQuery{SharerID: "alice"})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+}
+
+func TestRedisItemRepositoryQueryErrors(t *testing.T) {
+	repo, server := newRedisRepository(t)
+	now := time.Now()
+	require.NoError(t, repo.Create(&models.Item{ID: "item-1", PickupCode: "100001", SharerID: "alice", ExpiresAt: now.Add(time.Hour)}))
+
+	// Redis 不可用时返回错误，而不是空结果
+	server.Close()
+	for _, q := range []models.ItemQuery{{}, {SharerID: "alice"}, {ListingID: "listing-1"}, {ListedOnly: true}} {
+		_, err := repo.Query(q)
+		assert.Error(t, err)
+	}
+	assert.Error(t, repo.RebuildIndexes())
+}
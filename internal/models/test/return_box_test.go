@@ -0,0 +1,102 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 退回箱的创建函数，retention 为保留时间
+type returnBoxFactory func(t *testing.T, retention time.Duration, clk clock.Clock) models.ReturnBox
+
+func newInMemoryReturnBox(t *testing.T, retention time.Duration, clk clock.Clock) models.ReturnBox {
+	return models.NewInMemoryReturnBox(retention, clk)
+}
+
+func newRedisReturnBox(t *testing.T, retention time.Duration, clk clock.Clock) models.ReturnBox {
+	return models.NewRedisReturnBox(newRedisClient(t), "duckex:test:", retention, clk)
+}
+
+// 列出退回箱中的物品ID
+func returnedIDs(t *testing.T, box models.ReturnBox, sharerID string) []string {
+	returned, err := box.List(sharerID)
+	require.NoError(t, err)
+	ids := make([]string, 0, len(returned))
+	for _, r := range returned {
+		ids = append(ids, r.Item.ID)
+	}
+	return ids
+}
+
+func testReturnBox(t *testing.T, newBox returnBoxFactory) {
+	box := newBox(t, time.Hour, nil)
+
+	// 放入两个分享者的过期物品
+	assert.NoError(t, box.Add(&models.Item{ID: "item-1", SharerID: "sharer-a", PickupCode: "111111"}))
+	assert.NoError(t, box.Add(&models.Item{ID: "item-2", SharerID: "sharer-a"}))
+	assert.NoError(t, box.Add(&models.Item{ID: "item-3", SharerID: "sharer-b"}))
+
+	// 查看退回箱不会移除物品
+	assert.Equal(t, []string{"item-1", "item-2"}, returnedIDs(t, box, "sharer-a"))
+	assert.Equal(t, []string{"item-1", "item-2"}, returnedIDs(t, box, "sharer-a"))
+	assert.Equal(t, []string{"item-3"}, returnedIDs(t, box, "sharer-b"))
+	assert.Empty(t, returnedIDs(t, box, "sharer-c"))
+
+	// 领回后退回箱被清空
+	collected, err := box.Collect("sharer-a")
+	assert.NoError(t, err)
+	require.Len(t, collected, 2)
+	assert.Equal(t, "item-1", collected[0].Item.ID)
+	assert.Equal(t, "111111", collected[0].Item.PickupCode)
+	assert.Empty(t, returnedIDs(t, box, "sharer-a"))
+
+	// 其他分享者不受影响
+	assert.Equal(t, []string{"item-3"}, returnedIDs(t, box, "sharer-b"))
+}
+
+func testReturnBoxRetention(t *testing.T, newBox returnBoxFactory) {
+	clk := clock.NewFake(time.Now())
+	box := newBox(t, time.Hour, clk)
+	assert.NoError(t, box.Add(&models.Item{ID: "item-1", SharerID: "sharer-a"}))
+
+	// 模拟时间流逝超过保留期
+	clk.Advance(2 * time.Hour)
+
+	// 超过保留期的物品不可见也不可领回
+	assert.Empty(t, returnedIDs(t, box, "sharer-a"))
+	collected, err := box.Collect("sharer-a")
+	assert.NoError(t, err)
+	assert.Empty(t, collected)
+
+	// 清理时只删除超过保留期的物品
+	assert.NoError(t, box.Add(&models.Item{ID: "item-2", SharerID: "sharer-b"}))
+	clk.Advance(30 * time.Minute)
+	assert.NoError(t, box.Add(&models.Item{ID: "item-3", SharerID: "sharer-b"}))
+	clk.Advance(45 * time.Minute)
+	assert.NoError(t, box.DeleteExpired())
+	assert.Equal(t, []string{"item-3"}, returnedIDs(t, box, "sharer-b"))
+	clk.Advance(time.Hour)
+	assert.NoError(t, box.DeleteExpired())
+	assert.Empty(t, returnedIDs(t, box, "sharer-b"))
+}
+
+func TestInMemoryReturnBox(t *testing.T) {
+	testReturnBox(t, newInMemoryReturnBox)
+}
+
+func TestInMemoryReturnBoxRetention(t *testing.T) {
+	testReturnBoxRetention(t, newInMemoryReturnBox)
+}
+
+func TestRedisReturnBox(t *testing.T) {
+	testReturnBox(t, newRedisReturnBox)
+}
+
+func TestRedisReturnBoxRetention(t *testing.T) {
+	testReturnBoxRetention(t, newRedisReturnBox)
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedItemRepository(t *testing.T) {
+	repo := models.NewShardedItemRepository(8, nil)
+	assert.Equal(t, 8, repo.ShardCount())
+
+	var expiredCount int
+	var mu sync.Mutex
+	repo.SetExpiredHandler(func(item *models.Item) {
+		mu.Lock()
+		expiredCount++
+		mu.Unlock()
+	})
+
+	// 创建足够多的物品使其分布到各个分片
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		expiresAt := now.Add(time.Hour)
+		if i%4 == 0 {
+			expiresAt = now.Add(-time.Hour)
+		}
+		err := repo.Create(&models.Item{
+			ID:         fmt.Sprintf("item-%d", i),
+			PickupCode: fmt.Sprintf("%06d", i),
+			ExpiresAt:  expiresAt,
+		})
+		assert.NoError(t, err)
+	}
+
+	// 读取、更新、删除都路由到同一分片
+	item, err := repo.GetByPickupCode("000001")
+	assert.NoError(t, err)
+	assert.Equal(t, "item-1", item.ID)
+	updated := *item
+	updated.ClaimerID = "claimer"
+	assert.NoError(t, repo.Update(&updated))
+	item, _ = repo.GetByPickupCode("000001")
+	assert.Equal(t, "claimer", item.ClaimerID)
+	assert.NoError(t, repo.Delete("000001"))
+	item, _ = repo.GetByPickupCode("000001")
+	assert.Nil(t, item)
+
+	// 过期物品从所有分片中清理并交给过期回调
+	next, ok := repo.NextExpiry()
+	assert.True(t, ok)
+	assert.True(t, next.Before(now))
+	assert.NoError(t, repo.DeleteExpired())
+	assert.Equal(t, 25, expiredCount)
+	assert.Len(t, repo.GetAll(), 74)
+}
+
+func TestShardedItemRepositoryEmpty(t *testing.T) {
+	repo := models.NewShardedItemRepository(0, nil)
+	assert.Equal(t, 1, repo.ShardCount())
+	assert.NotNil(t, repo.GetAll())
+	assert.Empty(t, repo.GetAll())
+	_, ok := repo.NextExpiry()
+	assert.False(t, ok)
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// 创建基于内存SQLite的仓库
+func newSQLiteRepository(t *testing.T) (*models.SQLItemRepository, *sql.DB) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	// 内存数据库每个连接相互独立，必须只使用一个连接
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	repo, err := models.NewSQLItemRepository(db, nil)
+	require.NoError(t, err)
+	return repo, db
+}
+
+func TestSQLItemRepositoryMigrate(t *testing.T) {
+	_, db := newSQLiteRepository(t)
+
+	// 重复执行迁移不会出错，也不会重复记录
+	require.NoError(t, models.Migrate(db, nil))
+	migrations, err := models.Migrations()
+	require.NoError(t, err)
+	var applied int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&applied))
+	assert.Equal(t, len(migrations), applied)
+}
+
+func TestMigrateRecordsClockTime(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	// 迁移的执行时间来自注入的时间源
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, models.Migrate(db, clock.NewFake(appliedAt)))
+	var recorded int64
+	require.NoError(t, db.QueryRow(`SELECT MIN(applied_at) FROM schema_migrations`).Scan(&recorded))
+	assert.Equal(t, appliedAt.UnixNano(), recorded)
+}
+
+func TestSQLItemRepository(t *testing.T) {
+	repo, _ := newSQLiteRepository(t)
+	now := time.Now()
+
+	item := &models.Item{
+		ID:          "test-item-1",
+		Name:        "Test Item",
+		Description: "This is a test item",
+		TypeID:      123,
+		Num:         2,
+		Durability:  95.5,
+		SharerID:    "test-sharer",
+		PickupCode:  "123456",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	}
+	require.NoError(t, repo.Create(item))
+
+	// 读取的字段与写入一致
+	retrieved, err := repo.GetByPickupCode("123456")
+	require.NoError(t, err)
+	require.NotNil(t, retrieved)
+	assert.Equal(t, item.ID, retrieved.ID)
+	assert.Equal(t, item.Name, retrieved.Name)
+	assert.Equal(t, item.Description, retrieved.Description)
+	assert.Equal(t, item.TypeID, retrieved.TypeID)
+	assert.Equal(t, item.Num, retrieved.Num)
+	assert.Equal(t, item.Durability, retrieved.Durability)
+	assert.True(t, item.ExpiresAt.Equal(retrieved.ExpiresAt))
+
+	// 未过期的取件码不能重复使用
+	err = repo.Create(&models.Item{ID: "dup", PickupCode: "123456", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	assert.ErrorIs(t, err, models.ErrDuplicatePickupCode)
+
+	// 更新物品
+	retrieved.Name = "Renamed"
+	require.NoError(t, repo.Update(retrieved))
+	retrieved, _ = repo.GetByPickupCode("123456")
+	assert.Equal(t, "Renamed", retrieved.Name)
+	assert.ErrorIs(t, repo.Update(&models.Item{PickupCode: "000000"}), models.ErrItemNotFound)
+
+	// 领取后物品被删除
+	claimed, err := repo.Claim("123456", "claimer")
+	require.NoError(t, err)
+	assert.True(t, claimed.IsClaimed)
+	assert.Equal(t, "claimer", claimed.ClaimerID)
+	_, err = repo.Claim("123456", "claimer")
+	assert.ErrorIs(t, err, models.ErrItemNotFound)
+	retrieved, err = repo.GetByPickupCode("123456")
+	assert.NoError(t, err)
+	assert.Nil(t, retrieved)
+
+	// 删除
+	require.NoError(t, repo.Create(&models.Item{ID: "to-delete", PickupCode: "654321", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	require.NoError(t, repo.Delete("654321"))
+	retrieved, _ = repo.GetByPickupCode("654321")
+	assert.Nil(t, retrieved)
+}
+
+func TestSQLItemRepositoryExpiry(t *testing.T) {
+	repo, _ := newSQLiteRepository(t)
+	now := time.Now()
+
+	var mu sync.Mutex
+	var expiredIDs []string
+	repo.SetExpiredHandler(func(item *models.Item) {
+		mu.Lock()
+		expiredIDs = append(expiredIDs, item.ID)
+		mu.Unlock()
+	})
+
+	for i := 0; i < 10; i++ {
+		expiresAt := now.Add(time.Hour)
+		if i%2 == 0 {
+			expiresAt = now.Add(-time.Duration(i+1) * time.Minute)
+		}
+		require.NoError(t, repo.Create(&models.Item{
+			ID:         fmt.Sprintf("item-%d", i),
+			PickupCode: fmt.Sprintf("%06d", i),
+			CreatedAt:  now,
+			ExpiresAt:  expiresAt,
+		}))
+	}
+
+	// GetAll 只返回未过期物品
+	assert.Len(t, repo.GetAll(), 5)
+
+	// 读取过期物品时删除并交给过期回调
+	item, err := repo.GetByPickupCode("000000")
+	assert.NoError(t, err)
+	assert.Nil(t, item)
+	assert.Equal(t, []string{"item-0"}, expiredIDs)
+
+	// 过期物品的取件码可以被新物品使用，旧物品按过期处理
+	require.NoError(t, repo.Create(&models.Item{ID: "reuse", PickupCode: "000002", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+	assert.Equal(t, []string{"item-0", "item-2"}, expiredIDs)
+
+	// 按过期时间顺序清理剩余的过期物品
+	require.NoError(t, repo.DeleteExpired())
+	assert.Equal(t, []string{"item-0", "item-2", "item-8", "item-6", "item-4"}, expiredIDs)
+	assert.Len(t, repo.GetAll(), 6)
+}
+
+func TestSQLItemRepositoryConcurrentClaim(t *testing.T) {
+	repo, _ := newSQLiteRepository(t)
+	now := time.Now()
+	require.NoError(t, repo.Create(&models.Item{ID: "item", PickupCode: "123456", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	// 并发领取同一物品只有一个成功
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	successCount := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			if _, err := repo.Claim("123456", fmt.Sprintf("claimer-%d", index)); err == nil {
+				mu.Lock()
+				successCount++
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, successCount)
+}
@@ -0,0 +1,344 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TradeStatus 交易状态
+type TradeStatus string
+
+const (
+	// TradeOpen 发起方的物品已托管，等待对方接受
+	TradeOpen TradeStatus = "open"
+	// TradeAccepted 对方已存入物品，正在交付双方的物品
+	TradeAccepted TradeStatus = "accepted"
+	// TradeCompleted 双方物品已交付，各自凭取件码领取
+	TradeCompleted TradeStatus = "completed"
+	// TradeCancelled 发起方取消，托管物品退回
+	TradeCancelled TradeStatus = "cancelled"
+	// TradeExpired 超时未被接受，托管物品退回
+	TradeExpired TradeStatus = "expired"
+)
+
+// Closed 交易是否已结束
+func (s TradeStatus) Closed() bool {
+	return s == TradeCompleted || s == TradeCancelled || s == TradeExpired
+}
+
+// 交易仓库错误
+var (
+	// ErrTradeNotFound 交易不存在
+	ErrTradeNotFound = errors.New("trade not found")
+	// ErrTradeNotOpen 交易已被接受、已结束或已超时
+	ErrTradeNotOpen = errors.New("trade is not open")
+	// ErrTradeNotAccepted 交易不处于交付中
+	ErrTradeNotAccepted = errors.New("trade is not being settled")
+	// ErrTradeForbidden 玩家不能执行该操作（如接受自己的交易、取消他人的交易）
+	ErrTradeForbidden = errors.New("player is not allowed to perform this trade action")
+	// ErrDuplicateTradeID 交易ID已存在
+	ErrDuplicateTradeID = errors.New("duplicate trade id")
+)
+
+// TradeItem 交易中托管的物品
+type TradeItem struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	TypeID      int     `json:"type_id"`
+	Num         int     `json:"num"`
+	Durability  float64 `json:"durability"`
+}
+
+// TradeWant 发起方希望换得的物品，按类型和数量匹配
+type TradeWant struct {
+	TypeID int `json:"type_id"`
+	Num    int `json:"num"`
+}
+
+// Trade 玩家之间的交易：发起方托管 Offer 并请求 Request，接受方存入满足 Request 的 Deposit，
+// 交易完成后双方物品以新的取件码交付给对方
+type Trade struct {
+	ID             string      `json:"id"`
+	InitiatorID    string      `json:"initiator_id"`
+	CounterpartyID string      `json:"counterparty_id,omitempty"` // 为空时任何玩家都可以接受
+	AcceptorID     string      `json:"acceptor_id,omitempty"`
+	Offer          []TradeItem `json:"offer"`
+	Request        []TradeWant `json:"request"`
+	Deposit        []TradeItem `json:"deposit,omitempty"`
+	Status         TradeStatus `json:"status"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ExpiresAt      time.Time   `json:"expires_at"` // 超过该时间仍未被接受则超时
+	ClosedAt       *time.Time  `json:"closed_at,omitempty"`
+	// 交付给发起方的物品（接受方存入的物品）的取件码
+	InitiatorCodes []string `json:"initiator_codes,omitempty"`
+	// 交付给接受方的物品（发起方托管的物品）的取件码
+	AcceptorCodes []string `json:"acceptor_codes,omitempty"`
+}
+
+// Involves 玩家是否是交易的参与方
+func (t *Trade) Involves(playerID string) bool {
+	return playerID != "" && (t.InitiatorID == playerID || t.AcceptorID == playerID || t.CounterpartyID == playerID)
+}
+
+// ViewFor 返回玩家可见的交易副本，只保留交付给该玩家的取件码
+func (t *Trade) ViewFor(playerID string) *Trade {
+	view := t.clone()
+	if playerID == "" || view.InitiatorID != playerID {
+		view.InitiatorCodes = nil
+	}
+	if playerID == "" || view.AcceptorID != playerID {
+		view.AcceptorCodes = nil
+	}
+	return view
+}
+
+// Satisfies 存入的物品是否满足交易请求：每项请求需要一个类型相同、数量不少于请求数量的物品，一个物品只能满足一项请求
+func (t *Trade) Satisfies(deposit []TradeItem) bool {
+	used := make([]bool, len(deposit))
+	for _, want := range t.Request {
+		matched := false
+		for i, item := range deposit {
+			if !used[i] && item.TypeID == want.TypeID && item.Num >= want.Num {
+				used[i], matched = true, true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+// 深拷贝，避免调用者修改仓库中的交易
+func (t *Trade) clone() *Trade {
+	copied := *t
+	copied.Offer = append([]TradeItem(nil), t.Offer...)
+	copied.Request = append([]TradeWant(nil), t.Request...)
+	copied.Deposit = append([]TradeItem(nil), t.Deposit...)
+	copied.InitiatorCodes = append([]string(nil), t.InitiatorCodes...)
+	copied.AcceptorCodes = append([]string(nil), t.AcceptorCodes...)
+	return &copied
+}
+
+// 检查交易是否可以被该玩家接受
+func (t *Trade) checkAcceptor(acceptorID string, now time.Time) error {
+	switch {
+	case t.Status != TradeOpen || now.After(t.ExpiresAt):
+		return ErrTradeNotOpen
+	case acceptorID == t.InitiatorID:
+		return ErrTradeForbidden
+	case t.CounterpartyID != "" && acceptorID != t.CounterpartyID:
+		return ErrTradeForbidden
+	}
+	return nil
+}
+
+// 检查玩家能否取消交易
+func (t *Trade) checkCancel(initiatorID string) error {
+	switch {
+	case t.InitiatorID != initiatorID:
+		return ErrTradeForbidden
+	case t.Status != TradeOpen:
+		return ErrTradeNotOpen
+	}
+	return nil
+}
+
+// 交易的状态转换，各仓库实现在同一事务或锁内调用，返回错误时不做修改
+
+func acceptTrade(acceptorID string, deposit []TradeItem, now time.Time) func(*Trade) error {
+	return func(t *Trade) error {
+		if err := t.checkAcceptor(acceptorID, now); err != nil {
+			return err
+		}
+		t.Status = TradeAccepted
+		t.AcceptorID = acceptorID
+		t.Deposit = append([]TradeItem(nil), deposit...)
+		return nil
+	}
+}
+
+func completeTrade(initiatorCodes, acceptorCodes []string, now time.Time) func(*Trade) error {
+	return func(t *Trade) error {
+		if t.Status != TradeAccepted {
+			return ErrTradeNotAccepted
+		}
+		t.Status = TradeCompleted
+		t.ClosedAt = &now
+		t.InitiatorCodes = append([]string(nil), initiatorCodes...)
+		t.AcceptorCodes = append([]string(nil), acceptorCodes...)
+		return nil
+	}
+}
+
+func reopenTrade(t *Trade) error {
+	if t.Status != TradeAccepted {
+		return ErrTradeNotAccepted
+	}
+	t.Status = TradeOpen
+	t.AcceptorID = ""
+	t.Deposit = nil
+	return nil
+}
+
+func cancelTrade(initiatorID string, now time.Time) func(*Trade) error {
+	return func(t *Trade) error {
+		if err := t.checkCancel(initiatorID); err != nil {
+			return err
+		}
+		t.Status = TradeCancelled
+		t.ClosedAt = &now
+		return nil
+	}
+}
+
+// TradeRepository 交易仓库接口，状态转换都是原子的
+type TradeRepository interface {
+	// Create 保存新交易，ID 已存在时返回 ErrDuplicateTradeID
+	Create(trade *Trade) error
+	// Get 按ID获取交易，不存在时返回 (nil, nil)
+	Get(id string) (*Trade, error)
+	// List 返回玩家参与的全部交易，按创建时间倒序
+	List(playerID string) ([]*Trade, error)
+	// Accept 将未超时的 open 交易转为 accepted 并记录接受方和存入的物品
+	// 返回 ErrTradeNotFound、ErrTradeNotOpen 或 ErrTradeForbidden
+	Accept(id, acceptorID string, deposit []TradeItem, now time.Time) (*Trade, error)
+	// Complete 将 accepted 交易转为 completed 并记录交付给双方的取件码
+	Complete(id string, initiatorCodes, acceptorCodes []string, now time.Time) (*Trade, error)
+	// Reopen 交付失败时将 accepted 交易恢复为 open，清除接受方和存入的物品
+	Reopen(id string) (*Trade, error)
+	// Cancel 发起方取消 open 交易，返回 ErrTradeNotFound、ErrTradeNotOpen 或 ErrTradeForbidden
+	Cancel(id, initiatorID string, now time.Time) (*Trade, error)
+	// ExpireOpen 将超时的 open 交易转为 expired 并返回
+	ExpireOpen(now time.Time) ([]*Trade, error)
+	// DeleteClosed 删除在 before 之前结束的交易，返回删除的数量
+	DeleteClosed(before time.Time) (int, error)
+}
+
+// InMemoryTradeRepository 内存实现的交易仓库
+type InMemoryTradeRepository struct {
+	trades map[string]*Trade
+	mutex  sync.RWMutex
+}
+
+// NewInMemoryTradeRepository 创建新的内存交易仓库
+func NewInMemoryTradeRepository() *InMemoryTradeRepository {
+	return &InMemoryTradeRepository{
+		trades: make(map[string]*Trade),
+	}
+}
+
+// Create 保存新交易
+func (r *InMemoryTradeRepository) Create(trade *Trade) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, exists := r.trades[trade.ID]; exists {
+		return ErrDuplicateTradeID
+	}
+	r.trades[trade.ID] = trade.clone()
+	return nil
+}
+
+// Get 按ID获取交易
+func (r *InMemoryTradeRepository) Get(id string) (*Trade, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	trade, exists := r.trades[id]
+	if !exists {
+		return nil, nil
+	}
+	return trade.clone(), nil
+}
+
+// List 返回玩家参与的全部交易
+func (r *InMemoryTradeRepository) List(playerID string) ([]*Trade, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	trades := make([]*Trade, 0)
+	for _, trade := range r.trades {
+		if trade.Involves(playerID) {
+			trades = append(trades, trade.clone())
+		}
+	}
+	sortTrades(trades)
+	return trades, nil
+}
+
+// 在写锁内查找交易并执行状态转换，update 返回错误时不做修改
+func (r *InMemoryTradeRepository) transition(id string, update func(*Trade) error) (*Trade, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	trade, exists := r.trades[id]
+	if !exists {
+		return nil, ErrTradeNotFound
+	}
+	updated := trade.clone()
+	if err := update(updated); err != nil {
+		return nil, err
+	}
+	r.trades[id] = updated
+	return updated.clone(), nil
+}
+
+// Accept 接受交易
+func (r *InMemoryTradeRepository) Accept(id, acceptorID string, deposit []TradeItem, now time.Time) (*Trade, error) {
+	return r.transition(id, acceptTrade(acceptorID, deposit, now))
+}
+
+// Complete 完成交易
+func (r *InMemoryTradeRepository) Complete(id string, initiatorCodes, acceptorCodes []string, now time.Time) (*Trade, error) {
+	return r.transition(id, completeTrade(initiatorCodes, acceptorCodes, now))
+}
+
+// Reopen 恢复为 open
+func (r *InMemoryTradeRepository) Reopen(id string) (*Trade, error) {
+	return r.transition(id, reopenTrade)
+}
+
+// Cancel 取消交易
+func (r *InMemoryTradeRepository) Cancel(id, initiatorID string, now time.Time) (*Trade, error) {
+	return r.transition(id, cancelTrade(initiatorID, now))
+}
+
+// ExpireOpen 将超时的 open 交易转为 expired
+func (r *InMemoryTradeRepository) ExpireOpen(now time.Time) ([]*Trade, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var expired []*Trade
+	for _, trade := range r.trades {
+		if trade.Status == TradeOpen && now.After(trade.ExpiresAt) {
+			trade.Status = TradeExpired
+			trade.ClosedAt = &now
+			expired = append(expired, trade.clone())
+		}
+	}
+	sortTrades(expired)
+	return expired, nil
+}
+
+// DeleteClosed 删除已结束的旧交易
+func (r *InMemoryTradeRepository) DeleteClosed(before time.Time) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	deleted := 0
+	for id, trade := range r.trades {
+		if trade.Status.Closed() && trade.ClosedAt != nil && trade.ClosedAt.Before(before) {
+			delete(r.trades, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// 按创建时间倒序排列，创建时间相同时按ID排序
+func sortTrades(trades []*Trade) {
+	sort.Slice(trades, func(i, j int) bool {
+		if !trades[i].CreatedAt.Equal(trades[j].CreatedAt) {
+			return trades[i].CreatedAt.After(trades[j].CreatedAt)
+		}
+		return trades[i].ID < trades[j].ID
+	})
+}
@@ -0,0 +1,626 @@
+package openapi
+
+import (
+	"net/http"
+	"strings"
+
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/snapshot"
+)
+
+// Version API 文档版本
+const Version = "1.0.0"
+
+// MemoryStatus 内存监控状态，对应 utils.MemoryMonitor.GetStatus 的返回值
+type MemoryStatus struct {
+	CurrentUsageMB  int64   `json:"current_usage_mb"`
+	MaxMemoryMB     int64   `json:"max_memory_mb"`
+	UsagePercentage float64 `json:"usage_percentage"`
+	ShareDisabled   bool    `json:"share_disabled"`
+}
+
+// MemoryPressureResponse 内存过高时分享接口的响应
+type MemoryPressureResponse struct {
+	Error        string       `json:"error"`
+	MemoryStatus MemoryStatus `json:"memory_status"`
+}
+
+// 文档构建器
+type builder struct {
+	doc     *Document
+	schemas *schemaRegistry
+}
+
+func (b *builder) add(method, path string, op *Operation) {
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = op
+}
+
+// JSON 内容
+func (b *builder) json(v interface{}) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: b.schemas.schemaOf(v)}}
+}
+
+func (b *builder) body(v interface{}) *RequestBody {
+	return &RequestBody{Required: true, Content: b.json(v)}
+}
+
+func (b *builder) response(description string, v interface{}) Response {
+	return Response{Description: description, Content: b.json(v)}
+}
+
+func queryParam(name, description string, required bool, schema *Schema) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Required: required, Schema: schema}
+}
+
+// 限流响应
+func (b *builder) rateLimited() Response {
+	integer := func(description string) Header {
+		return Header{Description: description, Schema: &Schema{Type: "integer"}}
+	}
+	return Response{
+		Description: "超过限流",
+		Headers: map[string]Header{
+			"RateLimit-Limit":     integer("令牌桶容量"),
+			"RateLimit-Remaining": integer("剩余次数"),
+			"RateLimit-Reset":     integer("恢复满额所需秒数"),
+			"Retry-After":         integer("需要等待的秒数"),
+		},
+		Content: b.json(handlers.ErrorResponse{}),
+	}
+}
+
+// Build 生成 API 文档
+func Build() *Document {
+	b := &builder{
+		doc: &Document{
+			OpenAPI: "3.0.3",
+			Info: Info{
+				Title:       "DuckEx Server API",
+				Description: "鸭科夫物品交换服务：分享者存放物品获得取件码，领取者凭取件码领取物品",
+				Version:     Version,
+			},
+			Paths: make(map[string]PathItem),
+			Components: Components{
+				SecuritySchemes: map[string]SecurityScheme{
+					"adminToken":  {Type: "http", Scheme: "bearer"},
+					"playerToken": {Type: "http", Scheme: "bearer"},
+				},
+			},
+		},
+		schemas: newSchemaRegistry(),
+	}
+	sharerID := queryParam("sharer_id", "分享者ID", true, &Schema{Type: "string"})
+	admin := []map[string][]string{{"adminToken": {}}}
+	adminErrors := func(responses map[string]Response) map[string]Response {
+		responses["401"] = b.response("管理令牌无效", handlers.ErrorResponse{})
+		responses["403"] = b.response("未配置管理令牌，管理接口不可用", handlers.ErrorResponse{})
+		return responses
+	}
+
+	b.add(http.MethodGet, "/health", &Operation{
+		OperationID: "getHealth",
+		Summary:     "健康检查",
+		Description: "返回服务状态、未过期物品的数量以及事件和限流统计，不返回物品本身",
+		Tags:        []string{"system"},
+		Responses: map[string]Response{
+			"200": b.response("服务正常", handlers.HealthResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/openapi.json", &Operation{
+		OperationID: "getOpenAPI",
+		Summary:     "API 文档",
+		Tags:        []string{"system"},
+		Responses: map[string]Response{
+			"200": {Description: "OpenAPI 3 文档", Content: map[string]MediaType{"application/json": {Schema: &Schema{Type: "object"}}}},
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/items/share", &Operation{
+		OperationID: "shareItem",
+		Summary:     "分享物品",
+		Description: "存放物品并获得6位取件码，取件码24小时内有效。设置 group_id 时物品只分享给该群组，分享者必须是群组成员并携带自己的玩家令牌，且不能同时公开上架",
+		Tags:        []string{"items"},
+		RequestBody: b.body(handlers.ShareItemRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("分享成功", handlers.ShareItemResponse{}),
+			"400": b.response("请求格式错误", handlers.ErrorResponse{}),
+			"401": b.response("向群组分享时缺少分享者的玩家令牌", handlers.ErrorResponse{}),
+			"403": b.response("分享者不是目标群组的成员", handlers.ErrorResponse{}),
+			"429": b.rateLimited(),
+			"500": b.response("保存物品失败", handlers.ErrorResponse{}),
+			"503": b.response("内存使用过高，分享功能暂时禁用", MemoryPressureResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/items/claim", &Operation{
+		OperationID: "claimItem",
+		Summary:     "领取物品",
+		Description: "凭取件码领取物品。业务结果在响应体的 code 字段中返回：200 成功，401 物品只分享给群组且缺少领取者的玩家令牌，403 物品只分享给群组且领取者不是成员，404 取件码无效或已过期，409 已被领取，500 领取失败",
+		Tags:        []string{"items"},
+		RequestBody: b.body(handlers.ClaimItemRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("领取结果", handlers.ClaimItemResponse{}),
+			"400": b.response("请求格式错误", handlers.ClaimItemResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/items/reserve", &Operation{
+		OperationID: "reserveItem",
+		Summary:     "预留物品",
+		Description: "两阶段领取的第一步：凭取件码预留物品，返回预留令牌和物品快照。物品在预留期限内保留给领取者，到期未确认时恢复为可领取。业务结果在响应体的 code 字段中返回：200 成功，401 物品只分享给群组且缺少领取者的玩家令牌，403 物品只分享给群组且领取者不是成员，404 取件码无效或已过期，409 已被领取或预留，500 预留失败",
+		Tags:        []string{"items"},
+		RequestBody: b.body(handlers.ReserveItemRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("预留结果", handlers.ReserveItemResponse{}),
+			"400": b.response("请求格式错误", handlers.ReserveItemResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/items/confirm", &Operation{
+		OperationID: "confirmItem",
+		Summary:     "确认领取",
+		Description: "两阶段领取的第二步：物品已放入背包后凭预留令牌确认，物品被领走。业务结果在响应体的 code 字段中返回：200 成功，410 预留不存在、令牌不匹配或预留已过期，500 确认失败",
+		Tags:        []string{"items"},
+		RequestBody: b.body(handlers.ReservationRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("确认结果", handlers.ClaimItemResponse{}),
+			"400": b.response("请求格式错误", handlers.ClaimItemResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/items/release", &Operation{
+		OperationID: "releaseItem",
+		Summary:     "取消预留",
+		Description: "放弃预留，物品立即恢复为可领取。业务结果在响应体的 code 字段中返回：200 成功，410 预留不存在、令牌不匹配或预留已过期，500 取消失败",
+		Tags:        []string{"items"},
+		RequestBody: b.body(handlers.ReservationRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("取消结果", handlers.ClaimItemResponse{}),
+			"400": b.response("请求格式错误", handlers.ClaimItemResponse{}),
+		},
+	})
+	listingID := Parameter{Name: "id", In: "path", Description: "挂牌ID", Required: true, Schema: &Schema{Type: "string"}}
+	b.add(http.MethodGet, "/api/v1/listings", &Operation{
+		OperationID: "searchListings",
+		Summary:     "浏览市场",
+		Description: "按条件搜索公开上架且可以领取的物品，按上架时间从新到旧分页返回，结果不包含取件码",
+		Tags:        []string{"listings"},
+		Parameters: []Parameter{
+			queryParam("q", "全文检索，名称和描述需包含全部检索词，汉字逐字匹配", false, &Schema{Type: "string"}),
+			queryParam("type_id", "物品类型ID", false, &Schema{Type: "integer"}),
+			queryParam("name", "物品名称，不区分大小写的子串匹配", false, &Schema{Type: "string"}),
+			queryParam("sharer_id", "分享者ID", false, &Schema{Type: "string"}),
+			queryParam("min_durability", "最低耐久度", false, &Schema{Type: "number"}),
+			queryParam("max_durability", "最高耐久度", false, &Schema{Type: "number"}),
+			queryParam("limit", "每页数量，默认20，最大100", false, &Schema{Type: "integer"}),
+			queryParam("cursor", "上一页返回的 next_cursor", false, &Schema{Type: "string"}),
+		},
+		Responses: map[string]Response{
+			"200": b.response("市场挂牌", handlers.ListingsResponse{}),
+			"400": b.response("查询参数无效", handlers.ListingsResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/listings/{id}", &Operation{
+		OperationID: "getListing",
+		Summary:     "查看挂牌",
+		Tags:        []string{"listings"},
+		Parameters:  []Parameter{listingID},
+		Responses: map[string]Response{
+			"200": b.response("挂牌", handlers.ListingResponse{}),
+			"404": b.response("挂牌不存在、已过期或已被预留", handlers.ListingResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/listings/{id}/claim", &Operation{
+		OperationID: "claimListing",
+		Summary:     "凭挂牌ID领取物品",
+		Description: "与凭取件码领取使用同一个原子领取操作，业务结果通过响应体中的 code 返回（404 挂牌不存在，409 已被领取）",
+		Tags:        []string{"listings"},
+		Parameters:  []Parameter{listingID},
+		RequestBody: b.body(handlers.ClaimListingRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("领取结果", handlers.ClaimItemResponse{}),
+			"400": b.response("请求格式错误", handlers.ClaimItemResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/returns", &Operation{
+		OperationID: "listReturns",
+		Summary:     "查看退回箱",
+		Description: "列出分享者因过期未被领取而退回的物品，需要为该分享者签发的玩家令牌",
+		Tags:        []string{"returns"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{sharerID},
+		Responses: map[string]Response{
+			"200": b.response("退回物品", handlers.ReturnsResponse{}),
+			"400": b.response("缺少 sharer_id", handlers.ReturnsResponse{}),
+			"401": b.response("玩家令牌缺失或与分享者不匹配", handlers.ReturnsResponse{}),
+			"403": b.response("未配置玩家令牌密钥，退回箱不可用", handlers.ReturnsResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/returns/collect", &Operation{
+		OperationID: "collectReturns",
+		Summary:     "领回退回物品",
+		Description: "领回分享者退回箱中的全部物品，需要为该分享者签发的玩家令牌",
+		Tags:        []string{"returns"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		RequestBody: b.body(handlers.CollectReturnsRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("领回的物品", handlers.ReturnsResponse{}),
+			"400": b.response("请求格式错误", handlers.ReturnsResponse{}),
+			"401": b.response("玩家令牌缺失或与分享者不匹配", handlers.ReturnsResponse{}),
+			"403": b.response("未配置玩家令牌密钥，退回箱不可用", handlers.ReturnsResponse{}),
+			"500": b.response("领回失败", handlers.ReturnsResponse{}),
+		},
+	})
+	tradeID := Parameter{Name: "id", In: "path", Description: "交易ID", Required: true, Schema: &Schema{Type: "string"}}
+	b.add(http.MethodPost, "/api/v1/trades", &Operation{
+		OperationID: "openTrade",
+		Summary:     "发起交易",
+		Description: "托管发起方的物品并请求对方的物品，counterparty_id 为空时任何玩家都可以接受，超时未被接受的交易结束并退回托管物品，发布 trade_opened 事件。与分享相同，托管的物品只是描述，调用方需要先从发起方的背包中扣除。交易接口需要为执行操作的玩家签发的玩家令牌，未配置玩家令牌密钥时返回 403",
+		Tags:        []string{"trades"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		RequestBody: b.body(handlers.OpenTradeRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("发起的交易", handlers.TradeResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.TradeResponse{}),
+			"400": b.response("请求格式错误", handlers.TradeResponse{}),
+			"503": b.response("内存使用率过高，暂停发起交易", handlers.TradeResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/trades", &Operation{
+		OperationID: "listTrades",
+		Summary:     "列出交易",
+		Description: "列出玩家发起、被指定或已接受的交易，按发起时间倒序",
+		Tags:        []string{"trades"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{queryParam("player_id", "玩家ID", true, &Schema{Type: "string"})},
+		Responses: map[string]Response{
+			"200": b.response("交易列表", handlers.TradesResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.TradesResponse{}),
+			"400": b.response("缺少 player_id", handlers.TradesResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/trades/{id}", &Operation{
+		OperationID: "getTrade",
+		Summary:     "查看交易",
+		Description: "参与方可以查看交易及交付给自己的取件码，其他玩家只能查看未指定对方的进行中交易。带 player_id 时需要为该玩家签发的玩家令牌，不带时匿名查看",
+		Tags:        []string{"trades"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{tradeID, queryParam("player_id", "玩家ID", false, &Schema{Type: "string"})},
+		Responses: map[string]Response{
+			"200": b.response("交易", handlers.TradeResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.TradeResponse{}),
+			"404": b.response("交易不存在", handlers.TradeResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/trades/{id}/accept", &Operation{
+		OperationID: "acceptTrade",
+		Summary:     "接受交易",
+		Description: "存入满足请求的物品，双方物品以新的取件码交付给对方，发布 trade_completed 事件。存入的物品只是描述，调用方需要先从接受方的背包中扣除",
+		Tags:        []string{"trades"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{tradeID},
+		RequestBody: b.body(handlers.AcceptTradeRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("完成的交易，包含交付给接受方的取件码", handlers.TradeResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.TradeResponse{}),
+			"400": b.response("请求格式错误或物品不满足请求", handlers.TradeResponse{}),
+			"403": b.response("不能接受自己的交易或指定给其他玩家的交易", handlers.TradeResponse{}),
+			"404": b.response("交易不存在", handlers.TradeResponse{}),
+			"409": b.response("交易已结束", handlers.TradeResponse{}),
+			"503": b.response("内存使用率过高，暂停接受交易，交易保持进行中", handlers.TradeResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/trades/{id}/cancel", &Operation{
+		OperationID: "cancelTrade",
+		Summary:     "取消交易",
+		Description: "发起方取消进行中的交易，托管物品退回到退回箱，发布 trade_closed 事件",
+		Tags:        []string{"trades"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{tradeID},
+		RequestBody: b.body(handlers.CancelTradeRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("取消的交易", handlers.TradeResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.TradeResponse{}),
+			"400": b.response("请求格式错误", handlers.TradeResponse{}),
+			"403": b.response("只有发起方可以取消交易", handlers.TradeResponse{}),
+			"404": b.response("交易不存在", handlers.TradeResponse{}),
+			"409": b.response("交易已结束", handlers.TradeResponse{}),
+		},
+	})
+	groupID := Parameter{Name: "id", In: "path", Description: "群组ID", Required: true, Schema: &Schema{Type: "string"}}
+	b.add(http.MethodPost, "/api/v1/groups", &Operation{
+		OperationID: "createGroup",
+		Summary:     "创建群组",
+		Description: "创建玩家群组，创建者成为群主。成员可以向群组分享物品，分享给群组的物品只有成员可以领取。群组接口都需要为操作者签发的玩家令牌，未配置玩家令牌密钥时返回 403",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		RequestBody: b.body(handlers.CreateGroupRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("创建的群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("请求格式错误", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/groups", &Operation{
+		OperationID: "listGroups",
+		Summary:     "列出群组",
+		Description: "列出玩家所在或被邀请加入的群组，按创建时间倒序",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{queryParam("player_id", "玩家ID", true, &Schema{Type: "string"})},
+		Responses: map[string]Response{
+			"200": b.response("群组列表", handlers.GroupsResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupsResponse{}),
+			"400": b.response("缺少 player_id", handlers.GroupsResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/groups/{id}", &Operation{
+		OperationID: "getGroup",
+		Summary:     "查看群组",
+		Description: "成员和被邀请的玩家可以查看群组的成员和邀请",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{groupID, queryParam("player_id", "玩家ID", true, &Schema{Type: "string"})},
+		Responses: map[string]Response{
+			"200": b.response("群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/groups/{id}/invite", &Operation{
+		OperationID: "inviteToGroup",
+		Summary:     "邀请玩家",
+		Description: "管理员或群主邀请玩家，被邀请的玩家接受邀请后成为普通成员",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{groupID},
+		RequestBody: b.body(handlers.InviteGroupRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("更新后的群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("请求格式错误", handlers.GroupResponse{}),
+			"403": b.response("只有管理员和群主可以邀请", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+			"409": b.response("玩家已经是群组成员", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/groups/{id}/join", &Operation{
+		OperationID: "joinGroup",
+		Summary:     "加入群组",
+		Description: "接受邀请并成为普通成员",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{groupID},
+		RequestBody: b.body(handlers.GroupPlayerRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("更新后的群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("请求格式错误", handlers.GroupResponse{}),
+			"403": b.response("玩家没有收到邀请", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+			"409": b.response("玩家已经是群组成员", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/groups/{id}/leave", &Operation{
+		OperationID: "leaveGroup",
+		Summary:     "退出群组",
+		Description: "成员退出群组，群主需要先转让群组",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{groupID},
+		RequestBody: b.body(handlers.GroupPlayerRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("更新后的群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("请求格式错误", handlers.GroupResponse{}),
+			"403": b.response("玩家不是成员，或群主尚未转让群组", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/groups/{id}/role", &Operation{
+		OperationID: "setGroupRole",
+		Summary:     "调整成员角色",
+		Description: "群主将成员设为 admin 或 member；设为 owner 时转让群组，原群主成为管理员",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{groupID},
+		RequestBody: b.body(handlers.SetGroupRoleRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("更新后的群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("请求格式错误或角色未知", handlers.GroupResponse{}),
+			"403": b.response("只有群主可以调整其他成员的角色", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodPost, "/api/v1/groups/{id}/remove", &Operation{
+		OperationID: "removeGroupMember",
+		Summary:     "移除成员",
+		Description: "管理员和群主可以移除角色低于自己的成员，或撤回尚未接受的邀请",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters:  []Parameter{groupID},
+		RequestBody: b.body(handlers.RemoveGroupMemberRequest{}),
+		Responses: map[string]Response{
+			"200": b.response("更新后的群组", handlers.GroupResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("请求格式错误", handlers.GroupResponse{}),
+			"403": b.response("无权移除该成员，或目标不是成员", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/groups/{id}/items", &Operation{
+		OperationID: "listGroupItems",
+		Summary:     "列出群组物品",
+		Description: "成员查看分享给群组、可以领取的物品，包含取件码，按分享时间从新到旧分页返回",
+		Tags:        []string{"groups"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters: []Parameter{
+			groupID,
+			queryParam("player_id", "玩家ID，必须是群组成员", true, &Schema{Type: "string"}),
+			queryParam("limit", "每页数量，默认20，最大100", false, &Schema{Type: "integer"}),
+			queryParam("cursor", "上一页返回的 next_cursor", false, &Schema{Type: "string"}),
+		},
+		Responses: map[string]Response{
+			"200": b.response("群组物品", handlers.GroupItemsResponse{}),
+			"401": b.response("玩家令牌缺失或与操作者不匹配", handlers.GroupResponse{}),
+			"400": b.response("查询参数无效", handlers.GroupResponse{}),
+			"403": b.response("玩家不是群组成员", handlers.GroupResponse{}),
+			"404": b.response("群组不存在", handlers.GroupResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/events", &Operation{
+		OperationID: "streamEvents",
+		Summary:     "物品事件推送",
+		Description: "通过 Server-Sent Events 推送与分享者相关的事件：subscribed、item_shared、item_reserved、reservation_released、item_claimed、item_expired、item_cancelled、trade_opened、trade_completed、trade_closed、ping。需要为该分享者签发的玩家令牌，事件中的物品不包含取件码",
+		Tags:        []string{"events"},
+		Security:    []map[string][]string{{"playerToken": {}}},
+		Parameters: []Parameter{
+			sharerID,
+			queryParam("token", "玩家令牌，无法设置请求头时（如 EventSource）代替 Authorization 请求头", false, &Schema{Type: "string"}),
+		},
+		Responses: map[string]Response{
+			"200": {Description: "事件流", Content: map[string]MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}}},
+			"400": b.response("缺少 sharer_id", handlers.ErrorResponse{}),
+			"401": b.response("玩家令牌无效", handlers.ErrorResponse{}),
+			"403": b.response("未配置玩家令牌密钥，玩家事件流不可用", handlers.ErrorResponse{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/memory", &Operation{
+		OperationID: "getMemoryStatus",
+		Summary:     "内存状态",
+		Tags:        []string{"system"},
+		Responses: map[string]Response{
+			"200": b.response("内存监控状态", MemoryStatus{}),
+		},
+	})
+	b.add(http.MethodGet, "/api/v1/admin/webhooks/deliveries", &Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "Webhook 投递记录",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Parameters: []Parameter{
+			queryParam("status", "按投递状态过滤", false, &Schema{Type: "string", Enum: []string{"pending", "succeeded", "dead"}}),
+		},
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("投递记录", handlers.DeliveriesResponse{}),
+			"400": b.response("状态参数无效", handlers.ErrorResponse{}),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/admin/webhooks/dead-letters", &Operation{
+		OperationID: "listWebhookDeadLetters",
+		Summary:     "Webhook 死信",
+		Description: "列出重试耗尽的投递记录",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("死信记录", handlers.DeliveriesResponse{}),
+		}),
+	})
+
+	code := Parameter{Name: "code", In: "path", Description: "取件码", Required: true, Schema: &Schema{Type: "string"}}
+	b.add(http.MethodGet, "/api/v1/admin/items", &Operation{
+		OperationID: "listItems",
+		Summary:     "列出物品",
+		Description: "列出未过期的物品",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Parameters:  []Parameter{queryParam("sharer_id", "只列出该分享者的物品", false, &Schema{Type: "string"})},
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("物品列表", handlers.ItemsResponse{}),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/admin/items/{code}", &Operation{
+		OperationID: "getItem",
+		Summary:     "查看物品",
+		Description: "按取件码查看物品，不会领取物品",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Parameters:  []Parameter{code},
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("物品", handlers.ItemResponse{}),
+			"404": b.response("物品不存在或已过期", handlers.ErrorResponse{}),
+		}),
+	})
+	b.add(http.MethodDelete, "/api/v1/admin/items/{code}", &Operation{
+		OperationID: "cancelItem",
+		Summary:     "取消物品",
+		Description: "将物品从仓库移除并退回到分享者的退回箱，发布 item_cancelled 事件",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Parameters:  []Parameter{code},
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("被取消的物品", handlers.ItemResponse{}),
+			"404": b.response("物品不存在或已过期", handlers.ErrorResponse{}),
+			"409": b.response("物品已被领取", handlers.ErrorResponse{}),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/admin/snapshot", &Operation{
+		OperationID: "dumpSnapshot",
+		Summary:     "导出快照",
+		Description: "导出全部未过期物品",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("快照", snapshot.Snapshot{}),
+		}),
+	})
+	b.add(http.MethodPost, "/api/v1/admin/snapshot", &Operation{
+		OperationID: "restoreSnapshot",
+		Summary:     "恢复快照",
+		Description: "将快照中的物品写入仓库，已过期、缺少ID、取件码或分享者以及取件码冲突的物品被跳过",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		RequestBody: b.body(snapshot.Snapshot{}),
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("恢复结果", snapshot.RestoreResult{}),
+			"400": b.response("快照格式错误或版本不支持", handlers.ErrorResponse{}),
+			"500": b.response("写入仓库失败", handlers.ErrorResponse{}),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/admin/status", &Operation{
+		OperationID: "getAdminStatus",
+		Summary:     "运维状态",
+		Description: "内存、限流配额和事件统计",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Responses: adminErrors(map[string]Response{
+			"200": b.response("运维状态", handlers.AdminStatusResponse{}),
+		}),
+	})
+	b.add(http.MethodGet, "/api/v1/admin/events", &Operation{
+		OperationID: "streamAllEvents",
+		Summary:     "全部事件推送",
+		Description: "通过 Server-Sent Events 推送全部事件",
+		Tags:        []string{"admin"},
+		Security:    admin,
+		Parameters:  []Parameter{queryParam("type", "只推送该类型的事件", false, &Schema{Type: "string"})},
+		Responses: adminErrors(map[string]Response{
+			"200": {Description: "事件流", Content: map[string]MediaType{"text/event-stream": {Schema: &Schema{Type: "string"}}}},
+		}),
+	})
+
+	b.idempotentPosts()
+
+	b.doc.Components.Schemas = b.schemas.schemas
+	return b.doc
+}
+
+// 所有 POST 接口都支持 Idempotency-Key 请求头，见 middleware.Idempotency
+func (b *builder) idempotentPosts() {
+	key := Parameter{
+		Name:        "Idempotency-Key",
+		In:          "header",
+		Description: "幂等键，相同键和相同请求体的重试重放首次响应（带 Idempotent-Replayed: true 响应头）",
+		Schema:      &Schema{Type: "string", MaxLength: 255},
+	}
+	for _, item := range b.doc.Paths {
+		op, ok := item["post"]
+		if !ok {
+			continue
+		}
+		op.Parameters = append(op.Parameters, key)
+		op.Responses["409"] = b.response("相同幂等键的请求仍在处理中", handlers.ErrorResponse{})
+		op.Responses["422"] = b.response("幂等键已被请求体不同的请求使用", handlers.ErrorResponse{})
+	}
+}
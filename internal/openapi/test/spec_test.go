@@ -0,0 +1,125 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"duckex-server/internal/events"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/openapi"
+	"duckex-server/internal/router"
+	"duckex-server/internal/service"
+	"duckex-server/internal/utils"
+	"duckex-server/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	bus := events.NewBus()
+	monitor := utils.NewMemoryMonitor(500)
+	items := service.NewItemService(service.Deps{ItemRepo: itemRepo, MemoryMonitor: monitor, EventBus: bus})
+	r := gin.New()
+	router.Register(r, router.Handlers{
+		Health:        handlers.NewHealthHandler(itemRepo, nil, nil, nil),
+		Item:          handlers.NewItemHandler(items, monitor),
+		Listing:       handlers.NewListingHandler(items),
+		Return:        handlers.NewReturnHandler(models.NewInMemoryReturnBox(0, nil), ""),
+		Trade:         handlers.NewTradeHandler(service.NewTradeService(service.TradeDeps{Trades: models.NewInMemoryTradeRepository(), ItemRepo: itemRepo}), ""),
+		Group:         handlers.NewGroupHandler(service.NewGroupService(service.GroupDeps{Groups: models.NewInMemoryGroupRepository(), ItemRepo: itemRepo}), ""),
+		Event:         handlers.NewEventHandler(bus, "", nil),
+		Webhook:       handlers.NewWebhookHandler(webhooks.NewDispatcher(nil, webhooks.DefaultOptions())),
+		Admin:         handlers.NewAdminHandler(handlers.AdminDeps{ItemRepo: itemRepo, Items: items}),
+		MemoryMonitor: monitor,
+	})
+	return r
+}
+
+// gin 的 ":param" 和 "*param" 转换为 OpenAPI 的 "{param}"
+var ginParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+func TestSpecCoversAllRoutes(t *testing.T) {
+	doc := openapi.Build()
+	routes := setupRouter().Routes()
+	require.NotEmpty(t, routes)
+
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		assert.NotNil(t, doc.Operation(method, path), "route %s %s is missing from the OpenAPI document", route.Method, route.Path)
+	}
+
+	// 文档中也不应包含已不存在的路由
+	for path, item := range doc.Paths {
+		for method := range item {
+			assert.True(t, registered[method+" "+path], "OpenAPI document describes unregistered route %s %s", method, path)
+		}
+	}
+}
+
+func TestSpecSchemas(t *testing.T) {
+	doc := openapi.Build()
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+
+	// 请求结构的必填字段和最小值来自 binding 标签
+	share := doc.Components.Schemas["ShareItemRequest"]
+	require.NotNil(t, share)
+	assert.ElementsMatch(t, []string{"name", "description", "type_id", "num", "durability", "sharer_id"}, share.Required)
+	require.NotNil(t, share.Properties["num"].Minimum)
+	assert.Equal(t, 1.0, *share.Properties["num"].Minimum)
+
+	// 领取响应包含业务错误码，物品引用共享的 Item 结构
+	claim := doc.Components.Schemas["ClaimItemResponse"]
+	require.NotNil(t, claim)
+	assert.Contains(t, claim.Properties, "code")
+	assert.Equal(t, "#/components/schemas/Item", claim.Properties["item"].Ref)
+	item := doc.Components.Schemas["Item"]
+	require.NotNil(t, item)
+	assert.Equal(t, "date-time", item.Properties["expires_at"].Format)
+
+	// 所有引用的结构都已定义
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	for _, match := range regexp.MustCompile(`#/components/schemas/([A-Za-z0-9_]+)`).FindAllStringSubmatch(string(data), -1) {
+		assert.Contains(t, doc.Components.Schemas, match[1])
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	r := setupRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc["openapi"])
+	assert.Contains(t, doc["paths"], "/api/v1/items/share")
+}
+
+func TestSpecIdempotencyKey(t *testing.T) {
+	doc := openapi.Build()
+	for path, item := range doc.Paths {
+		op, ok := item["post"]
+		if !ok {
+			continue
+		}
+		var found bool
+		for _, param := range op.Parameters {
+			found = found || (param.In == "header" && param.Name == "Idempotency-Key")
+		}
+		assert.True(t, found, "POST %s does not document Idempotency-Key", path)
+		assert.Contains(t, op.Responses, "422")
+	}
+}
@@ -0,0 +1,24 @@
+// Package playertoken 签发和校验玩家令牌
+// 令牌为服务端密钥对玩家ID的 HMAC-SHA256，由持有同一密钥的游戏服务端签发给玩家，DuckEx 只负责校验
+package playertoken
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Sign 使用 secret 为玩家签发令牌
+func Sign(secret, playerID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(playerID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验令牌是否由 secret 为该玩家签发，secret 或 playerID 为空时总是返回 false
+func Verify(secret, playerID, token string) bool {
+	if secret == "" || playerID == "" {
+		return false
+	}
+	return hmac.Equal([]byte(token), []byte(Sign(secret, playerID)))
+}
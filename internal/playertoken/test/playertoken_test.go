@@ -0,0 +1,30 @@
+package test
+
+import (
+	"testing"
+
+	"duckex-server/internal/playertoken"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	token := playertoken.Sign("secret", "player123")
+	assert.Len(t, token, 64)
+	assert.True(t, playertoken.Verify("secret", "player123", token))
+
+	tests := []struct {
+		name, secret, playerID, token string
+	}{
+		{"other player", "secret", "player456", token},
+		{"other secret", "other", "player123", token},
+		{"empty token", "secret", "player123", ""},
+		{"disabled", "", "player123", playertoken.Sign("", "player123")},
+		{"empty player", "secret", "", playertoken.Sign("secret", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.False(t, playertoken.Verify(tt.secret, tt.playerID, tt.token))
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package ratelimit 提供按键划分的令牌桶限流器
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"duckex-server/internal/clock"
+)
+
+// Rule 令牌桶规则：每秒补充 Rate 个令牌，最多积累 Burst 个
+type Rule struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+}
+
+// Enabled 规则是否生效，Rate 或 Burst 为0时不限流
+func (r Rule) Enabled() bool {
+	return r.Rate > 0 && r.Burst > 0
+}
+
+// RouteRules 单个路由的限流规则，按客户端IP和分享者ID分别限流
+type RouteRules struct {
+	PerIP     Rule `json:"per_ip"`
+	PerSharer Rule `json:"per_sharer"`
+}
+
+// Result 一次请求的限流结果
+type Result struct {
+	Allowed    bool
+	Limit      int           // 桶容量
+	Remaining  int           // 本次请求后剩余的令牌数
+	Reset      time.Duration // 令牌桶恢复满额所需时间
+	RetryAfter time.Duration // 被拒绝时，下一个令牌可用前需要等待的时间
+}
+
+// Stats 限流器统计
+type Stats struct {
+	Allowed     int64 `json:"allowed"`
+	Rejected    int64 `json:"rejected"`
+	TrackedKeys int   `json:"tracked_keys"`
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter 按键划分的令牌桶限流器，每个键拥有独立的令牌桶
+type Limiter struct {
+	rule     Rule
+	clock    clock.Clock
+	buckets  map[string]*bucket
+	mutex    sync.Mutex
+	allowed  int64
+	rejected int64
+}
+
+// NewLimiter 创建新的限流器，clk 为 nil 时使用系统时间
+func NewLimiter(rule Rule, clk clock.Clock) *Limiter {
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &Limiter{
+		rule:    rule,
+		clock:   clk,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Rule 返回限流规则
+func (l *Limiter) Rule() Rule {
+	return l.rule
+}
+
+// 按经过的时间补充令牌，调用者需持有锁
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(float64(l.rule.Burst), b.tokens+elapsed*l.rule.Rate)
+	}
+	b.last = now
+}
+
+// Allow 为键消耗一个令牌，令牌不足时拒绝
+func (l *Limiter) Allow(key string) Result {
+	now := l.clock.Now()
+	l.mutex.Lock()
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: float64(l.rule.Burst), last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+
+	result := Result{Limit: l.rule.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.durationFor(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = l.durationFor(float64(l.rule.Burst) - b.tokens)
+	l.mutex.Unlock()
+
+	if result.Allowed {
+		atomic.AddInt64(&l.allowed, 1)
+	} else {
+		atomic.AddInt64(&l.rejected, 1)
+	}
+	return result
+}
+
+// 补充指定数量令牌所需的时间
+func (l *Limiter) durationFor(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+	return time.Duration(tokens / l.rule.Rate * float64(time.Second))
+}
+
+// Cleanup 删除已恢复满额的令牌桶，它们与新建的桶等价，避免长期积累大量闲置的键
+func (l *Limiter) Cleanup() {
+	now := l.clock.Now()
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= float64(l.rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// Stats 返回限流器统计
+func (l *Limiter) Stats() Stats {
+	l.mutex.Lock()
+	keys := len(l.buckets)
+	l.mutex.Unlock()
+	return Stats{
+		Allowed:     atomic.LoadInt64(&l.allowed),
+		Rejected:    atomic.LoadInt64(&l.rejected),
+		TrackedKeys: keys,
+	}
+}
@@ -0,0 +1,60 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterTokenBucket(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	limiter := ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 3}, clk)
+
+	// 突发容量内全部放行
+	for i := 2; i >= 0; i-- {
+		result := limiter.Allow("1.2.3.4")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	// 令牌耗尽后拒绝，并告知等待时间
+	result := limiter.Allow("1.2.3.4")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, time.Second, result.RetryAfter)
+	assert.Equal(t, 3*time.Second, result.Reset)
+
+	// 其他键不受影响
+	assert.True(t, limiter.Allow("5.6.7.8").Allowed)
+
+	// 时间推进后按速率补充令牌
+	clk.Advance(time.Second)
+	assert.True(t, limiter.Allow("1.2.3.4").Allowed)
+	assert.False(t, limiter.Allow("1.2.3.4").Allowed)
+
+	stats := limiter.Stats()
+	assert.Equal(t, int64(5), stats.Allowed)
+	assert.Equal(t, int64(2), stats.Rejected)
+	assert.Equal(t, 2, stats.TrackedKeys)
+}
+
+func TestLimiterCleanup(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	limiter := ratelimit.NewLimiter(ratelimit.Rule{Rate: 1, Burst: 2}, clk)
+	limiter.Allow("a")
+	limiter.Allow("b")
+	limiter.Allow("b")
+
+	// 只清理已恢复满额的令牌桶
+	clk.Advance(time.Second)
+	limiter.Cleanup()
+	assert.Equal(t, 1, limiter.Stats().TrackedKeys)
+
+	clk.Advance(time.Second)
+	limiter.Cleanup()
+	assert.Equal(t, 0, limiter.Stats().TrackedKeys)
+}
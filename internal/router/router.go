@@ -0,0 +1,115 @@
+// Package router 注册 DuckEx 的全部 HTTP 路由
+package router
+
+import (
+	"net/http"
+
+	"duckex-server/internal/config"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/middleware"
+	"duckex-server/internal/openapi"
+	"duckex-server/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handlers 路由使用的处理器
+type Handlers struct {
+	Health  *handlers.HealthHandler
+	Item    *handlers.ItemHandler
+	Return  *handlers.ReturnHandler
+	Trade   *handlers.TradeHandler
+	Listing *handlers.ListingHandler
+	Group   *handlers.GroupHandler
+	Event   *handlers.EventHandler
+	Webhook *handlers.WebhookHandler
+	Admin   *handlers.AdminHandler
+	// 内存监控器，提供 /api/v1/memory
+	MemoryMonitor *utils.MemoryMonitor
+	// 管理接口令牌，为空时管理接口不可用
+	AdminToken string
+}
+
+// NewEngine 创建带日志和错误恢复中间件的 Gin 引擎
+// 只有来自 trustedProxies 的请求才采信 X-Forwarded-For 和 X-Real-IP，为空时客户端IP总是连接的对端地址，
+// 否则客户端可以伪造请求头绕过按IP的限流
+func NewEngine(trustedProxies []string) (*gin.Engine, error) {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Register 在 r 上注册全部路由，新增路由时需同步更新 internal/openapi 中的文档
+func Register(r *gin.Engine, h Handlers) {
+	// 健康检查端点
+	r.GET("/health", h.Health.Health)
+
+	// API 文档
+	spec := openapi.Build()
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, spec)
+	})
+
+	// API路由组
+	api := r.Group("/api/v1")
+	{
+		// 分享物品
+		api.POST("/items/share", h.Item.ShareItem)
+		// 领取物品
+		api.POST("/items/claim", h.Item.ClaimItem)
+		// 两阶段领取：预留、确认和取消预留
+		api.POST("/items/reserve", h.Item.ReserveItem)
+		api.POST("/items/confirm", h.Item.ConfirmItem)
+		api.POST("/items/release", h.Item.ReleaseItem)
+		// 市场：浏览公开上架的物品并凭挂牌ID领取
+		api.GET("/listings", h.Listing.SearchListings)
+		api.GET("/listings/:id", h.Listing.GetListing)
+		api.POST("/listings/:id/claim", h.Listing.ClaimListing)
+		// 退回箱
+		api.GET("/returns", h.Return.ListReturns)
+		api.POST("/returns/collect", h.Return.CollectReturns)
+		// 玩家交易
+		api.POST("/trades", h.Trade.OpenTrade)
+		api.GET("/trades", h.Trade.ListTrades)
+		api.GET("/trades/:id", h.Trade.GetTrade)
+		api.POST("/trades/:id/accept", h.Trade.AcceptTrade)
+		api.POST("/trades/:id/cancel", h.Trade.CancelTrade)
+		// 玩家群组与群组物品
+		api.POST("/groups", h.Group.CreateGroup)
+		api.GET("/groups", h.Group.ListGroups)
+		api.GET("/groups/:id", h.Group.GetGroup)
+		api.POST("/groups/:id/invite", h.Group.InviteToGroup)
+		api.POST("/groups/:id/join", h.Group.JoinGroup)
+		api.POST("/groups/:id/leave", h.Group.LeaveGroup)
+		api.POST("/groups/:id/role", h.Group.SetGroupRole)
+		api.POST("/groups/:id/remove", h.Group.RemoveGroupMember)
+		api.GET("/groups/:id/items", h.Group.ListGroupItems)
+		// 物品事件推送（SSE）
+		api.GET("/events", h.Event.StreamEvents)
+		// 内存状态
+		api.GET("/memory", func(c *gin.Context) {
+			c.JSON(http.StatusOK, h.MemoryMonitor.GetStatus())
+		})
+	}
+
+	// 管理接口路由组
+	admin := r.Group(config.AdminRouteGroup, middleware.AdminAuth(h.AdminToken))
+	{
+		// Webhook 投递记录
+		admin.GET("/webhooks/deliveries", h.Webhook.ListDeliveries)
+		admin.GET("/webhooks/dead-letters", h.Webhook.ListDeadLetters)
+		// 物品查询与取消
+		admin.GET("/items", h.Admin.ListItems)
+		admin.GET("/items/:code", h.Admin.GetItem)
+		admin.DELETE("/items/:code", h.Admin.CancelItem)
+		// 快照导出与恢复
+		admin.GET("/snapshot", h.Admin.DumpSnapshot)
+		admin.POST("/snapshot", h.Admin.RestoreSnapshot)
+		// 内存与限流配额状态
+		admin.GET("/status", h.Admin.Status)
+		// 全部事件推送（SSE）
+		admin.GET("/events", h.Event.StreamAllEvents)
+	}
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/models"
+)
+
+// 群组业务错误，ErrNotGroupMember 与物品服务共用
+var (
+	// ErrGroupNotFound 群组不存在，或玩家既不是成员也没有被邀请
+	ErrGroupNotFound = errors.New("group not found")
+	// ErrGroupForbidden 玩家的角色不能执行该操作
+	ErrGroupForbidden = errors.New("player is not allowed to perform this group action")
+	// ErrAlreadyGroupMember 玩家已经是群组成员
+	ErrAlreadyGroupMember = errors.New("player is already a group member")
+	// ErrNotInvited 玩家没有收到群组的邀请
+	ErrNotInvited = errors.New("player has not been invited to the group")
+	// ErrGroupConflict 群组被并发修改，重试后仍然冲突
+	ErrGroupConflict = errors.New("group was modified concurrently")
+)
+
+// 群组名称的最大长度（字符数）
+const maxGroupNameLength = 64
+
+// 群组被并发修改时的最大尝试次数
+const maxGroupAttempts = 3
+
+// GroupDeps 群组服务的依赖
+type GroupDeps struct {
+	Groups   models.GroupRepository
+	ItemRepo models.ItemRepository
+	Clock    clock.Clock
+}
+
+// GroupService 玩家群组：创建、邀请、加入、退出和角色管理，以及查看分享给群组的物品
+type GroupService struct {
+	groups   models.GroupRepository
+	itemRepo models.ItemRepository
+	clock    clock.Clock
+}
+
+// NewGroupService 创建群组服务，Clock 为 nil 时使用系统时间
+func NewGroupService(deps GroupDeps) *GroupService {
+	if deps.Clock == nil {
+		deps.Clock = clock.System()
+	}
+	return &GroupService{
+		groups:   deps.Groups,
+		itemRepo: deps.ItemRepo,
+		clock:    deps.Clock,
+	}
+}
+
+// Create 创建群组，创建者成为群主
+// 返回 *ValidationError 或存储错误
+func (s *GroupService) Create(name, ownerID string) (*models.Group, error) {
+	switch {
+	case name == "":
+		return nil, &ValidationError{Field: "name", Message: "is required"}
+	case utf8.RuneCountInString(name) > maxGroupNameLength:
+		return nil, &ValidationError{Field: "name", Message: "must be at most " + strconv.Itoa(maxGroupNameLength) + " characters"}
+	case ownerID == "":
+		return nil, &ValidationError{Field: "owner_id", Message: "is required"}
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, fmt.Errorf("generate group id: %w", err)
+	}
+
+	now := s.clock.Now()
+	group := &models.Group{
+		ID:        "group-" + id,
+		Name:      name,
+		OwnerID:   ownerID,
+		CreatedAt: now,
+		Members:   []models.GroupMember{{PlayerID: ownerID, Role: models.RoleOwner, JoinedAt: now}},
+	}
+	if err := s.groups.Create(group); err != nil {
+		return nil, fmt.Errorf("store group: %w", err)
+	}
+	return group, nil
+}
+
+// Get 查看群组，只有成员和被邀请的玩家可以查看，其他玩家得到 ErrGroupNotFound
+func (s *GroupService) Get(id, playerID string) (*models.Group, error) {
+	group, err := s.groups.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil || (group.Member(playerID) == nil && !group.Invited(playerID)) {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+// List 返回玩家所在或被邀请加入的群组，新创建的在前
+func (s *GroupService) List(playerID string) ([]*models.Group, error) {
+	if playerID == "" {
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	}
+	return s.groups.ListForPlayer(playerID)
+}
+
+// Invite 管理员或群主邀请玩家，重复邀请不会改变第一次的邀请
+// 返回 *ValidationError、ErrGroupNotFound、ErrGroupForbidden、ErrAlreadyGroupMember 或存储错误
+func (s *GroupService) Invite(id, inviterID, inviteeID string) (*models.Group, error) {
+	switch {
+	case inviterID == "":
+		return nil, &ValidationError{Field: "inviter_id", Message: "is required"}
+	case inviteeID == "":
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	}
+	return s.update(func() (*models.Group, error) {
+		return s.groups.Invite(id, inviterID, inviteeID, s.clock.Now())
+	})
+}
+
+// Join 被邀请的玩家接受邀请，成为普通成员
+// 返回 *ValidationError、ErrGroupNotFound、ErrNotInvited、ErrAlreadyGroupMember 或存储错误
+func (s *GroupService) Join(id, playerID string) (*models.Group, error) {
+	if playerID == "" {
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	}
+	return s.update(func() (*models.Group, error) {
+		return s.groups.Join(id, playerID, s.clock.Now())
+	})
+}
+
+// Leave 成员退出群组，群主需要先把群组转让给其他成员
+// 返回 *ValidationError、ErrGroupNotFound、ErrNotGroupMember、ErrGroupForbidden 或存储错误
+func (s *GroupService) Leave(id, playerID string) (*models.Group, error) {
+	if playerID == "" {
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	}
+	return s.update(func() (*models.Group, error) {
+		return s.groups.Leave(id, playerID)
+	})
+}
+
+// SetRole 群主调整成员的角色，设为 owner 时转让群组，原群主成为管理员
+// 返回 *ValidationError、ErrGroupNotFound、ErrNotGroupMember、ErrGroupForbidden 或存储错误
+func (s *GroupService) SetRole(id, actorID, targetID string, role models.GroupRole) (*models.Group, error) {
+	switch {
+	case actorID == "":
+		return nil, &ValidationError{Field: "actor_id", Message: "is required"}
+	case targetID == "":
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	case !role.Valid():
+		return nil, &ValidationError{Field: "role", Message: "must be one of owner, admin, member"}
+	}
+	return s.update(func() (*models.Group, error) {
+		return s.groups.SetRole(id, actorID, targetID, role)
+	})
+}
+
+// Remove 移除等级低于操作者的成员，或由管理员撤回邀请
+// 返回 *ValidationError、ErrGroupNotFound、ErrNotGroupMember、ErrGroupForbidden 或存储错误
+func (s *GroupService) Remove(id, actorID, targetID string) (*models.Group, error) {
+	switch {
+	case actorID == "":
+		return nil, &ValidationError{Field: "actor_id", Message: "is required"}
+	case targetID == "":
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	}
+	return s.update(func() (*models.Group, error) {
+		return s.groups.Remove(id, actorID, targetID)
+	})
+}
+
+// Items 列出分享给群组、可以领取的物品，按分享时间从新到旧，只有成员可以查看
+// limit 为0时使用 DefaultListingLimit；返回 *ValidationError、ErrGroupNotFound、ErrNotGroupMember 或存储错误
+func (s *GroupService) Items(id, playerID string, limit int, cursor string) (*models.ItemPage, error) {
+	switch {
+	case playerID == "":
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	case limit < 0 || limit > MaxListingLimit:
+		return nil, &ValidationError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(MaxListingLimit)}
+	case limit == 0:
+		limit = DefaultListingLimit
+	}
+	group, err := s.groups.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	if group.Member(playerID) == nil {
+		return nil, ErrNotGroupMember
+	}
+
+	page, err := s.itemRepo.Query(models.ItemQuery{
+		GroupID:    id,
+		SortBy:     models.SortByCreatedAt,
+		Descending: true,
+		Limit:      limit,
+		Cursor:     cursor,
+	})
+	if errors.Is(err, models.ErrInvalidQuery) {
+		return nil, &ValidationError{Field: "cursor", Message: "is invalid"}
+	}
+	return page, err
+}
+
+// 执行群组变更，被并发修改时重试
+func (s *GroupService) update(change func() (*models.Group, error)) (*models.Group, error) {
+	var err error
+	for attempt := 0; attempt < maxGroupAttempts; attempt++ {
+		var group *models.Group
+		if group, err = change(); err == nil {
+			return group, nil
+		}
+		if !errors.Is(err, models.ErrGroupConflict) {
+			break
+		}
+	}
+	return nil, groupError(err)
+}
+
+// 将群组仓库错误转换为业务错误
+func groupError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrGroupNotFound):
+		return ErrGroupNotFound
+	case errors.Is(err, models.ErrGroupForbidden):
+		return ErrGroupForbidden
+	case errors.Is(err, models.ErrNotGroupMember):
+		return ErrNotGroupMember
+	case errors.Is(err, models.ErrAlreadyGroupMember):
+		return ErrAlreadyGroupMember
+	case errors.Is(err, models.ErrNotInvited):
+		return ErrNotInvited
+	case errors.Is(err, models.ErrGroupConflict):
+		return ErrGroupConflict
+	}
+	return err
+}
@@ -0,0 +1,401 @@
+// Package service 物品业务逻辑，与 HTTP、gRPC 等传输协议无关
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/utils"
+)
+
+// 业务错误，传输层据此映射为各自的状态码
+var (
+	// ErrShareDisabled 内存占用过高，暂停分享
+	ErrShareDisabled = errors.New("sharing temporarily disabled due to high memory usage")
+	// ErrNotFound 取件码对应的物品不存在或已过期
+	ErrNotFound = errors.New("item not found")
+	// ErrAlreadyClaimed 物品已被领取或预留
+	ErrAlreadyClaimed = errors.New("item already claimed")
+	// ErrReservationNotFound 预留不存在、令牌不匹配或预留已过期
+	ErrReservationNotFound = errors.New("reservation not found or expired")
+	// ErrNotGroupMember 玩家不是群组成员，不能向该群组分享或领取分享给该群组的物品
+	ErrNotGroupMember = errors.New("player is not a member of the group")
+	// ErrPlayerUnauthenticated 缺少为该玩家签发的玩家令牌，群组物品的分享和领取需要确认玩家身份
+	ErrPlayerUnauthenticated = errors.New("invalid player token")
+)
+
+// ValidationError 请求字段不合法
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// 取件码冲突时的最大生成次数
+const maxPickupCodeAttempts = 5
+
+// DefaultReservationLease 默认的预留期限，领取者需在期限内确认领取
+const DefaultReservationLease = 60 * time.Second
+
+// 取消物品时使用的领取者ID，取消通过原子领取实现，与并发领取互斥
+const cancelClaimerID = "admin:cancel"
+
+// ShareRequest 分享物品的参数
+type ShareRequest struct {
+	Name        string
+	Description string
+	TypeID      int
+	Num         int
+	Durability  float64
+	SharerID    string
+	// Listed 为 true 时物品公开上架到市场，其他玩家可以浏览并凭挂牌ID领取
+	Listed bool
+	// GroupID 不为空时物品只分享给该群组，只有群组成员可以领取，分享者也必须是成员
+	GroupID string
+	// PlayerToken 为分享者签发的玩家令牌，向群组分享时用于确认分享者身份
+	PlayerToken string
+}
+
+// Validate 检查分享参数，规则与 HTTP 接口请求结构上的 binding 标签一致
+func (r ShareRequest) Validate() error {
+	switch {
+	case r.Name == "":
+		return &ValidationError{Field: "name", Message: "is required"}
+	case r.Description == "":
+		return &ValidationError{Field: "description", Message: "is required"}
+	case r.TypeID == 0:
+		return &ValidationError{Field: "type_id", Message: "is required"}
+	case r.Num < 1:
+		return &ValidationError{Field: "num", Message: "must be at least 1"}
+	case r.Durability <= 0:
+		return &ValidationError{Field: "durability", Message: "must be greater than 0"}
+	case r.SharerID == "":
+		return &ValidationError{Field: "sharer_id", Message: "is required"}
+	case r.Listed && r.GroupID != "":
+		return &ValidationError{Field: "group_id", Message: "cannot be combined with listed"}
+	}
+	return nil
+}
+
+// Deps 物品服务的依赖，MemoryMonitor、ReturnBox 和 EventBus 为 nil 时跳过对应的步骤
+type Deps struct {
+	ItemRepo      models.ItemRepository
+	ReturnBox     models.ReturnBox
+	MemoryMonitor *utils.MemoryMonitor
+	EventBus      *events.Bus
+	Clock         clock.Clock
+	// Groups 为 nil 时不能向群组分享，已分享给群组的物品任何人都不能领取
+	Groups models.GroupRepository
+	// PlayerTokenSecret 玩家令牌密钥，群组物品的分享和领取用它确认玩家身份；为空时群组物品不能分享或领取
+	PlayerTokenSecret string
+	// ReservationLease 预留期限，不大于0时使用 DefaultReservationLease
+	ReservationLease time.Duration
+}
+
+// ItemService 物品服务：分享、领取、预留、取消和查看
+type ItemService struct {
+	itemRepo         models.ItemRepository
+	returnBox        models.ReturnBox
+	groups           models.GroupRepository
+	playerSecret     string
+	memoryMonitor    *utils.MemoryMonitor
+	eventBus         *events.Bus
+	clock            clock.Clock
+	codes            *utils.PickupCodeGenerator
+	reservationLease time.Duration
+}
+
+// NewItemService 创建物品服务，Clock 为 nil 时使用系统时间
+func NewItemService(deps Deps) *ItemService {
+	if deps.Clock == nil {
+		deps.Clock = clock.System()
+	}
+	if deps.ReservationLease <= 0 {
+		deps.ReservationLease = DefaultReservationLease
+	}
+	return &ItemService{
+		itemRepo:         deps.ItemRepo,
+		returnBox:        deps.ReturnBox,
+		groups:           deps.Groups,
+		playerSecret:     deps.PlayerTokenSecret,
+		memoryMonitor:    deps.MemoryMonitor,
+		eventBus:         deps.EventBus,
+		clock:            deps.Clock,
+		codes:            utils.NewPickupCodeGenerator(deps.Clock),
+		reservationLease: deps.ReservationLease,
+	}
+}
+
+// ReservationLease 返回预留期限
+func (s *ItemService) ReservationLease() time.Duration {
+	return s.reservationLease
+}
+
+// Share 分享物品：检查内存压力、校验参数、生成取件码并保存，被拒绝时发布 share_rejected 事件
+// 返回 ErrShareDisabled、*ValidationError、ErrPlayerUnauthenticated、ErrNotGroupMember 或存储错误
+func (s *ItemService) Share(req ShareRequest) (*models.Item, error) {
+	// 内存占用过高时暂停分享
+	if s.memoryMonitor != nil {
+		s.memoryMonitor.UpdateStatus()
+		if s.memoryMonitor.IsShareDisabled() {
+			s.eventBus.Publish(events.NewShareRejected(req.SharerID, events.RejectMemoryPressure, "memory usage above threshold", s.clock.Now()))
+			return nil, ErrShareDisabled
+		}
+	}
+	if err := req.Validate(); err != nil {
+		s.RejectShare(req.SharerID, err)
+		return nil, err
+	}
+	if req.GroupID != "" {
+		if s.groups == nil {
+			err := &ValidationError{Field: "group_id", Message: "groups are not enabled"}
+			s.RejectShare(req.SharerID, err)
+			return nil, err
+		}
+		if err := s.checkGroupMember(req.GroupID, req.SharerID, req.PlayerToken); err != nil {
+			if errors.Is(err, ErrNotGroupMember) || errors.Is(err, ErrPlayerUnauthenticated) {
+				s.RejectShare(req.SharerID, err)
+			}
+			return nil, err
+		}
+	}
+
+	// 创建时间与过期时间只取一次，保证返回与存储的过期时间一致
+	now := s.clock.Now()
+	item := &models.Item{
+		ID:          now.Format("20060102150405") + req.SharerID,
+		Name:        req.Name,
+		Description: req.Description,
+		TypeID:      req.TypeID,
+		Num:         req.Num,
+		Durability:  req.Durability,
+		SharerID:    req.SharerID,
+		GroupID:     req.GroupID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(utils.PickupCodeTTL),
+	}
+	if req.Listed {
+		listingID, err := randomHex(8)
+		if err != nil {
+			return nil, fmt.Errorf("generate listing id: %w", err)
+		}
+		item.ListingID = "listing-" + listingID
+	}
+
+	if err := storeWithPickupCode(s.itemRepo, s.codes, item); err != nil {
+		s.eventBus.Publish(events.NewShareRejected(req.SharerID, events.RejectStorageError, err.Error(), s.clock.Now()))
+		return nil, fmt.Errorf("store item: %w", err)
+	}
+	s.eventBus.Publish(events.NewItemShared(item, now))
+	return item, nil
+}
+
+// RejectShare 发布无效分享请求的 share_rejected 事件，供传输层在无法解析请求时调用
+func (s *ItemService) RejectShare(sharerID string, err error) {
+	s.eventBus.Publish(events.NewShareRejected(sharerID, events.RejectInvalidRequest, err.Error(), s.clock.Now()))
+}
+
+// Claim 原子地领取物品并发布领取事件，过期物品视为不存在，物品被领取后立即从仓库删除
+// 分享给群组的物品需要为领取者签发的 playerToken，其他物品凭取件码领取，playerToken 可以为空
+// 返回 *ValidationError、ErrNotFound、ErrAlreadyClaimed、ErrPlayerUnauthenticated、ErrNotGroupMember 或存储错误
+func (s *ItemService) Claim(pickupCode, claimerID, playerToken string) (*models.Item, error) {
+	if pickupCode == "" {
+		return nil, &ValidationError{Field: "pickup_code", Message: "is required"}
+	}
+	if claimerID == "" {
+		return nil, &ValidationError{Field: "claimer_id", Message: "is required"}
+	}
+	if err := s.checkItemGroup(pickupCode, claimerID, playerToken); err != nil {
+		return nil, err
+	}
+	item, err := s.itemRepo.Claim(pickupCode, claimerID)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	s.eventBus.Publish(events.NewItemClaimed(item, claimerID, s.clock.Now()))
+	return item, nil
+}
+
+// Reserve 为领取者预留物品，返回带有预留令牌和预留期限的物品快照，发布预留事件
+// 物品在确认前仍留在仓库中，预留期限内未确认的物品恢复为未领取；playerToken 的要求与 Claim 相同
+// 返回 *ValidationError、ErrNotFound、ErrAlreadyClaimed、ErrPlayerUnauthenticated、ErrNotGroupMember 或存储错误
+func (s *ItemService) Reserve(pickupCode, claimerID, playerToken string) (*models.Item, error) {
+	if pickupCode == "" {
+		return nil, &ValidationError{Field: "pickup_code", Message: "is required"}
+	}
+	if claimerID == "" {
+		return nil, &ValidationError{Field: "claimer_id", Message: "is required"}
+	}
+	if err := s.checkItemGroup(pickupCode, claimerID, playerToken); err != nil {
+		return nil, err
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, fmt.Errorf("generate reservation token: %w", err)
+	}
+	now := s.clock.Now()
+	item, err := s.itemRepo.Reserve(pickupCode, claimerID, token, now.Add(s.reservationLease))
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	s.eventBus.Publish(events.NewItemReserved(item, now))
+	return item, nil
+}
+
+// Confirm 确认预留，物品从仓库删除并发布领取事件
+// 返回 *ValidationError、ErrReservationNotFound 或存储错误
+func (s *ItemService) Confirm(pickupCode, token string) (*models.Item, error) {
+	if err := validateReservation(pickupCode, token); err != nil {
+		return nil, err
+	}
+	item, err := s.itemRepo.Confirm(pickupCode, token)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	s.eventBus.Publish(events.NewItemClaimed(item, item.ClaimerID, s.clock.Now()))
+	return item, nil
+}
+
+// Release 取消预留，物品恢复为未领取并发布预留释放事件，返回恢复后的物品
+// 返回 *ValidationError、ErrReservationNotFound 或存储错误
+func (s *ItemService) Release(pickupCode, token string) (*models.Item, error) {
+	if err := validateReservation(pickupCode, token); err != nil {
+		return nil, err
+	}
+	item, err := s.itemRepo.Release(pickupCode, token)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+	event := events.NewReservationReleased(item, events.ReleaseByClaimer, s.clock.Now())
+	s.eventBus.Publish(event)
+	released := event.Item
+	return &released, nil
+}
+
+// ReleaseExpiredReservations 恢复预留已过期的物品，为每个物品发布预留释放事件，返回恢复的数量
+func (s *ItemService) ReleaseExpiredReservations() (int, error) {
+	released, err := s.itemRepo.ReleaseExpiredReservations()
+	now := s.clock.Now()
+	for _, item := range released {
+		s.eventBus.Publish(events.NewReservationReleased(item, events.ReleaseExpired, now))
+	}
+	return len(released), err
+}
+
+// 分享给群组的物品只有群组成员可以领取或预留；物品不存在时交给随后的原子操作返回 ErrNotFound
+// 成员资格在原子领取之前检查，领取期间被移出群组的玩家仍可能完成这一次领取
+func (s *ItemService) checkItemGroup(pickupCode, claimerID, playerToken string) error {
+	item, err := s.itemRepo.GetByPickupCode(pickupCode)
+	if err != nil {
+		return err
+	}
+	if item == nil || item.GroupID == "" {
+		return nil
+	}
+	return s.checkGroupMember(item.GroupID, claimerID, playerToken)
+}
+
+// 确认 playerToken 是为 playerID 签发的，否则返回 ErrPlayerUnauthenticated；
+// 玩家不是群组成员时返回 ErrNotGroupMember，未配置群组仓库时同样拒绝
+func (s *ItemService) checkGroupMember(groupID, playerID, playerToken string) error {
+	if !playertoken.Verify(s.playerSecret, playerID, playerToken) {
+		return ErrPlayerUnauthenticated
+	}
+	if s.groups == nil {
+		return ErrNotGroupMember
+	}
+	member, err := s.groups.IsMember(groupID, playerID)
+	if err != nil {
+		return err
+	}
+	if !member {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+func validateReservation(pickupCode, token string) error {
+	if pickupCode == "" {
+		return &ValidationError{Field: "pickup_code", Message: "is required"}
+	}
+	if token == "" {
+		return &ValidationError{Field: "reservation_token", Message: "is required"}
+	}
+	return nil
+}
+
+// 生成 n 个随机字节的十六进制字符串，用于令牌和ID
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// 生成取件码并保存物品，取件码冲突时重新生成
+func storeWithPickupCode(repo models.ItemRepository, codes *utils.PickupCodeGenerator, item *models.Item) error {
+	var err error
+	for attempt := 0; attempt < maxPickupCodeAttempts; attempt++ {
+		item.PickupCode = codes.Generate()
+		if err = repo.Create(item); !errors.Is(err, models.ErrDuplicatePickupCode) {
+			break
+		}
+	}
+	return err
+}
+
+// Cancel 取消分享：物品从仓库移除并退回到分享者的退回箱，发布取消事件
+// 返回 ErrNotFound、ErrAlreadyClaimed 或存储错误
+func (s *ItemService) Cancel(pickupCode string) (*models.Item, error) {
+	item, err := s.itemRepo.Claim(pickupCode, cancelClaimerID)
+	if err != nil {
+		return nil, repositoryError(err)
+	}
+
+	// 退回的是未被领取的原物品
+	item.IsClaimed = false
+	item.ClaimerID = ""
+	if s.returnBox != nil {
+		if err := s.returnBox.Add(item); err != nil {
+			return nil, fmt.Errorf("return cancelled item: %w", err)
+		}
+	}
+	s.eventBus.Publish(events.NewItemCancelled(item, s.clock.Now()))
+	return item, nil
+}
+
+// Lookup 按取件码查看物品，不会领取物品，物品不存在或已过期时返回 ErrNotFound
+func (s *ItemService) Lookup(pickupCode string) (*models.Item, error) {
+	item, err := s.itemRepo.GetByPickupCode(pickupCode)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil {
+		return nil, ErrNotFound
+	}
+	return item, nil
+}
+
+// 将仓库错误转换为业务错误
+func repositoryError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrItemNotFound):
+		return ErrNotFound
+	case errors.Is(err, models.ErrItemClaimed):
+		return ErrAlreadyClaimed
+	case errors.Is(err, models.ErrReservationNotFound):
+		return ErrReservationNotFound
+	}
+	return err
+}
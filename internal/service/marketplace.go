@@ -0,0 +1,131 @@
+package service
+
+import (
+	"errors"
+	"strconv"
+
+	"duckex-server/internal/models"
+)
+
+// 市场浏览的分页大小
+const (
+	DefaultListingLimit = 20
+	MaxListingLimit     = 100
+)
+
+// ListingQuery 浏览和搜索市场的条件，零值字段不参与过滤
+type ListingQuery struct {
+	// Text 全文检索，名称和描述需包含全部检索词
+	Text   string
+	TypeID int
+	// Name 按物品名称过滤，不区分大小写的子串匹配
+	Name          string
+	SharerID      string
+	MinDurability *float64
+	MaxDurability *float64
+	// Limit 每页数量，为0时使用 DefaultListingLimit
+	Limit int
+	// Cursor 上一页返回的 NextCursor，为空时从第一页开始
+	Cursor string
+}
+
+// Validate 检查搜索条件，游标在查询时由仓库校验
+func (q ListingQuery) Validate() error {
+	switch {
+	case q.TypeID < 0:
+		return &ValidationError{Field: "type_id", Message: "must not be negative"}
+	case q.Limit < 0 || q.Limit > MaxListingLimit:
+		return &ValidationError{Field: "limit", Message: "must be between 1 and " + strconv.Itoa(MaxListingLimit)}
+	case q.MinDurability != nil && q.MaxDurability != nil && *q.MinDurability > *q.MaxDurability:
+		return &ValidationError{Field: "min_durability", Message: "must not exceed max_durability"}
+	}
+	return nil
+}
+
+// 转为仓库查询：只查上架且可以领取的物品，按上架时间从新到旧
+func (q ListingQuery) itemQuery() models.ItemQuery {
+	limit := q.Limit
+	if limit == 0 {
+		limit = DefaultListingLimit
+	}
+	return models.ItemQuery{
+		Text:          q.Text,
+		Name:          q.Name,
+		TypeID:        q.TypeID,
+		SharerID:      q.SharerID,
+		ListedOnly:    true,
+		MinDurability: q.MinDurability,
+		MaxDurability: q.MaxDurability,
+		SortBy:        models.SortByCreatedAt,
+		Descending:    true,
+		Limit:         limit,
+		Cursor:        q.Cursor,
+	}
+}
+
+// ListingPage 一页市场挂牌，NextCursor 为空表示没有更多结果
+type ListingPage struct {
+	Listings   []*models.Listing
+	NextCursor string
+}
+
+// SearchListings 按条件浏览市场中可以领取的挂牌，按上架时间从新到旧排列
+// 已被预留的物品不会出现在结果中；返回 *ValidationError 或存储错误
+func (s *ItemService) SearchListings(q ListingQuery) (*ListingPage, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	result, err := s.itemRepo.Query(q.itemQuery())
+	if errors.Is(err, models.ErrInvalidQuery) {
+		return nil, &ValidationError{Field: "cursor", Message: "is invalid"}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ListingPage{Listings: make([]*models.Listing, 0, len(result.Items)), NextCursor: result.NextCursor}
+	for _, item := range result.Items {
+		page.Listings = append(page.Listings, models.NewListing(item))
+	}
+	return page, nil
+}
+
+// GetListing 查看挂牌，挂牌不存在、已过期或已被预留时返回 ErrNotFound
+func (s *ItemService) GetListing(listingID string) (*models.Listing, error) {
+	item, err := s.findListing(listingID)
+	if err != nil {
+		return nil, err
+	}
+	if !item.Pending(s.clock.Now()) {
+		return nil, ErrNotFound
+	}
+	return models.NewListing(item), nil
+}
+
+// ClaimListing 凭挂牌ID领取物品，与凭取件码领取使用同一个原子领取操作并发布领取事件，playerToken 的要求与 Claim 相同
+// 返回 *ValidationError、ErrNotFound、ErrAlreadyClaimed 或存储错误
+func (s *ItemService) ClaimListing(listingID, claimerID, playerToken string) (*models.Item, error) {
+	if claimerID == "" {
+		return nil, &ValidationError{Field: "claimer_id", Message: "is required"}
+	}
+	item, err := s.findListing(listingID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Claim(item.PickupCode, claimerID, playerToken)
+}
+
+// 按挂牌ID查找未过期的物品，包括已被预留的
+func (s *ItemService) findListing(listingID string) (*models.Item, error) {
+	if listingID == "" {
+		return nil, &ValidationError{Field: "listing_id", Message: "is required"}
+	}
+	page, err := s.itemRepo.Query(models.ItemQuery{ListingID: listingID, IncludeReserved: true, Limit: 1})
+	if err != nil {
+		return nil, err
+	}
+	if len(page.Items) == 0 {
+		return nil, ErrNotFound
+	}
+	return page.Items[0], nil
+}
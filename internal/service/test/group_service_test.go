@@ -0,0 +1,265 @@
+package test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 群组物品的分享和领取使用的玩家令牌密钥
+const groupPlayerSecret = "player-secret"
+
+// 为玩家签发的令牌
+func tokenFor(playerID string) string {
+	return playertoken.Sign(groupPlayerSecret, playerID)
+}
+
+type groupFixture struct {
+	groups    *service.GroupService
+	items     *service.ItemService
+	itemRepo  models.ItemRepository
+	groupRepo models.GroupRepository
+	clock     *clock.Fake
+}
+
+// 群组服务与物品服务共享群组仓库和物品仓库
+func newGroupFixture(t *testing.T) *groupFixture {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	itemRepo := models.NewInMemoryItemRepository(clk)
+	groupRepo := models.NewInMemoryGroupRepository()
+	return &groupFixture{
+		groups: service.NewGroupService(service.GroupDeps{Groups: groupRepo, ItemRepo: itemRepo, Clock: clk}),
+		items: service.NewItemService(service.Deps{
+			ItemRepo:          itemRepo,
+			Groups:            groupRepo,
+			PlayerTokenSecret: groupPlayerSecret,
+			Clock:             clk,
+		}),
+		itemRepo:  itemRepo,
+		groupRepo: groupRepo,
+		clock:     clk,
+	}
+}
+
+// 创建 alice 为群主、bob 为成员的群组
+func (f *groupFixture) clan(t *testing.T) *models.Group {
+	group, err := f.groups.Create("Duck Clan", "alice")
+	require.NoError(t, err)
+	_, err = f.groups.Invite(group.ID, "alice", "bob")
+	require.NoError(t, err)
+	group, err = f.groups.Join(group.ID, "bob")
+	require.NoError(t, err)
+	return group
+}
+
+func (f *groupFixture) shareToGroup(t *testing.T, groupID, sharerID string) *models.Item {
+	req := validShare()
+	req.SharerID = sharerID
+	req.GroupID = groupID
+	req.PlayerToken = tokenFor(sharerID)
+	item, err := f.items.Share(req)
+	require.NoError(t, err)
+	return item
+}
+
+func TestCreateGroup(t *testing.T) {
+	f := newGroupFixture(t)
+
+	group, err := f.groups.Create("Duck Clan", "alice")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(group.ID, "group-"))
+	assert.Equal(t, "alice", group.OwnerID)
+	require.NotNil(t, group.Member("alice"))
+	assert.Equal(t, models.RoleOwner, group.Member("alice").Role)
+	assert.Equal(t, f.clock.Now(), group.CreatedAt)
+
+	groups, err := f.groups.List("alice")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+	assert.Equal(t, group.ID, groups[0].ID)
+
+	tests := []struct {
+		name, groupName, ownerID, field string
+	}{
+		{"missing name", "", "alice", "name"},
+		{"long name", strings.Repeat("鸭", 65), "alice", "name"},
+		{"missing owner", "Duck Clan", "", "owner_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.groups.Create(tt.groupName, tt.ownerID)
+			var invalid *service.ValidationError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.field, invalid.Field)
+		})
+	}
+}
+
+func TestGroupMembership(t *testing.T) {
+	f := newGroupFixture(t)
+	group, err := f.groups.Create("Duck Clan", "alice")
+	require.NoError(t, err)
+
+	// 没有邀请不能加入，也看不到群组
+	_, err = f.groups.Join(group.ID, "bob")
+	assert.ErrorIs(t, err, service.ErrNotInvited)
+	_, err = f.groups.Get(group.ID, "bob")
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+
+	_, err = f.groups.Invite(group.ID, "alice", "bob")
+	require.NoError(t, err)
+	invited, err := f.groups.Get(group.ID, "bob")
+	require.NoError(t, err)
+	assert.True(t, invited.Invited("bob"))
+
+	joined, err := f.groups.Join(group.ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, models.RoleMember, joined.Member("bob").Role)
+	_, err = f.groups.Invite(group.ID, "bob", "carol")
+	assert.ErrorIs(t, err, service.ErrGroupForbidden)
+
+	// 提升为管理员后可以邀请
+	_, err = f.groups.SetRole(group.ID, "alice", "bob", models.RoleAdmin)
+	require.NoError(t, err)
+	_, err = f.groups.Invite(group.ID, "bob", "carol")
+	require.NoError(t, err)
+	_, err = f.groups.SetRole(group.ID, "alice", "bob", models.GroupRole("king"))
+	var invalid *service.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "role", invalid.Field)
+
+	// 群主不能退出，普通成员可以退出
+	_, err = f.groups.Leave(group.ID, "alice")
+	assert.ErrorIs(t, err, service.ErrGroupForbidden)
+	left, err := f.groups.Leave(group.ID, "bob")
+	require.NoError(t, err)
+	assert.Nil(t, left.Member("bob"))
+	_, err = f.groups.Remove(group.ID, "alice", "bob")
+	assert.ErrorIs(t, err, service.ErrNotGroupMember)
+
+	_, err = f.groups.Invite("group-missing", "alice", "bob")
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+}
+
+func TestShareToGroup(t *testing.T) {
+	f := newGroupFixture(t)
+	group := f.clan(t)
+
+	item := f.shareToGroup(t, group.ID, "bob")
+	assert.Equal(t, group.ID, item.GroupID)
+
+	// 非成员不能向群组分享
+	req := validShare()
+	req.SharerID = "mallory"
+	req.GroupID = group.ID
+	req.PlayerToken = tokenFor("mallory")
+	_, err := f.items.Share(req)
+	assert.ErrorIs(t, err, service.ErrNotGroupMember)
+
+	// 冒用成员的ID需要该成员的玩家令牌
+	req.SharerID = "bob"
+	_, err = f.items.Share(req)
+	assert.ErrorIs(t, err, service.ErrPlayerUnauthenticated)
+	req.PlayerToken = ""
+	_, err = f.items.Share(req)
+	assert.ErrorIs(t, err, service.ErrPlayerUnauthenticated)
+
+	// 群组分享不能同时公开上架
+	req.SharerID = "alice"
+	req.PlayerToken = tokenFor("alice")
+	req.Listed = true
+	_, err = f.items.Share(req)
+	var invalid *service.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "group_id", invalid.Field)
+
+	// 未配置群组仓库时不能向群组分享
+	plain := service.NewItemService(service.Deps{ItemRepo: models.NewInMemoryItemRepository(f.clock), Clock: f.clock})
+	req.Listed = false
+	_, err = plain.Share(req)
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "group_id", invalid.Field)
+}
+
+func TestClaimGroupItem(t *testing.T) {
+	f := newGroupFixture(t)
+	group := f.clan(t)
+
+	item := f.shareToGroup(t, group.ID, "alice")
+	_, err := f.items.Claim(item.PickupCode, "mallory", tokenFor("mallory"))
+	assert.ErrorIs(t, err, service.ErrNotGroupMember)
+	_, err = f.items.Reserve(item.PickupCode, "mallory", tokenFor("mallory"))
+	assert.ErrorIs(t, err, service.ErrNotGroupMember)
+
+	// 以成员的ID领取需要该成员的玩家令牌，冒用失败时物品仍可领取
+	_, err = f.items.Claim(item.PickupCode, "bob", "")
+	assert.ErrorIs(t, err, service.ErrPlayerUnauthenticated)
+	_, err = f.items.Claim(item.PickupCode, "bob", tokenFor("mallory"))
+	assert.ErrorIs(t, err, service.ErrPlayerUnauthenticated)
+	_, err = f.items.Reserve(item.PickupCode, "bob", tokenFor("mallory"))
+	assert.ErrorIs(t, err, service.ErrPlayerUnauthenticated)
+
+	claimed, err := f.items.Claim(item.PickupCode, "bob", tokenFor("bob"))
+	require.NoError(t, err)
+	assert.Equal(t, "bob", claimed.ClaimerID)
+
+	// 退出群组后不能再领取
+	item = f.shareToGroup(t, group.ID, "alice")
+	_, err = f.groups.Leave(group.ID, "bob")
+	require.NoError(t, err)
+	_, err = f.items.Reserve(item.PickupCode, "bob", tokenFor("bob"))
+	assert.ErrorIs(t, err, service.ErrNotGroupMember)
+	reserved, err := f.items.Reserve(item.PickupCode, "alice", tokenFor("alice"))
+	require.NoError(t, err)
+	require.NotNil(t, reserved.Reservation)
+	assert.NotEmpty(t, reserved.Reservation.Token)
+
+	// 未配置玩家令牌密钥时无法确认身份，群组物品不能被领取
+	item = f.shareToGroup(t, group.ID, "alice")
+	noSecret := service.NewItemService(service.Deps{ItemRepo: f.itemRepo, Groups: f.groupRepo, Clock: f.clock})
+	_, err = noSecret.Claim(item.PickupCode, "alice", tokenFor("alice"))
+	assert.ErrorIs(t, err, service.ErrPlayerUnauthenticated)
+}
+
+func TestGroupItems(t *testing.T) {
+	f := newGroupFixture(t)
+	group := f.clan(t)
+	other, err := f.groups.Create("Goose Gang", "carol")
+	require.NoError(t, err)
+
+	first := f.shareToGroup(t, group.ID, "alice")
+	f.clock.Advance(time.Second)
+	second := f.shareToGroup(t, group.ID, "bob")
+	f.shareToGroup(t, other.ID, "carol")
+	_, err = f.items.Share(validShare())
+	require.NoError(t, err)
+
+	// 只列出本群组的物品，新分享的在前
+	page, err := f.groups.Items(group.ID, "bob", 1, "")
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, second.PickupCode, page.Items[0].PickupCode)
+	require.NotEmpty(t, page.NextCursor)
+	page, err = f.groups.Items(group.ID, "bob", 1, page.NextCursor)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, first.PickupCode, page.Items[0].PickupCode)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = f.groups.Items(group.ID, "carol", 0, "")
+	assert.ErrorIs(t, err, service.ErrNotGroupMember)
+	_, err = f.groups.Items("group-missing", "bob", 0, "")
+	assert.ErrorIs(t, err, service.ErrGroupNotFound)
+	_, err = f.groups.Items(group.ID, "bob", 0, "bogus")
+	var invalid *service.ValidationError
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "cursor", invalid.Field)
+}
@@ -0,0 +1,318 @@
+package test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+	"duckex-server/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fixture struct {
+	items     *service.ItemService
+	itemRepo  models.ItemRepository
+	returnBox models.ReturnBox
+	events    <-chan events.Event
+	clock     *clock.Fake
+}
+
+// 使用内存仓库和假时钟创建物品服务，monitor 可以为 nil
+func newFixture(t *testing.T, monitor *utils.MemoryMonitor) *fixture {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	itemRepo := models.NewInMemoryItemRepository(clk)
+	returnBox := models.NewInMemoryReturnBox(0, clk)
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(16)
+	t.Cleanup(unsubscribe)
+	return &fixture{
+		items: service.NewItemService(service.Deps{
+			ItemRepo:      itemRepo,
+			ReturnBox:     returnBox,
+			MemoryMonitor: monitor,
+			EventBus:      bus,
+			Clock:         clk,
+		}),
+		itemRepo:  itemRepo,
+		returnBox: returnBox,
+		events:    ch,
+		clock:     clk,
+	}
+}
+
+// 读取下一个事件
+func (f *fixture) nextEvent(t *testing.T) events.Event {
+	select {
+	case event := <-f.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event published")
+		return nil
+	}
+}
+
+func validShare() service.ShareRequest {
+	return service.ShareRequest{
+		Name:        "Golden Duck",
+		Description: "Shiny",
+		TypeID:      1001,
+		Num:         1,
+		Durability:  0.9,
+		SharerID:    "alice",
+	}
+}
+
+func TestShare(t *testing.T) {
+	f := newFixture(t, nil)
+
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	assert.NotEmpty(t, item.PickupCode)
+	assert.Equal(t, f.clock.Now().Add(utils.PickupCodeTTL), item.ExpiresAt)
+
+	stored, err := f.itemRepo.GetByPickupCode(item.PickupCode)
+	require.NoError(t, err)
+	require.NotNil(t, stored)
+	assert.Equal(t, "alice", stored.SharerID)
+
+	shared, ok := f.nextEvent(t).(*events.ItemShared)
+	require.True(t, ok)
+	assert.Equal(t, item.ID, shared.Item.ID)
+	assert.Empty(t, shared.Item.PickupCode, "events must not carry pickup codes")
+}
+
+func TestShareValidation(t *testing.T) {
+	tests := []struct {
+		field  string
+		modify func(*service.ShareRequest)
+	}{
+		{"name", func(r *service.ShareRequest) { r.Name = "" }},
+		{"type_id", func(r *service.ShareRequest) { r.TypeID = 0 }},
+		{"num", func(r *service.ShareRequest) { r.Num = 0 }},
+		{"durability", func(r *service.ShareRequest) { r.Durability = 0 }},
+		{"sharer_id", func(r *service.ShareRequest) { r.SharerID = "" }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.field, func(t *testing.T) {
+			f := newFixture(t, nil)
+			req := validShare()
+			tt.modify(&req)
+
+			_, err := f.items.Share(req)
+			var invalid *service.ValidationError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, tt.field, invalid.Field)
+
+			rejected, ok := f.nextEvent(t).(*events.ShareRejected)
+			require.True(t, ok)
+			assert.Equal(t, events.RejectInvalidRequest, rejected.Reason)
+		})
+	}
+}
+
+func TestShareDisabledUnderMemoryPressure(t *testing.T) {
+	// 保留足够的已分配内存，使用量超过 1MB 上限的阈值
+	ballast := make([]byte, 4<<20)
+	defer runtime.KeepAlive(ballast)
+	f := newFixture(t, utils.NewMemoryMonitor(1))
+
+	_, err := f.items.Share(validShare())
+	assert.ErrorIs(t, err, service.ErrShareDisabled)
+
+	rejected, ok := f.nextEvent(t).(*events.ShareRejected)
+	require.True(t, ok)
+	assert.Equal(t, events.RejectMemoryPressure, rejected.Reason)
+	assert.Equal(t, "alice", rejected.SharerID())
+}
+
+func TestClaim(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	f.nextEvent(t)
+
+	claimed, err := f.items.Claim(item.PickupCode, "bob", "")
+	require.NoError(t, err)
+	assert.True(t, claimed.IsClaimed)
+	assert.Equal(t, "bob", claimed.ClaimerID)
+
+	event, ok := f.nextEvent(t).(*events.ItemClaimed)
+	require.True(t, ok)
+	assert.Equal(t, "bob", event.ClaimerID)
+
+	// 领取后物品从仓库移除
+	_, err = f.items.Claim(item.PickupCode, "carol", "")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestClaimErrors(t *testing.T) {
+	f := newFixture(t, nil)
+
+	var invalid *service.ValidationError
+	_, err := f.items.Claim("", "bob", "")
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "pickup_code", invalid.Field)
+	_, err = f.items.Claim("123456", "", "")
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "claimer_id", invalid.Field)
+
+	_, err = f.items.Claim("123456", "bob", "")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+
+	// 过期物品视为不存在
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	f.clock.Advance(utils.PickupCodeTTL + time.Second)
+	_, err = f.items.Claim(item.PickupCode, "bob", "")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestCancel(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	f.nextEvent(t)
+
+	cancelled, err := f.items.Cancel(item.PickupCode)
+	require.NoError(t, err)
+	assert.False(t, cancelled.IsClaimed)
+	assert.Empty(t, cancelled.ClaimerID)
+
+	_, ok := f.nextEvent(t).(*events.ItemCancelled)
+	assert.True(t, ok)
+
+	returned, err := f.returnBox.List("alice")
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	assert.Equal(t, item.PickupCode, returned[0].Item.PickupCode)
+
+	_, err = f.items.Lookup(item.PickupCode)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	_, err = f.items.Cancel(item.PickupCode)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestLookup(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+
+	found, err := f.items.Lookup(item.PickupCode)
+	require.NoError(t, err)
+	assert.Equal(t, item.ID, found.ID)
+	assert.False(t, found.IsClaimed)
+}
+
+func TestReserveAndConfirm(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	f.nextEvent(t)
+
+	reserved, err := f.items.Reserve(item.PickupCode, "bob", "")
+	require.NoError(t, err)
+	require.NotNil(t, reserved.Reservation)
+	assert.Len(t, reserved.Reservation.Token, 32)
+	assert.Equal(t, f.clock.Now().Add(service.DefaultReservationLease), reserved.Reservation.ExpiresAt)
+	assert.Equal(t, "bob", reserved.ClaimerID)
+
+	// 事件中不包含预留令牌
+	event, ok := f.nextEvent(t).(*events.ItemReserved)
+	require.True(t, ok)
+	assert.Equal(t, "bob", event.ClaimerID)
+	assert.Nil(t, event.Item.Reservation)
+	assert.Equal(t, reserved.Reservation.ExpiresAt, event.LeaseExpiresAt)
+
+	// 预留期间其他人不能领取
+	_, err = f.items.Claim(item.PickupCode, "carol", "")
+	assert.ErrorIs(t, err, service.ErrAlreadyClaimed)
+	_, err = f.items.Confirm(item.PickupCode, "wrong")
+	assert.ErrorIs(t, err, service.ErrReservationNotFound)
+
+	confirmed, err := f.items.Confirm(item.PickupCode, reserved.Reservation.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "bob", confirmed.ClaimerID)
+	claimed, ok := f.nextEvent(t).(*events.ItemClaimed)
+	require.True(t, ok)
+	assert.Equal(t, "bob", claimed.ClaimerID)
+
+	_, err = f.items.Lookup(item.PickupCode)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestReserveValidation(t *testing.T) {
+	f := newFixture(t, nil)
+
+	var invalid *service.ValidationError
+	_, err := f.items.Reserve("", "bob", "")
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "pickup_code", invalid.Field)
+	_, err = f.items.Confirm("123456", "")
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "reservation_token", invalid.Field)
+
+	_, err = f.items.Reserve("123456", "bob", "")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestRelease(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	reserved, err := f.items.Reserve(item.PickupCode, "bob", "")
+	require.NoError(t, err)
+	f.nextEvent(t)
+	f.nextEvent(t)
+
+	released, err := f.items.Release(item.PickupCode, reserved.Reservation.Token)
+	require.NoError(t, err)
+	assert.False(t, released.IsClaimed)
+	assert.Empty(t, released.ClaimerID)
+
+	event, ok := f.nextEvent(t).(*events.ReservationReleased)
+	require.True(t, ok)
+	assert.Equal(t, events.ReleaseByClaimer, event.Reason)
+	assert.Equal(t, "bob", event.ClaimerID)
+
+	// 取消后物品可以被其他人领取
+	claimed, err := f.items.Claim(item.PickupCode, "carol", "")
+	require.NoError(t, err)
+	assert.Equal(t, "carol", claimed.ClaimerID)
+}
+
+func TestReservationLeaseExpires(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	reserved, err := f.items.Reserve(item.PickupCode, "bob", "")
+	require.NoError(t, err)
+	f.nextEvent(t)
+	f.nextEvent(t)
+
+	f.clock.Advance(service.DefaultReservationLease + time.Second)
+
+	// 预留过期后不能确认，物品恢复为未领取
+	_, err = f.items.Confirm(item.PickupCode, reserved.Reservation.Token)
+	assert.ErrorIs(t, err, service.ErrReservationNotFound)
+	found, err := f.items.Lookup(item.PickupCode)
+	require.NoError(t, err)
+	assert.False(t, found.IsClaimed)
+
+	count, err := f.items.ReleaseExpiredReservations()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	event, ok := f.nextEvent(t).(*events.ReservationReleased)
+	require.True(t, ok)
+	assert.Equal(t, events.ReleaseExpired, event.Reason)
+	assert.False(t, event.Item.IsClaimed)
+
+	count, err = f.items.ReleaseExpiredReservations()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
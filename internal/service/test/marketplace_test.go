@@ -0,0 +1,167 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 上架一个物品，每次上架推进一秒使上架时间各不相同
+func (f *fixture) list(t *testing.T, name string, typeID int, durability float64, sharerID string) *models.Item {
+	req := validShare()
+	req.Name = name
+	req.TypeID = typeID
+	req.Durability = durability
+	req.SharerID = sharerID
+	req.Listed = true
+	item, err := f.items.Share(req)
+	require.NoError(t, err)
+	require.NotEmpty(t, item.ListingID)
+	f.clock.Advance(time.Second)
+	return item
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+func TestShareUnlistedByDefault(t *testing.T) {
+	f := newFixture(t, nil)
+	item, err := f.items.Share(validShare())
+	require.NoError(t, err)
+	assert.Empty(t, item.ListingID)
+
+	page, err := f.items.SearchListings(service.ListingQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Listings)
+}
+
+func TestSearchListings(t *testing.T) {
+	f := newFixture(t, nil)
+	sword := f.list(t, "Iron Sword", 1001, 40, "alice")
+	f.list(t, "Golden Duck", 2001, 95, "bob")
+	axe := f.list(t, "Iron Axe", 1001, 80, "bob")
+
+	page, err := f.items.SearchListings(service.ListingQuery{})
+	require.NoError(t, err)
+	require.Len(t, page.Listings, 3)
+	// 最新上架的在前
+	assert.Equal(t, axe.ListingID, page.Listings[0].ListingID)
+	assert.Empty(t, page.NextCursor)
+
+	cases := []struct {
+		name  string
+		query service.ListingQuery
+		want  []string
+	}{
+		{"type", service.ListingQuery{TypeID: 1001}, []string{axe.ListingID, sword.ListingID}},
+		{"name", service.ListingQuery{Name: "iron"}, []string{axe.ListingID, sword.ListingID}},
+		{"sharer", service.ListingQuery{SharerID: "alice"}, []string{sword.ListingID}},
+		{"text", service.ListingQuery{Text: "AXE iron"}, []string{axe.ListingID}},
+		{"text no match", service.ListingQuery{Text: "iron duck"}, nil},
+		{"durability", service.ListingQuery{TypeID: 1001, MinDurability: float(50), MaxDurability: float(90)}, []string{axe.ListingID}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			page, err := f.items.SearchListings(c.query)
+			require.NoError(t, err)
+			var got []string
+			for _, listing := range page.Listings {
+				got = append(got, listing.ListingID)
+			}
+			assert.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestSearchListingsPagination(t *testing.T) {
+	f := newFixture(t, nil)
+	for i := 0; i < 5; i++ {
+		f.list(t, "Duck", 1001, 50, "alice")
+	}
+
+	seen := make(map[string]bool)
+	query := service.ListingQuery{Limit: 2}
+	pages := 0
+	for {
+		page, err := f.items.SearchListings(query)
+		require.NoError(t, err)
+		pages++
+		for _, listing := range page.Listings {
+			assert.False(t, seen[listing.ListingID], "listing returned twice")
+			seen[listing.ListingID] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, 3, pages)
+	assert.Len(t, seen, 5)
+}
+
+func TestSearchListingsValidation(t *testing.T) {
+	f := newFixture(t, nil)
+	cases := map[string]service.ListingQuery{
+		"limit":          {Limit: service.MaxListingLimit + 1},
+		"min_durability": {MinDurability: float(80), MaxDurability: float(20)},
+		"cursor":         {Cursor: "not-a-cursor"},
+	}
+	for field, query := range cases {
+		_, err := f.items.SearchListings(query)
+		var invalid *service.ValidationError
+		require.ErrorAs(t, err, &invalid, field)
+		assert.Equal(t, field, invalid.Field)
+	}
+}
+
+func TestClaimListing(t *testing.T) {
+	f := newFixture(t, nil)
+	item := f.list(t, "Golden Duck", 1001, 90, "alice")
+	f.nextEvent(t)
+
+	listing, err := f.items.GetListing(item.ListingID)
+	require.NoError(t, err)
+	assert.Equal(t, "Golden Duck", listing.Name)
+
+	claimed, err := f.items.ClaimListing(item.ListingID, "bob", "")
+	require.NoError(t, err)
+	assert.Equal(t, item.PickupCode, claimed.PickupCode)
+	event, ok := f.nextEvent(t).(*events.ItemClaimed)
+	require.True(t, ok)
+	assert.Equal(t, "bob", event.ClaimerID)
+
+	_, err = f.items.ClaimListing(item.ListingID, "carol", "")
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	_, err = f.items.GetListing(item.ListingID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+}
+
+func TestReservedListingIsHidden(t *testing.T) {
+	f := newFixture(t, nil)
+	item := f.list(t, "Golden Duck", 1001, 90, "alice")
+
+	_, err := f.items.Reserve(item.PickupCode, "bob", "")
+	require.NoError(t, err)
+
+	page, err := f.items.SearchListings(service.ListingQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Listings)
+	_, err = f.items.GetListing(item.ListingID)
+	assert.ErrorIs(t, err, service.ErrNotFound)
+	// 预留期间凭挂牌ID领取与凭取件码领取的结果相同
+	_, err = f.items.ClaimListing(item.ListingID, "carol", "")
+	assert.ErrorIs(t, err, service.ErrAlreadyClaimed)
+
+	// 预留过期后重新出现在市场中
+	f.clock.Advance(service.DefaultReservationLease + time.Second)
+	page, err = f.items.SearchListings(service.ListingQuery{})
+	require.NoError(t, err)
+	assert.Len(t, page.Listings, 1)
+}
@@ -0,0 +1,356 @@
+package test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/service"
+	"duckex-server/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tradeFixture struct {
+	trades    *service.TradeService
+	items     *service.ItemService
+	returnBox models.ReturnBox
+	events    <-chan events.Event
+	clock     *clock.Fake
+}
+
+// 交易服务与物品服务共享内存仓库，交付的取件码可以直接领取
+func newTradeFixture(t *testing.T) *tradeFixture {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	itemRepo := models.NewInMemoryItemRepository(clk)
+	returnBox := models.NewInMemoryReturnBox(0, clk)
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(16)
+	t.Cleanup(unsubscribe)
+	return &tradeFixture{
+		trades: service.NewTradeService(service.TradeDeps{
+			Trades:    models.NewInMemoryTradeRepository(),
+			ItemRepo:  itemRepo,
+			ReturnBox: returnBox,
+			EventBus:  bus,
+			Clock:     clk,
+			TradeTTL:  time.Hour,
+		}),
+		items:     service.NewItemService(service.Deps{ItemRepo: itemRepo, Clock: clk}),
+		returnBox: returnBox,
+		events:    ch,
+		clock:     clk,
+	}
+}
+
+func (f *tradeFixture) nextEvent(t *testing.T) events.Event {
+	select {
+	case event := <-f.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event published")
+		return nil
+	}
+}
+
+func validTrade() service.OpenTradeRequest {
+	return service.OpenTradeRequest{
+		InitiatorID: "alice",
+		Offer:       []models.TradeItem{{Name: "Golden Duck", Description: "Shiny", TypeID: 1001, Num: 1, Durability: 0.9}},
+		Request:     []models.TradeWant{{TypeID: 2002, Num: 3}},
+	}
+}
+
+func validDeposit() []models.TradeItem {
+	return []models.TradeItem{{Name: "Silver Feather", Description: "Light", TypeID: 2002, Num: 5, Durability: 1}}
+}
+
+func TestOpenTrade(t *testing.T) {
+	f := newTradeFixture(t)
+
+	trade, err := f.trades.Open(validTrade())
+	require.NoError(t, err)
+	assert.NotEmpty(t, trade.ID)
+	assert.Equal(t, models.TradeOpen, trade.Status)
+	assert.Equal(t, f.clock.Now().Add(time.Hour), trade.ExpiresAt)
+
+	event, ok := f.nextEvent(t).(*events.TradeOpened)
+	require.True(t, ok)
+	assert.Equal(t, trade.ID, event.Trade.ID)
+	assert.Equal(t, "alice", event.SharerID())
+
+	// 未指定对方的交易其他玩家可以查看
+	viewed, err := f.trades.Get(trade.ID, "bob")
+	require.NoError(t, err)
+	assert.Equal(t, trade.ID, viewed.ID)
+
+	listed, err := f.trades.List("alice")
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	listed, err = f.trades.List("bob")
+	require.NoError(t, err)
+	assert.Empty(t, listed)
+}
+
+func TestOpenTradeValidation(t *testing.T) {
+	f := newTradeFixture(t)
+	cases := []struct {
+		field  string
+		modify func(*service.OpenTradeRequest)
+	}{
+		{"initiator_id", func(r *service.OpenTradeRequest) { r.InitiatorID = "" }},
+		{"counterparty_id", func(r *service.OpenTradeRequest) { r.CounterpartyID = "alice" }},
+		{"offer", func(r *service.OpenTradeRequest) { r.Offer = nil }},
+		{"offer[0].name", func(r *service.OpenTradeRequest) { r.Offer[0].Name = "" }},
+		{"request", func(r *service.OpenTradeRequest) { r.Request = nil }},
+		{"request[0].num", func(r *service.OpenTradeRequest) { r.Request[0].Num = 0 }},
+	}
+	for _, c := range cases {
+		t.Run(c.field, func(t *testing.T) {
+			req := validTrade()
+			c.modify(&req)
+			_, err := f.trades.Open(req)
+			var invalid *service.ValidationError
+			require.ErrorAs(t, err, &invalid)
+			assert.Equal(t, c.field, invalid.Field)
+		})
+	}
+}
+
+func TestAcceptTradeSwapsItems(t *testing.T) {
+	f := newTradeFixture(t)
+	trade, err := f.trades.Open(validTrade())
+	require.NoError(t, err)
+	f.nextEvent(t)
+
+	accepted, err := f.trades.Accept(trade.ID, "bob", validDeposit())
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeCompleted, accepted.Status)
+	assert.Equal(t, "bob", accepted.AcceptorID)
+	require.Len(t, accepted.AcceptorCodes, 1)
+	assert.Empty(t, accepted.InitiatorCodes, "acceptor must not see the initiator's codes")
+
+	event, ok := f.nextEvent(t).(*events.TradeCompleted)
+	require.True(t, ok)
+	assert.Empty(t, event.Trade.InitiatorCodes)
+	assert.Empty(t, event.Trade.AcceptorCodes)
+
+	initiatorView, err := f.trades.Get(trade.ID, "alice")
+	require.NoError(t, err)
+	require.Len(t, initiatorView.InitiatorCodes, 1)
+	assert.Empty(t, initiatorView.AcceptorCodes)
+
+	// 双方凭各自的取件码领取对方的物品
+	duck, err := f.items.Claim(accepted.AcceptorCodes[0], "bob", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1001, duck.TypeID)
+	feather, err := f.items.Claim(initiatorView.InitiatorCodes[0], "alice", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2002, feather.TypeID)
+	assert.Equal(t, 5, feather.Num)
+
+	// 已完成的交易不能再次接受或取消
+	_, err = f.trades.Accept(trade.ID, "carol", validDeposit())
+	assert.ErrorIs(t, err, service.ErrTradeClosed)
+	_, err = f.trades.Cancel(trade.ID, "alice")
+	assert.ErrorIs(t, err, service.ErrTradeClosed)
+}
+
+func TestAcceptTradeErrors(t *testing.T) {
+	f := newTradeFixture(t)
+	req := validTrade()
+	req.CounterpartyID = "bob"
+	trade, err := f.trades.Open(req)
+	require.NoError(t, err)
+
+	var invalid *service.ValidationError
+	_, err = f.trades.Accept(trade.ID, "", validDeposit())
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "acceptor_id", invalid.Field)
+
+	// 数量不足的物品不满足请求
+	short := validDeposit()
+	short[0].Num = 2
+	_, err = f.trades.Accept(trade.ID, "bob", short)
+	require.ErrorAs(t, err, &invalid)
+	assert.Equal(t, "items", invalid.Field)
+
+	_, err = f.trades.Accept("missing", "bob", validDeposit())
+	assert.ErrorIs(t, err, service.ErrTradeNotFound)
+	_, err = f.trades.Accept(trade.ID, "alice", validDeposit())
+	assert.ErrorIs(t, err, service.ErrTradeForbidden)
+	_, err = f.trades.Accept(trade.ID, "carol", validDeposit())
+	assert.ErrorIs(t, err, service.ErrTradeForbidden)
+
+	// 指定了对方的交易对其他玩家不可见
+	_, err = f.trades.Get(trade.ID, "carol")
+	assert.ErrorIs(t, err, service.ErrTradeNotFound)
+	_, err = f.trades.Get(trade.ID, "bob")
+	assert.NoError(t, err)
+}
+
+func TestAcceptTradeUnderMemoryPressure(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	tradeRepo := models.NewInMemoryTradeRepository()
+	itemRepo := models.NewInMemoryItemRepository(clk)
+	opener := service.NewTradeService(service.TradeDeps{Trades: tradeRepo, ItemRepo: itemRepo, Clock: clk})
+	trade, err := opener.Open(validTrade())
+	require.NoError(t, err)
+
+	// 保留足够的已分配内存，使用量超过 1MB 上限的阈值；接受交易在改变交易状态之前被拒绝
+	ballast := make([]byte, 4<<20)
+	defer runtime.KeepAlive(ballast)
+	pressured := service.NewTradeService(service.TradeDeps{
+		Trades:        tradeRepo,
+		ItemRepo:      itemRepo,
+		MemoryMonitor: utils.NewMemoryMonitor(1),
+		Clock:         clk,
+	})
+	_, err = pressured.Accept(trade.ID, "bob", validDeposit())
+	assert.ErrorIs(t, err, service.ErrShareDisabled)
+
+	stored, err := opener.Get(trade.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeOpen, stored.Status)
+	assert.Empty(t, stored.AcceptorID)
+	assert.Empty(t, itemRepo.GetAll())
+}
+
+func TestCancelTradeReturnsEscrow(t *testing.T) {
+	f := newTradeFixture(t)
+	trade, err := f.trades.Open(validTrade())
+	require.NoError(t, err)
+	f.nextEvent(t)
+
+	_, err = f.trades.Cancel(trade.ID, "bob")
+	assert.ErrorIs(t, err, service.ErrTradeForbidden)
+
+	cancelled, err := f.trades.Cancel(trade.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeCancelled, cancelled.Status)
+
+	event, ok := f.nextEvent(t).(*events.TradeClosed)
+	require.True(t, ok)
+	assert.Equal(t, models.TradeCancelled, event.Trade.Status)
+
+	returned, err := f.returnBox.List("alice")
+	require.NoError(t, err)
+	require.Len(t, returned, 1)
+	assert.Equal(t, "Golden Duck", returned[0].Item.Name)
+
+	_, err = f.trades.Accept(trade.ID, "bob", validDeposit())
+	assert.ErrorIs(t, err, service.ErrTradeClosed)
+}
+
+// 为指定分享者退回物品时失败的退回箱
+type failingReturnBox struct {
+	models.ReturnBox
+	sharerID string
+}
+
+func (b failingReturnBox) Add(item *models.Item) error {
+	if item.SharerID == b.sharerID {
+		return errors.New("return box unavailable")
+	}
+	return b.ReturnBox.Add(item)
+}
+
+func TestExpireTradesContinuesAfterError(t *testing.T) {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	returnBox := models.NewInMemoryReturnBox(0, clk)
+	trades := service.NewTradeService(service.TradeDeps{
+		Trades:    models.NewInMemoryTradeRepository(),
+		ItemRepo:  models.NewInMemoryItemRepository(clk),
+		ReturnBox: failingReturnBox{ReturnBox: returnBox, sharerID: "alice"},
+		Clock:     clk,
+		TradeTTL:  time.Hour,
+	})
+	failed, err := trades.Open(validTrade())
+	require.NoError(t, err)
+	req := validTrade()
+	req.InitiatorID = "carol"
+	_, err = trades.Open(req)
+	require.NoError(t, err)
+
+	// alice 的托管物品退回失败不影响 carol 的交易，返回值只统计成功结束的交易
+	clk.Advance(time.Hour + time.Second)
+	expired, err := trades.ExpireTrades()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), failed.ID)
+	assert.Contains(t, err.Error(), "return box unavailable")
+	assert.Equal(t, 1, expired)
+	returned, err := returnBox.List("carol")
+	require.NoError(t, err)
+	assert.Len(t, returned, 1)
+	returned, err = returnBox.List("alice")
+	require.NoError(t, err)
+	assert.Empty(t, returned)
+}
+
+func TestExpireTrades(t *testing.T) {
+	f := newTradeFixture(t)
+	trade, err := f.trades.Open(validTrade())
+	require.NoError(t, err)
+
+	expired, err := f.trades.ExpireTrades()
+	require.NoError(t, err)
+	assert.Zero(t, expired)
+
+	f.clock.Advance(time.Hour + time.Second)
+	_, err = f.trades.Accept(trade.ID, "bob", validDeposit())
+	assert.ErrorIs(t, err, service.ErrTradeClosed)
+
+	expired, err = f.trades.ExpireTrades()
+	require.NoError(t, err)
+	assert.Equal(t, 1, expired)
+	returned, err := f.returnBox.List("alice")
+	require.NoError(t, err)
+	assert.Len(t, returned, 1)
+
+	viewed, err := f.trades.Get(trade.ID, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, models.TradeExpired, viewed.Status)
+
+	f.clock.Advance(time.Minute)
+	deleted, err := f.trades.DeleteClosed(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 1, deleted)
+	_, err = f.trades.Get(trade.ID, "alice")
+	assert.ErrorIs(t, err, service.ErrTradeNotFound)
+}
+
+func TestConcurrentAcceptTrade(t *testing.T) {
+	f := newTradeFixture(t)
+	trade, err := f.trades.Open(validTrade())
+	require.NoError(t, err)
+
+	const players = 8
+	var wg sync.WaitGroup
+	results := make(chan error, players)
+	for i := 0; i < players; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := f.trades.Accept(trade.ID, "player-"+string(rune('a'+i)), validDeposit())
+			results <- err
+		}(i)
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.ErrorIs(t, err, service.ErrTradeClosed)
+	}
+	assert.Equal(t, 1, succeeded)
+}
@@ -0,0 +1,365 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/utils"
+)
+
+// 交易业务错误
+var (
+	// ErrTradeNotFound 交易不存在，或玩家无权查看
+	ErrTradeNotFound = errors.New("trade not found")
+	// ErrTradeClosed 交易已被接受、已结束或已超时
+	ErrTradeClosed = errors.New("trade is no longer open")
+	// ErrTradeForbidden 玩家不能接受或取消该交易
+	ErrTradeForbidden = errors.New("player is not allowed to perform this trade action")
+)
+
+// DefaultTradeTTL 默认的交易有效期，超时未被接受的交易自动结束并退回托管物品
+const DefaultTradeTTL = time.Hour
+
+// 单方最多托管或请求的物品数
+const maxTradeItems = 16
+
+// OpenTradeRequest 发起交易的参数
+type OpenTradeRequest struct {
+	InitiatorID    string
+	CounterpartyID string // 为空时任何玩家都可以接受
+	Offer          []models.TradeItem
+	Request        []models.TradeWant
+}
+
+// Validate 检查发起交易的参数，托管物品的规则与分享物品一致
+func (r OpenTradeRequest) Validate() error {
+	switch {
+	case r.InitiatorID == "":
+		return &ValidationError{Field: "initiator_id", Message: "is required"}
+	case r.CounterpartyID == r.InitiatorID:
+		return &ValidationError{Field: "counterparty_id", Message: "must differ from initiator_id"}
+	}
+	if err := validateTradeItems("offer", r.Offer, r.InitiatorID); err != nil {
+		return err
+	}
+	if len(r.Request) == 0 || len(r.Request) > maxTradeItems {
+		return &ValidationError{Field: "request", Message: "must contain 1 to " + strconv.Itoa(maxTradeItems) + " items"}
+	}
+	for i, want := range r.Request {
+		field := "request[" + strconv.Itoa(i) + "]"
+		switch {
+		case want.TypeID == 0:
+			return &ValidationError{Field: field + ".type_id", Message: "is required"}
+		case want.Num < 1:
+			return &ValidationError{Field: field + ".num", Message: "must be at least 1"}
+		}
+	}
+	return nil
+}
+
+// 检查托管或存入的物品
+func validateTradeItems(field string, items []models.TradeItem, ownerID string) error {
+	if len(items) == 0 || len(items) > maxTradeItems {
+		return &ValidationError{Field: field, Message: "must contain 1 to " + strconv.Itoa(maxTradeItems) + " items"}
+	}
+	for i, item := range items {
+		err := ShareRequest{
+			Name:        item.Name,
+			Description: item.Description,
+			TypeID:      item.TypeID,
+			Num:         item.Num,
+			Durability:  item.Durability,
+			SharerID:    ownerID,
+		}.Validate()
+		var invalid *ValidationError
+		if errors.As(err, &invalid) {
+			return &ValidationError{Field: field + "[" + strconv.Itoa(i) + "]." + invalid.Field, Message: invalid.Message}
+		}
+	}
+	return nil
+}
+
+// TradeDeps 交易服务的依赖，ReturnBox、MemoryMonitor 和 EventBus 为 nil 时跳过对应的步骤
+type TradeDeps struct {
+	Trades        models.TradeRepository
+	ItemRepo      models.ItemRepository
+	ReturnBox     models.ReturnBox
+	MemoryMonitor *utils.MemoryMonitor
+	EventBus      *events.Bus
+	Clock         clock.Clock
+	// TradeTTL 交易有效期，不大于0时使用 DefaultTradeTTL
+	TradeTTL time.Duration
+}
+
+// TradeService 玩家之间的交易：发起方托管物品，接受方存入请求的物品后，双方物品以取件码交付给对方
+//
+// 与分享物品相同，交易只记录物品描述，不从已有的分享中扣除物品：托管和存入物品等同于分享，
+// 调用方（游戏服务端）需要在发起或接受交易前从玩家的背包中扣除这些物品。
+// 交易被取消或超时时，托管的物品像过期的分享一样退回到发起方的退回箱
+type TradeService struct {
+	trades        models.TradeRepository
+	itemRepo      models.ItemRepository
+	returnBox     models.ReturnBox
+	memoryMonitor *utils.MemoryMonitor
+	eventBus      *events.Bus
+	clock         clock.Clock
+	codes         *utils.PickupCodeGenerator
+	ttl           time.Duration
+}
+
+// NewTradeService 创建交易服务，Clock 为 nil 时使用系统时间
+func NewTradeService(deps TradeDeps) *TradeService {
+	if deps.Clock == nil {
+		deps.Clock = clock.System()
+	}
+	if deps.TradeTTL <= 0 {
+		deps.TradeTTL = DefaultTradeTTL
+	}
+	return &TradeService{
+		trades:        deps.Trades,
+		itemRepo:      deps.ItemRepo,
+		returnBox:     deps.ReturnBox,
+		memoryMonitor: deps.MemoryMonitor,
+		eventBus:      deps.EventBus,
+		clock:         deps.Clock,
+		codes:         utils.NewPickupCodeGenerator(deps.Clock),
+		ttl:           deps.TradeTTL,
+	}
+}
+
+// Open 发起交易，发起方的物品进入托管，发布 trade_opened 事件
+// 托管的物品只是描述，调用前需要已从发起方的背包中扣除；内存压力过高时与分享一样被拒绝
+// 返回 ErrShareDisabled、*ValidationError 或存储错误
+func (s *TradeService) Open(req OpenTradeRequest) (*models.Trade, error) {
+	if s.memoryMonitor != nil {
+		s.memoryMonitor.UpdateStatus()
+		if s.memoryMonitor.IsShareDisabled() {
+			return nil, ErrShareDisabled
+		}
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, fmt.Errorf("generate trade id: %w", err)
+	}
+
+	now := s.clock.Now()
+	trade := &models.Trade{
+		ID:             "trade-" + id,
+		InitiatorID:    req.InitiatorID,
+		CounterpartyID: req.CounterpartyID,
+		Offer:          req.Offer,
+		Request:        req.Request,
+		Status:         models.TradeOpen,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(s.ttl),
+	}
+	if err := s.trades.Create(trade); err != nil {
+		return nil, fmt.Errorf("store trade: %w", err)
+	}
+	s.eventBus.Publish(events.NewTradeOpened(trade, now))
+	return trade.ViewFor(req.InitiatorID), nil
+}
+
+// Get 查看交易：参与方可以查看交易及交付给自己的取件码，其他玩家只能查看未指定对方的进行中交易
+func (s *TradeService) Get(id, playerID string) (*models.Trade, error) {
+	trade, err := s.trades.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if trade == nil {
+		return nil, ErrTradeNotFound
+	}
+	if !trade.Involves(playerID) && (trade.Status != models.TradeOpen || trade.CounterpartyID != "") {
+		return nil, ErrTradeNotFound
+	}
+	return trade.ViewFor(playerID), nil
+}
+
+// List 返回玩家参与的全部交易
+func (s *TradeService) List(playerID string) ([]*models.Trade, error) {
+	if playerID == "" {
+		return nil, &ValidationError{Field: "player_id", Message: "is required"}
+	}
+	trades, err := s.trades.List(playerID)
+	if err != nil {
+		return nil, err
+	}
+	for i, trade := range trades {
+		trades[i] = trade.ViewFor(playerID)
+	}
+	return trades, nil
+}
+
+// Accept 接受交易：接受方存入满足请求的物品，交易原子地转为交付中，随后双方物品以新的取件码交付给对方，
+// 发布 trade_completed 事件。交付失败时已交付的物品被撤回，交易恢复为进行中
+// 存入的物品只是描述，调用前需要已从接受方的背包中扣除；交付会创建新的物品，内存压力过高时在交易状态改变之前被拒绝
+// 返回 ErrShareDisabled、*ValidationError、ErrTradeNotFound、ErrTradeClosed、ErrTradeForbidden 或存储错误
+func (s *TradeService) Accept(id, acceptorID string, deposit []models.TradeItem) (*models.Trade, error) {
+	if acceptorID == "" {
+		return nil, &ValidationError{Field: "acceptor_id", Message: "is required"}
+	}
+	if err := validateTradeItems("items", deposit, acceptorID); err != nil {
+		return nil, err
+	}
+	if s.memoryMonitor != nil {
+		s.memoryMonitor.UpdateStatus()
+		if s.memoryMonitor.IsShareDisabled() {
+			return nil, ErrShareDisabled
+		}
+	}
+	trade, err := s.trades.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if trade == nil {
+		return nil, ErrTradeNotFound
+	}
+	if !trade.Satisfies(deposit) {
+		return nil, &ValidationError{Field: "items", Message: "do not satisfy the trade request"}
+	}
+
+	trade, err = s.trades.Accept(id, acceptorID, deposit, s.clock.Now())
+	if err != nil {
+		return nil, tradeError(err)
+	}
+
+	// 发起方收到接受方存入的物品，接受方收到发起方托管的物品
+	initiatorCodes, err := s.deliver(trade, "initiator", trade.InitiatorID, trade.Deposit)
+	if err != nil {
+		return nil, s.abortDelivery(id, err, initiatorCodes)
+	}
+	acceptorCodes, err := s.deliver(trade, "acceptor", acceptorID, trade.Offer)
+	if err != nil {
+		return nil, s.abortDelivery(id, err, initiatorCodes, acceptorCodes)
+	}
+
+	now := s.clock.Now()
+	trade, err = s.trades.Complete(id, initiatorCodes, acceptorCodes, now)
+	if err != nil {
+		return nil, s.abortDelivery(id, err, initiatorCodes, acceptorCodes)
+	}
+	s.eventBus.Publish(events.NewTradeCompleted(trade, now))
+	return trade.ViewFor(acceptorID), nil
+}
+
+// 以新的取件码将物品交付给 recipientID，返回已交付物品的取件码
+// 交付的物品以接收方为分享者，过期未领取时退回到接收方的退回箱
+func (s *TradeService) deliver(trade *models.Trade, side, recipientID string, items []models.TradeItem) ([]string, error) {
+	now := s.clock.Now()
+	codes := make([]string, 0, len(items))
+	for i, tradeItem := range items {
+		item := tradeItemToItem(tradeItem, recipientID, now)
+		item.ID = trade.ID + "-" + side + "-" + strconv.Itoa(i)
+		item.ExpiresAt = now.Add(utils.PickupCodeTTL)
+		if err := storeWithPickupCode(s.itemRepo, s.codes, item); err != nil {
+			return codes, fmt.Errorf("deliver trade item: %w", err)
+		}
+		codes = append(codes, item.PickupCode)
+	}
+	return codes, nil
+}
+
+// 撤回已交付的物品并将交易恢复为进行中，返回原始错误
+func (s *TradeService) abortDelivery(id string, cause error, delivered ...[]string) error {
+	for _, codes := range delivered {
+		for _, code := range codes {
+			if err := s.itemRepo.Delete(code); err != nil {
+				return fmt.Errorf("%w (withdrawing delivered item %s: %v)", cause, code, err)
+			}
+		}
+	}
+	if _, err := s.trades.Reopen(id); err != nil {
+		return fmt.Errorf("%w (reopening trade: %v)", cause, err)
+	}
+	return cause
+}
+
+// Cancel 发起方取消进行中的交易，托管物品退回到发起方的退回箱，发布 trade_closed 事件
+// 返回 ErrTradeNotFound、ErrTradeClosed、ErrTradeForbidden 或存储错误
+func (s *TradeService) Cancel(id, initiatorID string) (*models.Trade, error) {
+	if initiatorID == "" {
+		return nil, &ValidationError{Field: "initiator_id", Message: "is required"}
+	}
+	trade, err := s.trades.Cancel(id, initiatorID, s.clock.Now())
+	if err != nil {
+		return nil, tradeError(err)
+	}
+	if err := s.closeTrade(trade); err != nil {
+		return nil, err
+	}
+	return trade.ViewFor(initiatorID), nil
+}
+
+// ExpireTrades 结束超时未被接受的交易，托管物品退回到发起方的退回箱，返回成功结束的交易数
+// 某笔交易退回物品失败时继续处理其余交易，所有错误合并后返回
+func (s *TradeService) ExpireTrades() (int, error) {
+	expired, err := s.trades.ExpireOpen(s.clock.Now())
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	var errs []error
+	for _, trade := range expired {
+		if err := s.closeTrade(trade); err != nil {
+			errs = append(errs, fmt.Errorf("trade %s: %w", trade.ID, err))
+			continue
+		}
+		closed++
+	}
+	return closed, errors.Join(errs...)
+}
+
+// DeleteClosed 删除结束超过 retention 的交易记录
+func (s *TradeService) DeleteClosed(retention time.Duration) (int, error) {
+	return s.trades.DeleteClosed(s.clock.Now().Add(-retention))
+}
+
+// 退回托管物品并发布交易结束事件
+func (s *TradeService) closeTrade(trade *models.Trade) error {
+	now := s.clock.Now()
+	if s.returnBox != nil {
+		for i, tradeItem := range trade.Offer {
+			item := tradeItemToItem(tradeItem, trade.InitiatorID, now)
+			item.ID = trade.ID + "-offer-" + strconv.Itoa(i)
+			item.ExpiresAt = trade.ExpiresAt
+			if err := s.returnBox.Add(item); err != nil {
+				return fmt.Errorf("return escrowed item: %w", err)
+			}
+		}
+	}
+	s.eventBus.Publish(events.NewTradeClosed(trade, now))
+	return nil
+}
+
+func tradeItemToItem(tradeItem models.TradeItem, ownerID string, now time.Time) *models.Item {
+	return &models.Item{
+		Name:        tradeItem.Name,
+		Description: tradeItem.Description,
+		TypeID:      tradeItem.TypeID,
+		Num:         tradeItem.Num,
+		Durability:  tradeItem.Durability,
+		SharerID:    ownerID,
+		CreatedAt:   now,
+	}
+}
+
+// 将交易仓库错误转换为业务错误
+func tradeError(err error) error {
+	switch {
+	case errors.Is(err, models.ErrTradeNotFound):
+		return ErrTradeNotFound
+	case errors.Is(err, models.ErrTradeNotOpen):
+		return ErrTradeClosed
+	case errors.Is(err, models.ErrTradeForbidden):
+		return ErrTradeForbidden
+	}
+	return err
+}
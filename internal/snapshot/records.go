@@ -0,0 +1,301 @@
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"duckex-server/internal/models"
+)
+
+// Format 物品数据的存储格式
+type Format string
+
+const (
+	// FormatJSON Write 写出的JSON快照
+	FormatJSON Format = "json"
+	// FormatSQL SQLite 数据库文件中的 items 表
+	FormatSQL Format = "sql"
+	// FormatLog 每行一个物品的JSON日志，可追加写入
+	FormatLog Format = "log"
+)
+
+// ParseFormat 解析格式名称
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatJSON, FormatSQL, FormatLog:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q (want json, sql or log)", name)
+}
+
+// DetectFormat 按文件扩展名推断格式
+func DetectFormat(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON, nil
+	case ".db", ".sqlite", ".sqlite3":
+		return FormatSQL, nil
+	case ".log", ".jsonl":
+		return FormatLog, nil
+	}
+	return "", fmt.Errorf("cannot detect format of %s, specify it explicitly", path)
+}
+
+// Record 文件中的一条物品记录，解析失败时 Item 为 nil、Err 为失败原因
+type Record struct {
+	Index int // 记录位置：JSON快照中的序号和日志的行号从1开始，SQL为行号（row_id）
+	Item  *models.Item
+	Err   error
+}
+
+// Contents 逐条读出的文件内容，单条记录损坏不影响读取其他记录
+type Contents struct {
+	Version   int
+	CreatedAt time.Time
+	Records   []Record
+	// Err 文件在最后一条记录之后无法继续解析（如被截断），已读出的记录仍然可用
+	Err error
+}
+
+// Items 返回解析成功的物品
+func (c *Contents) Items() []*models.Item {
+	items := make([]*models.Item, 0, len(c.Records))
+	for _, record := range c.Records {
+		if record.Item != nil {
+			items = append(items, record.Item)
+		}
+	}
+	return items
+}
+
+// DecodeJSON 逐条读取JSON快照，文件头损坏时返回错误
+func DecodeJSON(r io.Reader) (*Contents, error) {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	c := &Contents{Records: []Record{}}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			c.Err = err
+			return c, nil
+		}
+		switch token {
+		case "version":
+			err = dec.Decode(&c.Version)
+		case "created_at":
+			err = dec.Decode(&c.CreatedAt)
+		case "items":
+			err = c.decodeItems(dec)
+		default:
+			var skip json.RawMessage
+			err = dec.Decode(&skip)
+		}
+		if err != nil {
+			c.Err = err
+			return c, nil
+		}
+	}
+	if err := expectDelim(dec, '}'); err != nil {
+		c.Err = err
+	}
+	return c, nil
+}
+
+// 逐条读取 items 数组，语法错误时无法继续，类型错误只影响当前记录
+func (c *Contents) decodeItems(dec *json.Decoder) error {
+	if err := expectDelim(dec, '['); err != nil {
+		return err
+	}
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		c.Records = append(c.Records, decodeRecord(len(c.Records)+1, raw))
+	}
+	return expectDelim(dec, ']')
+}
+
+// DecodeLog 逐行读取物品日志，空行被忽略
+func DecodeLog(r io.Reader) (*Contents, error) {
+	c := &Contents{Version: FormatVersion, Records: []Record{}}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		c.Records = append(c.Records, decodeRecord(line, []byte(text)))
+	}
+	if err := scanner.Err(); err != nil {
+		c.Err = fmt.Errorf("line %d: %w", line+1, err)
+	}
+	return c, nil
+}
+
+// EncodeLog 以每行一个物品的JSON日志写出
+func EncodeLog(w io.Writer, items []*models.Item) error {
+	encoder := json.NewEncoder(w)
+	for _, item := range items {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 解析一条物品记录
+func decodeRecord(index int, raw []byte) Record {
+	var item models.Item
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return Record{Index: index, Err: err}
+	}
+	return Record{Index: index, Item: &item}
+}
+
+// 读取下一个分隔符并检查是否为 want
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != want {
+		return fmt.Errorf("expected %q, got %v", want, token)
+	}
+	return nil
+}
+
+// 物品字段校验失败的原因
+var (
+	errMissingID            = errors.New("missing id")
+	errMissingPickupCode    = errors.New("missing pickup_code")
+	errMissingSharerID      = errors.New("missing sharer_id")
+	errInvalidNum           = errors.New("num must be at least 1")
+	errInvalidDurability    = errors.New("durability must not be negative")
+	errMissingExpiresAt     = errors.New("missing expires_at")
+	errExpiresBeforeCreated = errors.New("expires_at is before created_at")
+	errMissingClaimerID     = errors.New("claimed item has no claimer_id")
+)
+
+// Validate 检查物品字段是否完整、取值是否合法
+func Validate(item *models.Item) error {
+	switch {
+	case item.ID == "":
+		return errMissingID
+	case item.PickupCode == "":
+		return errMissingPickupCode
+	case item.Num < 1:
+		return errInvalidNum
+	case item.Durability < 0:
+		return errInvalidDurability
+	case item.ExpiresAt.IsZero():
+		return errMissingExpiresAt
+	case item.ExpiresAt.Before(item.CreatedAt):
+		return errExpiresBeforeCreated
+	case item.IsClaimed && item.ClaimerID == "":
+		return errMissingClaimerID
+	}
+	return nil
+}
+
+// Issue 检查发现的一个问题，Record 为 0 表示文件级问题
+type Issue struct {
+	Record     int    `json:"record"`
+	PickupCode string `json:"pickup_code,omitempty"`
+	Problem    string `json:"problem"`
+}
+
+// Report 检查结果
+type Report struct {
+	Total   int     `json:"total"`   // 记录总数
+	Valid   int     `json:"valid"`   // 可用的物品数（含已过期）
+	Expired int     `json:"expired"` // 已过期的可用物品数
+	Claimed int     `json:"claimed"` // 已领取的可用物品数
+	Issues  []Issue `json:"issues"`
+}
+
+// OK 是否没有发现问题
+func (r *Report) OK() bool {
+	return len(r.Issues) == 0
+}
+
+// Inspect 检查文件内容：格式版本、无法解析的记录、字段错误和重复的取件码
+// 与仓库一致，同一取件码只能有一个未领取的物品，先出现的记录被视为有效
+func Inspect(c *Contents, now time.Time) Report {
+	report := Report{Total: len(c.Records), Issues: []Issue{}}
+	if c.Version != FormatVersion {
+		report.Issues = append(report.Issues, Issue{Problem: fmt.Sprintf("unsupported snapshot version %d", c.Version)})
+	}
+	for _, item := range check(c, &report) {
+		report.Valid++
+		if now.After(item.ExpiresAt) {
+			report.Expired++
+		}
+		if item.IsClaimed {
+			report.Claimed++
+		}
+	}
+	if c.Err != nil {
+		report.Issues = append(report.Issues, Issue{Problem: "unreadable after last record: " + c.Err.Error()})
+	}
+	return report
+}
+
+// Repair 丢弃无法解析、字段错误和取件码重复的记录，返回可写出的物品和被丢弃的记录
+func Repair(c *Contents) ([]*models.Item, []Issue) {
+	var report Report
+	items := check(c, &report)
+	return items, report.Issues
+}
+
+// 逐条校验记录，问题写入 report，返回通过校验的物品
+func check(c *Contents, report *Report) []*models.Item {
+	items := make([]*models.Item, 0, len(c.Records))
+	live := make(map[string]int)
+	for _, record := range c.Records {
+		if record.Err != nil {
+			report.Issues = append(report.Issues, Issue{Record: record.Index, Problem: record.Err.Error()})
+			continue
+		}
+		item := record.Item
+		if err := Validate(item); err != nil {
+			report.Issues = append(report.Issues, Issue{Record: record.Index, PickupCode: item.PickupCode, Problem: err.Error()})
+			continue
+		}
+		if !item.IsClaimed {
+			if first, ok := live[item.PickupCode]; ok {
+				report.Issues = append(report.Issues, Issue{
+					Record:     record.Index,
+					PickupCode: item.PickupCode,
+					Problem:    fmt.Sprintf("pickup code already used by record %d", first),
+				})
+				continue
+			}
+			live[item.PickupCode] = record.Index
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// Purge 移除已过期的物品，返回剩余物品和移除的数量
+func Purge(items []*models.Item, now time.Time) ([]*models.Item, int) {
+	kept := make([]*models.Item, 0, len(items))
+	for _, item := range items {
+		if !now.After(item.ExpiresAt) {
+			kept = append(kept, item)
+		}
+	}
+	return kept, len(items) - len(kept)
+}
@@ -0,0 +1,102 @@
+// Package snapshot 定义物品仓库快照的格式，用于备份、迁移和离线检查
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"duckex-server/internal/models"
+)
+
+// FormatVersion 当前的快照格式版本
+const FormatVersion = 1
+
+// Snapshot 某一时刻仓库中全部未过期物品的快照
+type Snapshot struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Items     []*models.Item `json:"items"`
+}
+
+// New 创建快照
+func New(items []*models.Item, createdAt time.Time) *Snapshot {
+	if items == nil {
+		items = []*models.Item{}
+	}
+	return &Snapshot{
+		Version:   FormatVersion,
+		CreatedAt: createdAt,
+		Items:     items,
+	}
+}
+
+// Write 以缩进的JSON写出快照
+func Write(w io.Writer, s *Snapshot) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// Read 读取JSON快照，不支持的版本返回错误
+func Read(r io.Reader) (*Snapshot, error) {
+	var s Snapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, fmt.Errorf("decode snapshot: %w", err)
+	}
+	if s.Version != FormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	return &s, nil
+}
+
+// RestoreResult 恢复快照的结果
+type RestoreResult struct {
+	Restored  int `json:"restored"`  // 成功写入的物品数
+	Expired   int `json:"expired"`   // 已过期而跳过的物品数
+	Conflicts int `json:"conflicts"` // 取件码已被占用而跳过的物品数
+	Rejected  int `json:"rejected"`  // 缺少ID、取件码或分享者而跳过的物品数
+}
+
+// Restore 将快照中的物品写入仓库，已过期、字段不完整和取件码冲突的物品被跳过
+func Restore(repo models.ItemRepository, s *Snapshot, now time.Time) (RestoreResult, error) {
+	var result RestoreResult
+	for _, item := range s.Items {
+		if validateRestore(item) != nil {
+			result.Rejected++
+			continue
+		}
+		if now.After(item.ExpiresAt) {
+			result.Expired++
+			continue
+		}
+		restored := *item
+		err := repo.Create(&restored)
+		switch {
+		case err == nil:
+			result.Restored++
+		case errors.Is(err, models.ErrDuplicatePickupCode):
+			result.Conflicts++
+		default:
+			return result, fmt.Errorf("restore item %s: %w", item.ID, err)
+		}
+	}
+	return result, nil
+}
+
+// 恢复前检查物品能否被领取和退回：空取件码的物品无法领取，缺少分享者的物品过期后无处退回
+func validateRestore(item *models.Item) error {
+	switch {
+	case item == nil:
+		return errMissingID
+	case item.ID == "":
+		return errMissingID
+	case item.PickupCode == "":
+		return errMissingPickupCode
+	case item.SharerID == "":
+		return errMissingSharerID
+	}
+	return nil
+}
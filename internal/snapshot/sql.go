@@ -0,0 +1,79 @@
+package snapshot
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"duckex-server/internal/models"
+)
+
+// DecodeSQL 逐行读取数据库 items 表中的全部物品（包括已过期的），无法转换的行作为损坏记录返回
+func DecodeSQL(db *sql.DB) (*Contents, error) {
+	rows, err := db.Query(`SELECT row_id, id, name, description, type_id, num, durability, sharer_id,
+		pickup_code, created_at, expires_at, is_claimed, claimer_id FROM items ORDER BY row_id`)
+	if err != nil {
+		return nil, fmt.Errorf("read items table: %w", err)
+	}
+	defer rows.Close()
+
+	c := &Contents{Version: FormatVersion, Records: []Record{}}
+	for rows.Next() {
+		var (
+			item                 models.Item
+			rowID                int64
+			createdAt, expiresAt int64
+			isClaimed            int
+		)
+		err := rows.Scan(&rowID, &item.ID, &item.Name, &item.Description, &item.TypeID, &item.Num,
+			&item.Durability, &item.SharerID, &item.PickupCode, &createdAt, &expiresAt, &isClaimed, &item.ClaimerID)
+		if err != nil {
+			// 行号本身无法读取时按读取顺序编号
+			if rowID == 0 {
+				rowID = int64(len(c.Records) + 1)
+			}
+			c.Records = append(c.Records, Record{Index: int(rowID), Err: err})
+			continue
+		}
+		item.CreatedAt = time.Unix(0, createdAt)
+		item.ExpiresAt = time.Unix(0, expiresAt)
+		item.IsClaimed = isClaimed != 0
+		c.Records = append(c.Records, Record{Index: int(rowID), Item: &item})
+	}
+	if err := rows.Err(); err != nil {
+		c.Err = err
+	}
+	return c, nil
+}
+
+// EncodeSQL 将物品写入数据库，数据库结构先迁移到最新版本，写入在同一事务中完成
+func EncodeSQL(db *sql.DB, items []*models.Item) error {
+	if err := models.Migrate(db, nil); err != nil {
+		return err
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO items (id, name, description, type_id, num, durability, sharer_id,
+		pickup_code, created_at, expires_at, is_claimed, claimer_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, item := range items {
+		isClaimed := 0
+		if item.IsClaimed {
+			isClaimed = 1
+		}
+		_, err := stmt.Exec(item.ID, item.Name, item.Description, item.TypeID, item.Num, item.Durability, item.SharerID,
+			item.PickupCode, item.CreatedAt.UnixNano(), item.ExpiresAt.UnixNano(), isClaimed, item.ClaimerID)
+		if err != nil {
+			return fmt.Errorf("write item %s: %w", item.ID, err)
+		}
+	}
+	return tx.Commit()
+}
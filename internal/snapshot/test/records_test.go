@@ -0,0 +1,122 @@
+package test
+
+import (
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/snapshot"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+var now = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func testItem(id, code string, expiresAt time.Time) *models.Item {
+	return &models.Item{
+		ID:         id,
+		Name:       "Golden Duck",
+		Num:        1,
+		SharerID:   "player123",
+		PickupCode: code,
+		CreatedAt:  now.Add(-time.Hour),
+		ExpiresAt:  expiresAt,
+	}
+}
+
+func TestDecodeJSONRecoversFromCorruption(t *testing.T) {
+	input := `{"version":1,"created_at":"2026-01-01T00:00:00Z","items":[
+		{"id":"a","num":1,"pickup_code":"111111","created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-02T00:00:00Z"},
+		{"id":5},
+		{"id":"c","num":1,"pickup_code":"333`
+
+	contents, err := snapshot.DecodeJSON(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Len(t, contents.Records, 2)
+	assert.Equal(t, "a", contents.Records[0].Item.ID)
+	assert.Error(t, contents.Records[1].Err)
+	assert.Error(t, contents.Err)
+	assert.Len(t, contents.Items(), 1)
+
+	_, err = snapshot.DecodeJSON(strings.NewReader("not json"))
+	assert.Error(t, err)
+}
+
+func TestInspectAndRepair(t *testing.T) {
+	var log bytes.Buffer
+	require.NoError(t, snapshot.EncodeLog(&log, []*models.Item{
+		testItem("a", "111111", now.Add(time.Hour)),
+		testItem("b", "222222", now.Add(-time.Minute)),
+		testItem("c", "111111", now.Add(time.Hour)),
+		testItem("", "444444", now.Add(time.Hour)),
+	}))
+	log.WriteString("{broken\n")
+
+	contents, err := snapshot.DecodeLog(&log)
+	require.NoError(t, err)
+	report := snapshot.Inspect(contents, now)
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 2, report.Valid)
+	assert.Equal(t, 1, report.Expired)
+	require.Len(t, report.Issues, 3)
+	assert.Equal(t, 3, report.Issues[0].Record)
+	assert.Contains(t, report.Issues[0].Problem, "record 1")
+	assert.Equal(t, 4, report.Issues[1].Record)
+	assert.Equal(t, 5, report.Issues[2].Record)
+
+	items, dropped := snapshot.Repair(contents)
+	assert.Len(t, items, 2)
+	assert.Len(t, dropped, 3)
+
+	kept, purged := snapshot.Purge(items, now)
+	assert.Equal(t, 1, purged)
+	require.Len(t, kept, 1)
+	assert.Equal(t, "a", kept[0].ID)
+}
+
+func TestSQLRoundTrip(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	claimed := testItem("b", "222222", now.Add(time.Hour))
+	claimed.IsClaimed = true
+	claimed.ClaimerID = "player456"
+	require.NoError(t, snapshot.EncodeSQL(db, []*models.Item{testItem("a", "111111", now.Add(time.Hour)), claimed}))
+
+	// 损坏的行只影响自身
+	_, err = db.Exec(`INSERT INTO items (id, pickup_code, created_at, expires_at) VALUES ('c', '333333', 'garbage', 0)`)
+	require.NoError(t, err)
+
+	contents, err := snapshot.DecodeSQL(db)
+	require.NoError(t, err)
+	require.Len(t, contents.Records, 3)
+	assert.True(t, contents.Records[0].Item.ExpiresAt.Equal(now.Add(time.Hour)))
+	assert.Equal(t, "player456", contents.Records[1].Item.ClaimerID)
+	assert.Error(t, contents.Records[2].Err)
+
+	report := snapshot.Inspect(contents, now)
+	assert.Equal(t, 2, report.Valid)
+	assert.Equal(t, 1, report.Claimed)
+	assert.Len(t, report.Issues, 1)
+}
+
+func TestFormats(t *testing.T) {
+	format, err := snapshot.DetectFormat("backup/items.sqlite")
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.FormatSQL, format)
+	_, err = snapshot.DetectFormat("items.bin")
+	assert.Error(t, err)
+
+	format, err = snapshot.ParseFormat("LOG")
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.FormatLog, format)
+	_, err = snapshot.ParseFormat("csv")
+	assert.Error(t, err)
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"duckex-server/internal/models"
+	"duckex-server/internal/snapshot"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRestoreRejectsIncompleteItems(t *testing.T) {
+	repo := models.NewInMemoryItemRepository(nil)
+	// 仓库按当前时间判断过期，恢复时间也使用当前时间
+	restoreAt := time.Now()
+	noCode := testItem("b", "", restoreAt.Add(time.Hour))
+	noID := testItem("", "333333", restoreAt.Add(time.Hour))
+	noSharer := testItem("d", "444444", restoreAt.Add(time.Hour))
+	noSharer.SharerID = ""
+	s := snapshot.New([]*models.Item{
+		testItem("a", "111111", restoreAt.Add(time.Hour)),
+		noCode,
+		noID,
+		noSharer,
+		nil,
+		testItem("e", "555555", restoreAt.Add(-time.Minute)),
+	}, restoreAt)
+
+	result, err := snapshot.Restore(repo, s, restoreAt)
+	require.NoError(t, err)
+	assert.Equal(t, snapshot.RestoreResult{Restored: 1, Expired: 1, Rejected: 4}, result)
+
+	// 被拒绝的物品没有写入仓库
+	items := repo.GetAll()
+	require.Len(t, items, 1)
+	assert.Equal(t, "111111", items[0].PickupCode)
+	item, _ := repo.GetByPickupCode("")
+	assert.Nil(t, item)
+}
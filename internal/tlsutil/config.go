@@ -0,0 +1,34 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+)
+
+// 支持的最低 TLS 版本
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseVersion 解析 "1.2"、"1.3" 等版本号，为空时返回 TLS 1.2
+func ParseVersion(version string) (uint16, error) {
+	if version == "" {
+		return tls.VersionTLS12, nil
+	}
+	v, ok := tlsVersions[version]
+	if !ok {
+		return 0, fmt.Errorf("unsupported TLS version %q", version)
+	}
+	return v, nil
+}
+
+// NewServerConfig 创建使用 reloader 当前证书的服务端 TLS 配置
+func NewServerConfig(reloader *CertReloader, minVersion uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+}
@@ -0,0 +1,27 @@
+package tlsutil
+
+import (
+	"net"
+	"net/http"
+)
+
+// RedirectHandler 将 HTTP 请求跳转到 HTTPS，httpsPort 为 HTTPS 监听端口，为 "443" 或空时不在跳转地址中包含端口
+// GET 和 HEAD 使用 301，其他方法使用 308 以保留请求方法和请求体
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		status := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		http.Redirect(w, r, target, status)
+	})
+}
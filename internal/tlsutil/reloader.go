@@ -0,0 +1,104 @@
+// Package tlsutil 提供 HTTPS 服务所需的证书热加载、TLS 配置和 HTTP 跳转
+package tlsutil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertReloader 从文件加载证书，文件变化或收到重载信号时无需重启即可替换证书
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	mutex    sync.RWMutex
+	cert     *tls.Certificate
+	modTimes [2]time.Time
+}
+
+// NewCertReloader 创建证书加载器并立即加载一次证书
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := &CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// 读取证书和私钥文件的修改时间
+func (r *CertReloader) readModTimes() ([2]time.Time, error) {
+	var times [2]time.Time
+	for i, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return times, err
+		}
+		times[i] = info.ModTime()
+	}
+	return times, nil
+}
+
+// Reload 重新加载证书，加载失败时继续使用原证书
+func (r *CertReloader) Reload() error {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return fmt.Errorf("stat certificate: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("load certificate: %w", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.cert = &cert
+	r.modTimes = modTimes
+	return nil
+}
+
+// ReloadIfChanged 证书或私钥文件的修改时间变化时重新加载，返回是否重新加载
+func (r *CertReloader) ReloadIfChanged() (bool, error) {
+	modTimes, err := r.readModTimes()
+	if err != nil {
+		return false, fmt.Errorf("stat certificate: %w", err)
+	}
+	r.mutex.RLock()
+	changed := modTimes != r.modTimes
+	r.mutex.RUnlock()
+	if !changed {
+		return false, nil
+	}
+	return true, r.Reload()
+}
+
+// Watch 每隔 interval 检查一次证书文件，直到 stop 被关闭
+func (r *CertReloader) Watch(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			reloaded, err := r.ReloadIfChanged()
+			if err != nil {
+				log.Printf("Failed to reload TLS certificate: %v", err)
+			} else if reloaded {
+				log.Printf("TLS certificate reloaded from %s", r.certFile)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// GetCertificate 返回当前证书，用作 tls.Config.GetCertificate
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.cert, nil
+}
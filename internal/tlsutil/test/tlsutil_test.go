@@ -0,0 +1,144 @@
+package test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"duckex-server/internal/tlsutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 写入自签名证书，返回证书和私钥文件路径
+func writeCertificate(t *testing.T, dir, commonName string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func servedCommonName(t *testing.T, reloader *tlsutil.CertReloader) string {
+	cert, err := reloader.GetCertificate(nil)
+	require.NoError(t, err)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	return parsed.Subject.CommonName
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCertificate(t, dir, "old.duckex.test")
+	reloader, err := tlsutil.NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	assert.Equal(t, "old.duckex.test", servedCommonName(t, reloader))
+
+	// 文件未变化时不重新加载
+	reloaded, err := reloader.ReloadIfChanged()
+	require.NoError(t, err)
+	assert.False(t, reloaded)
+
+	// 替换证书后重新加载
+	writeCertificate(t, dir, "new.duckex.test")
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	reloaded, err = reloader.ReloadIfChanged()
+	require.NoError(t, err)
+	assert.True(t, reloaded)
+	assert.Equal(t, "new.duckex.test", servedCommonName(t, reloader))
+
+	// 加载失败时继续使用原证书
+	require.NoError(t, os.WriteFile(certFile, []byte("broken"), 0o600))
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "new.duckex.test", servedCommonName(t, reloader))
+}
+
+func TestCertReloaderMissingFiles(t *testing.T) {
+	_, err := tlsutil.NewCertReloader(filepath.Join(t.TempDir(), "cert.pem"), filepath.Join(t.TempDir(), "key.pem"))
+	assert.Error(t, err)
+}
+
+func TestServeWithMinVersion(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir(), "duckex.test")
+	reloader, err := tlsutil.NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	minVersion, err := tlsutil.ParseVersion("1.3")
+	require.NoError(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("quack"))
+	}))
+	server.TLS = tlsutil.NewServerConfig(reloader, minVersion)
+	server.StartTLS()
+	defer server.Close()
+
+	// TLS 1.3 客户端可以连接
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, uint16(tls.VersionTLS13), resp.TLS.Version)
+
+	// 只支持 TLS 1.2 的客户端被拒绝
+	legacy := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		InsecureSkipVerify: true,
+		MaxVersion:         tls.VersionTLS12,
+	}}}
+	_, err = legacy.Get(server.URL)
+	assert.Error(t, err)
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := tlsutil.ParseVersion("")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+	v, err = tlsutil.ParseVersion("1.3")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS13), v)
+	_, err = tlsutil.ParseVersion("2.0")
+	assert.Error(t, err)
+}
+
+func TestRedirectHandler(t *testing.T) {
+	handler := tlsutil.RedirectHandler("8443")
+	req := httptest.NewRequest(http.MethodGet, "http://duckex.test:8080/health?verbose=1", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://duckex.test:8443/health?verbose=1", w.Header().Get("Location"))
+
+	// 非 GET 请求保留方法，默认端口不写入跳转地址
+	handler = tlsutil.RedirectHandler("443")
+	req = httptest.NewRequest(http.MethodPost, "http://duckex.test/api/v1/items/share", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusPermanentRedirect, w.Code)
+	assert.Equal(t, "https://duckex.test/api/v1/items/share", w.Header().Get("Location"))
+}
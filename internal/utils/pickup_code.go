@@ -2,32 +2,43 @@ package utils
 
 import (
 	"math/rand"
+	"sync"
 	"time"
+
+	"duckex-server/internal/clock"
 )
 
 const (
 	// 取件码长度
 	pickupCodeLength = 6
-	// 取件码有效期（24小时）
-	expirationDuration = 24 * time.Hour
+	// PickupCodeTTL 取件码有效期（24小时）
+	PickupCodeTTL = 24 * time.Hour
 )
 
-// 初始化随机数种子
-func init() {
-	rand.Seed(time.Now().UnixNano())
+// PickupCodeGenerator 取件码生成器，随机数种子取自注入的时间源
+type PickupCodeGenerator struct {
+	rand  *rand.Rand
+	mutex sync.Mutex
+}
+
+// NewPickupCodeGenerator 创建新的取件码生成器，clk 为 nil 时使用系统时间
+func NewPickupCodeGenerator(clk clock.Clock) *PickupCodeGenerator {
+	if clk == nil {
+		clk = clock.System()
+	}
+	return &PickupCodeGenerator{
+		rand: rand.New(rand.NewSource(clk.Now().UnixNano())),
+	}
 }
 
-// GeneratePickupCode 生成6位数的取件码
-func GeneratePickupCode() string {
+// Generate 生成6位数的取件码
+func (g *PickupCodeGenerator) Generate() string {
 	const charset = "0123456789"
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
 	code := make([]byte, pickupCodeLength)
 	for i := range code {
-		code[i] = charset[rand.Intn(len(charset))]
+		code[i] = charset[g.rand.Intn(len(charset))]
 	}
 	return string(code)
 }
-
-// GetExpirationTime 获取过期时间
-func GetExpirationTime() time.Time {
-	return time.Now().Add(expirationDuration)
-}
\ No newline at end of file
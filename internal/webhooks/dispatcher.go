@@ -0,0 +1,438 @@
+package webhooks
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+)
+
+// DeliveryStatus 投递状态
+type DeliveryStatus string
+
+const (
+	// StatusPending 等待投递或重试中
+	StatusPending DeliveryStatus = "pending"
+	// StatusSucceeded 投递成功
+	StatusSucceeded DeliveryStatus = "succeeded"
+	// StatusDead 重试耗尽，已写入死信日志
+	StatusDead DeliveryStatus = "dead"
+)
+
+// Delivery 一次事件投递的记录
+type Delivery struct {
+	ID             string         `json:"id"`
+	SubscriptionID string         `json:"subscription_id"`
+	URL            string         `json:"url"`
+	EventType      events.Type    `json:"event_type"`
+	Status         DeliveryStatus `json:"status"`
+	Attempts       int            `json:"attempts"`
+	LastStatusCode int            `json:"last_status_code,omitempty"`
+	LastError      string         `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at,omitempty"` // 等待重试时下次尝试的时间
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	Payload        Payload        `json:"payload"`
+}
+
+// Options 投递选项
+type Options struct {
+	MaxAttempts    int           // 最大尝试次数（含首次）
+	InitialBackoff time.Duration // 首次重试等待时间，之后按指数增长
+	MaxBackoff     time.Duration // 重试等待时间上限
+	Timeout        time.Duration // 单次请求超时
+	HistorySize    int           // 保留的最近投递记录数
+	DeadLetterFile string        // 死信日志文件（JSON Lines），为空时不写文件
+	Workers        int           // 并发发送请求的 worker 数
+	QueueSize      int           // 等待发送的投递队列容量，队列已满时新的投递直接写入死信
+	Clock          clock.Clock   // 时间源，用于投递时间戳和重试等待，为 nil 时使用系统时间
+}
+
+// DefaultOptions 返回默认投递选项
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:    6,
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Minute,
+		Timeout:        10 * time.Second,
+		HistorySize:    500,
+		Workers:        4,
+		QueueSize:      1024,
+		Clock:          clock.System(),
+	}
+}
+
+// 排队等待发送的一次投递
+type job struct {
+	sub      Subscription
+	delivery *Delivery
+	body     []byte
+	attempts int       // 已尝试的次数
+	due      time.Time // 等待重试时下次尝试的时间
+}
+
+// 队列已满时写入投递记录的错误
+const errQueueFull = "delivery queue is full"
+
+// Dispatcher 订阅事件总线并向 Webhook 订阅方投递事件
+// 投递由固定数量的 worker 从有界队列中取出发送，失败的投递按退避时间重新入队，等待重试不占用 worker
+type Dispatcher struct {
+	subscriptions []Subscription
+	opts          Options
+	clock         clock.Clock
+	client        *http.Client
+
+	mu          sync.RWMutex
+	deliveries  []*Delivery // 最近的投递记录，按创建时间排序
+	deadLetters []*Delivery
+
+	queue   chan *job
+	retryMu sync.Mutex
+	retries []*job        // 等待重试的投递
+	wake    chan struct{} // 新增重试时通知调度循环
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDispatcher 创建新的 Webhook 投递器并启动 worker，使用完毕后需调用 Close
+func NewDispatcher(subscriptions []Subscription, opts Options) *Dispatcher {
+	defaults := DefaultOptions()
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaults.MaxAttempts
+	}
+	if opts.InitialBackoff <= 0 {
+		opts.InitialBackoff = defaults.InitialBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaults.Timeout
+	}
+	if opts.HistorySize <= 0 {
+		opts.HistorySize = defaults.HistorySize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaults.Workers
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = defaults.QueueSize
+	}
+	if opts.Clock == nil {
+		opts.Clock = defaults.Clock
+	}
+	d := &Dispatcher{
+		subscriptions: subscriptions,
+		opts:          opts,
+		clock:         opts.Clock,
+		client:        &http.Client{Timeout: opts.Timeout},
+		queue:         make(chan *job, opts.QueueSize),
+		wake:          make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+	}
+	d.wg.Add(opts.Workers + 1)
+	for i := 0; i < opts.Workers; i++ {
+		go d.work()
+	}
+	go d.scheduleRetries()
+	return d
+}
+
+// Run 从事件通道读取事件并投递，直到通道关闭
+func (d *Dispatcher) Run(ch <-chan events.Event) {
+	for event := range ch {
+		d.Dispatch(event)
+	}
+}
+
+// Dispatch 为所有关注该事件的订阅创建投递任务并放入队列，不等待发送
+func (d *Dispatcher) Dispatch(event events.Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to encode %s event for webhooks: %v", event.Type(), err)
+		return
+	}
+	for _, sub := range d.subscriptions {
+		if !sub.Matches(event.Type()) {
+			continue
+		}
+		now := d.clock.Now()
+		delivery := &Delivery{
+			ID:             d.newDeliveryID(),
+			SubscriptionID: sub.ID,
+			URL:            sub.URL,
+			EventType:      event.Type(),
+			Status:         StatusPending,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		delivery.Payload = Payload{
+			DeliveryID: delivery.ID,
+			Type:       event.Type(),
+			OccurredAt: event.OccurredAt(),
+			Data:       data,
+		}
+		d.record(delivery)
+
+		body, err := json.Marshal(delivery.Payload)
+		if err != nil {
+			d.fail(delivery, err.Error())
+			continue
+		}
+		select {
+		case d.queue <- &job{sub: sub, delivery: delivery, body: body}:
+		default:
+			d.fail(delivery, errQueueFull)
+		}
+	}
+}
+
+// Close 停止发送和重试，等待进行中的请求结束；队列中和等待重试的投递保持 pending 状态
+func (d *Dispatcher) Close() {
+	close(d.stop)
+	d.wg.Wait()
+}
+
+// Deliveries 返回最近的投递记录，status 为空时返回全部
+func (d *Dispatcher) Deliveries(status DeliveryStatus) []Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	result := make([]Delivery, 0, len(d.deliveries))
+	for _, delivery := range d.deliveries {
+		if status == "" || delivery.Status == status {
+			result = append(result, *delivery)
+		}
+	}
+	return result
+}
+
+// DeadLetters 返回重试耗尽的投递记录
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	result := make([]Delivery, 0, len(d.deadLetters))
+	for _, delivery := range d.deadLetters {
+		result = append(result, *delivery)
+	}
+	return result
+}
+
+// 记录新的投递，超出历史容量时丢弃最旧的记录
+func (d *Dispatcher) record(delivery *Delivery) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deliveries = append(d.deliveries, delivery)
+	if overflow := len(d.deliveries) - d.opts.HistorySize; overflow > 0 {
+		d.deliveries = append([]*Delivery(nil), d.deliveries[overflow:]...)
+	}
+}
+
+// worker 循环：从队列取出投递并发送
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case j := <-d.queue:
+			d.attempt(j)
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// 发送一次，失败时安排退避重试，重试耗尽后写入死信
+func (d *Dispatcher) attempt(j *job) {
+	j.attempts++
+	statusCode, err := d.send(j.sub, j.delivery, j.body)
+	d.update(j.delivery, func() {
+		j.delivery.Attempts = j.attempts
+		j.delivery.LastStatusCode = statusCode
+		j.delivery.LastError = ""
+		j.delivery.NextAttemptAt = nil
+		if err != nil {
+			j.delivery.LastError = err.Error()
+		} else {
+			j.delivery.Status = StatusSucceeded
+		}
+	})
+	if err == nil {
+		return
+	}
+	if j.attempts >= d.opts.MaxAttempts {
+		d.fail(j.delivery, "")
+		return
+	}
+
+	j.due = d.clock.Now().Add(d.backoff(j.attempts))
+	d.update(j.delivery, func() {
+		due := j.due
+		j.delivery.NextAttemptAt = &due
+	})
+	d.retryMu.Lock()
+	d.retries = append(d.retries, j)
+	d.retryMu.Unlock()
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// 第 attempts 次失败后的等待时间：从 InitialBackoff 开始按指数增长，不超过 MaxBackoff
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	backoff := d.opts.InitialBackoff
+	for i := 1; i < attempts && backoff < d.opts.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > d.opts.MaxBackoff {
+		backoff = d.opts.MaxBackoff
+	}
+	return backoff
+}
+
+// 调度循环：等到最早的重试到期后将到期的投递重新放入队列
+func (d *Dispatcher) scheduleRetries() {
+	defer d.wg.Done()
+	for {
+		ready, next := d.takeDueRetries()
+		for _, j := range ready {
+			select {
+			case d.queue <- j:
+			case <-d.stop:
+				return
+			}
+		}
+
+		var timer <-chan time.Time
+		if !next.IsZero() {
+			timer = d.clock.After(next.Sub(d.clock.Now()))
+		}
+		select {
+		case <-timer:
+		case <-d.wake:
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// 取出已到期的重试，返回剩余重试中最早的到期时间，没有剩余时为零值
+func (d *Dispatcher) takeDueRetries() ([]*job, time.Time) {
+	d.retryMu.Lock()
+	defer d.retryMu.Unlock()
+	now := d.clock.Now()
+	var ready []*job
+	var next time.Time
+	pending := d.retries[:0]
+	for _, j := range d.retries {
+		if !j.due.After(now) {
+			ready = append(ready, j)
+			continue
+		}
+		pending = append(pending, j)
+		if next.IsZero() || j.due.Before(next) {
+			next = j.due
+		}
+	}
+	d.retries = pending
+	return ready, next
+}
+
+// 发送一次请求，非2xx响应视为失败
+func (d *Dispatcher) send(sub Subscription, delivery *Delivery, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := d.clock.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DuckEx-Webhook/1.0")
+	req.Header.Set(HeaderEvent, string(delivery.EventType))
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, fmt.Sprintf("%d", timestamp))
+	if sub.Secret != "" {
+		req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) update(delivery *Delivery, fn func()) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	fn()
+	delivery.UpdatedAt = d.clock.Now()
+}
+
+// 将投递标记为失败并写入死信，reason 不为空时覆盖最后一次错误
+func (d *Dispatcher) fail(delivery *Delivery, reason string) {
+	d.update(delivery, func() {
+		delivery.Status = StatusDead
+		if reason != "" {
+			delivery.LastError = reason
+		}
+	})
+	d.deadLetter(delivery)
+}
+
+// 记录死信，并追加到死信日志文件
+func (d *Dispatcher) deadLetter(delivery *Delivery) {
+	d.mu.Lock()
+	d.deadLetters = append(d.deadLetters, delivery)
+	if overflow := len(d.deadLetters) - d.opts.HistorySize; overflow > 0 {
+		d.deadLetters = append([]*Delivery(nil), d.deadLetters[overflow:]...)
+	}
+	snapshot := *delivery
+	d.mu.Unlock()
+
+	log.Printf("Webhook delivery %s to %s failed after %d attempts: %s",
+		snapshot.ID, snapshot.URL, snapshot.Attempts, snapshot.LastError)
+
+	if d.opts.DeadLetterFile == "" {
+		return
+	}
+	line, err := json.Marshal(snapshot)
+	if err != nil {
+		log.Printf("Failed to encode dead letter %s: %v", snapshot.ID, err)
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, err := os.OpenFile(d.opts.DeadLetterFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Printf("Failed to open dead letter file: %v", err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		log.Printf("Failed to write dead letter %s: %v", snapshot.ID, err)
+	}
+}
+
+func (d *Dispatcher) newDeliveryID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", d.clock.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
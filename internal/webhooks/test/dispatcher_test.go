@@ -0,0 +1,249 @@
+package test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"duckex-server/internal/clock"
+	"duckex-server/internal/events"
+	"duckex-server/internal/models"
+	"duckex-server/internal/webhooks"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 测试用的快速重试选项
+func testOptions() webhooks.Options {
+	return webhooks.Options{
+		MaxAttempts:    3,
+		InitialBackoff: 10 * time.Millisecond,
+		MaxBackoff:     20 * time.Millisecond,
+		Timeout:        time.Second,
+	}
+}
+
+func testItem() *models.Item {
+	return &models.Item{
+		ID:         "item-1",
+		Name:       "Test Weapon",
+		SharerID:   "player123",
+		PickupCode: "123456",
+	}
+}
+
+// 接收方解析投递内容使用的结构
+type receivedPayload struct {
+	DeliveryID string      `json:"delivery_id"`
+	Type       events.Type `json:"type"`
+	Data       struct {
+		Item      models.Item `json:"item"`
+		ClaimerID string      `json:"claimer_id"`
+	} `json:"data"`
+}
+
+// 等待投递达到指定状态
+func waitForStatus(t *testing.T, d *webhooks.Dispatcher, status webhooks.DeliveryStatus, count int) []webhooks.Delivery {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if deliveries := d.Deliveries(status); len(deliveries) >= count {
+			return deliveries
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d %s deliveries", count, status)
+	return nil
+}
+
+func TestDispatcherSignedDelivery(t *testing.T) {
+	var mu sync.Mutex
+	var received []receivedPayload
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, err := strconv.ParseInt(r.Header.Get(webhooks.HeaderTimestamp), 10, 64)
+		if err != nil || !webhooks.Verify("s3cret", timestamp, body, r.Header.Get(webhooks.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var payload receivedPayload
+		json.Unmarshal(body, &payload)
+		assert.Equal(t, payload.DeliveryID, r.Header.Get(webhooks.HeaderDelivery))
+		assert.Equal(t, string(payload.Type), r.Header.Get(webhooks.HeaderEvent))
+		mu.Lock()
+		received = append(received, payload)
+		mu.Unlock()
+	}))
+	defer receiver.Close()
+
+	dispatcher := webhooks.NewDispatcher([]webhooks.Subscription{
+		{ID: "discord", URL: receiver.URL, Secret: "s3cret", Events: []string{"item_claimed"}},
+		{ID: "stats", URL: receiver.URL, Secret: "s3cret"},
+	}, testOptions())
+	defer dispatcher.Close()
+
+	// item_shared 只投递给订阅全部事件的 stats，item_claimed 投递给两者
+	dispatcher.Dispatch(events.NewItemShared(testItem(), time.Now()))
+	dispatcher.Dispatch(events.NewItemClaimed(testItem(), "player456", time.Now()))
+
+	deliveries := waitForStatus(t, dispatcher, webhooks.StatusSucceeded, 3)
+	assert.Len(t, deliveries, 3)
+	for _, delivery := range deliveries {
+		assert.Equal(t, 1, delivery.Attempts)
+		assert.Equal(t, http.StatusOK, delivery.LastStatusCode)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, received, 3)
+	for _, payload := range received {
+		assert.Equal(t, "item-1", payload.Data.Item.ID)
+		assert.Empty(t, payload.Data.Item.PickupCode)
+		if payload.Type == events.TypeItemClaimed {
+			assert.Equal(t, "player456", payload.Data.ClaimerID)
+		}
+	}
+}
+
+// 等待满足条件的投递记录
+func waitForDelivery(t *testing.T, d *webhooks.Dispatcher, match func(webhooks.Delivery) bool) webhooks.Delivery {
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, delivery := range d.Deliveries("") {
+			if match(delivery) {
+				return delivery
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("timed out waiting for delivery")
+	return webhooks.Delivery{}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	var calls int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 前三次返回503，第四次成功
+		if atomic.AddInt32(&calls, 1) < 4 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	opts := testOptions()
+	opts.MaxAttempts = 4
+	opts.InitialBackoff = time.Second
+	opts.MaxBackoff = 2 * time.Second
+	opts.Clock = clk
+	dispatcher := webhooks.NewDispatcher([]webhooks.Subscription{
+		{ID: "bot", URL: receiver.URL, Secret: "s3cret"},
+	}, opts)
+	defer dispatcher.Close()
+
+	dispatcher.Dispatch(events.NewItemClaimed(testItem(), "player456", clk.Now()))
+
+	// 等待时间按 1s、2s 增长，之后不超过 MaxBackoff
+	next := start
+	for attempt, backoff := range []time.Duration{time.Second, 2 * time.Second, 2 * time.Second} {
+		delivery := waitForDelivery(t, dispatcher, func(d webhooks.Delivery) bool {
+			return d.Attempts == attempt+1 && d.NextAttemptAt != nil
+		})
+		next = next.Add(backoff)
+		assert.Equal(t, webhooks.StatusPending, delivery.Status)
+		assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+		assert.Equal(t, next, *delivery.NextAttemptAt)
+
+		// 到期前不会重试
+		clk.Advance(backoff - time.Millisecond)
+		time.Sleep(20 * time.Millisecond)
+		assert.Equal(t, int32(attempt+1), atomic.LoadInt32(&calls))
+		clk.Advance(time.Millisecond)
+	}
+
+	deliveries := waitForStatus(t, dispatcher, webhooks.StatusSucceeded, 1)
+	assert.Equal(t, 4, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusNoContent, deliveries[0].LastStatusCode)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+	assert.Equal(t, start, deliveries[0].CreatedAt)
+	assert.Equal(t, next, deliveries[0].UpdatedAt)
+	assert.Empty(t, dispatcher.DeadLetters())
+}
+
+func TestDispatcherQueueFull(t *testing.T) {
+	started := make(chan struct{}, 4)
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+	}))
+	defer receiver.Close()
+
+	opts := testOptions()
+	opts.Workers = 1
+	opts.QueueSize = 1
+	dispatcher := webhooks.NewDispatcher([]webhooks.Subscription{
+		{ID: "bot", URL: receiver.URL},
+	}, opts)
+	defer dispatcher.Close()
+
+	// 唯一的 worker 正在发送第一个投递，第二个投递占满队列，第三个投递直接写入死信
+	dispatcher.Dispatch(events.NewItemShared(testItem(), time.Now()))
+	<-started
+	dispatcher.Dispatch(events.NewItemClaimed(testItem(), "player456", time.Now()))
+	dispatcher.Dispatch(events.NewItemExpired(testItem(), time.Now()))
+
+	dead := dispatcher.DeadLetters()
+	require.Len(t, dead, 1)
+	assert.Equal(t, events.TypeItemExpired, dead[0].EventType)
+	assert.Equal(t, 0, dead[0].Attempts)
+	assert.Equal(t, "delivery queue is full", dead[0].LastError)
+
+	close(release)
+	deliveries := waitForStatus(t, dispatcher, webhooks.StatusSucceeded, 2)
+	assert.Len(t, deliveries, 2)
+}
+
+func TestDispatcherDeadLetter(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	opts := testOptions()
+	opts.DeadLetterFile = filepath.Join(t.TempDir(), "dead_letters.jsonl")
+	dispatcher := webhooks.NewDispatcher([]webhooks.Subscription{
+		{ID: "bot", URL: receiver.URL},
+	}, opts)
+	defer dispatcher.Close()
+
+	dispatcher.Dispatch(events.NewItemExpired(testItem(), time.Now()))
+
+	deliveries := waitForStatus(t, dispatcher, webhooks.StatusDead, 1)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Equal(t, "unexpected status 500", deliveries[0].LastError)
+	require.Len(t, dispatcher.DeadLetters(), 1)
+
+	// 死信写入了日志文件
+	f, err := os.Open(opts.DeadLetterFile)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+	var logged webhooks.Delivery
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &logged))
+	assert.Equal(t, deliveries[0].ID, logged.ID)
+	assert.Equal(t, webhooks.StatusDead, logged.Status)
+}
@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"duckex-server/internal/events"
+)
+
+// 请求头
+const (
+	HeaderEvent     = "X-DuckEx-Event"
+	HeaderDelivery  = "X-DuckEx-Delivery"
+	HeaderTimestamp = "X-DuckEx-Timestamp"
+	HeaderSignature = "X-DuckEx-Signature"
+)
+
+// Subscription Webhook 订阅
+type Subscription struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"` // 为空时订阅全部事件
+}
+
+// Matches 判断订阅是否关注该事件类型
+func (s Subscription) Matches(eventType events.Type) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload 投递给订阅方的JSON内容，Data 为编码后的具体事件
+type Payload struct {
+	DeliveryID string          `json:"delivery_id"`
+	Type       events.Type     `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Sign 计算签名：HMAC-SHA256(secret, timestamp + "." + body)，结果为 "sha256=<hex>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 校验签名，供接收方和测试使用
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
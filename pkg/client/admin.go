@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ListItems 列出未过期的物品，sharerID 为空时列出全部（需要管理令牌）
+func (c *Client) ListItems(ctx context.Context, sharerID string) (*ItemsResponse, error) {
+	var resp ItemsResponse
+	var query url.Values
+	if sharerID != "" {
+		query = url.Values{"sharer_id": {sharerID}}
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/items", query, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// LookupItem 按取件码查看物品，不会领取物品（需要管理令牌）
+func (c *Client) LookupItem(ctx context.Context, pickupCode string) (*Item, error) {
+	var resp ItemResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/items/"+url.PathEscape(pickupCode), nil, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return resp.Item, nil
+}
+
+// CancelItem 取消分享，物品退回到分享者的退回箱（需要管理令牌）
+func (c *Client) CancelItem(ctx context.Context, pickupCode string) (*Item, error) {
+	var resp ItemResponse
+	if err := c.do(ctx, http.MethodDelete, "/api/v1/admin/items/"+url.PathEscape(pickupCode), nil, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return resp.Item, nil
+}
+
+// DumpSnapshot 导出全部未过期物品的快照（需要管理令牌）
+func (c *Client) DumpSnapshot(ctx context.Context) (*Snapshot, error) {
+	var resp Snapshot
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/snapshot", nil, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// RestoreSnapshot 从快照恢复物品（需要管理令牌）
+func (c *Client) RestoreSnapshot(ctx context.Context, s *Snapshot) (*RestoreResult, error) {
+	var resp RestoreResult
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/snapshot", nil, s, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// AdminStatus 查看内存、限流配额和事件统计（需要管理令牌）
+func (c *Client) AdminStatus(ctx context.Context) (*AdminStatusResponse, error) {
+	var resp AdminStatusResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/status", nil, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
@@ -0,0 +1,281 @@
+// Package client 是 DuckEx HTTP API 的 Go 客户端
+//
+//	c := client.New("http://localhost:8080")
+//	shared, err := c.ShareItem(ctx, client.ShareItemRequest{...})
+//	claimed, err := c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player456"})
+//
+// 服务因内存过高暂停分享（503）时按指数退避自动重试；POST 请求携带 Idempotency-Key，
+// 同一次调用的重试使用同一个键，服务端启用幂等键时重试不会重复执行
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy 503 响应的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 总尝试次数，包括第一次请求
+	BaseDelay   time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxDelay    time.Duration // 单次等待时间上限
+}
+
+// DefaultRetryPolicy 默认重试策略：最多尝试4次，等待 500ms、1s、2s
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    10 * time.Second,
+	}
+}
+
+// 第 attempt 次重试前的等待时间（attempt 从1开始）
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	d := p.BaseDelay << (attempt - 1)
+	if d <= 0 || (p.MaxDelay > 0 && d > p.MaxDelay) {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// Client DuckEx API 客户端，可以被多个 goroutine 同时使用
+type Client struct {
+	baseURL     string
+	httpClient  *http.Client
+	adminToken  string
+	playerToken string
+	retry       RetryPolicy
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithHTTPClient 使用自定义的 http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) { c.httpClient = httpClient }
+}
+
+// WithAdminToken 设置调用管理接口使用的令牌
+func WithAdminToken(token string) Option {
+	return func(c *Client) { c.adminToken = token }
+}
+
+// WithRetryPolicy 设置 503 响应的重试策略，MaxAttempts 为1时不重试
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) { c.retry = policy }
+}
+
+// New 创建客户端，baseURL 为服务地址，如 "http://localhost:8080"
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		retry:      DefaultRetryPolicy(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.retry.MaxAttempts < 1 {
+		c.retry.MaxAttempts = 1
+	}
+	return c
+}
+
+// AsPlayer 返回以 token 对应玩家身份调用的客户端副本，token 为游戏服务端签发的玩家令牌
+// 群组操作、领取或预留群组物品、向群组分享时需要玩家令牌
+func (c *Client) AsPlayer(token string) *Client {
+	player := *c
+	player.playerToken = token
+	return &player
+}
+
+// APIError 服务端返回的错误
+// HTTP 状态码非2xx，或领取接口在响应体的 code 字段中返回了业务错误
+type APIError struct {
+	StatusCode int    // HTTP 状态码
+	Code       int    // 业务错误码，未返回时与 StatusCode 相同
+	Message    string // 服务端的错误信息
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("duckex: %d %s", e.Code, e.Message)
+}
+
+// IsNotFound 错误是否表示取件码无效或物品已过期
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound
+}
+
+// IsAlreadyClaimed 错误是否表示物品已被领取
+func IsAlreadyClaimed(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
+
+// IsUnauthorized 错误是否表示缺少玩家令牌或令牌与玩家不匹配
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized
+}
+
+// IsForbidden 错误是否表示玩家无权执行该操作，如领取只分享给群组的物品
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden
+}
+
+// IsUnavailable 错误是否表示服务暂时不可用（重试耗尽后仍为503）
+func IsUnavailable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable
+}
+
+// IdempotencyKeyHeader 幂等键请求头
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type idempotencyKeyContextKey struct{}
+
+// WithIdempotencyKey 返回携带幂等键的 context，使用它发起的 POST 请求以 key 作为 Idempotency-Key；
+// 调用者自己重试同一个逻辑操作（如进程重启后）时传入相同的键，服务端会重放首次响应
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// POST 请求使用的幂等键：优先使用 context 中的键，否则为本次调用生成一个
+func idempotencyKey(ctx context.Context) (string, error) {
+	if key, ok := ctx.Value(idempotencyKeyContextKey{}).(string); ok && key != "" {
+		return key, nil
+	}
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// 发送请求，503 时按重试策略重试，成功时将响应体解析到 out
+// POST 请求的每次重试都携带同一个幂等键
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}, admin bool) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var key string
+	if method == http.MethodPost {
+		var err error
+		if key, err = idempotencyKey(ctx); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := c.send(ctx, method, target, payload, key, admin)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != http.StatusServiceUnavailable || attempt >= c.retry.MaxAttempts {
+			return decodeResponse(resp, out)
+		}
+
+		// 优先使用服务端给出的 Retry-After
+		wait := c.retry.delay(attempt)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			wait = time.Duration(seconds) * time.Second
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, target string, payload []byte, idempotencyKey string, admin bool) (*http.Response, error) {
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	if admin && c.adminToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.adminToken)
+	} else if !admin && c.playerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+c.playerToken)
+	}
+	return c.httpClient.Do(req)
+}
+
+// 解析响应，非2xx时返回 APIError
+func decodeResponse(resp *http.Response, out interface{}) error {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newAPIError(resp.StatusCode, data)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("duckex: decode response: %w", err)
+	}
+	return nil
+}
+
+// 错误响应可能是 {"error": ...} 或 {"code": ..., "message": ...}
+func newAPIError(status int, data []byte) *APIError {
+	var body struct {
+		Error   string `json:"error"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	apiErr := &APIError{StatusCode: status, Code: status}
+	if json.Unmarshal(data, &body) == nil {
+		if body.Code != 0 {
+			apiErr.Code = body.Code
+		}
+		apiErr.Message = body.Error
+		if apiErr.Message == "" {
+			apiErr.Message = body.Message
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(status)
+	}
+	return apiErr
+}
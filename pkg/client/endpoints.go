@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Health 健康检查
+func (c *Client) Health(ctx context.Context) (*HealthResponse, error) {
+	var resp HealthResponse
+	if err := c.do(ctx, http.MethodGet, "/health", nil, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// OpenAPI 获取服务端的 OpenAPI 文档
+func (c *Client) OpenAPI(ctx context.Context) (*Document, error) {
+	var doc Document
+	if err := c.do(ctx, http.MethodGet, "/openapi.json", nil, nil, &doc, false); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// ShareItem 分享物品，返回取件码
+func (c *Client) ShareItem(ctx context.Context, req ShareItemRequest) (*ShareItemResponse, error) {
+	var resp ShareItemResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/items/share", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ClaimItem 领取物品，响应体中的业务错误码（404、409等）以 APIError 返回
+func (c *Client) ClaimItem(ctx context.Context, req ClaimItemRequest) (*ClaimItemResponse, error) {
+	var resp ClaimItemResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/items/claim", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	if resp.Code != http.StatusOK {
+		return &resp, &APIError{StatusCode: http.StatusOK, Code: resp.Code, Message: resp.Message}
+	}
+	return &resp, nil
+}
+
+// ReserveItem 预留物品，返回预留令牌，业务错误码以 APIError 返回
+func (c *Client) ReserveItem(ctx context.Context, req ReserveItemRequest) (*ReserveItemResponse, error) {
+	var resp ReserveItemResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/items/reserve", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	if resp.Code != http.StatusOK {
+		return &resp, &APIError{StatusCode: http.StatusOK, Code: resp.Code, Message: resp.Message}
+	}
+	return &resp, nil
+}
+
+// ConfirmItem 确认预留，物品被领走；预留不存在或已过期时返回 Code 为 410 的 APIError
+func (c *Client) ConfirmItem(ctx context.Context, req ReservationRequest) (*ClaimItemResponse, error) {
+	return c.settleReservation(ctx, "/api/v1/items/confirm", req)
+}
+
+// ReleaseItem 取消预留，物品恢复为可领取
+func (c *Client) ReleaseItem(ctx context.Context, req ReservationRequest) (*ClaimItemResponse, error) {
+	return c.settleReservation(ctx, "/api/v1/items/release", req)
+}
+
+func (c *Client) settleReservation(ctx context.Context, path string, req ReservationRequest) (*ClaimItemResponse, error) {
+	var resp ClaimItemResponse
+	if err := c.do(ctx, http.MethodPost, path, nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	if resp.Code != http.StatusOK {
+		return &resp, &APIError{StatusCode: http.StatusOK, Code: resp.Code, Message: resp.Message}
+	}
+	return &resp, nil
+}
+
+// ListReturns 查看分享者的退回箱，需要通过 AsPlayer 使用分享者的玩家令牌
+func (c *Client) ListReturns(ctx context.Context, sharerID string) (*ReturnsResponse, error) {
+	var resp ReturnsResponse
+	query := url.Values{"sharer_id": {sharerID}}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/returns", query, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// CollectReturns 领回分享者退回箱中的全部物品，需要通过 AsPlayer 使用分享者的玩家令牌
+func (c *Client) CollectReturns(ctx context.Context, sharerID string) (*ReturnsResponse, error) {
+	var resp ReturnsResponse
+	req := CollectReturnsRequest{SharerID: sharerID}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/returns/collect", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// MemoryStatus 查看内存监控状态
+func (c *Client) MemoryStatus(ctx context.Context) (*MemoryStatus, error) {
+	var resp MemoryStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/memory", nil, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListWebhookDeliveries 查看最近的 Webhook 投递记录，status 为空时不过滤（需要管理令牌）
+func (c *Client) ListWebhookDeliveries(ctx context.Context, status DeliveryStatus) (*DeliveriesResponse, error) {
+	var resp DeliveriesResponse
+	var query url.Values
+	if status != "" {
+		query = url.Values{"status": {string(status)}}
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/webhooks/deliveries", query, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListWebhookDeadLetters 查看重试耗尽的 Webhook 投递（需要管理令牌）
+func (c *Client) ListWebhookDeadLetters(ctx context.Context) (*DeliveriesResponse, error) {
+	var resp DeliveriesResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/webhooks/dead-letters", nil, nil, &resp, true); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
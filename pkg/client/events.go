@@ -0,0 +1,88 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Event 服务端推送的事件，Name 为事件类型（如 "item_claimed"、"ping"），Data 为事件内容
+type Event struct {
+	Name string
+	Data json.RawMessage
+}
+
+// StreamEvents 订阅分享者的事件，token 为游戏服务端为该分享者签发的玩家令牌，每收到一个事件调用一次 handle
+// 阻塞直到 ctx 被取消、连接断开或 handle 返回错误；ctx 被取消时返回 ctx.Err()
+func (c *Client) StreamEvents(ctx context.Context, sharerID, token string, handle func(Event) error) error {
+	return c.stream(ctx, "/api/v1/events", url.Values{"sharer_id": {sharerID}}, token, handle)
+}
+
+// TailEvents 订阅全部事件，eventType 为空时不过滤（需要管理令牌），返回条件同 StreamEvents
+func (c *Client) TailEvents(ctx context.Context, eventType string, handle func(Event) error) error {
+	var query url.Values
+	if eventType != "" {
+		query = url.Values{"type": {eventType}}
+	}
+	return c.stream(ctx, "/api/v1/admin/events", query, c.adminToken, handle)
+}
+
+// 读取 SSE 事件流并逐个交给 handle，token 不为空时作为 Bearer 令牌发送
+func (c *Client) stream(ctx context.Context, path string, query url.Values, token string, handle func(Event) error) error {
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return decodeResponse(resp, nil)
+	}
+	defer resp.Body.Close()
+
+	var (
+		event Event
+		data  []string
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			// 空行表示一个事件结束
+			if event.Name != "" || len(data) > 0 {
+				event.Data = json.RawMessage(strings.Join(data, "\n"))
+				if err := handle(event); err != nil {
+					return err
+				}
+			}
+			event, data = Event{}, nil
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Name = value
+		case "data":
+			data = append(data, value)
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// CreateGroup 创建群组，ownerID 成为群主
+func (c *Client) CreateGroup(ctx context.Context, name, ownerID string) (*Group, error) {
+	req := CreateGroupRequest{Name: name, OwnerID: ownerID}
+	return c.groupCall(ctx, http.MethodPost, "/api/v1/groups", nil, req)
+}
+
+// ListGroups 列出玩家所在或被邀请加入的群组
+func (c *Client) ListGroups(ctx context.Context, playerID string) ([]*Group, error) {
+	var resp GroupsResponse
+	query := url.Values{"player_id": {playerID}}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/groups", query, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Groups, nil
+}
+
+// GetGroup 以 playerID 的身份查看群组，只有成员和被邀请的玩家可以查看
+func (c *Client) GetGroup(ctx context.Context, id, playerID string) (*Group, error) {
+	query := url.Values{"player_id": {playerID}}
+	return c.groupCall(ctx, http.MethodGet, groupPath(id, ""), query, nil)
+}
+
+// InviteToGroup 管理员或群主邀请玩家
+func (c *Client) InviteToGroup(ctx context.Context, id, inviterID, playerID string) (*Group, error) {
+	req := InviteGroupRequest{InviterID: inviterID, PlayerID: playerID}
+	return c.groupCall(ctx, http.MethodPost, groupPath(id, "/invite"), nil, req)
+}
+
+// JoinGroup 接受邀请加入群组
+func (c *Client) JoinGroup(ctx context.Context, id, playerID string) (*Group, error) {
+	return c.groupCall(ctx, http.MethodPost, groupPath(id, "/join"), nil, GroupPlayerRequest{PlayerID: playerID})
+}
+
+// LeaveGroup 退出群组，群主需要先转让群组
+func (c *Client) LeaveGroup(ctx context.Context, id, playerID string) (*Group, error) {
+	return c.groupCall(ctx, http.MethodPost, groupPath(id, "/leave"), nil, GroupPlayerRequest{PlayerID: playerID})
+}
+
+// SetGroupRole 群主调整成员的角色，设为 owner 时转让群组
+func (c *Client) SetGroupRole(ctx context.Context, id, actorID, playerID string, role GroupRole) (*Group, error) {
+	req := SetGroupRoleRequest{ActorID: actorID, PlayerID: playerID, Role: role}
+	return c.groupCall(ctx, http.MethodPost, groupPath(id, "/role"), nil, req)
+}
+
+// RemoveGroupMember 移除角色更低的成员，或撤回邀请
+func (c *Client) RemoveGroupMember(ctx context.Context, id, actorID, playerID string) (*Group, error) {
+	req := RemoveGroupMemberRequest{ActorID: actorID, PlayerID: playerID}
+	return c.groupCall(ctx, http.MethodPost, groupPath(id, "/remove"), nil, req)
+}
+
+// ListGroupItems 成员列出分享给群组的物品，limit 为0时使用服务端默认值，响应中的 NextCursor 为空表示没有更多结果
+func (c *Client) ListGroupItems(ctx context.Context, id, playerID string, limit int, cursor string) (*GroupItemsResponse, error) {
+	var resp GroupItemsResponse
+	query := url.Values{"player_id": {playerID}}
+	if limit != 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if err := c.do(ctx, http.MethodGet, groupPath(id, "/items"), query, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func groupPath(id, action string) string {
+	return "/api/v1/groups/" + url.PathEscape(id) + action
+}
+
+// 发送返回单个群组的请求
+func (c *Client) groupCall(ctx context.Context, method, path string, query url.Values, body interface{}) (*Group, error) {
+	var resp GroupResponse
+	if err := c.do(ctx, method, path, query, body, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Group, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ListingQuery 浏览市场的条件，零值字段不参与过滤
+type ListingQuery struct {
+	// Text 全文检索，名称和描述需包含全部检索词
+	Text          string
+	TypeID        int
+	Name          string
+	SharerID      string
+	MinDurability *float64
+	MaxDurability *float64
+	Limit         int
+	// Cursor 上一页返回的 NextCursor
+	Cursor string
+}
+
+func (q ListingQuery) values() url.Values {
+	values := url.Values{}
+	if q.Text != "" {
+		values.Set("q", q.Text)
+	}
+	if q.TypeID != 0 {
+		values.Set("type_id", strconv.Itoa(q.TypeID))
+	}
+	if q.Name != "" {
+		values.Set("name", q.Name)
+	}
+	if q.SharerID != "" {
+		values.Set("sharer_id", q.SharerID)
+	}
+	if q.MinDurability != nil {
+		values.Set("min_durability", strconv.FormatFloat(*q.MinDurability, 'f', -1, 64))
+	}
+	if q.MaxDurability != nil {
+		values.Set("max_durability", strconv.FormatFloat(*q.MaxDurability, 'f', -1, 64))
+	}
+	if q.Limit != 0 {
+		values.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.Cursor != "" {
+		values.Set("cursor", q.Cursor)
+	}
+	return values
+}
+
+// SearchListings 浏览市场，响应中的 NextCursor 为空表示没有更多结果
+func (c *Client) SearchListings(ctx context.Context, q ListingQuery) (*ListingsResponse, error) {
+	var resp ListingsResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/listings", q.values(), nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// GetListing 查看挂牌
+func (c *Client) GetListing(ctx context.Context, listingID string) (*Listing, error) {
+	var resp ListingResponse
+	if err := c.do(ctx, http.MethodGet, "/api/v1/listings/"+url.PathEscape(listingID), nil, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Listing, nil
+}
+
+// ClaimListing 凭挂牌ID领取物品，业务错误码（404、409等）以 APIError 返回
+func (c *Client) ClaimListing(ctx context.Context, listingID, claimerID string) (*ClaimItemResponse, error) {
+	var resp ClaimItemResponse
+	req := ClaimListingRequest{ClaimerID: claimerID}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/listings/"+url.PathEscape(listingID)+"/claim", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	if resp.Code != http.StatusOK {
+		return &resp, &APIError{StatusCode: http.StatusOK, Code: resp.Code, Message: resp.Message}
+	}
+	return &resp, nil
+}
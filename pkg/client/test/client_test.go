@@ -0,0 +1,538 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"duckex-server/internal/events"
+	"duckex-server/internal/handlers"
+	"duckex-server/internal/models"
+	"duckex-server/internal/playertoken"
+	"duckex-server/internal/router"
+	"duckex-server/internal/service"
+	"duckex-server/internal/utils"
+	"duckex-server/internal/webhooks"
+	"duckex-server/pkg/client"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 启动使用真实路由的测试服务
+func newTestServer(t *testing.T) *httptest.Server {
+	gin.SetMode(gin.TestMode)
+	itemRepo := models.NewInMemoryItemRepository(nil)
+	returnBox := models.NewInMemoryReturnBox(0, nil)
+	itemRepo.SetExpiredHandler(func(item *models.Item) { returnBox.Add(item) })
+	groupRepo := models.NewInMemoryGroupRepository()
+	bus := events.NewBus()
+	monitor := utils.NewMemoryMonitor(4096)
+	items := service.NewItemService(service.Deps{
+		ItemRepo:          itemRepo,
+		ReturnBox:         returnBox,
+		MemoryMonitor:     monitor,
+		EventBus:          bus,
+		Groups:            groupRepo,
+		PlayerTokenSecret: "player-secret",
+	})
+	trades := service.NewTradeService(service.TradeDeps{
+		Trades:        models.NewInMemoryTradeRepository(),
+		ItemRepo:      itemRepo,
+		ReturnBox:     returnBox,
+		MemoryMonitor: monitor,
+		EventBus:      bus,
+	})
+	adminHandler := handlers.NewAdminHandler(handlers.AdminDeps{
+		ItemRepo:      itemRepo,
+		Items:         items,
+		MemoryMonitor: monitor,
+	})
+	r := gin.New()
+	router.Register(r, router.Handlers{
+		Health:        handlers.NewHealthHandler(itemRepo, nil, nil, nil),
+		Item:          handlers.NewItemHandler(items, monitor),
+		Return:        handlers.NewReturnHandler(returnBox, "player-secret"),
+		Trade:         handlers.NewTradeHandler(trades, "player-secret"),
+		Listing:       handlers.NewListingHandler(items),
+		Group:         handlers.NewGroupHandler(service.NewGroupService(service.GroupDeps{Groups: groupRepo, ItemRepo: itemRepo}), "player-secret"),
+		Event:         handlers.NewEventHandler(bus, "player-secret", nil),
+		Webhook:       handlers.NewWebhookHandler(webhooks.NewDispatcher(nil, webhooks.DefaultOptions())),
+		Admin:         adminHandler,
+		MemoryMonitor: monitor,
+		AdminToken:    "admin-token",
+	})
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func shareRequest() client.ShareItemRequest {
+	return client.ShareItemRequest{
+		Name:        "Golden Duck",
+		Description: "Shiny",
+		TypeID:      1001,
+		Num:         1,
+		Durability:  90,
+		SharerID:    "player123",
+	}
+}
+
+// 以 playerID 的身份调用的客户端
+func asPlayer(c *client.Client, playerID string) *client.Client {
+	return c.AsPlayer(playertoken.Sign("player-secret", playerID))
+}
+
+func TestShareAndClaim(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	ctx := context.Background()
+
+	shared, err := c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+	assert.Len(t, shared.PickupCode, 6)
+
+	health, err := c.Health(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, health.PendingItemsCount)
+
+	claimed, err := c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player456"})
+	require.NoError(t, err)
+	assert.Equal(t, "Golden Duck", claimed.Item.Name)
+	assert.Equal(t, "player456", claimed.Item.ClaimerID)
+
+	// 再次领取返回业务错误
+	_, err = c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player789"})
+	assert.True(t, client.IsNotFound(err))
+}
+
+func TestReserveAndConfirm(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	ctx := context.Background()
+	shared, err := c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+
+	reserved, err := c.ReserveItem(ctx, client.ReserveItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player456"})
+	require.NoError(t, err)
+	assert.NotEmpty(t, reserved.ReservationToken)
+	assert.Equal(t, "Golden Duck", reserved.Item.Name)
+
+	_, err = c.ReserveItem(ctx, client.ReserveItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player789"})
+	assert.True(t, client.IsAlreadyClaimed(err))
+
+	confirmed, err := c.ConfirmItem(ctx, client.ReservationRequest{PickupCode: shared.PickupCode, ReservationToken: reserved.ReservationToken})
+	require.NoError(t, err)
+	assert.Equal(t, "player456", confirmed.Item.ClaimerID)
+
+	// 重复确认返回预留不存在
+	_, err = c.ConfirmItem(ctx, client.ReservationRequest{PickupCode: shared.PickupCode, ReservationToken: reserved.ReservationToken})
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusGone, apiErr.Code)
+}
+
+func TestTrade(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	ctx := context.Background()
+
+	open := client.OpenTradeRequest{
+		InitiatorID: "player123",
+		Offer:       []client.TradeItem{{Name: "Golden Duck", Description: "Shiny", TypeID: 1001, Num: 1, Durability: 90}},
+		Request:     []client.TradeWant{{TypeID: 2001, Num: 1}},
+	}
+	_, err := c.OpenTrade(ctx, open)
+	assert.True(t, client.IsUnauthorized(err))
+	trade, err := asPlayer(c, "player123").OpenTrade(ctx, open)
+	require.NoError(t, err)
+
+	accepted, err := asPlayer(c, "player456").AcceptTrade(ctx, trade.ID, client.AcceptTradeRequest{
+		AcceptorID: "player456",
+		Items:      []client.TradeItem{{Name: "Health Potion", Description: "Restores health", TypeID: 2001, Num: 1, Durability: 100}},
+	})
+	require.NoError(t, err)
+	require.Len(t, accepted.AcceptorCodes, 1)
+
+	claimed, err := c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: accepted.AcceptorCodes[0], ClaimerID: "player456"})
+	require.NoError(t, err)
+	assert.Equal(t, "Golden Duck", claimed.Item.Name)
+
+	viewed, err := asPlayer(c, "player123").GetTrade(ctx, trade.ID, "player123")
+	require.NoError(t, err)
+	require.Len(t, viewed.InitiatorCodes, 1)
+	trades, err := asPlayer(c, "player123").ListTrades(ctx, "player123")
+	require.NoError(t, err)
+	assert.Len(t, trades, 1)
+
+	// 已完成的交易不能取消
+	_, err = asPlayer(c, "player123").CancelTrade(ctx, trade.ID, "player123")
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusConflict, apiErr.StatusCode)
+	_, err = asPlayer(c, "player123").GetTrade(ctx, "missing", "player123")
+	assert.True(t, client.IsNotFound(err))
+}
+
+func TestListings(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	ctx := context.Background()
+	req := shareRequest()
+	req.Listed = true
+	shared, err := c.ShareItem(ctx, req)
+	require.NoError(t, err)
+	require.NotEmpty(t, shared.ListingID)
+
+	page, err := c.SearchListings(ctx, client.ListingQuery{TypeID: req.TypeID, Name: "golden"})
+	require.NoError(t, err)
+	require.Len(t, page.Listings, 1)
+	listing, err := c.GetListing(ctx, shared.ListingID)
+	require.NoError(t, err)
+	assert.Equal(t, "Golden Duck", listing.Name)
+
+	claimed, err := c.ClaimListing(ctx, shared.ListingID, "player456")
+	require.NoError(t, err)
+	assert.Equal(t, shared.PickupCode, claimed.Item.PickupCode)
+	_, err = c.ClaimListing(ctx, shared.ListingID, "player789")
+	assert.True(t, client.IsNotFound(err))
+}
+
+func TestValidationError(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	_, err := c.ShareItem(context.Background(), client.ShareItemRequest{Name: "missing fields"})
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	assert.Contains(t, apiErr.Message, "Invalid request format")
+}
+
+func TestReturnsAndMemory(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	ctx := context.Background()
+
+	_, err := c.ListReturns(ctx, "player123")
+	assert.True(t, client.IsUnauthorized(err))
+	_, err = asPlayer(c, "player999").CollectReturns(ctx, "player123")
+	assert.True(t, client.IsUnauthorized(err))
+	returns, err := asPlayer(c, "player123").ListReturns(ctx, "player123")
+	require.NoError(t, err)
+	assert.Empty(t, returns.Items)
+	collected, err := asPlayer(c, "player123").CollectReturns(ctx, "player123")
+	require.NoError(t, err)
+	assert.Equal(t, 200, collected.Code)
+
+	status, err := c.MemoryStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4096), status.MaxMemoryMB)
+
+	doc, err := c.OpenAPI(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, doc.Operation("post", "/api/v1/items/share"))
+}
+
+func TestAdminEndpoints(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	_, err := client.New(server.URL).ListWebhookDeliveries(ctx, "")
+	var apiErr *client.APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+
+	c := client.New(server.URL, client.WithAdminToken("admin-token"))
+	deliveries, err := c.ListWebhookDeliveries(ctx, client.DeliveryDead)
+	require.NoError(t, err)
+	assert.Empty(t, deliveries.Deliveries)
+	_, err = c.ListWebhookDeadLetters(ctx)
+	assert.NoError(t, err)
+}
+
+func TestRetryOnServiceUnavailable(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(client.ErrorResponse{Error: "Storage temporarily disabled"})
+			return
+		}
+		json.NewEncoder(w).Encode(client.ShareItemResponse{PickupCode: "123456"})
+	}))
+	defer server.Close()
+
+	policy := client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	c := client.New(server.URL, client.WithRetryPolicy(policy))
+	resp, err := c.ShareItem(context.Background(), shareRequest())
+	require.NoError(t, err)
+	assert.Equal(t, "123456", resp.PickupCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+
+	// 重试耗尽后返回 503 错误
+	atomic.StoreInt32(&attempts, -10)
+	_, err = c.ShareItem(context.Background(), shareRequest())
+	assert.True(t, client.IsUnavailable(err))
+	assert.Equal(t, int32(-7), atomic.LoadInt32(&attempts))
+}
+
+func TestRetryStopsOnContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := client.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Hour}
+	c := client.New(server.URL, client.WithRetryPolicy(policy))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.ShareItem(ctx, shareRequest())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRetriesReuseIdempotencyKey(t *testing.T) {
+	var mutex sync.Mutex
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		keys = append(keys, r.Header.Get(client.IdempotencyKeyHeader))
+		attempt := len(keys)
+		mutex.Unlock()
+		if r.Method == http.MethodPost && attempt%3 != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(client.ShareItemResponse{PickupCode: "123456"})
+	}))
+	defer server.Close()
+
+	policy := client.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	c := client.New(server.URL, client.WithRetryPolicy(policy))
+	ctx := context.Background()
+
+	// 同一次调用的重试使用同一个生成的键
+	_, err := c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+	require.Len(t, keys, 3)
+	assert.NotEmpty(t, keys[0])
+	assert.Equal(t, keys[0], keys[1])
+	assert.Equal(t, keys[0], keys[2])
+
+	// 新的调用生成新的键
+	_, err = c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+	assert.NotEqual(t, keys[0], keys[3])
+
+	// 调用者指定的键用于所有重试
+	_, err = c.ShareItem(client.WithIdempotencyKey(ctx, "share-1"), shareRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"share-1", "share-1", "share-1"}, keys[6:9])
+
+	// GET 请求不携带幂等键
+	_, err = c.Health(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, keys[9])
+}
+
+// 结构体的 JSON 字段名
+func jsonFields(t reflect.Type) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func TestTypesMatchServerSchemas(t *testing.T) {
+	doc, err := client.New(newTestServer(t).URL).OpenAPI(context.Background())
+	require.NoError(t, err)
+	var components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	}
+	require.NoError(t, json.Unmarshal(doc.Components, &components))
+
+	// 客户端自行定义的结构与服务端文档中的同名结构字段一致
+	types := []interface{}{
+		client.ShareItemRequest{}, client.ShareItemResponse{}, client.ClaimItemRequest{}, client.ClaimItemResponse{},
+		client.ReserveItemRequest{}, client.ReserveItemResponse{}, client.ReservationRequest{},
+		client.ClaimListingRequest{}, client.ListingsResponse{}, client.ListingResponse{},
+		client.CollectReturnsRequest{}, client.ReturnsResponse{},
+		client.OpenTradeRequest{}, client.AcceptTradeRequest{}, client.CancelTradeRequest{},
+		client.TradeResponse{}, client.TradesResponse{},
+		client.CreateGroupRequest{}, client.InviteGroupRequest{}, client.GroupPlayerRequest{},
+		client.SetGroupRoleRequest{}, client.RemoveGroupMemberRequest{},
+		client.GroupResponse{}, client.GroupsResponse{}, client.GroupItemsResponse{},
+		client.HealthResponse{}, client.AdminStatusResponse{}, client.ErrorResponse{},
+		client.ItemsResponse{}, client.ItemResponse{}, client.MemoryStatus{},
+		client.Item{}, client.Reservation{}, client.ReturnedItem{}, client.Listing{},
+		client.Trade{}, client.TradeItem{}, client.TradeWant{},
+		client.Group{}, client.GroupMember{}, client.GroupInvite{},
+		client.Snapshot{}, client.RestoreResult{}, client.DeliveriesResponse{}, client.Delivery{},
+	}
+	checked := 0
+	for _, v := range types {
+		typ := reflect.TypeOf(v)
+		schema, ok := components.Schemas[typ.Name()]
+		if !ok {
+			continue
+		}
+		var properties []string
+		for name := range schema.Properties {
+			properties = append(properties, name)
+		}
+		sort.Strings(properties)
+		assert.Equal(t, properties, jsonFields(typ), typ.Name())
+		checked++
+	}
+	assert.Greater(t, checked, 20)
+}
+
+func TestStreamEvents(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan client.Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.StreamEvents(ctx, "player123", playertoken.Sign("player-secret", "player123"), func(event client.Event) error {
+			received <- event
+			return nil
+		})
+	}()
+
+	// 订阅生效后再分享物品
+	event := <-received
+	assert.Equal(t, "subscribed", event.Name)
+	_, err := c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+
+	event = <-received
+	assert.Equal(t, "item_shared", event.Name)
+	var shared events.ItemShared
+	require.NoError(t, json.Unmarshal(event.Data, &shared))
+	assert.Equal(t, "Golden Duck", shared.Item.Name)
+	assert.Empty(t, shared.Item.PickupCode)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestAdminItemsAndSnapshot(t *testing.T) {
+	c := client.New(newTestServer(t).URL, client.WithAdminToken("admin-token"))
+	ctx := context.Background()
+
+	shared, err := c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+
+	list, err := c.ListItems(ctx, "player123")
+	require.NoError(t, err)
+	require.Len(t, list.Items, 1)
+
+	item, err := c.LookupItem(ctx, shared.PickupCode)
+	require.NoError(t, err)
+	assert.Equal(t, "Golden Duck", item.Name)
+
+	dump, err := c.DumpSnapshot(ctx)
+	require.NoError(t, err)
+	assert.Len(t, dump.Items, 1)
+
+	cancelled, err := c.CancelItem(ctx, shared.PickupCode)
+	require.NoError(t, err)
+	assert.Equal(t, item.ID, cancelled.ID)
+	_, err = c.LookupItem(ctx, shared.PickupCode)
+	assert.True(t, client.IsNotFound(err))
+	returns, err := asPlayer(c, "player123").ListReturns(ctx, "player123")
+	require.NoError(t, err)
+	assert.Len(t, returns.Items, 1)
+
+	result, err := c.RestoreSnapshot(ctx, dump)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Restored)
+
+	status, err := c.AdminStatus(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.PendingItemsCount)
+}
+
+func TestTailEvents(t *testing.T) {
+	c := client.New(newTestServer(t).URL, client.WithAdminToken("admin-token"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	received := make(chan client.Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.TailEvents(ctx, string(events.TypeItemClaimed), func(event client.Event) error {
+			received <- event
+			return nil
+		})
+	}()
+
+	assert.Equal(t, "subscribed", (<-received).Name)
+	shared, err := c.ShareItem(ctx, shareRequest())
+	require.NoError(t, err)
+	_, err = c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player456"})
+	require.NoError(t, err)
+
+	// item_shared 被过滤，只收到 item_claimed
+	assert.Equal(t, "item_claimed", (<-received).Name)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestGroupShareAndClaim(t *testing.T) {
+	c := client.New(newTestServer(t).URL)
+	as := func(playerID string) *client.Client { return asPlayer(c, playerID) }
+	ctx := context.Background()
+
+	// 没有玩家令牌不能以他人身份操作群组
+	_, err := c.CreateGroup(ctx, "Duck Clan", "player123")
+	assert.True(t, client.IsUnauthorized(err))
+	group, err := as("player123").CreateGroup(ctx, "Duck Clan", "player123")
+	require.NoError(t, err)
+	_, err = as("player456").InviteToGroup(ctx, group.ID, "player123", "player456")
+	assert.True(t, client.IsUnauthorized(err))
+	_, err = as("player123").InviteToGroup(ctx, group.ID, "player123", "player456")
+	require.NoError(t, err)
+	group, err = as("player456").JoinGroup(ctx, group.ID, "player456")
+	require.NoError(t, err)
+	assert.Len(t, group.Members, 2)
+	groups, err := as("player456").ListGroups(ctx, "player456")
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	req := shareRequest()
+	req.GroupID = group.ID
+	shared, err := as(req.SharerID).ShareItem(ctx, req)
+	require.NoError(t, err)
+
+	page, err := as("player456").ListGroupItems(ctx, group.ID, "player456", 0, "")
+	require.NoError(t, err)
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, shared.PickupCode, page.Items[0].PickupCode)
+	_, err = as("player789").ListGroupItems(ctx, group.ID, "player789", 0, "")
+	assert.True(t, client.IsForbidden(err))
+
+	_, err = as("player789").ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player789"})
+	assert.True(t, client.IsForbidden(err))
+	_, err = c.ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player456"})
+	assert.True(t, client.IsUnauthorized(err))
+	claimed, err := as("player456").ClaimItem(ctx, client.ClaimItemRequest{PickupCode: shared.PickupCode, ClaimerID: "player456"})
+	require.NoError(t, err)
+	assert.Equal(t, group.ID, claimed.Item.GroupID)
+
+	_, err = as("player789").GetGroup(ctx, group.ID, "player789")
+	assert.True(t, client.IsNotFound(err))
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// OpenTrade 发起交易，发起方的物品进入托管
+//
+// 交易接口需要通过 AsPlayer 使用执行操作的玩家（发起方、接受方或 playerID）的玩家令牌
+func (c *Client) OpenTrade(ctx context.Context, req OpenTradeRequest) (*Trade, error) {
+	var resp TradeResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/trades", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Trade, nil
+}
+
+// ListTrades 列出玩家参与的交易
+func (c *Client) ListTrades(ctx context.Context, playerID string) ([]*Trade, error) {
+	var resp TradesResponse
+	query := url.Values{"player_id": {playerID}}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/trades", query, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Trades, nil
+}
+
+// GetTrade 以 playerID 的身份查看交易，参与方可以看到交付给自己的取件码；playerID 为空时匿名查看公开的交易
+func (c *Client) GetTrade(ctx context.Context, id, playerID string) (*Trade, error) {
+	var resp TradeResponse
+	var query url.Values
+	if playerID != "" {
+		query = url.Values{"player_id": {playerID}}
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/trades/"+url.PathEscape(id), query, nil, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Trade, nil
+}
+
+// AcceptTrade 接受交易，返回的交易包含交付给接受方的取件码
+func (c *Client) AcceptTrade(ctx context.Context, id string, req AcceptTradeRequest) (*Trade, error) {
+	var resp TradeResponse
+	if err := c.do(ctx, http.MethodPost, "/api/v1/trades/"+url.PathEscape(id)+"/accept", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Trade, nil
+}
+
+// CancelTrade 发起方取消交易，托管物品退回到退回箱
+func (c *Client) CancelTrade(ctx context.Context, id, initiatorID string) (*Trade, error) {
+	var resp TradeResponse
+	req := CancelTradeRequest{InitiatorID: initiatorID}
+	if err := c.do(ctx, http.MethodPost, "/api/v1/trades/"+url.PathEscape(id)+"/cancel", nil, req, &resp, false); err != nil {
+		return nil, err
+	}
+	return resp.Trade, nil
+}
@@ -0,0 +1,453 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 请求和响应结构，字段与服务端 API 的 JSON 一致；客户端不依赖服务端的内部包，
+// 服务端新增字段时旧版本客户端会忽略这些字段
+
+// ShareItemRequest 分享物品的请求
+type ShareItemRequest struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	TypeID      int     `json:"type_id"`
+	Num         int     `json:"num"`
+	Durability  float64 `json:"durability"`
+	SharerID    string  `json:"sharer_id"`
+	// 为 true 时公开上架到市场
+	Listed bool `json:"listed"`
+	// 不为空时只分享给该群组，只有群组成员可以领取
+	GroupID string `json:"group_id"`
+}
+
+// ShareItemResponse 分享物品的响应
+type ShareItemResponse struct {
+	Message    string `json:"message"`
+	PickupCode string `json:"pickup_code"`
+	ExpiresAt  string `json:"expires_at"`
+	ListingID  string `json:"listing_id,omitempty"`
+}
+
+// ClaimItemRequest 领取物品的请求
+type ClaimItemRequest struct {
+	PickupCode string `json:"pickup_code"`
+	ClaimerID  string `json:"claimer_id"`
+}
+
+// ClaimItemResponse 领取、确认或取消预留的响应
+type ClaimItemResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Item    *Item  `json:"item,omitempty"`
+}
+
+// ReserveItemRequest 预留物品的请求
+type ReserveItemRequest struct {
+	PickupCode string `json:"pickup_code"`
+	ClaimerID  string `json:"claimer_id"`
+}
+
+// ReserveItemResponse 预留物品的响应
+type ReserveItemResponse struct {
+	Code             int    `json:"code"`
+	Message          string `json:"message"`
+	ReservationToken string `json:"reservation_token,omitempty"`
+	LeaseExpiresAt   string `json:"lease_expires_at,omitempty"`
+	Item             *Item  `json:"item,omitempty"`
+}
+
+// ReservationRequest 确认或取消预留的请求
+type ReservationRequest struct {
+	PickupCode       string `json:"pickup_code"`
+	ReservationToken string `json:"reservation_token"`
+}
+
+// ClaimListingRequest 按挂牌ID领取的请求
+type ClaimListingRequest struct {
+	ClaimerID string `json:"claimer_id"`
+}
+
+// ListingsResponse 市场浏览的响应
+type ListingsResponse struct {
+	Code       int        `json:"code"`
+	Message    string     `json:"message"`
+	Listings   []*Listing `json:"listings"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+// ListingResponse 单个挂牌的响应
+type ListingResponse struct {
+	Code    int      `json:"code"`
+	Message string   `json:"message"`
+	Listing *Listing `json:"listing,omitempty"`
+}
+
+// CollectReturnsRequest 领回退回箱的请求
+type CollectReturnsRequest struct {
+	SharerID string `json:"sharer_id"`
+}
+
+// ReturnsResponse 退回箱的响应
+type ReturnsResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Items   []*ReturnedItem `json:"items"`
+}
+
+// OpenTradeRequest 发起交易的请求
+type OpenTradeRequest struct {
+	InitiatorID    string      `json:"initiator_id"`
+	CounterpartyID string      `json:"counterparty_id"`
+	Offer          []TradeItem `json:"offer"`
+	Request        []TradeWant `json:"request"`
+}
+
+// AcceptTradeRequest 接受交易的请求
+type AcceptTradeRequest struct {
+	AcceptorID string      `json:"acceptor_id"`
+	Items      []TradeItem `json:"items"`
+}
+
+// CancelTradeRequest 取消交易的请求
+type CancelTradeRequest struct {
+	InitiatorID string `json:"initiator_id"`
+}
+
+// TradeResponse 单个交易的响应
+type TradeResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Trade   *Trade `json:"trade,omitempty"`
+}
+
+// TradesResponse 交易列表的响应
+type TradesResponse struct {
+	Code    int      `json:"code"`
+	Message string   `json:"message"`
+	Trades  []*Trade `json:"trades"`
+}
+
+// CreateGroupRequest 创建群组的请求
+type CreateGroupRequest struct {
+	Name    string `json:"name"`
+	OwnerID string `json:"owner_id"`
+}
+
+// InviteGroupRequest 邀请玩家的请求
+type InviteGroupRequest struct {
+	InviterID string `json:"inviter_id"`
+	PlayerID  string `json:"player_id"`
+}
+
+// GroupPlayerRequest 加入或退出群组的请求
+type GroupPlayerRequest struct {
+	PlayerID string `json:"player_id"`
+}
+
+// SetGroupRoleRequest 调整成员角色的请求
+type SetGroupRoleRequest struct {
+	ActorID  string    `json:"actor_id"`
+	PlayerID string    `json:"player_id"`
+	Role     GroupRole `json:"role"`
+}
+
+// RemoveGroupMemberRequest 移除成员或撤回邀请的请求
+type RemoveGroupMemberRequest struct {
+	ActorID  string `json:"actor_id"`
+	PlayerID string `json:"player_id"`
+}
+
+// GroupResponse 单个群组的响应
+type GroupResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Group   *Group `json:"group,omitempty"`
+}
+
+// GroupsResponse 群组列表的响应
+type GroupsResponse struct {
+	Code    int      `json:"code"`
+	Message string   `json:"message"`
+	Groups  []*Group `json:"groups"`
+}
+
+// GroupItemsResponse 群组物品列表的响应
+type GroupItemsResponse struct {
+	Code       int     `json:"code"`
+	Message    string  `json:"message"`
+	Items      []*Item `json:"items"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+// RateLimitStats 单个限流维度的统计
+type RateLimitStats struct {
+	Allowed     int64 `json:"allowed"`
+	Rejected    int64 `json:"rejected"`
+	TrackedKeys int   `json:"tracked_keys"`
+}
+
+// IdempotencyStats 幂等键的统计
+type IdempotencyStats struct {
+	Replayed    int64 `json:"replayed"`
+	Conflicts   int64 `json:"conflicts"`
+	TrackedKeys int   `json:"tracked_keys"`
+}
+
+// HealthResponse 健康检查的响应
+type HealthResponse struct {
+	Status            string                               `json:"status"`
+	Message           string                               `json:"message"`
+	Timestamp         string                               `json:"timestamp"`
+	PendingItemsCount int                                  `json:"pending_items_count"`
+	EventCounts       map[string]int64                     `json:"event_counts,omitempty"`
+	RateLimits        map[string]map[string]RateLimitStats `json:"rate_limits,omitempty"`
+}
+
+// AdminStatusResponse 运维状态的响应
+type AdminStatusResponse struct {
+	Timestamp         string                               `json:"timestamp"`
+	PendingItemsCount int                                  `json:"pending_items_count"`
+	Memory            map[string]interface{}               `json:"memory,omitempty"`
+	RateLimits        map[string]map[string]RateLimitStats `json:"rate_limits,omitempty"`
+	Idempotency       *IdempotencyStats                    `json:"idempotency,omitempty"`
+	EventCounts       map[string]int64                     `json:"event_counts,omitempty"`
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// ItemsResponse 管理接口的物品列表响应
+type ItemsResponse struct {
+	Items []*Item `json:"items"`
+}
+
+// ItemResponse 管理接口的单个物品响应
+type ItemResponse struct {
+	Item *Item `json:"item"`
+}
+
+// MemoryStatus 内存监控状态
+type MemoryStatus struct {
+	CurrentUsageMB  int64   `json:"current_usage_mb"`
+	MaxMemoryMB     int64   `json:"max_memory_mb"`
+	UsagePercentage float64 `json:"usage_percentage"`
+	ShareDisabled   bool    `json:"share_disabled"`
+}
+
+// Item 分享的物品
+type Item struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TypeID      int       `json:"type_id"`
+	Num         int       `json:"num"`
+	Durability  float64   `json:"durability"`
+	SharerID    string    `json:"sharer_id"`
+	PickupCode  string    `json:"pickup_code"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	IsClaimed   bool      `json:"is_claimed"`
+	ClaimerID   string    `json:"claimer_id"`
+	// Reservation 两阶段领取中未确认的预留
+	Reservation *Reservation `json:"reservation,omitempty"`
+	// ListingID 公开上架到市场时的挂牌ID
+	ListingID string `json:"listing_id,omitempty"`
+	// GroupID 分享给群组时的群组ID
+	GroupID string `json:"group_id,omitempty"`
+}
+
+// Reservation 两阶段领取的预留
+type Reservation struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ReturnedItem 过期后退回给分享者的物品
+type ReturnedItem struct {
+	Item       *Item     `json:"item"`
+	ReturnedAt time.Time `json:"returned_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Listing 市场中公开展示的物品，领取者凭挂牌ID领取
+type Listing struct {
+	ListingID   string    `json:"listing_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	TypeID      int       `json:"type_id"`
+	Num         int       `json:"num"`
+	Durability  float64   `json:"durability"`
+	SharerID    string    `json:"sharer_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// TradeStatus 交易状态
+type TradeStatus string
+
+// 交易状态
+const (
+	TradeOpen      TradeStatus = "open"
+	TradeAccepted  TradeStatus = "accepted"
+	TradeCompleted TradeStatus = "completed"
+	TradeCancelled TradeStatus = "cancelled"
+	TradeExpired   TradeStatus = "expired"
+)
+
+// TradeItem 交易中托管或存入的物品
+type TradeItem struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	TypeID      int     `json:"type_id"`
+	Num         int     `json:"num"`
+	Durability  float64 `json:"durability"`
+}
+
+// TradeWant 发起方希望换得的物品，按类型和数量匹配
+type TradeWant struct {
+	TypeID int `json:"type_id"`
+	Num    int `json:"num"`
+}
+
+// Trade 玩家之间的交易
+type Trade struct {
+	ID             string      `json:"id"`
+	InitiatorID    string      `json:"initiator_id"`
+	CounterpartyID string      `json:"counterparty_id,omitempty"`
+	AcceptorID     string      `json:"acceptor_id,omitempty"`
+	Offer          []TradeItem `json:"offer"`
+	Request        []TradeWant `json:"request"`
+	Deposit        []TradeItem `json:"deposit,omitempty"`
+	Status         TradeStatus `json:"status"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ExpiresAt      time.Time   `json:"expires_at"`
+	ClosedAt       *time.Time  `json:"closed_at,omitempty"`
+	// 交付给发起方的物品的取件码，只对发起方可见
+	InitiatorCodes []string `json:"initiator_codes,omitempty"`
+	// 交付给接受方的物品的取件码，只对接受方可见
+	AcceptorCodes []string `json:"acceptor_codes,omitempty"`
+}
+
+// GroupRole 群组成员的角色
+type GroupRole string
+
+// 群组角色
+const (
+	RoleOwner  GroupRole = "owner"
+	RoleAdmin  GroupRole = "admin"
+	RoleMember GroupRole = "member"
+)
+
+// GroupMember 群组成员
+type GroupMember struct {
+	PlayerID string    `json:"player_id"`
+	Role     GroupRole `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// GroupInvite 尚未接受的邀请
+type GroupInvite struct {
+	PlayerID  string    `json:"player_id"`
+	InvitedBy string    `json:"invited_by"`
+	InvitedAt time.Time `json:"invited_at"`
+}
+
+// Group 玩家群组
+type Group struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	OwnerID   string        `json:"owner_id"`
+	CreatedAt time.Time     `json:"created_at"`
+	Members   []GroupMember `json:"members"`
+	Invites   []GroupInvite `json:"invites,omitempty"`
+}
+
+// Snapshot 物品快照，DumpSnapshot 的结果可以直接传给 RestoreSnapshot
+type Snapshot struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Items     []*Item   `json:"items"`
+}
+
+// RestoreResult 恢复快照的结果
+type RestoreResult struct {
+	Restored  int `json:"restored"`  // 成功写入的物品数
+	Expired   int `json:"expired"`   // 已过期而跳过的物品数
+	Conflicts int `json:"conflicts"` // 取件码已被占用而跳过的物品数
+	Rejected  int `json:"rejected"`  // 缺少ID、取件码或分享者而跳过的物品数
+}
+
+// DeliveryStatus Webhook 投递状态
+type DeliveryStatus string
+
+// Webhook 投递状态
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// DeliveryPayload 投递给订阅者的请求体
+type DeliveryPayload struct {
+	DeliveryID string          `json:"delivery_id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Delivery 一次 Webhook 投递的记录
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	URL            string          `json:"url"`
+	EventType      string          `json:"event_type"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	LastStatusCode int             `json:"last_status_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	Payload        DeliveryPayload `json:"payload"`
+}
+
+// DeliveriesResponse Webhook 投递记录的响应
+type DeliveriesResponse struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+// Document 服务端的 OpenAPI 文档，解析文档信息和接口列表，结构定义保留为原始JSON
+type Document struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       DocumentInfo                     `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components json.RawMessage                  `json:"components"`
+}
+
+// DocumentInfo 文档信息
+type DocumentInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// Operation 文档中的单个接口，参数、请求体和响应保留为原始JSON
+type Operation struct {
+	OperationID string                     `json:"operationId"`
+	Summary     string                     `json:"summary"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Parameters  json.RawMessage            `json:"parameters,omitempty"`
+	RequestBody json.RawMessage            `json:"requestBody,omitempty"`
+	Responses   map[string]json.RawMessage `json:"responses"`
+	Security    []map[string][]string      `json:"security,omitempty"`
+}
+
+// Operation 返回路径和方法对应的接口，method 为小写的 HTTP 方法，不存在时返回 nil
+func (d *Document) Operation(method, path string) *Operation {
+	return d.Paths[path][method]
+}
@@ -0,0 +1,414 @@
+// DuckEx gRPC 接口，与 HTTP 接口共用同一仓库和业务逻辑
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: duckex/v1/duckex.proto
+
+package duckexpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	DuckEx_Share_FullMethodName       = "/duckex.v1.DuckEx/Share"
+	DuckEx_Claim_FullMethodName       = "/duckex.v1.DuckEx/Claim"
+	DuckEx_Reserve_FullMethodName     = "/duckex.v1.DuckEx/Reserve"
+	DuckEx_Confirm_FullMethodName     = "/duckex.v1.DuckEx/Confirm"
+	DuckEx_Release_FullMethodName     = "/duckex.v1.DuckEx/Release"
+	DuckEx_Cancel_FullMethodName      = "/duckex.v1.DuckEx/Cancel"
+	DuckEx_Lookup_FullMethodName      = "/duckex.v1.DuckEx/Lookup"
+	DuckEx_WatchEvents_FullMethodName = "/duckex.v1.DuckEx/WatchEvents"
+)
+
+// DuckExClient is the client API for DuckEx service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type DuckExClient interface {
+	// Share 分享物品，返回取件码
+	Share(ctx context.Context, in *ShareRequest, opts ...grpc.CallOption) (*ShareResponse, error)
+	// Claim 领取物品
+	Claim(ctx context.Context, in *ClaimRequest, opts ...grpc.CallOption) (*ClaimResponse, error)
+	// Reserve 预留物品，返回预留令牌，物品在确认前保留给领取者
+	Reserve(ctx context.Context, in *ReserveRequest, opts ...grpc.CallOption) (*ReserveResponse, error)
+	// Confirm 确认预留，物品被领走
+	Confirm(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*ClaimResponse, error)
+	// Release 取消预留，物品恢复为可领取
+	Release(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*ClaimResponse, error)
+	// Cancel 取消分享，物品退回到分享者的退回箱（需要管理令牌）
+	Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error)
+	// Lookup 按取件码查看物品，不会领取物品（需要管理令牌）
+	Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error)
+	// WatchEvents 订阅事件，未指定 sharer_id 时推送全部事件（需要管理令牌）
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (DuckEx_WatchEventsClient, error)
+}
+
+type duckExClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDuckExClient(cc grpc.ClientConnInterface) DuckExClient {
+	return &duckExClient{cc}
+}
+
+func (c *duckExClient) Share(ctx context.Context, in *ShareRequest, opts ...grpc.CallOption) (*ShareResponse, error) {
+	out := new(ShareResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Share_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) Claim(ctx context.Context, in *ClaimRequest, opts ...grpc.CallOption) (*ClaimResponse, error) {
+	out := new(ClaimResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Claim_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) Reserve(ctx context.Context, in *ReserveRequest, opts ...grpc.CallOption) (*ReserveResponse, error) {
+	out := new(ReserveResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Reserve_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) Confirm(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*ClaimResponse, error) {
+	out := new(ClaimResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Confirm_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) Release(ctx context.Context, in *ReservationRequest, opts ...grpc.CallOption) (*ClaimResponse, error) {
+	out := new(ClaimResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Release_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) Cancel(ctx context.Context, in *CancelRequest, opts ...grpc.CallOption) (*CancelResponse, error) {
+	out := new(CancelResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Cancel_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) Lookup(ctx context.Context, in *LookupRequest, opts ...grpc.CallOption) (*LookupResponse, error) {
+	out := new(LookupResponse)
+	err := c.cc.Invoke(ctx, DuckEx_Lookup_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *duckExClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (DuckEx_WatchEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &DuckEx_ServiceDesc.Streams[0], DuckEx_WatchEvents_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &duckExWatchEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type DuckEx_WatchEventsClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type duckExWatchEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *duckExWatchEventsClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// DuckExServer is the server API for DuckEx service.
+// All implementations must embed UnimplementedDuckExServer
+// for forward compatibility
+type DuckExServer interface {
+	// Share 分享物品，返回取件码
+	Share(context.Context, *ShareRequest) (*ShareResponse, error)
+	// Claim 领取物品
+	Claim(context.Context, *ClaimRequest) (*ClaimResponse, error)
+	// Reserve 预留物品，返回预留令牌，物品在确认前保留给领取者
+	Reserve(context.Context, *ReserveRequest) (*ReserveResponse, error)
+	// Confirm 确认预留，物品被领走
+	Confirm(context.Context, *ReservationRequest) (*ClaimResponse, error)
+	// Release 取消预留，物品恢复为可领取
+	Release(context.Context, *ReservationRequest) (*ClaimResponse, error)
+	// Cancel 取消分享，物品退回到分享者的退回箱（需要管理令牌）
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+	// Lookup 按取件码查看物品，不会领取物品（需要管理令牌）
+	Lookup(context.Context, *LookupRequest) (*LookupResponse, error)
+	// WatchEvents 订阅事件，未指定 sharer_id 时推送全部事件（需要管理令牌）
+	WatchEvents(*WatchEventsRequest, DuckEx_WatchEventsServer) error
+	mustEmbedUnimplementedDuckExServer()
+}
+
+// UnimplementedDuckExServer must be embedded to have forward compatible implementations.
+type UnimplementedDuckExServer struct {
+}
+
+func (UnimplementedDuckExServer) Share(context.Context, *ShareRequest) (*ShareResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Share not implemented")
+}
+func (UnimplementedDuckExServer) Claim(context.Context, *ClaimRequest) (*ClaimResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Claim not implemented")
+}
+func (UnimplementedDuckExServer) Reserve(context.Context, *ReserveRequest) (*ReserveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Reserve not implemented")
+}
+func (UnimplementedDuckExServer) Confirm(context.Context, *ReservationRequest) (*ClaimResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Confirm not implemented")
+}
+func (UnimplementedDuckExServer) Release(context.Context, *ReservationRequest) (*ClaimResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Release not implemented")
+}
+func (UnimplementedDuckExServer) Cancel(context.Context, *CancelRequest) (*CancelResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Cancel not implemented")
+}
+func (UnimplementedDuckExServer) Lookup(context.Context, *LookupRequest) (*LookupResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lookup not implemented")
+}
+func (UnimplementedDuckExServer) WatchEvents(*WatchEventsRequest, DuckEx_WatchEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedDuckExServer) mustEmbedUnimplementedDuckExServer() {}
+
+// UnsafeDuckExServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DuckExServer will
+// result in compilation errors.
+type UnsafeDuckExServer interface {
+	mustEmbedUnimplementedDuckExServer()
+}
+
+func RegisterDuckExServer(s grpc.ServiceRegistrar, srv DuckExServer) {
+	s.RegisterService(&DuckEx_ServiceDesc, srv)
+}
+
+func _DuckEx_Share_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ShareRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Share(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Share_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Share(ctx, req.(*ShareRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_Claim_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Claim(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Claim_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Claim(ctx, req.(*ClaimRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_Reserve_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReserveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Reserve(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Reserve_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Reserve(ctx, req.(*ReserveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_Confirm_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Confirm(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Confirm_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Confirm(ctx, req.(*ReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_Release_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReservationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Release(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Release_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Release(ctx, req.(*ReservationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_Cancel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Cancel_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_Lookup_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LookupRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DuckExServer).Lookup(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: DuckEx_Lookup_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DuckExServer).Lookup(ctx, req.(*LookupRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _DuckEx_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DuckExServer).WatchEvents(m, &duckExWatchEventsServer{stream})
+}
+
+type DuckEx_WatchEventsServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type duckExWatchEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *duckExWatchEventsServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// DuckEx_ServiceDesc is the grpc.ServiceDesc for DuckEx service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var DuckEx_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "duckex.v1.DuckEx",
+	HandlerType: (*DuckExServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Share",
+			Handler:    _DuckEx_Share_Handler,
+		},
+		{
+			MethodName: "Claim",
+			Handler:    _DuckEx_Claim_Handler,
+		},
+		{
+			MethodName: "Reserve",
+			Handler:    _DuckEx_Reserve_Handler,
+		},
+		{
+			MethodName: "Confirm",
+			Handler:    _DuckEx_Confirm_Handler,
+		},
+		{
+			MethodName: "Release",
+			Handler:    _DuckEx_Release_Handler,
+		},
+		{
+			MethodName: "Cancel",
+			Handler:    _DuckEx_Cancel_Handler,
+		},
+		{
+			MethodName: "Lookup",
+			Handler:    _DuckEx_Lookup_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _DuckEx_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "duckex/v1/duckex.proto",
+}
@@ -0,0 +1,4 @@
+// Package duckexpb 由 proto/duckex/v1/duckex.proto 生成的 gRPC 代码
+package duckexpb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=duckex-server --go-grpc_out=../.. --go-grpc_opt=module=duckex-server duckex/v1/duckex.proto